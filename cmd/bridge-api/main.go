@@ -1,13 +1,29 @@
 package main
 
 import (
+	"context"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/mvg-fi-dev/bridge/internal/api"
 	"github.com/mvg-fi-dev/bridge/internal/config"
 	"github.com/mvg-fi-dev/bridge/internal/db"
+	"github.com/mvg-fi-dev/bridge/internal/executor"
+	"github.com/mvg-fi-dev/bridge/internal/health"
+	"github.com/mvg-fi-dev/bridge/internal/ledger"
+	"github.com/mvg-fi-dev/bridge/internal/limits"
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/metrics"
+	"github.com/mvg-fi-dev/bridge/internal/orderfeed"
+	"github.com/mvg-fi-dev/bridge/internal/pricing"
+	"github.com/mvg-fi-dev/bridge/internal/ratelimit"
+	"github.com/mvg-fi-dev/bridge/internal/screening"
+	"github.com/mvg-fi-dev/bridge/internal/tracing"
 )
 
 func main() {
@@ -15,25 +31,83 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := logging.Setup(os.Stderr, cfg.LogFormat, cfg.LogLevel); err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracesExporter, "bridge-api")
+	if err != nil {
+		logging.Fatal("tracing", "err", err)
+	}
+	defer shutdownTracing(context.Background())
 
-	dbConn, err := db.Open(cfg.SQLitePath)
+	dbConn, err := db.Open(cfg.DBDriver, cfg.DBSource())
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("open db", "err", err)
 	}
 	defer dbConn.SQL.Close()
 
-	if err := db.Migrate(dbConn.SQL); err != nil {
-		log.Fatal(err)
+	if err := db.Migrate(dbConn); err != nil {
+		logging.Fatal("migrate", "err", err)
 	}
 
 	r := gin.New()
 	r.Use(gin.Recovery())
+	// Only trust X-Forwarded-For from configured proxies; otherwise clients
+	// could pick their own rate-limit bucket.
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logging.Fatal("trusted proxies", "err", err)
+	}
 
-	s := &api.Server{DB: dbConn.SQL, PayWindowSeconds: cfg.PayWindowSeconds, MixinBotUserID: cfg.MixinBotUserID, MixinWebhookSecret: cfg.MixinWebhookSecret}
+	s := &api.Server{Orders: db.NewOrdersRepo(dbConn), PayWindowSeconds: cfg.PayWindowSeconds, MixinBotUserID: cfg.MixinBotUserID, MixinWebhookSecret: cfg.MixinWebhookSecret}
+	s.Admin = db.NewAdminRepo(dbConn)
+	s.AdminTokens = cfg.AdminTokens
+	s.KV = db.NewStateRepo(dbConn)
+	s.Blocklist = db.NewBlocklistRepo(dbConn)
+	s.Screener = screening.New(s.Blocklist, cfg.ScreeningBlocklistFile)
+	s.Limits = limits.NewChecker(s.KV, s.Orders, pricing.NewMixinTicker(time.Minute), time.Duration(cfg.PayWindowSeconds)*time.Second)
+	s.Partners = db.NewPartnerRepo(dbConn)
+	s.RequireAPIKey = cfg.RequireAPIKey
+	s.Webhooks = db.NewWebhookRepo(dbConn)
+	s.AllowInsecureWebhooks = cfg.WebhookAllowHTTP
+	s.OrderEvents = db.NewOrderEventRepo(dbConn)
+	s.OrderFeed = orderfeed.New(s.OrderEvents, time.Duration(cfg.OrderStreamPollMs)*time.Millisecond)
+	s.MaxOrderStreams = int(cfg.OrderStreamMax)
+	go s.OrderFeed.Run(context.Background())
+	s.Ledger = ledger.New(db.NewLedgerRepo(dbConn), s.Orders, executor.ExinSwapBotUserID)
+	switch cfg.RateLimitStore {
+	case "memory":
+		s.RateLimiter = ratelimit.NewMemory()
+	case "db":
+		s.RateLimiter = db.NewRateLimitRepo(dbConn)
+	}
+	s.RateLimitIP = ratelimit.PerMinute(cfg.RateLimitIPPerMin, cfg.RateLimitIPBurst)
+	s.RateLimitKey = ratelimit.PerMinute(cfg.RateLimitKeyPerMin, cfg.RateLimitKeyBurst)
+	s.MaxBodyBytes = cfg.MaxBodyBytes
+	s.ReadyChecks = []health.Check{
+		health.DB(dbConn),
+		health.Migrations(dbConn),
+		health.Switches(s.KV),
+		health.Heartbeats(s.KV, time.Duration(cfg.PollStaleSeconds)*time.Second),
+		health.Venues(),
+	}
 	s.Register(r)
+	metrics.RegisterOrderStatus(s.Orders)
 
-	log.Printf("bridge-api listening on :%s", cfg.Port)
+	// Metrics reveal volumes and venue health, so they get their own
+	// listener instead of the public engine.
+	if cfg.APIMetricsPort != "" && cfg.APIMetricsPort != "0" {
+		go func() {
+			slog.Info("bridge-api metrics listening", "addr", ":"+cfg.APIMetricsPort)
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Handler())
+			if err := http.ListenAndServe(":"+cfg.APIMetricsPort, mux); err != nil {
+				slog.Error("metrics server failed", "err", err)
+			}
+		}()
+	}
+
+	slog.Info("bridge-api listening", "addr", ":"+cfg.Port)
 	if err := r.Run(":" + cfg.Port); err != nil {
-		log.Fatal(err)
+		logging.Fatal("listen", "err", err)
 	}
 }
@@ -3,14 +3,35 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mvg-fi-dev/bridge/internal/alerts"
 	"github.com/mvg-fi-dev/bridge/internal/config"
 	"github.com/mvg-fi-dev/bridge/internal/db"
 	"github.com/mvg-fi-dev/bridge/internal/executor"
+	"github.com/mvg-fi-dev/bridge/internal/health"
+	"github.com/mvg-fi-dev/bridge/internal/ledger"
+	"github.com/mvg-fi-dev/bridge/internal/limits"
+	"github.com/mvg-fi-dev/bridge/internal/liquidity"
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/metrics"
 	"github.com/mvg-fi-dev/bridge/internal/mixin"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/pricing"
+	"github.com/mvg-fi-dev/bridge/internal/reconcile"
+	"github.com/mvg-fi-dev/bridge/internal/retry"
+	"github.com/mvg-fi-dev/bridge/internal/screening"
+	"github.com/mvg-fi-dev/bridge/internal/switches"
+	"github.com/mvg-fi-dev/bridge/internal/tracing"
+	"github.com/mvg-fi-dev/bridge/internal/webhooks"
 )
 
 const cursorKey = "mixin.snapshots.offset"
@@ -20,32 +41,40 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := logging.Setup(os.Stderr, cfg.LogFormat, cfg.LogLevel); err != nil {
+		log.Fatal(err)
+	}
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracesExporter, "bridge-worker")
+	if err != nil {
+		logging.Fatal("tracing", "err", err)
+	}
+	defer shutdownTracing(context.Background())
 
-	dbConn, err := db.Open(cfg.SQLitePath)
+	dbConn, err := db.Open(cfg.DBDriver, cfg.DBSource())
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("open db", "err", err)
 	}
 	defer dbConn.SQL.Close()
-	if err := db.Migrate(dbConn.SQL); err != nil {
-		log.Fatal(err)
+	if err := db.Migrate(dbConn); err != nil {
+		logging.Fatal("migrate", "err", err)
 	}
 
 	ksPath := os.Getenv("MIXIN_KEYSTORE_PATH")
 	if ksPath == "" {
-		log.Fatal("MIXIN_KEYSTORE_PATH is required for snapshot polling (keystore json from Mixin dashboard)")
+		logging.Fatal("MIXIN_KEYSTORE_PATH is required for snapshot polling (keystore json from Mixin dashboard)")
 	}
 	ksBytes, err := os.ReadFile(ksPath)
 	if err != nil {
-		log.Fatalf("read keystore: %v", err)
+		logging.Fatal("read keystore", "path", ksPath, "err", err)
 	}
 	ks, err := mixin.ParseSafeKeystore(ksBytes)
 	if err != nil {
-		log.Fatalf("parse keystore: %v", err)
+		logging.Fatal("parse keystore", "path", ksPath, "err", err)
 	}
+	logging.AddSecrets(ks.PrivateKey, ks.SpendPrivateKey)
 	client := mixin.NewSDKClient(ks)
-	state := db.NewStateRepo(dbConn.SQL)
-	snapRepo := db.NewSnapshotsRepo(dbConn.SQL)
-	ordersRepo := db.NewOrdersRepo(dbConn.SQL)
+	state := db.NewStateRepo(dbConn)
+	ordersRepo := db.NewOrdersRepo(dbConn)
 
 	interval := 3 * time.Second
 	if v := os.Getenv("MIXIN_POLL_INTERVAL_MS"); v != "" {
@@ -61,24 +90,147 @@ func main() {
 	if cfg.ExinSwapLatestExecSeconds > 0 {
 		execSwap.SwapTimeoutSeconds = cfg.ExinSwapLatestExecSeconds
 	}
-
-	// ExinSwap snapshot reconciler (result memo -> order state)
-	recSwap := executor.NewReconcileExinSwapSnapshots(ordersRepo)
+	execSwap.Limits = limits.NewChecker(state, ordersRepo, pricing.NewMixinTicker(time.Minute), time.Duration(cfg.PayWindowSeconds)*time.Second)
 
 	// Withdrawal executor
 	execW := executor.NewWithdrawExecutor(ordersRepo, client)
 	// Refund executor
 	execR := executor.NewRefundExecutor(ordersRepo, client)
 
-	log.Printf("bridge-worker polling mixin snapshots every %s", interval)
+	// Screen payout/refund addresses right before funds move.
+	screener := screening.New(db.NewBlocklistRepo(dbConn), cfg.ScreeningBlocklistFile)
+	execW.Screener = screener
+	execR.Screener = screener
+
+	// Submitted transfers are booked in the ledger as soon as they are sent.
+	book := ledger.New(db.NewLedgerRepo(dbConn), ordersRepo, executor.ExinSwapBotUserID)
+	execSwap.Ledger = book
+	execW.Ledger = book
+	execR.Ledger = book
+
+	// Wallet balances (safe unspent outputs) feed reconciliation and the
+	// payout liquidity checks.
+	var balances reconcile.BalanceSource = client
+	var withdrawalFees liquidity.FeeSource = client
+	if cfg.MixinFakeBalancesFile != "" {
+		slog.Warn("using fake wallet balances", "path", cfg.MixinFakeBalancesFile)
+		balances = &mixin.FakeBalances{Path: cfg.MixinFakeBalancesFile}
+		withdrawalFees = nil
+	}
+
+	// Payouts the wallet cannot cover wait in awaiting_liquidity.
+	liq := liquidity.NewChecker(balances, withdrawalFees, state, time.Duration(cfg.LiquidityCacheSeconds)*time.Second)
+	execW.Liquidity = liq
+	execR.Liquidity = liq
+
+	// Periodically compare the ledger with the wallet's unspent outputs.
+	var reconciler *reconcile.Reconciler
+	if cfg.ReconcileIntervalSeconds > 0 {
+		reconciler = reconcile.New(book, balances, state, cfg.ReconcileTolerance, int(cfg.ReconcileConfirmRuns))
+	}
+	reconcileEvery := time.Duration(cfg.ReconcileIntervalSeconds) * time.Second
+	nextReconcile := time.Now()
+
+	// Failed withdraw/refund attempts are backed off per error class and
+	// escalated to failed_manual_review once the budget is exhausted.
+	retrier := executor.NewRetrier(ordersRepo, executor.DefaultRetryPolicies(
+		cfg.RetryMaxAttempts,
+		time.Duration(cfg.RetryBaseDelaySeconds)*time.Second,
+		time.Duration(cfg.RetryMaxDelaySeconds)*time.Second,
+	))
+
+	// Row leases let several worker replicas share the stages below.
+	leaser := executor.NewLeaser(ordersRepo, cfg.WorkerID, time.Duration(cfg.WorkerLeaseSeconds)*time.Second)
+
+	// Health: /healthz only fails on a wedged poller (restart it), /readyz
+	// runs every check.
+	poll := &health.Poll{}
+	pollCheck := poll.Check(time.Now(), time.Duration(cfg.PollStaleSeconds)*time.Second)
+
+	metrics.RegisterOrderStatus(ordersRepo)
+	if cfg.MetricsPort != "" && cfg.MetricsPort != "0" {
+		go func() {
+			slog.Info("bridge-worker http listening", "addr", ":"+cfg.MetricsPort)
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Handler())
+			mux.Handle("/healthz", health.Handler(pollCheck))
+			mux.Handle("/readyz", health.Handler(
+				health.DB(dbConn),
+				health.Migrations(dbConn),
+				health.Keystore(ks),
+				pollCheck,
+				health.Switches(state),
+				health.Venues(),
+			))
+			if err := http.ListenAndServe(":"+cfg.MetricsPort, mux); err != nil {
+				slog.Error("metrics server failed", "err", err)
+			}
+		}()
+	}
+
+	// Alert rules run on their own ticker so a wedged poll loop still alerts.
+	if cfg.AlertIntervalSeconds > 0 {
+		var sinks []alerts.Sink
+		if cfg.AlertStdout {
+			sinks = append(sinks, &alerts.Stdout{})
+		}
+		if cfg.AlertWebhookURL != "" {
+			sinks = append(sinks, alerts.NewWebhook(cfg.AlertWebhookURL))
+		}
+		if cfg.AlertMixinConversationID != "" {
+			sinks = append(sinks, &alerts.Mixin{Sender: client, ConversationID: cfg.AlertMixinConversationID})
+		}
+		evaluator := alerts.NewEvaluator(ordersRepo, state, int(cfg.ReconcileConfirmRuns), sinks...)
+		go func() {
+			for range time.Tick(time.Duration(cfg.AlertIntervalSeconds) * time.Second) {
+				ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+				ctx, span := tracing.Start(ctx, "worker.alerts")
+				_, err := evaluator.Run(ctx)
+				tracing.End(span, err)
+				if err != nil {
+					slog.ErrorContext(ctx, "alert evaluation failed", "err", err)
+				}
+				cancel()
+			}
+		}()
+	}
+
+	// Partner webhooks: fan the order change feed out to registered
+	// endpoints and send due deliveries. Replicas claim deliveries, so this
+	// is safe to run everywhere.
+	dispatcher := webhooks.NewDispatcher(db.NewWebhookRepo(dbConn), ordersRepo, retry.Policy{
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		BaseDelay:   time.Duration(cfg.WebhookBaseDelaySeconds) * time.Second,
+		MaxDelay:    time.Duration(cfg.WebhookMaxDelaySeconds) * time.Second,
+	}, cfg.WebhookAllowPrivate)
+	go func() {
+		for range time.Tick(interval) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			ctx, span := tracing.Start(ctx, "worker.webhooks")
+			err := dispatcher.Run(ctx, 100)
+			tracing.End(span, err)
+			if err != nil {
+				slog.ErrorContext(ctx, "webhook dispatch failed", "err", err)
+			}
+			cancel()
+		}
+	}()
+
+	slog.Info("bridge-worker polling mixin snapshots", "worker", cfg.WorkerID, "interval", interval.String())
 
 	for {
 		ctx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+		ctx, tick := tracing.Start(ctx, "worker.tick", attribute.String("worker", cfg.WorkerID))
 		offset, _, _ := state.Get(ctx, cursorKey)
 
-		snaps, err := client.ListSafeSnapshots(ctx, limit, offset)
+		vctx, span := tracing.Start(ctx, "mixin.list_snapshots")
+		pollStart := time.Now()
+		snaps, err := client.ListSafeSnapshots(vctx, limit, offset)
+		metrics.ObserveVenue("mixin", "list_snapshots", pollStart, err)
+		tracing.End(span, err)
 		if err != nil {
-			log.Printf("poll error: %v", err)
+			slog.ErrorContext(ctx, "poll failed", "err", err)
+			tracing.End(tick, err)
 			cancel()
 			time.Sleep(interval)
 			continue
@@ -86,71 +238,218 @@ func main() {
 
 		// Mixin snapshots are usually returned in reverse-chronological order.
 		// We'll process from oldest to newest within this batch.
+		// Each snapshot is ingested in one transaction: the snapshot row, the
+		// order updates it causes and the cursor commit together. On error we
+		// stop the batch so the cursor never moves past an unapplied snapshot.
+		caughtUp := len(snaps) < limit
+		ingested := true
+		var newest time.Time
 		for i := len(snaps) - 1; i >= 0; i-- {
 			s := snaps[i]
 			internalSnap := mixin.SafeSnapshotToInternal(s)
 			raw, _ := json.Marshal(map[string]any{"data": internalSnap})
-			inserted, err := snapRepo.InsertIfNew(ctx, s.SnapshotID, time.Now().UTC(), string(raw), internalSnap)
+			credited := false
+			ctx, span := tracing.Start(logging.With(ctx, logging.SnapshotID, s.SnapshotID), "worker.ingest_snapshot", attribute.String(logging.SnapshotID, s.SnapshotID))
+			err := dbConn.InTx(ctx, func(tx *db.DB) error {
+				inserted, err := db.NewSnapshotsRepo(tx).InsertIfNew(ctx, s.SnapshotID, time.Now().UTC(), string(raw), internalSnap)
+				if err != nil {
+					return fmt.Errorf("insert snapshot: %w", err)
+				}
+				if inserted {
+					txOrders := db.NewOrdersRepo(tx)
+					// Try match order by memo for Mixin-internal payments.
+					if s.Memo != "" {
+						creditedAt := s.CreatedAt.UTC()
+						n, err := txOrders.SetDepositCreditedByMemo(ctx, s.Memo, s.SnapshotID, creditedAt, s.Amount, s.AssetID, s.OpponentID)
+						if err != nil {
+							return fmt.Errorf("credit by memo: %w", err)
+						}
+						credited = n > 0
+					}
+
+					// Reconcile ExinSwap result memos (credits from ExinSwap bot back to us).
+					if err := executor.NewReconcileExinSwapSnapshots(txOrders).HandleSnapshot(ctx, internalSnap); err != nil {
+						return fmt.Errorf("reconcile exinswap: %w", err)
+					}
+
+					// Book the movement after the order updates above so the
+					// ledger sees the deposit / result it belongs to.
+					if err := ledger.New(db.NewLedgerRepo(tx), txOrders, executor.ExinSwapBotUserID).PostSnapshot(ctx, internalSnap); err != nil {
+						return fmt.Errorf("post ledger: %w", err)
+					}
+				}
+				// advance cursor to newest snapshot id we saw
+				return db.NewStateRepo(tx).Set(ctx, cursorKey, s.SnapshotID)
+			})
+			tracing.End(span, err)
 			if err != nil {
-				log.Printf("insert snapshot err=%v", err)
-				continue
+				slog.ErrorContext(ctx, "ingest snapshot failed", "err", err)
+				caughtUp, ingested = false, false
+				break
 			}
-			if inserted {
-				// Try match order by memo for Mixin-internal payments.
-				if s.Memo != "" {
-					creditedAt := s.CreatedAt.UTC()
-					_, _ = ordersRepo.SetDepositCreditedByMemo(ctx, s.Memo, s.SnapshotID, creditedAt, s.Amount, s.AssetID, s.OpponentID)
+			newest = s.CreatedAt
+			if credited {
+				if o, err := ordersRepo.GetByDepositTxID(ctx, s.SnapshotID); err == nil {
+					slog.InfoContext(logging.WithOrder(ctx, o), "deposit credited", "asset", s.AssetID, "amount", s.Amount)
+					metrics.ObserveCredited(o)
 				}
-
-				// Reconcile ExinSwap result memos (credits from ExinSwap bot back to us).
-				recSwap.HandleSnapshot(ctx, internalSnap)
 			}
+		}
+		metrics.ObservePoll(time.Now(), newest)
+		// A poll only counts for health once its snapshots were ingested: a
+		// worker stuck on one snapshot is as wedged as one that cannot poll.
+		if ingested {
+			poll.Record(time.Now())
+			if err := health.SaveHeartbeat(ctx, state, health.Heartbeat{WorkerID: cfg.WorkerID, LastPollAt: time.Now().UTC()}, time.Duration(cfg.PollStaleSeconds)*time.Second); err != nil {
+				slog.WarnContext(ctx, "save heartbeat failed", "err", err)
+			}
+		}
 
-			// advance cursor to newest snapshot id we saw
-			offset = s.SnapshotID
+		// Reconcile only once every snapshot seen so far is in the ledger, and
+		// before switches are read so a pause applies to this tick.
+		if reconciler != nil && caughtUp && !time.Now().Before(nextReconcile) {
+			nextReconcile = time.Now().Add(reconcileEvery)
+			if rep, err := reconciler.Run(ctx); err != nil {
+				slog.ErrorContext(ctx, "reconcile failed", "err", err)
+			} else {
+				expected := map[string]string{}
+				for _, a := range rep.Assets {
+					expected[a.AssetID] = a.Expected
+				}
+				metrics.SetLedgerBalances(expected)
+				if !rep.OK {
+					slog.WarnContext(ctx, "reconcile mismatch", "streak", rep.Streak)
+				}
+			}
 		}
 
-		if offset != "" {
-			_ = state.Set(ctx, cursorKey, offset)
+		// Kill-switches are re-read every tick so operators can pause a stage
+		// without restarting. If they cannot be read we fail closed.
+		gate, gateErr := switches.StageGate(ctx, state)
+		if gateErr != nil {
+			slog.ErrorContext(ctx, "load switches failed; pausing all stages this tick", "err", gateErr)
+		}
+		paused := func(stage models.Stage) bool {
+			s, off := gate(stage)
+			if off && gateErr == nil {
+				slog.InfoContext(ctx, "stage paused", logging.Stage, string(stage), "operator", s.Operator, "reason", s.Reason)
+			}
+			return off
+		}
+		listUnlessPaused := func(stage models.Stage, list func(context.Context, int) ([]*models.Order, error)) ([]*models.Order, error) {
+			if paused(stage) {
+				return nil, nil
+			}
+			return list(ctx, 20)
 		}
 
-		// Execute any deposit_credited orders.
-		orders, err := ordersRepo.ListExecutable(ctx, 20)
+		// Execute deposit_credited orders and due swap resubmits.
+		orders, err := listUnlessPaused(models.StageSwap, ordersRepo.ListExecutable)
 		if err != nil {
-			log.Printf("list executable err=%v", err)
+			slog.ErrorContext(ctx, "list executable failed", "err", err)
 		} else {
 			for _, o := range orders {
-				if err := execSwap.ExecuteDepositCredited(ctx, o); err != nil {
-					log.Printf("execute order=%s err=%v", o.PublicID, err)
+				ctx := logging.WithStage(ctx, o, models.StageSwap)
+				o, ok, err := leaser.Acquire(ctx, o)
+				if err != nil {
+					slog.ErrorContext(ctx, "lease failed", "err", err)
+					continue
+				}
+				if !ok {
+					continue // leased by another worker or already moved on
 				}
+				ctx, span := orderSpan(ctx, o, models.StageSwap)
+				err = execSwap.ExecuteDepositCredited(ctx, o)
+				tracing.End(span, err)
+				if err != nil {
+					slog.ErrorContext(ctx, "swap failed", "err", err)
+					if err := retrier.Fail(ctx, o, models.StageSwap, err); err != nil {
+						slog.ErrorContext(ctx, "record retry failed", "err", err)
+					}
+				}
+				leaser.Release(ctx, o)
 			}
 		}
 
+		// Low-balance warnings, and parked payouts the wallet can cover again.
+		if _, err := liq.Warn(ctx); err != nil {
+			slog.ErrorContext(ctx, "liquidity warn failed", "err", err)
+		}
+		if err := executor.ResumeLiquidity(ctx, liq, ordersRepo, 20); err != nil {
+			slog.ErrorContext(ctx, "liquidity resume failed", "err", err)
+		}
+
 		// Execute withdrawals.
-		wos, err := ordersRepo.ListWithdrawing(ctx, 20)
+		wos, err := listUnlessPaused(models.StageWithdraw, ordersRepo.ListWithdrawing)
 		if err != nil {
-			log.Printf("list withdrawing err=%v", err)
+			slog.ErrorContext(ctx, "list withdrawing failed", "err", err)
 		} else {
 			for _, o := range wos {
-				if err := execW.ExecuteWithdrawing(ctx, o); err != nil {
-					log.Printf("withdraw order=%s err=%v", o.PublicID, err)
+				ctx := logging.WithStage(ctx, o, models.StageWithdraw)
+				o, ok, err := leaser.Acquire(ctx, o)
+				if err != nil {
+					slog.ErrorContext(ctx, "lease failed", "err", err)
+					continue
 				}
+				if !ok {
+					continue // leased by another worker or already moved on
+				}
+				ctx, span := orderSpan(ctx, o, models.StageWithdraw)
+				err = execW.ExecuteWithdrawing(ctx, o)
+				tracing.End(span, err)
+				if err != nil {
+					slog.ErrorContext(ctx, "withdraw failed", "err", err)
+					if err := retrier.Fail(ctx, o, models.StageWithdraw, err); err != nil {
+						slog.ErrorContext(ctx, "record retry failed", "err", err)
+					}
+				}
+				leaser.Release(ctx, o)
 			}
 		}
 
 		// Execute refunds (when we decide to actively refund).
-		ros, err := ordersRepo.ListRefunding(ctx, 20)
+		ros, err := listUnlessPaused(models.StageRefund, ordersRepo.ListRefunding)
 		if err != nil {
-			log.Printf("list refunding err=%v", err)
+			slog.ErrorContext(ctx, "list refunding failed", "err", err)
 		} else {
 			for _, o := range ros {
-				if err := execR.ExecuteRefunding(ctx, o); err != nil {
-					log.Printf("refund order=%s err=%v", o.PublicID, err)
+				ctx := logging.WithStage(ctx, o, models.StageRefund)
+				o, ok, err := leaser.Acquire(ctx, o)
+				if err != nil {
+					slog.ErrorContext(ctx, "lease failed", "err", err)
+					continue
+				}
+				if !ok {
+					continue // leased by another worker or already moved on
+				}
+				ctx, span := orderSpan(ctx, o, models.StageRefund)
+				err = execR.ExecuteRefunding(ctx, o)
+				tracing.End(span, err)
+				if err != nil {
+					slog.ErrorContext(ctx, "refund failed", "err", err)
+					if err := retrier.Fail(ctx, o, models.StageRefund, err); err != nil {
+						slog.ErrorContext(ctx, "record retry failed", "err", err)
+					}
 				}
+				leaser.Release(ctx, o)
 			}
 		}
 
+		tick.End()
 		cancel()
 		time.Sleep(interval)
 	}
 }
+
+// orderSpan starts the span for running o's stage, linked to the API request
+// that created the order.
+func orderSpan(ctx context.Context, o *models.Order, stage models.Stage) (context.Context, trace.Span) {
+	tp := ""
+	if o.TraceContext != nil {
+		tp = *o.TraceContext
+	}
+	return tracing.StartLinked(ctx, "worker."+string(stage), tp,
+		attribute.String(logging.OrderID, o.ID),
+		attribute.String(logging.PublicID, o.PublicID),
+	)
+}
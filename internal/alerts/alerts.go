@@ -0,0 +1,346 @@
+// Package alerts evaluates the operator alert rules in the worker and
+// notifies sinks (webhook, Mixin group message, stdout) when an alert starts
+// firing and again when it resolves.
+//
+// Rule thresholds live in the kv table (key "alerts") and are edited via the
+// admin API. The alerts currently firing are stored under StateKey, so a
+// restart neither repeats a notification nor forgets to send the resolve.
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+const (
+	kvKey = "alerts"
+
+	// StateKey is the kv key holding the alerts currently firing.
+	StateKey = "alerts.state"
+)
+
+// Rule names; an alert's Key starts with its rule.
+const (
+	RuleStuckOrders       = "stuck_orders"
+	RuleRefundRate        = "refund_rate"
+	RulePollStale         = "poll_stale"
+	RuleReconcileMismatch = "reconcile_mismatch"
+	RuleLiquidityLow      = "liquidity_low"
+	RuleLiquidityHold     = "liquidity_hold"
+)
+
+type Severity string
+
+const (
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Alert statuses as sent to sinks.
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// stuckStatuses are the statuses an order can sit in waiting on the bridge.
+var stuckStatuses = map[models.OrderStatus]bool{
+	models.StatusAwaitingDeposit:     true,
+	models.StatusDepositDetected:     true,
+	models.StatusDepositPendingMixin: true,
+	models.StatusDepositCredited:     true,
+	models.StatusExecutingSwap:       true,
+	models.StatusWithdrawing:         true,
+	models.StatusRefunding:           true,
+	models.StatusAwaitingLiquidity:   true,
+	models.StatusFailedManual:        true,
+	models.StatusComplianceHold:      true,
+}
+
+// Rules is the operator document. A zero threshold disables its rule.
+type Rules struct {
+	// StuckAfterSeconds alerts on orders that sat in a status for longer
+	// than this, measured from the order's last update.
+	StuckAfterSeconds map[models.OrderStatus]int64 `json:"stuck_after_seconds"`
+	RefundRate        RefundRate                   `json:"refund_rate"`
+	// PollStaleSeconds alerts when no worker completed a snapshot poll for
+	// this long.
+	PollStaleSeconds int64 `json:"poll_stale_seconds"`
+	// ReconcileMismatch alerts on a confirmed wallet/ledger discrepancy.
+	ReconcileMismatch bool `json:"reconcile_mismatch"`
+	// LiquidityLow alerts on assets below their liquidity threshold.
+	LiquidityLow bool `json:"liquidity_low"`
+	// LiquidityHold alerts while payouts wait in awaiting_liquidity.
+	LiquidityHold bool `json:"liquidity_hold"`
+	// RenotifySeconds repeats a still-firing alert this often (0: only once).
+	RenotifySeconds int64 `json:"renotify_seconds"`
+}
+
+// RefundRate alerts when more than Max of the orders finished in the last
+// WindowSeconds were refunded, once at least MinOrders finished.
+type RefundRate struct {
+	WindowSeconds int64  `json:"window_seconds"`
+	Max           string `json:"max"` // fraction, e.g. "0.2"; empty disables
+	MinOrders     int64  `json:"min_orders"`
+}
+
+// DefaultRules apply until an operator stores their own. awaiting_deposit is
+// off: abandoned quotes stay in it for good.
+func DefaultRules() *Rules {
+	return &Rules{
+		StuckAfterSeconds: map[models.OrderStatus]int64{
+			models.StatusAwaitingDeposit: 0,
+			models.StatusDepositCredited: 600,
+			models.StatusExecutingSwap:   900,
+			models.StatusWithdrawing:     1800,
+			models.StatusRefunding:       1800,
+		},
+		RefundRate:        RefundRate{WindowSeconds: 3600, Max: "0.2", MinOrders: 10},
+		PollStaleSeconds:  120,
+		ReconcileMismatch: true,
+		LiquidityLow:      true,
+		LiquidityHold:     true,
+	}
+}
+
+func (r *Rules) Validate() error {
+	for st, secs := range r.StuckAfterSeconds {
+		if !stuckStatuses[st] {
+			return fmt.Errorf("stuck_after_seconds.%s: not a status orders wait in", st)
+		}
+		if secs < 0 {
+			return fmt.Errorf("stuck_after_seconds.%s: must not be negative", st)
+		}
+	}
+	if r.RefundRate.Max != "" {
+		max, err := decimal.NewFromString(r.RefundRate.Max)
+		if err != nil || max.IsNegative() || max.GreaterThan(decimal.NewFromInt(1)) {
+			return fmt.Errorf("refund_rate.max: must be a decimal between 0 and 1")
+		}
+		if r.RefundRate.WindowSeconds <= 0 {
+			return fmt.Errorf("refund_rate.window_seconds: must be positive")
+		}
+	}
+	if r.RefundRate.WindowSeconds < 0 || r.RefundRate.MinOrders < 0 {
+		return fmt.Errorf("refund_rate: window_seconds and min_orders must not be negative")
+	}
+	if r.PollStaleSeconds < 0 {
+		return fmt.Errorf("poll_stale_seconds: must not be negative")
+	}
+	if r.RenotifySeconds < 0 {
+		return fmt.Errorf("renotify_seconds: must not be negative")
+	}
+	return nil
+}
+
+// Load returns the stored rules, or DefaultRules if none were stored.
+func Load(ctx context.Context, kv store.KVStore) (*Rules, error) {
+	v, ok, err := kv.Get(ctx, kvKey)
+	if err != nil {
+		return nil, fmt.Errorf("load alerts: %w", err)
+	}
+	if !ok {
+		return DefaultRules(), nil
+	}
+	r := &Rules{}
+	if err := json.Unmarshal([]byte(v), r); err != nil {
+		return nil, fmt.Errorf("load alerts: %w", err)
+	}
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("load alerts: %w", err)
+	}
+	if r.StuckAfterSeconds == nil {
+		r.StuckAfterSeconds = map[models.OrderStatus]int64{}
+	}
+	return r, nil
+}
+
+func Save(ctx context.Context, kv store.KVStore, r *Rules) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return kv.Set(ctx, kvKey, string(raw))
+}
+
+// Alert is one firing (or just resolved) condition. Key identifies it across
+// evaluations, e.g. "stuck_orders.withdrawing" or "liquidity_low.<asset_id>".
+type Alert struct {
+	Key        string     `json:"key"`
+	Rule       string     `json:"rule"`
+	Severity   Severity   `json:"severity"`
+	Status     string     `json:"status"`
+	Summary    string     `json:"summary"`
+	Detail     any        `json:"detail,omitempty"`
+	Since      time.Time  `json:"since"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	NotifiedAt time.Time  `json:"notified_at"`
+}
+
+// Text is the one-line form sent as a chat message.
+func (a *Alert) Text() string {
+	if a.Status == StatusResolved {
+		return fmt.Sprintf("[RESOLVED] %s (%s, firing since %s)", a.Summary, a.Key, a.Since.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("[%s] %s (%s)", a.Severity, a.Summary, a.Key)
+}
+
+// State is what the last evaluation left firing, stored under StateKey.
+type State struct {
+	CheckedAt time.Time         `json:"checked_at"`
+	Firing    map[string]*Alert `json:"firing"`
+}
+
+// LoadState returns the stored state (empty before the first evaluation).
+func LoadState(ctx context.Context, kv store.KVStore) (*State, error) {
+	st := &State{Firing: map[string]*Alert{}}
+	raw, ok, err := kv.Get(ctx, StateKey)
+	if err != nil {
+		return nil, fmt.Errorf("alerts: load state: %w", err)
+	}
+	if !ok || raw == "" {
+		return st, nil
+	}
+	if err := json.Unmarshal([]byte(raw), st); err != nil {
+		return nil, fmt.Errorf("alerts: load state: %w", err)
+	}
+	if st.Firing == nil {
+		st.Firing = map[string]*Alert{}
+	}
+	return st, nil
+}
+
+type Evaluator struct {
+	Orders store.OrderStore
+	KV     store.KVStore
+	Sinks  []Sink
+
+	// ReconcileConfirm is how many consecutive mismatching reconcile runs
+	// confirm a discrepancy (the reconciler pauses stages at the same count).
+	ReconcileConfirm int
+
+	Now func() time.Time
+
+	started time.Time
+}
+
+func NewEvaluator(orders store.OrderStore, kv store.KVStore, reconcileConfirm int, sinks ...Sink) *Evaluator {
+	if reconcileConfirm < 1 {
+		reconcileConfirm = 1
+	}
+	return &Evaluator{
+		Orders:           orders,
+		KV:               kv,
+		Sinks:            sinks,
+		ReconcileConfirm: reconcileConfirm,
+		Now:              time.Now,
+		started:          time.Now(),
+	}
+}
+
+// Run evaluates every rule once, notifies alerts that started firing (or are
+// due a repeat) and alerts that resolved, and stores the new state. A rule
+// that cannot be evaluated keeps its alerts as they were.
+func (e *Evaluator) Run(ctx context.Context) (*State, error) {
+	rules, err := Load(ctx, e.KV)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := LoadState(ctx, e.KV)
+	if err != nil {
+		return nil, err
+	}
+	now := e.Now().UTC()
+	renotify := time.Duration(rules.RenotifySeconds) * time.Second
+	next := &State{CheckedAt: now, Firing: map[string]*Alert{}}
+
+	for _, r := range []struct {
+		name string
+		eval func(context.Context, *Rules, time.Time) ([]*Alert, error)
+	}{
+		{RuleStuckOrders, e.stuckOrders},
+		{RuleRefundRate, e.refundRate},
+		{RulePollStale, e.pollStale},
+		{RuleReconcileMismatch, e.reconcileMismatch},
+		{RuleLiquidityLow, e.liquidityLow},
+		{RuleLiquidityHold, e.liquidityHold},
+	} {
+		found, err := r.eval(ctx, rules, now)
+		if err != nil {
+			slog.ErrorContext(ctx, "alert rule failed", "rule", r.name, "err", err)
+			for k, a := range prev.Firing {
+				if a.Rule == r.name {
+					next.Firing[k] = a
+				}
+			}
+			continue
+		}
+		for _, a := range found {
+			a.Rule, a.Status = r.name, StatusFiring
+			old, ok := prev.Firing[a.Key]
+			if ok {
+				a.Since, a.NotifiedAt = old.Since, old.NotifiedAt
+			} else {
+				a.Since = now
+			}
+			if a.NotifiedAt.IsZero() || (renotify > 0 && now.Sub(a.NotifiedAt) >= renotify) {
+				e.notify(ctx, a, now)
+			}
+			next.Firing[a.Key] = a
+		}
+	}
+
+	for k, a := range prev.Firing {
+		if _, ok := next.Firing[k]; ok {
+			continue
+		}
+		resolved := *a
+		resolved.Status, resolved.ResolvedAt = StatusResolved, &now
+		if !e.notify(ctx, &resolved, now) {
+			// Keep it so the resolve is sent on the next run.
+			next.Firing[k] = a
+		}
+	}
+
+	raw, err := json.Marshal(next)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.KV.Set(ctx, StateKey, string(raw)); err != nil {
+		return nil, fmt.Errorf("alerts: save state: %w", err)
+	}
+	return next, nil
+}
+
+// notify sends a to every sink and records when. It reports false only if
+// every sink failed, so a notification one sink delivered is not repeated.
+func (e *Evaluator) notify(ctx context.Context, a *Alert, now time.Time) bool {
+	if a.Status == StatusResolved {
+		slog.InfoContext(ctx, "alert resolved", "alert", a.Key, "summary", a.Summary)
+	} else {
+		slog.WarnContext(ctx, "ALERT "+a.Summary, "alert", a.Key, "severity", string(a.Severity))
+	}
+	a.NotifiedAt = now
+	ok := len(e.Sinks) == 0
+	for _, s := range e.Sinks {
+		if err := s.Notify(ctx, a); err != nil {
+			slog.ErrorContext(ctx, "alert notify failed", "alert", a.Key, "sink", s.Name(), "err", err)
+			continue
+		}
+		ok = true
+	}
+	if !ok {
+		a.NotifiedAt = time.Time{}
+	}
+	return ok
+}
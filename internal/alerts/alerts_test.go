@@ -0,0 +1,104 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/liquidity"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store/memstore"
+)
+
+// recorder is a Sink keeping every notification.
+type recorder struct{ got []Alert }
+
+func (r *recorder) Name() string { return "recorder" }
+
+func (r *recorder) Notify(ctx context.Context, a *Alert) error {
+	r.got = append(r.got, *a)
+	return nil
+}
+
+func (r *recorder) take() map[string]string {
+	out := map[string]string{}
+	for _, a := range r.got {
+		out[a.Key] = a.Status
+	}
+	r.got = nil
+	return out
+}
+
+func saveLiquidityReport(t *testing.T, kv *memstore.KV, low ...liquidity.Low) {
+	t.Helper()
+	raw, err := json.Marshal(&liquidity.Report{CheckedAt: time.Now().UTC(), Balances: map[string]string{}, Low: low})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.Set(context.Background(), liquidity.ReportKey, string(raw)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLiquidityAlerts(t *testing.T) {
+	ctx := context.Background()
+	orders, kv := memstore.NewOrders(), memstore.NewKV()
+	if err := Save(ctx, kv, &Rules{LiquidityLow: true, LiquidityHold: true}); err != nil {
+		t.Fatal(err)
+	}
+	sink := &recorder{}
+	e := NewEvaluator(orders, kv, 1, sink)
+
+	now := time.Now().UTC()
+	final := "2"
+	err := orders.Insert(ctx, &models.Order{
+		ID: "o1", PublicID: "p1", Status: models.StatusWithdrawing, CreatedAt: now, UpdatedAt: now,
+		SourceAsset: "a", TargetAsset: "b", AmountIn: "1", FinalOut: &final,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := orders.MarkAwaitingLiquidity(ctx, "o1", models.StatusWithdrawing, models.StageWithdraw, "liquidity: asset b: need 2, have 1"); err != nil || !ok {
+		t.Fatalf("park: %v %v", ok, err)
+	}
+	saveLiquidityReport(t, kv, liquidity.Low{AssetID: "b", Balance: "1", Min: "5"})
+
+	st, err := e.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := sink.take()
+	if got[RuleLiquidityHold] != StatusFiring || got[RuleLiquidityLow+".b"] != StatusFiring || len(got) != 2 {
+		t.Fatalf("first run notified %v", got)
+	}
+	hold := st.Firing[RuleLiquidityHold]
+	if hold == nil || hold.Summary != "1 payouts waiting for wallet liquidity" {
+		t.Errorf("hold alert = %+v", hold)
+	}
+
+	// Still firing: no second notification.
+	if _, err := e.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := sink.take(); len(got) != 0 {
+		t.Errorf("repeat run notified %v", got)
+	}
+
+	// Topped up: the order resumes and the asset is no longer low.
+	if ok, err := orders.ResumeFromLiquidity(ctx, "o1", models.StageWithdraw); err != nil || !ok {
+		t.Fatalf("resume: %v %v", ok, err)
+	}
+	saveLiquidityReport(t, kv)
+	st, err = e.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = sink.take()
+	if got[RuleLiquidityHold] != StatusResolved || got[RuleLiquidityLow+".b"] != StatusResolved {
+		t.Errorf("after top-up notified %v", got)
+	}
+	if len(st.Firing) != 0 {
+		t.Errorf("still firing: %v", st.Firing)
+	}
+}
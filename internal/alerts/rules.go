@@ -0,0 +1,224 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/health"
+	"github.com/mvg-fi-dev/bridge/internal/liquidity"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/reconcile"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// stuckSample is how many public ids a stuck-orders alert lists.
+const stuckSample = 5
+
+// stuckOrders fires one alert per status with orders not updated for longer
+// than the status' threshold.
+func (e *Evaluator) stuckOrders(ctx context.Context, rules *Rules, now time.Time) ([]*Alert, error) {
+	statuses := make([]models.OrderStatus, 0, len(rules.StuckAfterSeconds))
+	for st := range rules.StuckAfterSeconds {
+		statuses = append(statuses, st)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i] < statuses[j] })
+
+	var out []*Alert
+	for _, st := range statuses {
+		secs := rules.StuckAfterSeconds[st]
+		if secs <= 0 {
+			continue
+		}
+		after := time.Duration(secs) * time.Second
+		f := store.OrderFilter{Status: st, UpdatedBefore: now.Add(-after)}
+		n, err := e.Orders.CountOrders(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			continue
+		}
+		f.Limit = stuckSample
+		sample, err := e.Orders.ListOrders(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		publicIDs := make([]string, 0, len(sample))
+		for _, o := range sample {
+			publicIDs = append(publicIDs, o.PublicID)
+		}
+		out = append(out, &Alert{
+			Key:      RuleStuckOrders + "." + string(st),
+			Severity: SeverityWarning,
+			Summary:  fmt.Sprintf("%d orders in %s for over %s", n, st, after),
+			Detail:   map[string]any{"status": st, "count": n, "threshold_seconds": secs, "public_ids": publicIDs},
+		})
+	}
+	return out, nil
+}
+
+// refundRate fires when the share of refunded (or refunding) orders among
+// those that finished within the window exceeds the maximum.
+func (e *Evaluator) refundRate(ctx context.Context, rules *Rules, now time.Time) ([]*Alert, error) {
+	rr := rules.RefundRate
+	if rr.Max == "" {
+		return nil, nil
+	}
+	max, err := decimal.NewFromString(rr.Max)
+	if err != nil {
+		return nil, err
+	}
+	window := time.Duration(rr.WindowSeconds) * time.Second
+	count := func(st models.OrderStatus) (int64, error) {
+		return e.Orders.CountOrders(ctx, store.OrderFilter{Status: st, UpdatedFrom: now.Add(-window)})
+	}
+	var refunds, completed int64
+	for _, st := range []models.OrderStatus{models.StatusRefunded, models.StatusRefunding} {
+		n, err := count(st)
+		if err != nil {
+			return nil, err
+		}
+		refunds += n
+	}
+	if completed, err = count(models.StatusCompleted); err != nil {
+		return nil, err
+	}
+	finished := refunds + completed
+	if finished == 0 || finished < rr.MinOrders {
+		return nil, nil
+	}
+	rate := decimal.NewFromInt(refunds).Div(decimal.NewFromInt(finished))
+	if !rate.GreaterThan(max) {
+		return nil, nil
+	}
+	return []*Alert{{
+		Key:      RuleRefundRate,
+		Severity: SeverityWarning,
+		Summary:  fmt.Sprintf("refund rate %s%% over the last %s (%d of %d orders)", rate.Mul(decimal.NewFromInt(100)).StringFixed(1), window, refunds, finished),
+		Detail:   map[string]any{"refunds": refunds, "completed": completed, "rate": rate.StringFixed(4), "max": max.String(), "window_seconds": rr.WindowSeconds},
+	}}, nil
+}
+
+// pollStale fires when no worker recorded a successful snapshot poll within
+// the threshold. Before any heartbeat exists the evaluator's start counts.
+func (e *Evaluator) pollStale(ctx context.Context, rules *Rules, now time.Time) ([]*Alert, error) {
+	if rules.PollStaleSeconds <= 0 {
+		return nil, nil
+	}
+	_, newest, err := health.LoadHeartbeats(ctx, e.KV)
+	if err != nil {
+		return nil, err
+	}
+	since := newest
+	if since.IsZero() {
+		since = e.started
+	}
+	age := now.Sub(since)
+	if age <= time.Duration(rules.PollStaleSeconds)*time.Second {
+		return nil, nil
+	}
+	detail := map[string]any{"threshold_seconds": rules.PollStaleSeconds}
+	if !newest.IsZero() {
+		detail["last_poll_at"] = newest.UTC()
+	}
+	return []*Alert{{
+		Key:      RulePollStale,
+		Severity: SeverityCritical,
+		Summary:  fmt.Sprintf("no worker completed a snapshot poll for %s", age.Truncate(time.Second)),
+		Detail:   detail,
+	}}, nil
+}
+
+// reconcileMismatch fires while the latest reconciliation report shows a
+// confirmed discrepancy between the wallet and the ledger.
+func (e *Evaluator) reconcileMismatch(ctx context.Context, rules *Rules, now time.Time) ([]*Alert, error) {
+	if !rules.ReconcileMismatch {
+		return nil, nil
+	}
+	rep, err := reconcile.LoadReport(ctx, e.KV)
+	if err != nil {
+		return nil, err
+	}
+	if rep == nil || rep.OK || rep.Streak < e.ReconcileConfirm {
+		return nil, nil
+	}
+	var breach []reconcile.AssetReport
+	for _, a := range rep.Assets {
+		if a.Breach {
+			breach = append(breach, a)
+		}
+	}
+	return []*Alert{{
+		Key:      RuleReconcileMismatch,
+		Severity: SeverityCritical,
+		Summary:  fmt.Sprintf("wallet balance does not match ledger for %d assets (%d runs)", len(breach), rep.Streak),
+		Detail:   map[string]any{"checked_at": rep.CheckedAt, "assets": breach},
+	}}, nil
+}
+
+// liquidityLow fires one alert per asset the latest liquidity report lists as
+// below its threshold.
+func (e *Evaluator) liquidityLow(ctx context.Context, rules *Rules, now time.Time) ([]*Alert, error) {
+	if !rules.LiquidityLow {
+		return nil, nil
+	}
+	rep, err := liquidity.LoadReport(ctx, e.KV)
+	if err != nil {
+		return nil, err
+	}
+	if rep == nil {
+		return nil, nil
+	}
+	out := make([]*Alert, 0, len(rep.Low))
+	for _, l := range rep.Low {
+		out = append(out, &Alert{
+			Key:      RuleLiquidityLow + "." + l.AssetID,
+			Severity: SeverityWarning,
+			Summary:  fmt.Sprintf("hot wallet balance of %s is %s, below %s", l.AssetID, l.Balance, l.Min),
+			Detail:   l,
+		})
+	}
+	return out, nil
+}
+
+// liquidityHold fires while payouts are parked in awaiting_liquidity because
+// the wallet could not cover them; it resolves once they all resumed.
+func (e *Evaluator) liquidityHold(ctx context.Context, rules *Rules, now time.Time) ([]*Alert, error) {
+	if !rules.LiquidityHold {
+		return nil, nil
+	}
+	f := store.OrderFilter{Status: models.StatusAwaitingLiquidity}
+	n, err := e.Orders.CountOrders(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	f.Limit = stuckSample
+	sample, err := e.Orders.ListOrders(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	held := make([]map[string]any, 0, len(sample))
+	for _, o := range sample {
+		h := map[string]any{"public_id": o.PublicID}
+		if o.RetryStage != nil {
+			h["stage"] = *o.RetryStage
+		}
+		if o.LastError != nil {
+			h["shortfall"] = *o.LastError
+		}
+		held = append(held, h)
+	}
+	return []*Alert{{
+		Key:      RuleLiquidityHold,
+		Severity: SeverityWarning,
+		Summary:  fmt.Sprintf("%d payouts waiting for wallet liquidity", n),
+		Detail:   map[string]any{"count": n, "orders": held},
+	}}, nil
+}
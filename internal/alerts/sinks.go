@@ -0,0 +1,98 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/ids"
+	"github.com/mvg-fi-dev/bridge/internal/metrics"
+	"github.com/mvg-fi-dev/bridge/internal/tracing"
+)
+
+// Sink delivers alert notifications (firing and resolved).
+type Sink interface {
+	Name() string
+	Notify(ctx context.Context, a *Alert) error
+}
+
+// Stdout writes each notification as a JSON line to W (os.Stdout if nil),
+// apart from the logs on stderr.
+type Stdout struct {
+	W io.Writer
+}
+
+func (s *Stdout) Name() string { return "stdout" }
+
+func (s *Stdout) Notify(ctx context.Context, a *Alert) error {
+	w := s.W
+	if w == nil {
+		w = os.Stdout
+	}
+	return json.NewEncoder(w).Encode(a)
+}
+
+// Webhook POSTs each notification as JSON (an Alert) to URL.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhook(url string) *Webhook {
+	return &Webhook{URL: url, Client: &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)}}
+}
+
+func (s *Webhook) Name() string { return "webhook" }
+
+func (s *Webhook) Notify(ctx context.Context, a *Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: %s", resp.Status)
+	}
+	return nil
+}
+
+// MessageSender posts a text message to a Mixin conversation.
+// mixin.SDKClient implements it.
+type MessageSender interface {
+	SendText(ctx context.Context, conversationID, messageID, text string) error
+}
+
+// Mixin posts each notification as a text message to an ops group the bot
+// is a member of.
+type Mixin struct {
+	Sender         MessageSender
+	ConversationID string
+}
+
+func (s *Mixin) Name() string { return "mixin" }
+
+func (s *Mixin) Notify(ctx context.Context, a *Alert) error {
+	// One message id per notification, so a retried post is not shown twice.
+	messageID := ids.DeterministicUUID("alert:" + a.Key + ":" + a.Status + ":" + a.NotifiedAt.Format(time.RFC3339Nano))
+	vctx, span := tracing.Start(ctx, "mixin.send_message")
+	start := time.Now()
+	err := s.Sender.SendText(vctx, s.ConversationID, messageID, a.Text())
+	metrics.ObserveVenue("mixin", "send_message", start, err)
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,408 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mvg-fi-dev/bridge/internal/ids"
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/metrics"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/orderview"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// errStatusChanged is returned from an admin transaction when the order's
+// status guard did not match (a worker moved it in the meantime).
+var errStatusChanged = errors.New("order status changed; reload and retry")
+
+func (s *Server) registerAdmin(r *gin.Engine) {
+	if s.Admin == nil || len(s.AdminTokens) == 0 {
+		slog.Warn("admin api disabled (ADMIN_TOKENS not set)")
+		return
+	}
+	g := r.Group("/admin", requireOperator(s.AdminTokens))
+	g.GET("/orders", s.handleAdminListOrders)
+	g.GET("/orders/:id", s.handleAdminGetOrder)
+	g.POST("/orders/:id/retry", s.handleAdminRetry)
+	g.POST("/orders/:id/review", s.handleAdminReview)
+	g.POST("/orders/:id/release", s.handleAdminRelease)
+	g.POST("/orders/:id/refund", s.handleAdminRefund)
+	g.POST("/orders/:id/notes", s.handleAdminNote)
+	g.GET("/audit", s.handleAdminListAudit)
+	g.GET("/switches", s.handleAdminListSwitches)
+	g.PUT("/switches/:kind/:name", s.handleAdminDisableSwitch)
+	g.DELETE("/switches/:kind/:name", s.handleAdminEnableSwitch)
+	g.GET("/limits", s.handleAdminGetLimits)
+	g.PUT("/limits", s.handleAdminPutLimits)
+	g.GET("/fees", s.handleAdminGetFees)
+	g.PUT("/fees", s.handleAdminPutFees)
+	g.GET("/revenue", s.handleAdminRevenue)
+	g.GET("/blocklist", s.handleAdminListBlocklist)
+	g.POST("/blocklist", s.handleAdminAddBlocklist)
+	g.DELETE("/blocklist/:id", s.handleAdminRemoveBlocklist)
+	g.GET("/partners", s.handleAdminListPartners)
+	g.POST("/partners", s.handleAdminCreatePartner)
+	g.GET("/partners/:id", s.handleAdminGetPartner)
+	g.PATCH("/partners/:id", s.handleAdminUpdatePartner)
+	g.POST("/partners/:id/rotate", s.handleAdminRotatePartnerSecret)
+	g.GET("/partners/:id/orders", s.handleAdminPartnerOrders)
+	g.GET("/partners/:id/volume", s.handleAdminPartnerVolume)
+	if s.Webhooks != nil {
+		g.GET("/partners/:id/webhooks", s.handleAdminPartnerWebhooks)
+		g.GET("/webhooks/deliveries", s.handleAdminListDeliveries)
+		g.POST("/webhooks/deliveries/:id/redeliver", s.handleAdminRedeliver)
+	}
+	if s.Ledger != nil {
+		g.GET("/orders/:id/ledger", s.handleAdminOrderLedger)
+		g.GET("/ledger/balances", s.handleAdminLedgerBalances)
+		g.GET("/ledger/leaks", s.handleAdminLedgerLeaks)
+	}
+	g.GET("/reconcile", s.handleAdminReconcile)
+	g.GET("/liquidity", s.handleAdminGetLiquidity)
+	g.PUT("/liquidity", s.handleAdminPutLiquidity)
+	g.GET("/alerts", s.handleAdminGetAlerts)
+	g.PUT("/alerts", s.handleAdminPutAlerts)
+}
+
+// handleAdminListOrders serves the order listing (see parseOrderFilter)
+// across partners, plus ?partner_id=.
+func (s *Server) handleAdminListOrders(c *gin.Context) {
+	f, err := parseOrderFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	f.PartnerID = c.Query("partner_id")
+	s.writeOrders(c, f, true)
+}
+
+func (s *Server) handleAdminGetOrder(c *gin.Context) {
+	o, ok := s.adminOrder(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	notes, err := s.Admin.ListNotes(ctx, o.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	audit, err := s.Admin.ListAudit(ctx, o.ID, 100)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"order": orderview.NewAdmin(o), "notes": notes, "audit": audit})
+}
+
+func (s *Server) handleAdminListAudit(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	entries, err := s.Admin.ListAudit(c.Request.Context(), c.Query("order_id"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	if entries == nil {
+		entries = []*models.AuditEntry{}
+	}
+	c.JSON(http.StatusOK, gin.H{"audit": entries})
+}
+
+// handleAdminRetry clears the backoff of an order waiting in an executor stage
+// so the worker picks it up on its next tick.
+func (s *Server) handleAdminRetry(c *gin.Context) {
+	o, ok := s.adminOrder(c)
+	if !ok {
+		return
+	}
+	stage, ok := models.StageForStatus(o.Status)
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "order is not waiting in a retryable stage", "status": o.Status})
+		return
+	}
+	s.adminAction(c, o, "force_retry", gin.H{"status": o.Status, "stage": stage}, func(ctx context.Context, orders store.OrderStore) error {
+		ok, err := orders.ForceRetry(ctx, o.ID, o.Status)
+		if err == nil && !ok {
+			err = errStatusChanged
+		}
+		return err
+	})
+}
+
+// swapInFlight reports whether a swap transfer may be out at ExinSwap with no
+// reply recorded yet: no RL payout (final_out) and no RF refund. The reply
+// would still be credited to the order, so reviewing or refunding it now could
+// pay the user twice.
+func swapInFlight(o *models.Order) bool {
+	return o.Status == models.StatusExecutingSwap ||
+		(o.ExinSwapTraceID != nil && o.FinalOut == nil && o.RefundReceivedSnapshotID == nil)
+}
+
+func rejectSwapInFlight(c *gin.Context, o *models.Order) bool {
+	if !swapInFlight(o) {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": "swap is in flight at ExinSwap; wait for its reply", "status": o.Status})
+	return true
+}
+
+// rejectTransferSubmitted refuses a manual refund once a withdrawal or refund
+// trace was assigned: the transfer may have been broadcast even if the
+// attempt timed out, and a refund on top of it would pay twice. Releasing the
+// order to that stage retries under the same trace, which Mixin dedupes.
+func rejectTransferSubmitted(c *gin.Context, o *models.Order) bool {
+	var stage models.Stage
+	switch {
+	case o.WithdrawTraceID != nil && *o.WithdrawTraceID != "":
+		stage = models.StageWithdraw
+	case o.RefundTraceID != nil && *o.RefundTraceID != "":
+		stage = models.StageRefund
+	default:
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": "a " + string(stage) + " transfer was already submitted; release the order to that stage instead", "stage": stage, "status": o.Status})
+	return true
+}
+
+type adminReasonRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// handleAdminReview parks an order in failed_manual_review. The stage it was
+// in is kept in retry_stage so a later release resumes it.
+func (s *Server) handleAdminReview(c *gin.Context) {
+	var req adminReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	o, ok := s.adminOrder(c)
+	if !ok {
+		return
+	}
+	switch o.Status {
+	case models.StatusCompleted, models.StatusRefunded, models.StatusFailedManual, models.StatusComplianceHold:
+		c.JSON(http.StatusConflict, gin.H{"error": "order cannot be moved to manual review", "status": o.Status})
+		return
+	}
+	if rejectSwapInFlight(c, o) {
+		return
+	}
+	stage, ok := models.StageForStatus(o.Status)
+	if !ok && o.RetryStage != nil {
+		stage = models.Stage(*o.RetryStage) // awaiting_liquidity keeps its stage there
+	}
+	s.adminAction(c, o, "hold_for_review", gin.H{"from": o.Status, "stage": stage, "reason": req.Reason}, func(ctx context.Context, orders store.OrderStore) error {
+		if err := orders.MarkFailedManual(ctx, o.ID, o.Status, stage, o.Attempts, "manual: "+req.Reason); err != nil {
+			return err
+		}
+		cur, err := orders.GetByID(ctx, o.ID)
+		if err != nil {
+			return err
+		}
+		if cur.Status != models.StatusFailedManual {
+			return errStatusChanged
+		}
+		return nil
+	})
+}
+
+type adminReleaseRequest struct {
+	// Stage to resume (swap, withdraw, refund); defaults to the order's retry_stage.
+	Stage models.Stage `json:"stage"`
+}
+
+// handleAdminRelease moves an order out of failed_manual_review,
+// compliance_hold or awaiting_liquidity into the status of the stage to resume, with a fresh
+// attempt budget.
+func (s *Server) handleAdminRelease(c *gin.Context) {
+	var req adminReleaseRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	o, ok := s.adminOrder(c)
+	if !ok {
+		return
+	}
+	switch o.Status {
+	case models.StatusFailedManual, models.StatusComplianceHold, models.StatusAwaitingLiquidity:
+	default:
+		c.JSON(http.StatusConflict, gin.H{"error": "order is not in failed_manual_review, compliance_hold or awaiting_liquidity", "status": o.Status})
+		return
+	}
+	stage := req.Stage
+	if stage == "" && o.RetryStage != nil {
+		stage = models.Stage(*o.RetryStage)
+	}
+	if _, ok := stage.Status(); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stage must be one of swap, withdraw, refund"})
+		return
+	}
+	s.adminAction(c, o, "release_from_review", gin.H{"from": o.Status, "stage": stage}, func(ctx context.Context, orders store.OrderStore) error {
+		ok, err := orders.ReleaseFromReview(ctx, o.ID, o.Status, stage)
+		if err == nil && !ok {
+			err = errStatusChanged
+		}
+		return err
+	})
+}
+
+// handleAdminRefund sends an order with funds on hand to refunding.
+// If the swap already paid out (the order failed while withdrawing) the
+// target asset is refunded instead of the source asset.
+func (s *Server) handleAdminRefund(c *gin.Context) {
+	var req adminReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	o, ok := s.adminOrder(c)
+	if !ok {
+		return
+	}
+	switch o.Status {
+	case models.StatusDepositCredited, models.StatusFailedManual, models.StatusComplianceHold:
+	default:
+		c.JSON(http.StatusConflict, gin.H{"error": "only deposit_credited, failed_manual_review or compliance_hold orders can be refunded", "status": o.Status})
+		return
+	}
+	if o.RefundToAddress == nil || *o.RefundToAddress == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "order has no refund_to_address"})
+		return
+	}
+	if rejectSwapInFlight(c, o) || rejectTransferSubmitted(c, o) {
+		return
+	}
+	assetID, amount := "", ""
+	if o.RetryStage != nil && models.Stage(*o.RetryStage) == models.StageWithdraw && o.FinalOut != nil {
+		assetID, amount = o.TargetAsset, *o.FinalOut
+	} else if o.AmountCredited == nil && o.RefundAmount == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "order has no credited amount to refund"})
+		return
+	}
+	s.adminAction(c, o, "manual_refund", gin.H{"from": o.Status, "reason": req.Reason, "refund_asset_id": assetID, "refund_amount": amount}, func(ctx context.Context, orders store.OrderStore) error {
+		ok, err := orders.MarkManualRefund(ctx, o.ID, o.Status, assetID, amount)
+		if err == nil && !ok {
+			err = errStatusChanged
+		}
+		if err == nil {
+			metrics.Refund("manual")
+		}
+		return err
+	})
+}
+
+type adminNoteRequest struct {
+	Note string `json:"note" binding:"required"`
+}
+
+func (s *Server) handleAdminNote(c *gin.Context) {
+	var req adminNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	o, ok := s.adminOrder(c)
+	if !ok {
+		return
+	}
+	note := &models.OrderNote{
+		ID:        ids.NewUUID(),
+		OrderID:   o.ID,
+		Operator:  operatorFrom(c),
+		Note:      req.Note,
+		CreatedAt: time.Now().UTC(),
+	}
+	err := s.Admin.InAdminTx(c.Request.Context(), func(tx store.AdminTx) error {
+		if err := tx.Audit.InsertNote(c.Request.Context(), note); err != nil {
+			return err
+		}
+		return insertAudit(c.Request.Context(), tx.Audit, operatorFrom(c), "annotate", o.ID, gin.H{"note_id": note.ID})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	c.JSON(http.StatusOK, note)
+}
+
+// adminOrder resolves :id as a public id first, then as an internal id.
+// It writes the error response itself and returns ok=false on failure.
+func (s *Server) adminOrder(c *gin.Context) (*models.Order, bool) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	o, err := s.Orders.GetByPublicID(ctx, id)
+	if errors.Is(err, store.ErrNotFound) {
+		o, err = s.Orders.GetByID(ctx, id)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return nil, false
+	}
+	return o, true
+}
+
+// adminAction runs fn and the audit entry for it in one transaction, then
+// responds with the operator view of the updated order.
+func (s *Server) adminAction(c *gin.Context, o *models.Order, action string, detail gin.H, fn func(ctx context.Context, orders store.OrderStore) error) {
+	ctx := c.Request.Context()
+	operator := operatorFrom(c)
+	err := s.Admin.InAdminTx(ctx, func(tx store.AdminTx) error {
+		if err := fn(ctx, tx.Orders); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx.Audit, operator, action, o.ID, detail)
+	})
+	if errors.Is(err, errStatusChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(logging.WithOrder(ctx, o), "admin action failed", "action", action, "operator", operator, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	slog.InfoContext(logging.WithOrder(ctx, o), "admin action", "action", action, "operator", operator)
+
+	updated, err := s.Orders.GetByID(ctx, o.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	c.JSON(http.StatusOK, orderview.NewAdmin(updated))
+}
+
+// insertAudit records one operator action; orderID is empty for actions that
+// are not about a single order (e.g. kill-switches).
+func insertAudit(ctx context.Context, audit store.AuditStore, operator, action, orderID string, detail gin.H) error {
+	raw, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+	var oid *string
+	if orderID != "" {
+		oid = &orderID
+	}
+	return audit.InsertAudit(ctx, &models.AuditEntry{
+		ID:        ids.NewUUID(),
+		Operator:  operator,
+		Action:    action,
+		OrderID:   oid,
+		Detail:    string(raw),
+		CreatedAt: time.Now().UTC(),
+	})
+}
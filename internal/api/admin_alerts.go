@@ -0,0 +1,60 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mvg-fi-dev/bridge/internal/alerts"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// handleAdminGetAlerts returns the alert rules and the alerts firing as of
+// the worker's last evaluation.
+func (s *Server) handleAdminGetAlerts(c *gin.Context) {
+	ctx := c.Request.Context()
+	rules, err := alerts.Load(ctx, s.KV)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	st, err := alerts.LoadState(ctx, s.KV)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules, "state": st})
+}
+
+// handleAdminPutAlerts replaces the alert rules.
+func (s *Server) handleAdminPutAlerts(c *gin.Context) {
+	var rules alerts.Rules
+	if err := c.ShouldBindJSON(&rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := rules.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if rules.StuckAfterSeconds == nil {
+		rules.StuckAfterSeconds = map[models.OrderStatus]int64{}
+	}
+	ctx := c.Request.Context()
+	operator := operatorFrom(c)
+	err := s.Admin.InAdminTx(ctx, func(tx store.AdminTx) error {
+		if err := alerts.Save(ctx, tx.KV, &rules); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx.Audit, operator, "set_alerts", "", gin.H{"alerts": rules})
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "admin action failed", "action", "set_alerts", "operator", operator, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	slog.InfoContext(ctx, "admin action", "action", "set_alerts", "operator", operator)
+	c.JSON(http.StatusOK, rules)
+}
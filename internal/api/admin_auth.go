@@ -0,0 +1,37 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const operatorKey = "admin.operator"
+
+// requireOperator authenticates `Authorization: Bearer <token>` against the
+// configured admin tokens and stores the operator name for audit logging.
+func requireOperator(tokens map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		operator := ""
+		if provided != "" {
+			for token, name := range tokens {
+				if subtle.ConstantTimeCompare([]byte(token), []byte(provided)) == 1 {
+					operator = name
+				}
+			}
+		}
+		if operator == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Set(operatorKey, operator)
+		c.Next()
+	}
+}
+
+func operatorFrom(c *gin.Context) string {
+	return c.GetString(operatorKey)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mvg-fi-dev/bridge/internal/ids"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/screening"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+func (s *Server) handleAdminListBlocklist(c *gin.Context) {
+	entries, err := s.Blocklist.ListBlocklist(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	if entries == nil {
+		entries = []*models.BlocklistEntry{}
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+type adminBlocklistRequest struct {
+	// Chain the address belongs to (e.g. ETH, MIXIN); "*" or empty for any chain.
+	Chain   string `json:"chain"`
+	Address string `json:"address" binding:"required"`
+	Reason  string `json:"reason" binding:"required"`
+}
+
+func (s *Server) handleAdminAddBlocklist(c *gin.Context) {
+	var req adminBlocklistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	e := &models.BlocklistEntry{
+		ID:        ids.NewUUID(),
+		Chain:     screening.NormalizeChain(req.Chain),
+		Address:   screening.NormalizeAddress(req.Address),
+		Reason:    req.Reason,
+		CreatedBy: operatorFrom(c),
+		CreatedAt: time.Now().UTC(),
+	}
+	if e.Address == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "address is required"})
+		return
+	}
+	ctx := c.Request.Context()
+	err := s.Admin.InAdminTx(ctx, func(tx store.AdminTx) error {
+		if err := tx.Blocklist.AddBlocklist(ctx, e); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx.Audit, e.CreatedBy, "blocklist_add", "", gin.H{"chain": e.Chain, "address": e.Address, "reason": e.Reason})
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "admin action failed", "action", "blocklist_add", "operator", e.CreatedBy, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	slog.InfoContext(ctx, "admin action", "action", "blocklist_add", "chain", e.Chain, "operator", e.CreatedBy)
+	c.JSON(http.StatusOK, e)
+}
+
+func (s *Server) handleAdminRemoveBlocklist(c *gin.Context) {
+	id := c.Param("id")
+	ctx := c.Request.Context()
+	operator := operatorFrom(c)
+	err := s.Admin.InAdminTx(ctx, func(tx store.AdminTx) error {
+		ok, err := tx.Blocklist.RemoveBlocklist(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return store.ErrNotFound
+		}
+		return insertAudit(ctx, tx.Audit, operator, "blocklist_remove", "", gin.H{"id": id})
+	})
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "admin action failed", "action", "blocklist_remove", "operator", operator, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	slog.InfoContext(ctx, "admin action", "action", "blocklist_remove", "id", id, "operator", operator)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
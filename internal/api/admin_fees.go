@@ -0,0 +1,121 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/fees"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+func (s *Server) handleAdminGetFees(c *gin.Context) {
+	sched, err := fees.Load(c.Request.Context(), s.KV)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sched)
+}
+
+// handleAdminPutFees replaces the fee schedule. It applies to orders quoted
+// from now on; existing orders keep their terms.
+func (s *Server) handleAdminPutFees(c *gin.Context) {
+	var sched fees.Schedule
+	if err := c.ShouldBindJSON(&sched); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := sched.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	operator := operatorFrom(c)
+	err := s.Admin.InAdminTx(ctx, func(tx store.AdminTx) error {
+		if err := fees.Save(ctx, tx.KV, &sched); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx.Audit, operator, "set_fees", "", gin.H{"fees": sched})
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "admin action failed", "action", "set_fees", "operator", operator, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	slog.InfoContext(ctx, "admin action", "action", "set_fees", "operator", operator)
+	c.JSON(http.StatusOK, sched)
+}
+
+// revenueRow aggregates retained fees of one source asset on one UTC day.
+type revenueRow struct {
+	Date       string `json:"date"`
+	AssetID    string `json:"asset_id"`
+	Orders     int64  `json:"orders"`
+	Fee        string `json:"fee"`
+	PartnerFee string `json:"partner_fee"`
+	Net        string `json:"net"`
+
+	fee, partnerFee decimal.Decimal
+}
+
+// handleAdminRevenue reports retained fees by order creation day and source
+// asset: ?from=&to= (UTC dates, default the last 30 days) and optional
+// partner_id.
+func (s *Server) handleAdminRevenue(c *gin.Context) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, to, err := parseDateRange(c, today.AddDate(0, 0, -29))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rows, err := s.Orders.ListOrderAmounts(c.Request.Context(), store.OrderFilter{PartnerID: c.Query("partner_id"), CreatedFrom: from, CreatedTo: to})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+
+	byKey := map[string]*revenueRow{}
+	for _, r := range rows {
+		if r.FeeAmount == nil {
+			continue
+		}
+		fee, err := decimal.NewFromString(*r.FeeAmount)
+		if err != nil || !fee.IsPositive() {
+			continue
+		}
+		date := r.CreatedAt.UTC().Format(dateLayout)
+		v, ok := byKey[date+"/"+r.AssetID]
+		if !ok {
+			v = &revenueRow{Date: date, AssetID: r.AssetID}
+			byKey[date+"/"+r.AssetID] = v
+		}
+		v.Orders++
+		v.fee = v.fee.Add(fee)
+		if r.PartnerFeeAmount != nil {
+			if pf, err := decimal.NewFromString(*r.PartnerFeeAmount); err == nil {
+				v.partnerFee = v.partnerFee.Add(pf)
+			}
+		}
+	}
+	out := make([]*revenueRow, 0, len(byKey))
+	for _, v := range byKey {
+		v.Fee, v.PartnerFee, v.Net = v.fee.String(), v.partnerFee.String(), v.fee.Sub(v.partnerFee).String()
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Date != out[j].Date {
+			return out[i].Date < out[j].Date
+		}
+		return out[i].AssetID < out[j].AssetID
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"from":    from.Format(dateLayout),
+		"to":      to.AddDate(0, 0, -1).Format(dateLayout),
+		"revenue": out,
+	})
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+func TestCreateOrderFees(t *testing.T) {
+	ts := newTestServer(t)
+	w := ts.do(t, http.MethodPut, "/admin/fees", adminToken,
+		`{"spread_bps":30,"flat":{"src":"0.01"},"partners":{"acme":{"spread_bps":10}}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("put fees: %d %s", w.Code, w.Body)
+	}
+	if w := ts.do(t, http.MethodPut, "/admin/fees", adminToken, `{"spread_bps":10000}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid schedule: %d, want 400", w.Code)
+	}
+
+	// amount_in 1: fee 0.003 + 0.01; the quote is scaled to the 0.987 swapped.
+	w, id := ts.createOrder(t, "203.0.113.1", "", createOrderBody)
+	var resp CreateOrderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+	if resp.Fee == nil || resp.Fee.Amount != "0.013" || resp.Fee.SpreadBps != 30 || resp.Fee.Flat != "0.01" {
+		t.Errorf("fee = %+v", resp.Fee)
+	}
+	if resp.Quote.EstimatedOut != "1.974" || resp.Quote.MinOut != "1.8753" {
+		t.Errorf("quote = %+v, want net of fee", resp.Quote)
+	}
+	o, err := ts.orders.GetByPublicID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.FeeBps == nil || *o.FeeBps != 30 || o.FeeFlat == nil || *o.FeeFlat != "0.01" || o.FeeShareBps != nil {
+		t.Errorf("terms on order: %v %v %v", o.FeeBps, o.FeeFlat, o.FeeShareBps)
+	}
+
+	// An amount the fee swallows is refused up front.
+	w, _ = ts.createOrder(t, "203.0.113.1", "", strings.Replace(createOrderBody, `"amount_in":"1"`, `"amount_in":"0.01"`, 1))
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "amount_below_fee") {
+		t.Errorf("tiny amount: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestAdminRevenue(t *testing.T) {
+	ts := newTestServer(t)
+	day := func(d, h int) time.Time { return time.Date(2026, 1, d, h, 0, 0, 0, time.UTC) }
+	for i, r := range []struct {
+		at              time.Time
+		asset           string
+		partner         string
+		fee, partnerFee *string
+	}{
+		{day(1, 1), "btc", "", strp("0.001"), strp("0")},
+		{day(1, 23), "btc", "acme", strp("0.002"), strp("0.001")},
+		{day(1, 12), "eth", "acme", strp("0.03"), strp("0.015")},
+		{day(2, 0), "btc", "", strp("0.004"), nil},
+		{day(2, 5), "btc", "", nil, nil},       // no fee terms
+		{day(2, 6), "btc", "", strp("0"), nil}, // refunded before the swap
+		{day(3, 0), "btc", "", strp("1"), nil}, // outside the range
+		{day(1, 0).Add(-time.Second), "btc", "", strp("1"), nil},
+	} {
+		o := &models.Order{
+			ID:          "o" + string(rune('a'+i)),
+			PublicID:    "p" + string(rune('a'+i)),
+			Status:      models.StatusCompleted,
+			CreatedAt:   r.at,
+			UpdatedAt:   r.at,
+			SourceAsset: r.asset,
+			TargetAsset: "dst",
+			AmountIn:    "1",
+			FeeAmount:   r.fee,
+
+			PartnerFeeAmount: r.partnerFee,
+		}
+		if r.partner != "" {
+			o.PartnerID = strp(r.partner)
+		}
+		if err := ts.orders.Insert(context.Background(), o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var resp struct {
+		From, To string
+		Revenue  []revenueRow
+	}
+	get := func(query string) {
+		t.Helper()
+		w := ts.do(t, http.MethodGet, "/admin/revenue?"+query, adminToken, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("revenue: %d %s", w.Code, w.Body)
+		}
+		resp.Revenue = nil
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get("from=2026-01-01&to=2026-01-02")
+	want := []revenueRow{
+		{Date: "2026-01-01", AssetID: "btc", Orders: 2, Fee: "0.003", PartnerFee: "0.001", Net: "0.002"},
+		{Date: "2026-01-01", AssetID: "eth", Orders: 1, Fee: "0.03", PartnerFee: "0.015", Net: "0.015"},
+		{Date: "2026-01-02", AssetID: "btc", Orders: 1, Fee: "0.004", PartnerFee: "0", Net: "0.004"},
+	}
+	if resp.From != "2026-01-01" || resp.To != "2026-01-02" || len(resp.Revenue) != len(want) {
+		t.Fatalf("revenue = %+v", resp)
+	}
+	for i := range want {
+		if resp.Revenue[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, resp.Revenue[i], want[i])
+		}
+	}
+
+	get("from=2026-01-01&to=2026-01-02&partner_id=acme")
+	if len(resp.Revenue) != 2 || resp.Revenue[0].Fee != "0.002" || resp.Revenue[1].AssetID != "eth" {
+		t.Errorf("partner revenue = %+v", resp.Revenue)
+	}
+
+	if w := ts.do(t, http.MethodGet, "/admin/revenue?from=2026-01-02&to=2026-01-01", adminToken, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("reversed range: %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mvg-fi-dev/bridge/internal/ledger"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// handleAdminLedgerBalances returns per-account balances (optionally only
+// accounts starting with ?account=) and the totals the wallet should hold.
+func (s *Server) handleAdminLedgerBalances(c *gin.Context) {
+	ctx := c.Request.Context()
+	balances, err := s.Ledger.Balances(ctx, c.Query("account"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	wallet, err := s.Ledger.WalletBalances(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"balances": balances, "wallet": wallet})
+}
+
+// handleAdminOrderLedger returns an order's journals and what its account
+// still holds.
+func (s *Server) handleAdminOrderLedger(c *gin.Context) {
+	o, ok := s.adminOrder(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	journals, err := s.Ledger.Store.ListOrderJournals(ctx, o.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	if journals == nil {
+		journals = []*models.LedgerJournal{}
+	}
+	balances, err := s.Ledger.Balances(ctx, ledger.OrderAccount(o.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"journals": journals, "balances": balances})
+}
+
+// ledgerLeak is a closed order whose account is not empty: funds were
+// received for it but not (fully) paid out or refunded.
+type ledgerLeak struct {
+	OrderID  string             `json:"order_id"`
+	PublicID string             `json:"public_id"`
+	Status   models.OrderStatus `json:"status"`
+	AssetID  string             `json:"asset_id"`
+	Amount   string             `json:"amount"`
+}
+
+// handleAdminLedgerLeaks lists completed or refunded orders whose ledger
+// account is not empty.
+func (s *Server) handleAdminLedgerLeaks(c *gin.Context) {
+	ctx := c.Request.Context()
+	balances, err := s.Ledger.Balances(ctx, ledger.OrderAccount(""))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	leaks := []ledgerLeak{}
+	for _, b := range balances {
+		id := strings.TrimPrefix(b.Account, ledger.OrderAccount(""))
+		o, err := s.Orders.GetByID(ctx, id)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+			return
+		}
+		if o.Status != models.StatusCompleted && o.Status != models.StatusRefunded {
+			continue
+		}
+		leaks = append(leaks, ledgerLeak{OrderID: o.ID, PublicID: o.PublicID, Status: o.Status, AssetID: b.AssetID, Amount: b.Amount})
+	}
+	c.JSON(http.StatusOK, gin.H{"leaks": leaks})
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mvg-fi-dev/bridge/internal/limits"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+func (s *Server) handleAdminGetLimits(c *gin.Context) {
+	cfg, err := limits.Load(c.Request.Context(), s.KV)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cfg)
+}
+
+// handleAdminPutLimits replaces the whole limits document.
+func (s *Server) handleAdminPutLimits(c *gin.Context) {
+	var cfg limits.Config
+	if err := c.ShouldBindJSON(&cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	operator := operatorFrom(c)
+	err := s.Admin.InAdminTx(ctx, func(tx store.AdminTx) error {
+		if err := limits.Save(ctx, tx.KV, &cfg); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx.Audit, operator, "set_limits", "", gin.H{"limits": cfg})
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "admin action failed", "action", "set_limits", "operator", operator, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	slog.InfoContext(ctx, "admin action", "action", "set_limits", "operator", operator)
+	c.JSON(http.StatusOK, cfg)
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mvg-fi-dev/bridge/internal/liquidity"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// handleAdminGetLiquidity returns the low-balance thresholds and the worker's
+// latest balance report.
+func (s *Server) handleAdminGetLiquidity(c *gin.Context) {
+	ctx := c.Request.Context()
+	t, err := liquidity.Load(ctx, s.KV)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	rep, err := liquidity.LoadReport(ctx, s.KV)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"thresholds": t, "report": rep})
+}
+
+// handleAdminPutLiquidity replaces the low-balance thresholds.
+func (s *Server) handleAdminPutLiquidity(c *gin.Context) {
+	var t liquidity.Thresholds
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := t.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if t.MinBalances == nil {
+		t.MinBalances = map[string]string{}
+	}
+	ctx := c.Request.Context()
+	operator := operatorFrom(c)
+	err := s.Admin.InAdminTx(ctx, func(tx store.AdminTx) error {
+		if err := liquidity.Save(ctx, tx.KV, &t); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx.Audit, operator, "set_liquidity", "", gin.H{"liquidity": t})
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "admin action failed", "action", "set_liquidity", "operator", operator, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	slog.InfoContext(ctx, "admin action", "action", "set_liquidity", "operator", operator)
+	c.JSON(http.StatusOK, t)
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mvg-fi-dev/bridge/internal/ids"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+func (s *Server) handleAdminListPartners(c *gin.Context) {
+	partners, err := s.Partners.ListPartners(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	if partners == nil {
+		partners = []*models.Partner{}
+	}
+	c.JSON(http.StatusOK, gin.H{"partners": partners})
+}
+
+// adminPartnerRequest is the body of create (name required) and update
+// (only the fields sent are changed).
+type adminPartnerRequest struct {
+	Name            *string `json:"name"`
+	FeeShareBps     *int64  `json:"fee_share_bps"`
+	DailyOrderQuota *int64  `json:"daily_order_quota"`
+	RateLimitPerMin *int64  `json:"rate_limit_per_min"`
+	Disabled        *bool   `json:"disabled"`
+}
+
+func (req *adminPartnerRequest) apply(p *models.Partner) error {
+	if req.Name != nil {
+		if *req.Name == "" {
+			return fmt.Errorf("name must not be empty")
+		}
+		p.Name = *req.Name
+	}
+	if req.FeeShareBps != nil {
+		if *req.FeeShareBps < 0 || *req.FeeShareBps > 10000 {
+			return fmt.Errorf("fee_share_bps must be between 0 and 10000")
+		}
+		p.FeeShareBps = *req.FeeShareBps
+	}
+	if req.DailyOrderQuota != nil {
+		if *req.DailyOrderQuota < 0 {
+			return fmt.Errorf("daily_order_quota must not be negative")
+		}
+		p.DailyOrderQuota = *req.DailyOrderQuota
+	}
+	if req.RateLimitPerMin != nil {
+		if *req.RateLimitPerMin < 0 {
+			return fmt.Errorf("rate_limit_per_min must not be negative")
+		}
+		p.RateLimitPerMin = *req.RateLimitPerMin
+	}
+	if req.Disabled != nil {
+		p.Disabled = *req.Disabled
+	}
+	return nil
+}
+
+func newPartnerSecret() (string, error) {
+	t, err := ids.NewToken(32)
+	if err != nil {
+		return "", err
+	}
+	return "sk_" + t, nil
+}
+
+// handleAdminCreatePartner issues an API key and signing secret. The secret
+// is only returned here and on rotation.
+func (s *Server) handleAdminCreatePartner(c *gin.Context) {
+	var req adminPartnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	now := time.Now().UTC()
+	p := &models.Partner{ID: ids.NewUUID(), CreatedAt: now, UpdatedAt: now}
+	if err := req.apply(p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key, err := ids.NewToken(16)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token"})
+		return
+	}
+	p.APIKey = "pk_" + key
+	if p.Secret, err = newPartnerSecret(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token"})
+		return
+	}
+
+	operator := operatorFrom(c)
+	ctx := c.Request.Context()
+	err = s.Admin.InAdminTx(ctx, func(tx store.AdminTx) error {
+		if err := tx.Partners.InsertPartner(ctx, p); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx.Audit, operator, "partner_create", "", gin.H{"partner_id": p.ID, "name": p.Name, "request": req})
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "admin action failed", "action", "partner_create", "operator", operator, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	slog.InfoContext(ctx, "admin action", "action", "partner_create", "partner_id", p.ID, "operator", operator)
+	c.JSON(http.StatusOK, gin.H{"partner": p, "secret": p.Secret})
+}
+
+func (s *Server) adminPartner(c *gin.Context) (*models.Partner, bool) {
+	p, err := s.Partners.GetPartner(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return nil, false
+	}
+	return p, true
+}
+
+func (s *Server) handleAdminGetPartner(c *gin.Context) {
+	p, ok := s.adminPartner(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"partner": p})
+}
+
+func (s *Server) handleAdminUpdatePartner(c *gin.Context) {
+	var req adminPartnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p, ok := s.adminPartner(c)
+	if !ok {
+		return
+	}
+	if err := req.apply(p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p.UpdatedAt = time.Now().UTC()
+	s.partnerAction(c, p, "partner_update", gin.H{"partner_id": p.ID, "request": req})
+	if !c.IsAborted() {
+		c.JSON(http.StatusOK, gin.H{"partner": p})
+	}
+}
+
+// handleAdminRotatePartnerSecret replaces the signing secret; requests signed
+// with the old one fail immediately.
+func (s *Server) handleAdminRotatePartnerSecret(c *gin.Context) {
+	p, ok := s.adminPartner(c)
+	if !ok {
+		return
+	}
+	var err error
+	if p.Secret, err = newPartnerSecret(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token"})
+		return
+	}
+	p.UpdatedAt = time.Now().UTC()
+	s.partnerAction(c, p, "partner_rotate_secret", gin.H{"partner_id": p.ID})
+	if !c.IsAborted() {
+		c.JSON(http.StatusOK, gin.H{"partner": p, "secret": p.Secret})
+	}
+}
+
+// partnerAction writes p and its audit entry in one transaction. It aborts
+// the request with an error response on failure.
+func (s *Server) partnerAction(c *gin.Context, p *models.Partner, action string, detail gin.H) {
+	operator := operatorFrom(c)
+	ctx := c.Request.Context()
+	err := s.Admin.InAdminTx(ctx, func(tx store.AdminTx) error {
+		if err := tx.Partners.UpdatePartner(ctx, p); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx.Audit, operator, action, "", detail)
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "admin action failed", "action", action, "partner_id", p.ID, "operator", operator, "err", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	slog.InfoContext(ctx, "admin action", "action", action, "partner_id", p.ID, "operator", operator)
+}
+
+func (s *Server) handleAdminPartnerOrders(c *gin.Context) {
+	if p, ok := s.adminPartner(c); ok {
+		s.listPartnerOrders(c, p.ID, true)
+	}
+}
+
+func (s *Server) handleAdminPartnerVolume(c *gin.Context) {
+	if p, ok := s.adminPartner(c); ok {
+		s.partnerVolume(c, p.ID)
+	}
+}
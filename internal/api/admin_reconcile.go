@@ -0,0 +1,20 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mvg-fi-dev/bridge/internal/reconcile"
+)
+
+// handleAdminReconcile returns the worker's latest wallet reconciliation
+// report (null before the first run).
+func (s *Server) handleAdminReconcile(c *gin.Context) {
+	rep, err := reconcile.LoadReport(c.Request.Context(), s.KV)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"report": rep})
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+	"github.com/mvg-fi-dev/bridge/internal/switches"
+)
+
+func (s *Server) handleAdminListSwitches(c *gin.Context) {
+	st, err := switches.Load(c.Request.Context(), s.KV)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+// handleAdminDisableSwitch turns a chain, asset or stage off:
+// PUT /admin/switches/{chain|asset|stage}/{name} {"reason": "..."}
+func (s *Server) handleAdminDisableSwitch(c *gin.Context) {
+	var req adminReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	kind := switches.Kind(c.Param("kind"))
+	name, err := switches.Normalize(kind, c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sw := switches.Switch{Kind: kind, Name: name, Reason: req.Reason, Operator: operatorFrom(c), Since: time.Now().UTC()}
+	s.switchAction(c, "switch_off", sw, func(tx store.AdminTx) error {
+		return switches.Disable(c.Request.Context(), tx.KV, sw)
+	})
+}
+
+func (s *Server) handleAdminEnableSwitch(c *gin.Context) {
+	kind := switches.Kind(c.Param("kind"))
+	name, err := switches.Normalize(kind, c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sw := switches.Switch{Kind: kind, Name: name}
+	s.switchAction(c, "switch_on", sw, func(tx store.AdminTx) error {
+		return switches.Enable(c.Request.Context(), tx.KV, kind, name)
+	})
+}
+
+func (s *Server) switchAction(c *gin.Context, action string, sw switches.Switch, fn func(tx store.AdminTx) error) {
+	ctx := c.Request.Context()
+	operator := operatorFrom(c)
+	err := s.Admin.InAdminTx(ctx, func(tx store.AdminTx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx.Audit, operator, action, "", gin.H{"kind": sw.Kind, "name": sw.Name, "reason": sw.Reason})
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "admin action failed", "action", action, string(sw.Kind), sw.Name, "operator", operator, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	slog.InfoContext(ctx, "admin action", "action", action, string(sw.Kind), sw.Name, "operator", operator)
+	s.handleAdminListSwitches(c)
+}
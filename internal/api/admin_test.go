@@ -0,0 +1,362 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store/memstore"
+)
+
+const adminToken = "s3cret"
+
+func strp(s string) *string { return &s }
+
+// testServer is a Server over memstores with the admin API enabled for
+// operator "alice".
+type testServer struct {
+	s      *Server
+	r      *gin.Engine
+	orders *memstore.Orders
+	admin  *memstore.Admin
+	kv     *memstore.KV
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	ts := &testServer{orders: memstore.NewOrders(), kv: memstore.NewKV()}
+	ts.admin = memstore.NewAdmin(ts.orders, ts.kv)
+	ts.s = &Server{
+		Orders:      ts.orders,
+		Admin:       ts.admin,
+		AdminTokens: map[string]string{adminToken: "alice"},
+		KV:          ts.kv,
+	}
+	ts.r = gin.New()
+	ts.s.Register(ts.r)
+	return ts
+}
+
+// do serves one request; token is sent as the admin bearer token when set.
+func (ts *testServer) do(t *testing.T, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	var rd io.Reader
+	if body != "" {
+		rd = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, rd)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	ts.r.ServeHTTP(w, req)
+	return w
+}
+
+// insert stores o as order id "o1" / public id "p1" unless set otherwise.
+func (ts *testServer) insert(t *testing.T, o *models.Order) {
+	t.Helper()
+	now := time.Now().UTC()
+	if o.ID == "" {
+		o.ID, o.PublicID = "o1", "p1"
+	}
+	o.CreatedAt, o.UpdatedAt = now, now
+	if o.SourceAsset == "" {
+		o.SourceAsset, o.TargetAsset, o.AmountIn = "src", "dst", "1"
+	}
+	if err := ts.orders.Insert(context.Background(), o); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (ts *testServer) order(t *testing.T, id string) *models.Order {
+	t.Helper()
+	o, err := ts.orders.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+// actions returns the audited actions for order id, oldest first.
+func (ts *testServer) actions(t *testing.T, id string) []string {
+	t.Helper()
+	entries, err := ts.admin.ListAudit(context.Background(), id, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Operator != "alice" {
+			t.Errorf("audit %s by %q, want alice", entries[i].Action, entries[i].Operator)
+		}
+		out = append(out, entries[i].Action)
+	}
+	return out
+}
+
+func TestAdminAuth(t *testing.T) {
+	ts := newTestServer(t)
+	ts.insert(t, &models.Order{Status: models.StatusWithdrawing})
+
+	for name, header := range map[string]string{
+		"missing": "",
+		"wrong":   "Bearer nope",
+		"other":   "Basic " + adminToken,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/admin/orders/p1/retry", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		ts.r.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s token: %d, want 401", name, w.Code)
+		}
+	}
+	if got := ts.actions(t, "o1"); len(got) != 0 {
+		t.Errorf("unauthorized requests audited: %v", got)
+	}
+
+	if w := ts.do(t, http.MethodGet, "/admin/orders/p1", adminToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("get: %d %s", w.Code, w.Body)
+	}
+	// The internal id resolves too.
+	if w := ts.do(t, http.MethodGet, "/admin/orders/o1", adminToken, ""); w.Code != http.StatusOK {
+		t.Errorf("get by internal id: %d", w.Code)
+	}
+	if w := ts.do(t, http.MethodGet, "/admin/orders/nope", adminToken, ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown order: %d, want 404", w.Code)
+	}
+}
+
+func TestAdminRetry(t *testing.T) {
+	ts := newTestServer(t)
+	later := time.Now().Add(time.Hour)
+	ts.insert(t, &models.Order{Status: models.StatusWithdrawing, NextAttemptAt: &later})
+	ts.insert(t, &models.Order{ID: "o2", PublicID: "p2", Status: models.StatusCompleted})
+
+	if w := ts.do(t, http.MethodPost, "/admin/orders/p1/retry", adminToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("retry: %d %s", w.Code, w.Body)
+	}
+	if o := ts.order(t, "o1"); o.NextAttemptAt != nil || o.Status != models.StatusWithdrawing {
+		t.Errorf("after retry: status %s, next attempt %v", o.Status, o.NextAttemptAt)
+	}
+	if w := ts.do(t, http.MethodPost, "/admin/orders/p2/retry", adminToken, ""); w.Code != http.StatusConflict {
+		t.Errorf("retry completed: %d, want 409", w.Code)
+	}
+	if got := ts.actions(t, "o1"); len(got) != 1 || got[0] != "force_retry" {
+		t.Errorf("o1 audit = %v", got)
+	}
+	if got := ts.actions(t, "o2"); len(got) != 0 {
+		t.Errorf("o2 audit = %v, want none", got)
+	}
+}
+
+func TestAdminReviewAndRelease(t *testing.T) {
+	ts := newTestServer(t)
+	ts.insert(t, &models.Order{Status: models.StatusWithdrawing, Attempts: 3, FinalOut: strp("2")})
+
+	if w := ts.do(t, http.MethodPost, "/admin/orders/p1/review", adminToken, `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("review without reason: %d, want 400", w.Code)
+	}
+	if w := ts.do(t, http.MethodPost, "/admin/orders/p1/release", adminToken, ""); w.Code != http.StatusConflict {
+		t.Errorf("release while withdrawing: %d, want 409", w.Code)
+	}
+
+	w := ts.do(t, http.MethodPost, "/admin/orders/p1/review", adminToken, `{"reason":"check address"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("review: %d %s", w.Code, w.Body)
+	}
+	var view struct {
+		Status     models.OrderStatus `json:"status"`
+		RetryStage *string            `json:"retry_stage"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &view); err != nil {
+		t.Fatal(err)
+	}
+	if view.Status != models.StatusFailedManual || view.RetryStage == nil || *view.RetryStage != string(models.StageWithdraw) {
+		t.Errorf("review response = %+v", view)
+	}
+	if w := ts.do(t, http.MethodPost, "/admin/orders/p1/review", adminToken, `{"reason":"again"}`); w.Code != http.StatusConflict {
+		t.Errorf("second review: %d, want 409", w.Code)
+	}
+	if w := ts.do(t, http.MethodPost, "/admin/orders/p1/release", adminToken, `{"stage":"nope"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("release to unknown stage: %d, want 400", w.Code)
+	}
+
+	// Without a body the order resumes the stage it was held in.
+	if w := ts.do(t, http.MethodPost, "/admin/orders/p1/release", adminToken, ""); w.Code != http.StatusOK {
+		t.Fatalf("release: %d %s", w.Code, w.Body)
+	}
+	if o := ts.order(t, "o1"); o.Status != models.StatusWithdrawing || o.Attempts != 0 {
+		t.Errorf("after release: status %s, attempts %d", o.Status, o.Attempts)
+	}
+	got := ts.actions(t, "o1")
+	if strings.Join(got, ",") != "hold_for_review,release_from_review" {
+		t.Errorf("audit = %v", got)
+	}
+}
+
+func TestAdminReleaseComplianceHold(t *testing.T) {
+	ts := newTestServer(t)
+	ts.insert(t, &models.Order{Status: models.StatusComplianceHold, RetryStage: strp(string(models.StageSwap)), RefundToAddress: strp("addr")})
+
+	if w := ts.do(t, http.MethodPost, "/admin/orders/p1/review", adminToken, `{"reason":"x"}`); w.Code != http.StatusConflict {
+		t.Errorf("review compliance_hold: %d, want 409", w.Code)
+	}
+	if w := ts.do(t, http.MethodPost, "/admin/orders/p1/release", adminToken, `{"stage":"swap"}`); w.Code != http.StatusOK {
+		t.Fatalf("release: %d %s", w.Code, w.Body)
+	}
+	o := ts.order(t, "o1")
+	if o.Status != models.StatusDepositCredited || o.ScreeningClearedAt == nil {
+		t.Errorf("after release: status %s, screening cleared %v", o.Status, o.ScreeningClearedAt)
+	}
+}
+
+func TestAdminSwapInFlight(t *testing.T) {
+	ts := newTestServer(t)
+	ts.insert(t, &models.Order{Status: models.StatusExecutingSwap, RefundToAddress: strp("addr"), AmountCredited: strp("1")})
+	// Held after submitting the swap, before ExinSwap replied.
+	ts.insert(t, &models.Order{
+		ID: "o2", PublicID: "p2", Status: models.StatusFailedManual, RetryStage: strp(string(models.StageSwap)),
+		RefundToAddress: strp("addr"), AmountCredited: strp("1"), ExinSwapTraceID: strp("swap-trace"),
+	})
+
+	for _, path := range []string{
+		"/admin/orders/p1/review",
+		"/admin/orders/p2/refund",
+	} {
+		w := ts.do(t, http.MethodPost, path, adminToken, `{"reason":"stuck"}`)
+		if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "in flight") {
+			t.Errorf("%s: %d %s, want 409 swap in flight", path, w.Code, w.Body)
+		}
+	}
+	if o := ts.order(t, "o2"); o.Status != models.StatusFailedManual {
+		t.Errorf("o2 status = %s", o.Status)
+	}
+	if got := ts.actions(t, ""); len(got) != 0 {
+		t.Errorf("refused actions audited: %v", got)
+	}
+}
+
+func TestAdminRefund(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		order models.Order
+		code  int
+		// for 200: the refund asset and amount recorded on the order
+		asset, amount string
+	}{
+		{
+			name:  "deposit credited",
+			order: models.Order{Status: models.StatusDepositCredited, RefundToAddress: strp("addr"), AmountCredited: strp("1")},
+			code:  http.StatusOK,
+		},
+		{
+			name: "compliance hold",
+			order: models.Order{Status: models.StatusComplianceHold, RetryStage: strp(string(models.StageSwap)),
+				RefundToAddress: strp("addr"), AmountCredited: strp("1")},
+			code: http.StatusOK,
+		},
+		{
+			name: "held after swap paid out",
+			order: models.Order{Status: models.StatusFailedManual, RetryStage: strp(string(models.StageWithdraw)),
+				RefundToAddress: strp("addr"), AmountCredited: strp("1"), ExinSwapTraceID: strp("swap-trace"), FinalOut: strp("2")},
+			code: http.StatusOK, asset: "dst", amount: "2",
+		},
+		{
+			name:  "wrong status",
+			order: models.Order{Status: models.StatusWithdrawing, RefundToAddress: strp("addr"), AmountCredited: strp("1")},
+			code:  http.StatusConflict,
+		},
+		{
+			name:  "no refund_to_address",
+			order: models.Order{Status: models.StatusDepositCredited, AmountCredited: strp("1")},
+			code:  http.StatusConflict,
+		},
+		{
+			name:  "nothing credited",
+			order: models.Order{Status: models.StatusFailedManual, RetryStage: strp(string(models.StageSwap)), RefundToAddress: strp("addr")},
+			code:  http.StatusConflict,
+		},
+		{
+			name: "withdrawal submitted",
+			order: models.Order{Status: models.StatusFailedManual, RetryStage: strp(string(models.StageWithdraw)),
+				RefundToAddress: strp("addr"), ExinSwapTraceID: strp("swap-trace"), FinalOut: strp("2"), WithdrawTraceID: strp("wd-trace")},
+			code: http.StatusConflict,
+		},
+		{
+			name: "refund submitted",
+			order: models.Order{Status: models.StatusFailedManual, RetryStage: strp(string(models.StageRefund)),
+				RefundToAddress: strp("addr"), AmountCredited: strp("1"), RefundTraceID: strp("rf-trace")},
+			code: http.StatusConflict,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newTestServer(t)
+			o := tc.order
+			ts.insert(t, &o)
+
+			w := ts.do(t, http.MethodPost, "/admin/orders/p1/refund", adminToken, `{"reason":"user asked"}`)
+			if w.Code != tc.code {
+				t.Fatalf("refund: %d %s, want %d", w.Code, w.Body, tc.code)
+			}
+			got := ts.order(t, "o1")
+			audit := ts.actions(t, "o1")
+			if tc.code != http.StatusOK {
+				if got.Status != o.Status || len(audit) != 0 {
+					t.Errorf("refused refund changed status to %s, audit %v", got.Status, audit)
+				}
+				return
+			}
+			if got.Status != models.StatusRefunding || got.Attempts != 0 {
+				t.Errorf("status %s, attempts %d", got.Status, got.Attempts)
+			}
+			if tc.asset != "" && (got.RefundAssetID == nil || *got.RefundAssetID != tc.asset || got.RefundAmount == nil || *got.RefundAmount != tc.amount) {
+				t.Errorf("refund asset %v amount %v, want %s %s", got.RefundAssetID, got.RefundAmount, tc.asset, tc.amount)
+			}
+			if len(audit) != 1 || audit[0] != "manual_refund" {
+				t.Errorf("audit = %v", audit)
+			}
+		})
+	}
+}
+
+func TestAdminNotes(t *testing.T) {
+	ts := newTestServer(t)
+	ts.insert(t, &models.Order{Status: models.StatusWithdrawing})
+
+	if w := ts.do(t, http.MethodPost, "/admin/orders/p1/notes", adminToken, `{"note":"called the user"}`); w.Code != http.StatusOK {
+		t.Fatalf("note: %d %s", w.Code, w.Body)
+	}
+	w := ts.do(t, http.MethodGet, "/admin/orders/p1", adminToken, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("get: %d", w.Code)
+	}
+	var resp struct {
+		Notes []models.OrderNote  `json:"notes"`
+		Audit []models.AuditEntry `json:"audit"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Notes) != 1 || resp.Notes[0].Note != "called the user" || resp.Notes[0].Operator != "alice" {
+		t.Errorf("notes = %+v", resp.Notes)
+	}
+	if len(resp.Audit) != 1 || resp.Audit[0].Action != "annotate" || !strings.Contains(resp.Audit[0].Detail, resp.Notes[0].ID) {
+		t.Errorf("audit = %+v", resp.Audit)
+	}
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/orderview"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+const (
+	defaultOrderPage = 50
+	maxOrderPage     = 500
+)
+
+// maxExportRows bounds one CSV/JSON export; narrow the filters (or page with
+// the cursor) for more. A var so tests can lower it.
+var maxExportRows = 50000
+
+// parseOrderFilter reads the listing query shared by the partner and admin
+// routes: ?status=&asset=&target_address=&sender=&venue=&q=&from=&to=
+// &cursor=&limit=&offset=. partner_id is read by the admin route only.
+func parseOrderFilter(c *gin.Context) (store.OrderFilter, error) {
+	from, to, err := parseDateRange(c, time.Time{})
+	if err != nil {
+		return store.OrderFilter{}, err
+	}
+	f := store.OrderFilter{
+		Status:        models.OrderStatus(c.Query("status")),
+		Asset:         c.Query("asset"),
+		TargetAddress: c.Query("target_address"),
+		Sender:        c.Query("sender"),
+		Venue:         c.Query("venue"),
+		Search:        strings.TrimSpace(c.Query("q")),
+		CreatedFrom:   from,
+		CreatedTo:     to,
+	}
+	if f.Venue != "" && !slices.Contains(store.Venues, f.Venue) {
+		return f, fmt.Errorf("venue must be one of %s", strings.Join(store.Venues, ", "))
+	}
+	f.Limit, _ = strconv.Atoi(c.Query("limit"))
+	if f.Limit <= 0 || f.Limit > maxOrderPage {
+		f.Limit = defaultOrderPage
+	}
+	f.Offset, _ = strconv.Atoi(c.Query("offset"))
+	if v := c.Query("cursor"); v != "" {
+		// An offset past a cursor would skip orders the client never saw.
+		if f.Offset != 0 {
+			return f, fmt.Errorf("offset cannot be combined with cursor")
+		}
+		if f.After, err = decodeOrderCursor(v); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// The cursor is opaque to clients: base64url("<created_at>|<id>") of the
+// last order of a page.
+func encodeOrderCursor(o *models.Order) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(o.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + o.ID))
+}
+
+func decodeOrderCursor(v string) (*store.OrderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(v)
+	if err == nil {
+		at, id, ok := strings.Cut(string(raw), "|")
+		if t, perr := time.Parse(time.RFC3339Nano, at); ok && perr == nil && id != "" {
+			return &store.OrderCursor{CreatedAt: t, ID: id}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid cursor")
+}
+
+// orderColumn is one CSV export column. Exports have orderColumns; admin
+// exports add adminColumns.
+type orderColumn struct {
+	name string
+	get  func(o *models.Order) string
+}
+
+func str(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+func timeStr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// csvCell keeps spreadsheets from evaluating user-supplied text (target
+// addresses, errors) as a formula.
+func csvCell(v string) string {
+	if v == "" || !strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return v
+	}
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		return v
+	}
+	return "'" + v
+}
+
+var orderColumns = []orderColumn{
+	{"public_id", func(o *models.Order) string { return o.PublicID }},
+	{"status", func(o *models.Order) string { return string(o.Status) }},
+	{"created_at", func(o *models.Order) string { return timeStr(&o.CreatedAt) }},
+	{"updated_at", func(o *models.Order) string { return timeStr(&o.UpdatedAt) }},
+	{"source_chain", func(o *models.Order) string { return o.SourceChain }},
+	{"source_asset", func(o *models.Order) string { return o.SourceAsset }},
+	{"amount_in", func(o *models.Order) string { return o.AmountIn }},
+	{"target_chain", func(o *models.Order) string { return o.TargetChain }},
+	{"target_asset", func(o *models.Order) string { return o.TargetAsset }},
+	{"target_address", func(o *models.Order) string { return o.TargetAddress }},
+	{"estimated_out", func(o *models.Order) string { return o.EstimatedOut }},
+	{"min_out", func(o *models.Order) string { return o.MinOut }},
+	{"deposit_tx_id", func(o *models.Order) string { return str(o.DepositTxID) }},
+	{"amount_credited", func(o *models.Order) string { return str(o.AmountCredited) }},
+	{"deposit_credited_at", func(o *models.Order) string { return timeStr(o.DepositCreditedAt) }},
+	{"fee_amount", func(o *models.Order) string { return str(o.FeeAmount) }},
+	{"final_out", func(o *models.Order) string { return str(o.FinalOut) }},
+	{"withdraw_tx_id", func(o *models.Order) string { return str(o.WithdrawTxID) }},
+	{"refund_asset_id", func(o *models.Order) string { return str(o.RefundAssetID) }},
+	{"refund_amount", func(o *models.Order) string { return str(o.RefundAmount) }},
+	{"refund_tx_id", func(o *models.Order) string { return str(o.RefundTxID) }},
+}
+
+var adminColumns = []orderColumn{
+	{"id", func(o *models.Order) string { return o.ID }},
+	{"partner_id", func(o *models.Order) string { return str(o.PartnerID) }},
+	{"sender", func(o *models.Order) string { return str(o.RefundToAddress) }},
+	{"partner_fee_amount", func(o *models.Order) string { return str(o.PartnerFeeAmount) }},
+	{"exinswap_trace_id", func(o *models.Order) string { return str(o.ExinSwapTraceID) }},
+	{"withdraw_trace_id", func(o *models.Order) string { return str(o.WithdrawTraceID) }},
+	{"refund_trace_id", func(o *models.Order) string { return str(o.RefundTraceID) }},
+	{"attempts", func(o *models.Order) string { return strconv.FormatInt(o.Attempts, 10) }},
+	{"last_error", func(o *models.Order) string { return str(o.LastError) }},
+}
+
+// writeOrders answers a listing: one page as JSON ({"orders", "next_cursor"})
+// or, with ?format=csv|json, every matching order as a download. admin picks
+// the operator view over the public one.
+func (s *Server) writeOrders(c *gin.Context, f store.OrderFilter, admin bool) {
+	switch format := c.Query("format"); format {
+	case "":
+	case "csv", "json":
+		s.exportOrders(c, f, format, admin)
+		return
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+	orders, err := s.Orders.ListOrders(c.Request.Context(), f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	resp := gin.H{}
+	if admin {
+		resp["orders"] = orderview.NewAdmins(orders)
+	} else {
+		resp["orders"] = orderview.NewList(orders)
+	}
+	if len(orders) == f.Limit {
+		resp["next_cursor"] = encodeOrderCursor(orders[len(orders)-1])
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// exportOrders streams every order matching f, walking the cursor a page at
+// a time. X-Export-Truncated is true when maxExportRows (or a failed page)
+// cut the export short, i.e. some matching order was left out.
+func (s *Server) exportOrders(c *gin.Context, f store.OrderFilter, format string, admin bool) {
+	ctx := c.Request.Context()
+	f.Limit, f.Offset = maxOrderPage, 0
+	page, err := s.Orders.ListOrders(ctx, f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+
+	cols := orderColumns
+	if admin {
+		cols = append(slices.Clone(orderColumns), adminColumns...)
+	}
+	name := "orders-" + time.Now().UTC().Format("20060102T150405Z") + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Header("Trailer", "X-Export-Truncated")
+	var cw *csv.Writer
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		cw = csv.NewWriter(c.Writer)
+		header := make([]string, len(cols))
+		for i, col := range cols {
+			header[i] = col.name
+		}
+		_ = cw.Write(header)
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.Status(http.StatusOK)
+		_, _ = c.Writer.WriteString("[")
+	}
+
+	rows, truncated := 0, false
+	for len(page) > 0 {
+		if left := maxExportRows - rows; len(page) > left {
+			page, truncated = page[:left], true
+		}
+		for _, o := range page {
+			if cw != nil {
+				rec := make([]string, len(cols))
+				for i, col := range cols {
+					rec[i] = csvCell(col.get(o))
+				}
+				_ = cw.Write(rec)
+				continue
+			}
+			var v any = orderview.New(o)
+			if admin {
+				v = orderview.NewAdmin(o)
+			}
+			b, _ := json.Marshal(v)
+			if rows > 0 {
+				_, _ = c.Writer.WriteString(",")
+			}
+			_, _ = c.Writer.Write(b)
+			rows++
+		}
+		if cw != nil {
+			rows += len(page)
+			cw.Flush()
+		}
+		if truncated || len(page) < maxOrderPage || ctx.Err() != nil {
+			break
+		}
+		f.After = &store.OrderCursor{CreatedAt: page[len(page)-1].CreatedAt, ID: page[len(page)-1].ID}
+		if rows == maxExportRows {
+			f.Limit = 1 // only to learn whether another order matches
+		}
+		if page, err = s.Orders.ListOrders(ctx, f); err != nil {
+			// Headers are out; cut the export short and flag it.
+			truncated = true
+			break
+		}
+	}
+	if cw == nil {
+		_, _ = c.Writer.WriteString("]")
+	}
+	c.Writer.Header().Set("X-Export-Truncated", strconv.FormatBool(truncated))
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+func TestOrderCursor(t *testing.T) {
+	at := time.Date(2026, 10, 19, 8, 30, 0, 123456789, time.FixedZone("CEST", 2*3600))
+	o := &models.Order{ID: "o|1", CreatedAt: at}
+	cur, err := decodeOrderCursor(encodeOrderCursor(o))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cur.CreatedAt.Equal(at) || cur.ID != "o|1" {
+		t.Errorf("round trip = %v %q", cur.CreatedAt, cur.ID)
+	}
+
+	for _, bad := range []string{"", "!!!", "bm8tc2VwYXJhdG9y", "MjAyNi0xMC0xOXw"} { // "no-separator", "2026-10-19|"
+		if _, err := decodeOrderCursor(bad); err == nil {
+			t.Errorf("decodeOrderCursor(%q) accepted", bad)
+		}
+	}
+}
+
+func TestCSVCell(t *testing.T) {
+	for in, want := range map[string]string{
+		"":               "",
+		"0xabc":          "0xabc",
+		"=HYPERLINK(1)":  "'=HYPERLINK(1)",
+		"+cmd":           "'+cmd",
+		"-2+3":           "'-2+3",
+		"@SUM(A1)":       "'@SUM(A1)",
+		"\tfoo":          "'\tfoo",
+		"\rfoo":          "'\rfoo",
+		"-1.5":           "-1.5", // numbers stay numbers
+		"+2":             "+2",
+		"a=b":            "a=b",
+		"swap failed: x": "swap failed: x",
+	} {
+		if got := csvCell(in); got != want {
+			t.Errorf("csvCell(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// insertOrders stores n orders o000, o001, ... all created at the same
+// instant, so only the id orders them.
+func (ts *testServer) insertOrders(t *testing.T, n int) {
+	t.Helper()
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("o%03d", i)
+		o := &models.Order{ID: id, PublicID: "p" + id, Status: models.StatusCompleted, CreatedAt: at, UpdatedAt: at,
+			SourceAsset: "src", TargetAsset: "dst", AmountIn: "1", TargetAddress: "=cmd|' /C calc'!A0"}
+		if err := ts.orders.Insert(context.Background(), o); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListOrdersCursor(t *testing.T) {
+	ts := newTestServer(t)
+	ts.insertOrders(t, 5)
+
+	var seen []string
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		w := ts.do(t, http.MethodGet, "/admin/orders?limit=2&cursor="+cursor, adminToken, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("page %d: %d %s", page, w.Code, w.Body)
+		}
+		var resp struct {
+			Orders     []struct{ ID string }
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range resp.Orders {
+			seen = append(seen, o.ID)
+		}
+		if cursor = resp.NextCursor; cursor == "" {
+			break
+		}
+	}
+	// Equal created_at: the id breaks the tie, newest (highest) first.
+	if got := strings.Join(seen, ","); got != "o004,o003,o002,o001,o000" {
+		t.Errorf("paged through %s", got)
+	}
+
+	if w := ts.do(t, http.MethodGet, "/admin/orders?cursor=nope", adminToken, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("bad cursor: %d, want 400", w.Code)
+	}
+	cur := encodeOrderCursor(&models.Order{ID: "o003", CreatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if w := ts.do(t, http.MethodGet, "/admin/orders?offset=1&cursor="+cur, adminToken, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("offset with cursor: %d %s, want 400", w.Code, w.Body)
+	}
+}
+
+func TestExportOrders(t *testing.T) {
+	defer func(n int) { maxExportRows = n }(maxExportRows)
+
+	export := func(ts *testServer, format string) (body string, truncated string) {
+		t.Helper()
+		w := ts.do(t, http.MethodGet, "/admin/orders?format="+format, adminToken, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("export: %d %s", w.Code, w.Body)
+		}
+		return w.Body.String(), w.Result().Trailer.Get("X-Export-Truncated")
+	}
+
+	for _, tc := range []struct {
+		name      string
+		max, n    int
+		rows      int
+		truncated string
+	}{
+		{"under the cap", 3, 2, 2, "false"},
+		{"exactly the cap", 3, 3, 3, "false"},
+		{"over the cap", 3, 4, 3, "true"},
+		// The cap falls on a page boundary: only a probe tells these apart.
+		{"exactly a full page", maxOrderPage, maxOrderPage, maxOrderPage, "false"},
+		{"one past a full page", maxOrderPage, maxOrderPage + 1, maxOrderPage, "true"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			maxExportRows = tc.max
+			ts := newTestServer(t)
+			ts.insertOrders(t, tc.n)
+
+			body, truncated := export(ts, "csv")
+			recs, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(recs)-1 != tc.rows || truncated != tc.truncated {
+				t.Errorf("csv: %d rows, truncated %q; want %d, %q", len(recs)-1, truncated, tc.rows, tc.truncated)
+			}
+			if len(recs) > 1 && !strings.HasPrefix(recs[1][9], "'=") {
+				t.Errorf("target_address exported as %q", recs[1][9])
+			}
+
+			body, truncated = export(ts, "json")
+			var orders []map[string]any
+			if err := json.Unmarshal([]byte(body), &orders); err != nil {
+				t.Fatal(err)
+			}
+			if len(orders) != tc.rows || truncated != tc.truncated {
+				t.Errorf("json: %d rows, truncated %q; want %d, %q", len(orders), truncated, tc.rows, tc.truncated)
+			}
+		})
+	}
+}
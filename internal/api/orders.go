@@ -1,13 +1,30 @@
 package api
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/mvg-fi-dev/bridge/internal/db"
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/fees"
 	"github.com/mvg-fi-dev/bridge/internal/ids"
+	"github.com/mvg-fi-dev/bridge/internal/limits"
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/mixin"
 	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/orderview"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+	"github.com/mvg-fi-dev/bridge/internal/switches"
+	"github.com/mvg-fi-dev/bridge/internal/tracing"
 )
 
 type CreateOrderRequest struct {
@@ -15,7 +32,7 @@ type CreateOrderRequest struct {
 	// Later we can add chain/asset mapping.
 	MixinAssetID string `json:"mixin_asset_id" binding:"required"`
 	AmountIn     string `json:"amount_in" binding:"required"`
-	
+
 	TargetChain   string `json:"target_chain" binding:"required"`
 	TargetAsset   string `json:"target_asset" binding:"required"`
 	TargetAddress string `json:"target_address" binding:"required"`
@@ -26,29 +43,74 @@ type CreateOrderRequest struct {
 }
 
 type CreateOrderResponse struct {
-	PublicID string            `json:"public_id"`
-	Status   models.OrderStatus `json:"status"`
-	PayWindowSeconds int64     `json:"pay_window_seconds"`
-	MixinPayment struct {
+	PublicID         string             `json:"public_id"`
+	Status           models.OrderStatus `json:"status"`
+	PayWindowSeconds int64              `json:"pay_window_seconds"`
+	MixinPayment     struct {
 		OpponentID string `json:"opponent_id"`
 		AssetID    string `json:"asset_id"`
 		Amount     string `json:"amount"`
 		Memo       string `json:"memo"`
+		// Prefilled transfer links (and a QR code of PayURL); unset when
+		// the bot user id is not configured.
+		PayURL    string `json:"pay_url,omitempty"`
+		MixinURL  string `json:"mixin_url,omitempty"`
+		QRCodeURL string `json:"qr_code_url,omitempty"`
 	} `json:"mixin_payment"`
 	Quote struct {
 		EstimatedOut string `json:"estimated_out"`
 		MinOut       string `json:"min_out"`
 	} `json:"quote"`
+	// Fee withheld from the deposit before the swap; quote amounts are net of it.
+	Fee   *OrderFee         `json:"fee,omitempty"`
 	Terms map[string]string `json:"terms"`
 }
 
+type OrderFee struct {
+	AssetID   string `json:"asset_id"`
+	Amount    string `json:"amount"`
+	SpreadBps int64  `json:"spread_bps"`
+	Flat      string `json:"flat"`
+}
+
 func (s *Server) handleCreateOrder(c *gin.Context) {
 	var req CreateOrderRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
+	// Idempotency-Key: a retried request returns the order created by the
+	// first one instead of a new order with a new memo.
+	var idem *models.IdempotencyKey
+	if key := strings.TrimSpace(c.GetHeader("Idempotency-Key")); key != "" {
+		if len(key) > maxIdempotencyKeyLen {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key too long"})
+			return
+		}
+		raw, _ := json.Marshal(req)
+		sum := sha256.Sum256(raw)
+		idem = &models.IdempotencyKey{Scope: idempotencyScope(c), Key: key, RequestHash: hex.EncodeToString(sum[:])}
+		if s.replayIdempotent(c, idem) {
+			return
+		}
+	}
+
+	// Kill-switches: refuse new orders for disabled chains/assets.
+	if s.KV != nil {
+		st, err := switches.Load(c.Request.Context(), s.KV)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+			return
+		}
+		if sw, off := st.CheckNewOrder("MIXIN", req.MixinAssetID, req.TargetChain, req.TargetAsset); off {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":  fmt.Sprintf("%s %s is temporarily disabled", sw.Kind, sw.Name),
+				"reason": sw.Reason,
+			})
+			return
+		}
+	}
+
 	memo, err := ids.NewToken(10) // ~16 chars base32
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "token"})
@@ -63,28 +125,201 @@ func (s *Server) handleCreateOrder(c *gin.Context) {
 		CreatedAt: now,
 		UpdatedAt: now,
 
-		SourceChain:  "MIXIN",
-		SourceAsset:  req.MixinAssetID,
-		AmountIn:     req.AmountIn,
-		TargetChain:  req.TargetChain,
-		TargetAsset:  req.TargetAsset,
+		SourceChain:   "MIXIN",
+		SourceAsset:   req.MixinAssetID,
+		AmountIn:      req.AmountIn,
+		TargetChain:   req.TargetChain,
+		TargetAsset:   req.TargetAsset,
 		TargetAddress: req.TargetAddress,
 
-		EstimatedOut: req.EstimatedOut,
-		MinOut:       req.MinOut,
+		EstimatedOut:     req.EstimatedOut,
+		MinOut:           req.MinOut,
 		PayWindowSeconds: s.PayWindowSeconds,
 
 		MixinOpponentID: s.MixinBotUserID,
 		MixinAssetID:    req.MixinAssetID,
 		MixinPayMemo:    memo,
 	}
+	if o.MixinOpponentID != "" {
+		// One trace per order, so a second scan of the same link cannot pay twice.
+		o.MixinPayURL = mixin.PayURL(o.MixinOpponentID, o.MixinAssetID, o.AmountIn, ids.DeterministicUUID("pay:"+o.ID), o.MixinPayMemo)
+	}
+	partner := partnerFrom(c)
+	if partner != nil {
+		o.PartnerID = &partner.ID
+	}
+
+	// Screening: reject flagged target addresses. The response deliberately
+	// does not say which list matched.
+	if s.Screener != nil {
+		r, err := s.Screener.Screen(c.Request.Context(), req.TargetChain, req.TargetAddress)
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "screening failed", "err", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "screening unavailable"})
+			return
+		}
+		if r.Hit {
+			slog.WarnContext(c.Request.Context(), "screening rejected order", "target_chain", req.TargetChain, "source", r.Source, "reason", r.Reason)
+			c.JSON(http.StatusForbidden, gin.H{"error": "target_address is not allowed", "code": "address_blocked"})
+			return
+		}
+	}
+
+	// Fees: fix the terms on the order and quote the output net of the fee.
+	if s.KV != nil {
+		if err := s.applyFees(c.Request.Context(), o, partner); err != nil {
+			var b *limits.Breach
+			if errors.As(err, &b) {
+				writeBreach(c, b)
+				return
+			}
+			slog.ErrorContext(c.Request.Context(), "fees failed", "err", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "fees unavailable"})
+			return
+		}
+	}
+
+	if s.Limits != nil {
+		if err := s.Limits.CheckNewOrder(c.Request.Context(), o); err != nil {
+			var b *limits.Breach
+			switch {
+			case errors.As(err, &b):
+				writeBreach(c, b)
+			default:
+				slog.ErrorContext(c.Request.Context(), "limits check failed", "err", err)
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "limits unavailable"})
+			}
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if partner != nil && partner.DailyOrderQuota > 0 {
+		dayStart := now.Truncate(24 * time.Hour)
+		n, err := s.Orders.CountOrders(ctx, store.OrderFilter{PartnerID: partner.ID, CreatedFrom: dayStart})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+			return
+		}
+		if n >= partner.DailyOrderQuota {
+			abortTooManyRequests(c, "partner_quota_exceeded", "daily order quota reached", dayStart.Add(24*time.Hour).Sub(now))
+			return
+		}
+	}
+
+	if tp := tracing.Inject(ctx); tp != "" {
+		o.TraceContext = &tp
+	}
+	err = s.Orders.InTx(ctx, func(tx store.OrderStore) error {
+		if err := tx.Insert(ctx, o); err != nil {
+			return err
+		}
+		if idem == nil {
+			return nil
+		}
+		idem.OrderID = o.ID
+		idem.CreatedAt = now
+		return tx.InsertIdempotencyKey(ctx, idem)
+	})
+	// A concurrent request with the same key won the race; answer with its order.
+	if errors.Is(err, store.ErrConflict) && s.replayIdempotent(c, idem) {
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "insert order failed", "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	slog.InfoContext(logging.WithOrder(ctx, o), "order created", "source_asset", o.SourceAsset, "target_asset", o.TargetAsset, "target_chain", o.TargetChain)
+
+	c.JSON(http.StatusOK, newCreateOrderResponse(o))
+}
+
+const maxIdempotencyKeyLen = 255
+
+// idempotencyScope keeps one partner's keys from colliding with another's.
+// Anonymous keys are scoped by client IP, so one caller cannot replay (and
+// read) an order another caller created under the same key.
+func idempotencyScope(c *gin.Context) string {
+	if p := partnerFrom(c); p != nil {
+		return "partner:" + p.ID
+	}
+	return "ip:" + c.ClientIP()
+}
 
-	repo := db.NewOrdersRepo(s.DB)
-	if err := repo.Insert(c.Request.Context(), o); err != nil {
+// replayIdempotent answers the request from a previously stored key. It
+// returns false if the key is unused and the order should be created.
+func (s *Server) replayIdempotent(c *gin.Context, idem *models.IdempotencyKey) bool {
+	ctx := c.Request.Context()
+	prev, err := s.Orders.GetIdempotencyKey(ctx, idem.Scope, idem.Key)
+	if errors.Is(err, store.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return true
+	}
+	if prev.RequestHash != idem.RequestHash {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request", "code": "idempotency_key_reused"})
+		return true
+	}
+	o, err := s.Orders.GetByID(ctx, prev.OrderID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return true
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.JSON(http.StatusOK, newCreateOrderResponse(o))
+	return true
+}
+
+// writeBreach answers a rejected quote: 400 for malformed amounts, 422 for
+// policy violations.
+func writeBreach(c *gin.Context, b *limits.Breach) {
+	if b.Code == "invalid_amount" || b.Code == "invalid_quote" {
+		c.JSON(http.StatusBadRequest, b)
 		return
 	}
+	c.JSON(http.StatusUnprocessableEntity, b)
+}
+
+// applyFees sets o's fee terms from the schedule and scales the caller's
+// quote (for the gross amount_in) down to the amount actually swapped. It
+// returns a *limits.Breach if the fee would consume the whole amount.
+func (s *Server) applyFees(ctx context.Context, o *models.Order, partner *models.Partner) error {
+	sched, err := fees.Load(ctx, s.KV)
+	if err != nil {
+		return err
+	}
+	partnerID := ""
+	if partner != nil {
+		partnerID = partner.ID
+	}
+	terms := sched.TermsFor(partnerID, o.SourceAsset)
+	amount, err := decimal.NewFromString(o.AmountIn)
+	if err != nil || !amount.IsPositive() {
+		return &limits.Breach{Code: "invalid_amount", Message: "amount must be a positive decimal"}
+	}
+	fee := terms.Fee(amount)
+	if fee.GreaterThanOrEqual(amount) {
+		return &limits.Breach{Code: "amount_below_fee", Message: "amount does not cover the fee", Limit: fee.String()}
+	}
+	if o.EstimatedOut, err = fees.Net(o.EstimatedOut, amount, fee); err != nil {
+		return &limits.Breach{Code: "invalid_quote", Message: "estimated_out must be a decimal"}
+	}
+	if o.MinOut, err = fees.Net(o.MinOut, amount, fee); err != nil {
+		return &limits.Breach{Code: "invalid_quote", Message: "min_out must be a decimal"}
+	}
+	bps, flat := terms.SpreadBps, terms.Flat.String()
+	o.FeeBps, o.FeeFlat = &bps, &flat
+	if partner != nil {
+		share := partner.FeeShareBps
+		o.FeeShareBps = &share
+	}
+	return nil
+}
 
+func newCreateOrderResponse(o *models.Order) CreateOrderResponse {
 	var resp CreateOrderResponse
 	resp.PublicID = o.PublicID
 	resp.Status = o.Status
@@ -93,27 +328,45 @@ func (s *Server) handleCreateOrder(c *gin.Context) {
 	resp.MixinPayment.AssetID = o.MixinAssetID
 	resp.MixinPayment.Amount = o.AmountIn
 	resp.MixinPayment.Memo = o.MixinPayMemo
+	if o.MixinPayURL != "" {
+		resp.MixinPayment.PayURL = o.MixinPayURL
+		resp.MixinPayment.MixinURL = mixin.SchemeURL(o.MixinPayURL)
+		resp.MixinPayment.QRCodeURL = qrCodePath(o)
+	}
 	resp.Quote.EstimatedOut = o.EstimatedOut
 	resp.Quote.MinOut = o.MinOut
+	if o.FeeBps != nil {
+		f := &OrderFee{AssetID: o.SourceAsset, SpreadBps: *o.FeeBps, Flat: "0"}
+		if o.FeeFlat != nil {
+			f.Flat = *o.FeeFlat
+		}
+		terms := fees.Terms{SpreadBps: *o.FeeBps, Flat: decimal.RequireFromString(f.Flat)}
+		if amount, err := decimal.NewFromString(o.AmountIn); err == nil {
+			f.Amount = terms.Fee(amount).String()
+		}
+		resp.Fee = f
+	}
 	resp.Terms = map[string]string{
-		"late_deposit": "auto_refund",
-		"below_min_out": "auto_refund",
-		"refund_fee": "paid_by_user",
-		"refund_to": "original_address",
+		"late_deposit":    "auto_refund",
+		"below_min_out":   "auto_refund",
+		"refund_fee":      "paid_by_user",
+		"refund_to":       "original_address",
 		"paid_definition": "mixin_credited",
-		"late_cutoff": "first_detected_time",
+		"late_cutoff":     "first_detected_time",
 	}
-
-	c.JSON(http.StatusOK, resp)
+	return resp
 }
 
 func (s *Server) handleGetOrder(c *gin.Context) {
 	pid := c.Param("public_id")
-	repo := db.NewOrdersRepo(s.DB)
-	o, err := repo.GetByPublicID(c.Request.Context(), pid)
-	if err != nil {
+	o, err := s.Orders.GetByPublicID(c.Request.Context(), pid)
+	if errors.Is(err, store.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
-	c.JSON(http.StatusOK, o)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	c.JSON(http.StatusOK, orderview.New(o))
 }
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mvg-fi-dev/bridge/internal/store/memstore"
+)
+
+const createOrderBody = `{"mixin_asset_id":"src","amount_in":"1","target_chain":"ETH","target_asset":"dst",` +
+	`"target_address":"0xabc","estimated_out":"2","min_out":"1.9"}`
+
+// createOrder posts body from ip with an Idempotency-Key and returns the
+// response and its public id.
+func (ts *testServer) createOrder(t *testing.T, ip, key, body string) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	w := ts.serve(req, ip)
+	var resp CreateOrderResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp.PublicID
+}
+
+func TestCreateOrderIdempotency(t *testing.T) {
+	ts := newTestServer(t)
+
+	w, first := ts.createOrder(t, "203.0.113.1", "k1", createOrderBody)
+	if w.Code != http.StatusOK || first == "" {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+
+	// Same key, same body: the first order again.
+	w, again := ts.createOrder(t, "203.0.113.1", "k1", createOrderBody)
+	if w.Code != http.StatusOK || again != first || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replay: %d %s (replayed %q), want order %s", w.Code, again, w.Header().Get("Idempotent-Replayed"), first)
+	}
+
+	// Same key, different body: refused.
+	w, _ = ts.createOrder(t, "203.0.113.1", "k1", strings.Replace(createOrderBody, `"amount_in":"1"`, `"amount_in":"5"`, 1))
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "idempotency_key_reused") {
+		t.Errorf("reused key: %d %s, want 422", w.Code, w.Body)
+	}
+
+	// Anonymous keys are scoped by client IP: another caller gets its own order.
+	w, other := ts.createOrder(t, "203.0.113.2", "k1", createOrderBody)
+	if w.Code != http.StatusOK || other == "" || other == first || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("other ip: %d %s, want a new order", w.Code, other)
+	}
+
+	// No key: every request is a new order.
+	_, a := ts.createOrder(t, "203.0.113.1", "", createOrderBody)
+	_, b := ts.createOrder(t, "203.0.113.1", "", createOrderBody)
+	if a == "" || a == b || a == first {
+		t.Errorf("without key: %q and %q", a, b)
+	}
+
+	if w, _ := ts.createOrder(t, "203.0.113.1", strings.Repeat("k", maxIdempotencyKeyLen+1), createOrderBody); w.Code != http.StatusBadRequest {
+		t.Errorf("long key: %d, want 400", w.Code)
+	}
+}
+
+// brokenKV fails every List, as a lost DB connection would.
+type brokenKV struct{ *memstore.KV }
+
+func (brokenKV) List(ctx context.Context, prefix string) (map[string]string, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestCreateOrderKillSwitch(t *testing.T) {
+	ts := newTestServer(t)
+
+	for _, tc := range []struct{ kind, name string }{
+		{"chain", "eth"},   // target chain, any case
+		{"chain", "MIXIN"}, // source chain
+		{"asset", "SRC"},
+		{"asset", "dst"},
+	} {
+		path := "/admin/switches/" + tc.kind + "/" + tc.name
+		if w := ts.do(t, http.MethodPut, path, adminToken, `{"reason":"incident"}`); w.Code != http.StatusOK {
+			t.Fatalf("disable %s %s: %d %s", tc.kind, tc.name, w.Code, w.Body)
+		}
+		w, _ := ts.createOrder(t, "203.0.113.1", "", createOrderBody)
+		if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "incident") {
+			t.Errorf("%s %s off: %d %s, want 503", tc.kind, tc.name, w.Code, w.Body)
+		}
+		if w := ts.do(t, http.MethodDelete, path, adminToken, ""); w.Code != http.StatusOK {
+			t.Fatalf("enable %s %s: %d %s", tc.kind, tc.name, w.Code, w.Body)
+		}
+		if w, id := ts.createOrder(t, "203.0.113.1", "", createOrderBody); w.Code != http.StatusOK || id == "" {
+			t.Errorf("%s %s back on: %d %s", tc.kind, tc.name, w.Code, w.Body)
+		}
+	}
+
+	// An unrelated switch does not block the order.
+	ts.do(t, http.MethodPut, "/admin/switches/chain/BTC", adminToken, `{"reason":"halt"}`)
+	if w, _ := ts.createOrder(t, "203.0.113.1", "", createOrderBody); w.Code != http.StatusOK {
+		t.Errorf("BTC off: %d %s", w.Code, w.Body)
+	}
+
+	// Unreadable switches refuse new orders.
+	ts.s.KV = brokenKV{ts.kv}
+	if w, _ := ts.createOrder(t, "203.0.113.1", "", createOrderBody); w.Code != http.StatusInternalServerError {
+		t.Errorf("switches unreadable: %d %s, want 500", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+const partnerCtxKey = "api.partner"
+
+// signatureMaxSkew bounds how old (or how far in the future) X-Timestamp may
+// be, which limits the replay window of a captured request.
+const signatureMaxSkew = 5 * time.Minute
+
+// partnerAuth authenticates integrators. A request carrying X-Api-Key must
+// also carry X-Timestamp (unix seconds) and X-Signature, the hex
+// HMAC-SHA256 of requestSigningString with the partner's secret. Requests
+// without a key are anonymous unless RequireAPIKey is set.
+func (s *Server) partnerAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("X-Api-Key")
+		if key == "" {
+			if s.RequireAPIKey {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "api key required", "code": "unauthorized"})
+				return
+			}
+			c.Next()
+			return
+		}
+		if s.Partners == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key", "code": "unauthorized"})
+			return
+		}
+		p, err := s.Partners.GetPartnerByKey(c.Request.Context(), key)
+		if errors.Is(err, store.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key", "code": "unauthorized"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "db"})
+			return
+		}
+		if p.Disabled {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "api key disabled", "code": "partner_disabled"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				abortBodyTooLarge(c)
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "read body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if err := verifySignature(p.Secret, c.Request.Method, c.Request.URL.RequestURI(), c.GetHeader("X-Timestamp"), c.GetHeader("X-Signature"), body, time.Now()); err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "code": "invalid_signature"})
+			return
+		}
+		c.Set(partnerCtxKey, p)
+		c.Next()
+	}
+}
+
+// requirePartner rejects anonymous requests on partner-only routes.
+func requirePartner(c *gin.Context) {
+	if partnerFrom(c) == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "api key required", "code": "unauthorized"})
+		return
+	}
+	c.Next()
+}
+
+// partnerFrom returns the authenticated partner, or nil for anonymous requests.
+func partnerFrom(c *gin.Context) *models.Partner {
+	v, ok := c.Get(partnerCtxKey)
+	if !ok {
+		return nil
+	}
+	return v.(*models.Partner)
+}
+
+// requestSigningString is what partners sign:
+//
+//	timestamp + "\n" + METHOD + "\n" + request URI (path and query) + "\n" + hex(sha256(body))
+func requestSigningString(timestamp, method, uri string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return timestamp + "\n" + method + "\n" + uri + "\n" + hex.EncodeToString(sum[:])
+}
+
+func signRequest(secret, timestamp, method, uri string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(requestSigningString(timestamp, method, uri, body)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifySignature(secret, method, uri, timestamp, signature string, body []byte, now time.Time) error {
+	if timestamp == "" || signature == "" {
+		return fmt.Errorf("X-Timestamp and X-Signature are required")
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid X-Timestamp")
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > signatureMaxSkew || d < -signatureMaxSkew {
+		return fmt.Errorf("X-Timestamp outside the allowed window")
+	}
+	want := signRequest(secret, timestamp, method, uri, body)
+	if !hmac.Equal([]byte(want), []byte(signature)) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/ratelimit"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+	"github.com/mvg-fi-dev/bridge/internal/store/memstore"
+)
+
+const (
+	partnerKey    = "pk_test"
+	partnerSecret = "sk_test"
+)
+
+// countingPartners counts key lookups, to see which requests reach auth.
+type countingPartners struct {
+	store.PartnerStore
+	lookups atomic.Int64
+}
+
+func (p *countingPartners) GetPartnerByKey(ctx context.Context, apiKey string) (*models.Partner, error) {
+	p.lookups.Add(1)
+	return p.PartnerStore.GetPartnerByKey(ctx, apiKey)
+}
+
+// withPartner registers partner "acme" (changed by edit) and rebuilds the routes.
+func (ts *testServer) withPartner(t *testing.T, edit func(p *models.Partner)) *countingPartners {
+	t.Helper()
+	p := &models.Partner{ID: "acme", Name: "Acme", APIKey: partnerKey, Secret: partnerSecret, CreatedAt: time.Now().UTC()}
+	if edit != nil {
+		edit(p)
+	}
+	partners := memstore.NewPartners()
+	if err := partners.InsertPartner(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	counting := &countingPartners{PartnerStore: partners}
+	ts.s.Partners = counting
+	ts.reregister()
+	return counting
+}
+
+// signed builds a request signed at ts with the test partner's secret; the
+// body sent may differ from the one signed.
+func signed(method, uri, signedBody, sentBody string, at time.Time) *http.Request {
+	req := httptest.NewRequest(method, uri, strings.NewReader(sentBody))
+	if sentBody != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	stamp := strconv.FormatInt(at.Unix(), 10)
+	req.Header.Set("X-Api-Key", partnerKey)
+	req.Header.Set("X-Timestamp", stamp)
+	req.Header.Set("X-Signature", signRequest(partnerSecret, stamp, method, uri, []byte(signedBody)))
+	return req
+}
+
+func TestPartnerAuth(t *testing.T) {
+	ts := newTestServer(t)
+	ts.withPartner(t, nil)
+	now := time.Now()
+	ip := "203.0.113.1"
+
+	for _, tc := range []struct {
+		name string
+		req  *http.Request
+		code int
+		body string
+	}{
+		{"valid", signed(http.MethodGet, "/v1/partner", "", "", now), http.StatusOK, `"id":"acme"`},
+		{"valid with query", signed(http.MethodGet, "/v1/partner/orders?limit=5", "", "", now), http.StatusOK, `"orders"`},
+		{"tampered body", signed(http.MethodPost, "/v1/orders", createOrderBody, strings.Replace(createOrderBody, "0xabc", "0xdef", 1), now), http.StatusUnauthorized, "invalid_signature"},
+		{"stale timestamp", signed(http.MethodGet, "/v1/partner", "", "", now.Add(-signatureMaxSkew-time.Minute)), http.StatusUnauthorized, "outside the allowed window"},
+		{"future timestamp", signed(http.MethodGet, "/v1/partner", "", "", now.Add(signatureMaxSkew+time.Minute)), http.StatusUnauthorized, "outside the allowed window"},
+		{"other uri", func() *http.Request {
+			r := signed(http.MethodGet, "/v1/partner", "", "", now)
+			r.URL.Path, r.RequestURI = "/v1/partner/orders", "/v1/partner/orders"
+			return r
+		}(), http.StatusUnauthorized, "invalid_signature"},
+		{"unknown key", func() *http.Request {
+			r := signed(http.MethodGet, "/v1/partner", "", "", now)
+			r.Header.Set("X-Api-Key", "pk_other")
+			return r
+		}(), http.StatusUnauthorized, "invalid api key"},
+		{"no signature", func() *http.Request {
+			r := signed(http.MethodGet, "/v1/partner", "", "", now)
+			r.Header.Del("X-Signature")
+			return r
+		}(), http.StatusUnauthorized, "invalid_signature"},
+		{"anonymous on partner route", httptest.NewRequest(http.MethodGet, "/v1/partner", nil), http.StatusUnauthorized, "api key required"},
+	} {
+		w := ts.serve(tc.req, ip)
+		if w.Code != tc.code || !strings.Contains(w.Body.String(), tc.body) {
+			t.Errorf("%s: %d %s, want %d with %q", tc.name, w.Code, w.Body, tc.code, tc.body)
+		}
+	}
+
+	// A valid signed order is attributed to the partner.
+	w := ts.serve(signed(http.MethodPost, "/v1/orders", createOrderBody, createOrderBody, now), ip)
+	if w.Code != http.StatusOK {
+		t.Fatalf("signed create: %d %s", w.Code, w.Body)
+	}
+	n, err := ts.orders.CountOrders(context.Background(), store.OrderFilter{PartnerID: "acme"})
+	if err != nil || n != 1 {
+		t.Errorf("partner orders = %d, %v", n, err)
+	}
+}
+
+func TestPartnerDisabledAndRequired(t *testing.T) {
+	ts := newTestServer(t)
+	ts.s.RequireAPIKey = true
+	ts.withPartner(t, func(p *models.Partner) { p.Disabled = true })
+
+	if w := ts.serve(signed(http.MethodGet, "/v1/partner", "", "", time.Now()), "203.0.113.1"); w.Code != http.StatusForbidden {
+		t.Errorf("disabled partner: %d, want 403", w.Code)
+	}
+	if w := ts.serve(httptest.NewRequest(http.MethodGet, "/v1/orders/nope", nil), "203.0.113.1"); w.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous with RequireAPIKey: %d, want 401", w.Code)
+	}
+}
+
+func TestPartnerQuota(t *testing.T) {
+	ts := newTestServer(t)
+	ts.withPartner(t, func(p *models.Partner) { p.DailyOrderQuota = 1 })
+	create := func() *httptest.ResponseRecorder {
+		return ts.serve(signed(http.MethodPost, "/v1/orders", createOrderBody, createOrderBody, time.Now()), "203.0.113.1")
+	}
+	if w := create(); w.Code != http.StatusOK {
+		t.Fatalf("first order: %d %s", w.Code, w.Body)
+	}
+	w := create()
+	if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), "partner_quota_exceeded") || w.Header().Get("Retry-After") == "" {
+		t.Errorf("over quota: %d %s Retry-After %q", w.Code, w.Body, w.Header().Get("Retry-After"))
+	}
+	// Anonymous orders do not count against the partner.
+	if w, _ := ts.createOrder(t, "203.0.113.1", "", createOrderBody); w.Code != http.StatusOK {
+		t.Errorf("anonymous order: %d", w.Code)
+	}
+}
+
+// The IP limiter runs before auth, so a flood of bad keys is throttled
+// without a key lookup or signature check per request.
+func TestRateLimitBeforeAuth(t *testing.T) {
+	ts := newTestServer(t)
+	ts.s.RateLimiter = ratelimit.NewMemory()
+	ts.s.RateLimitIP = ratelimit.Rule{Rate: 0.01, Burst: 2}
+	partners := ts.withPartner(t, nil)
+
+	bad := func() *httptest.ResponseRecorder {
+		r := signed(http.MethodGet, "/v1/partner", "", "", time.Now())
+		r.Header.Set("X-Api-Key", "pk_guess")
+		return ts.serve(r, "203.0.113.9")
+	}
+	for i := 0; i < 2; i++ {
+		if w := bad(); w.Code != http.StatusUnauthorized {
+			t.Fatalf("bad key %d: %d, want 401", i+1, w.Code)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if w := bad(); w.Code != http.StatusTooManyRequests {
+			t.Errorf("throttled bad key: %d, want 429", w.Code)
+		}
+	}
+	if n := partners.lookups.Load(); n != 2 {
+		t.Errorf("key lookups = %d, want 2 (throttled requests must not reach auth)", n)
+	}
+}
+
+// A signed partner is charged to its own bucket too, at its own rate.
+func TestRateLimitPartnerKey(t *testing.T) {
+	ts := newTestServer(t)
+	ts.s.RateLimiter = ratelimit.NewMemory()
+	ts.s.RateLimitKey = ratelimit.Rule{Rate: 100, Burst: 1}
+	ts.withPartner(t, func(p *models.Partner) { p.RateLimitPerMin = 1 })
+
+	get := func(ip string) int {
+		return ts.serve(signed(http.MethodGet, "/v1/partner", "", "", time.Now()), ip).Code
+	}
+	if code := get("203.0.113.1"); code != http.StatusOK {
+		t.Fatalf("first: %d", code)
+	}
+	// rate_limit_per_min replaces the default rate of 100/s, so the next
+	// request is refused whatever IP it comes from.
+	if code := get("203.0.113.2"); code != http.StatusTooManyRequests {
+		t.Errorf("second from another ip: %d, want 429", code)
+	}
+}
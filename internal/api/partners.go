@@ -0,0 +1,149 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+const dateLayout = "2006-01-02"
+
+// maxReportDays bounds volume report ranges; rows are summed in memory.
+const maxReportDays = 366
+
+func (s *Server) handlePartnerGet(c *gin.Context) {
+	c.JSON(http.StatusOK, partnerFrom(c))
+}
+
+func (s *Server) handlePartnerListOrders(c *gin.Context) {
+	s.listPartnerOrders(c, partnerFrom(c).ID, false)
+}
+
+func (s *Server) handlePartnerVolume(c *gin.Context) {
+	s.partnerVolume(c, partnerFrom(c).ID)
+}
+
+// listPartnerOrders serves the order listing (see parseOrderFilter) for one
+// partner.
+func (s *Server) listPartnerOrders(c *gin.Context, partnerID string, admin bool) {
+	f, err := parseOrderFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	f.PartnerID = partnerID
+	s.writeOrders(c, f, admin)
+}
+
+// volumeRow aggregates one source asset on one UTC day.
+type volumeRow struct {
+	Date            string `json:"date"`
+	AssetID         string `json:"asset_id"`
+	Orders          int64  `json:"orders"`
+	PaidOrders      int64  `json:"paid_orders"`
+	CompletedOrders int64  `json:"completed_orders"`
+	RefundedOrders  int64  `json:"refunded_orders"`
+	AmountCredited  string `json:"amount_credited"`
+
+	credited decimal.Decimal
+}
+
+// partnerVolume serves ?from=&to= (UTC dates, default the last 30 days).
+func (s *Server) partnerVolume(c *gin.Context, partnerID string) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, to, err := parseDateRange(c, today.AddDate(0, 0, -29))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rows, err := s.Orders.ListOrderAmounts(c.Request.Context(), store.OrderFilter{PartnerID: partnerID, CreatedFrom: from, CreatedTo: to})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"partner_id": partnerID,
+		"from":       from.Format(dateLayout),
+		"to":         to.AddDate(0, 0, -1).Format(dateLayout),
+		"volume":     aggregateVolume(rows),
+	})
+}
+
+func aggregateVolume(rows []store.OrderAmount) []*volumeRow {
+	byKey := map[string]*volumeRow{}
+	for _, r := range rows {
+		date := r.CreatedAt.UTC().Format(dateLayout)
+		v, ok := byKey[date+"/"+r.AssetID]
+		if !ok {
+			v = &volumeRow{Date: date, AssetID: r.AssetID}
+			byKey[date+"/"+r.AssetID] = v
+		}
+		v.Orders++
+		if r.AmountCredited != nil {
+			v.PaidOrders++
+			if amt, err := decimal.NewFromString(*r.AmountCredited); err == nil {
+				v.credited = v.credited.Add(amt)
+			}
+		}
+		switch r.Status {
+		case models.StatusCompleted:
+			v.CompletedOrders++
+		case models.StatusRefunding, models.StatusRefunded:
+			v.RefundedOrders++
+		}
+	}
+	out := make([]*volumeRow, 0, len(byKey))
+	for _, v := range byKey {
+		v.AmountCredited = v.credited.String()
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Date != out[j].Date {
+			return out[i].Date < out[j].Date
+		}
+		return out[i].AssetID < out[j].AssetID
+	})
+	return out
+}
+
+// parseDateRange reads ?from=YYYY-MM-DD&to=YYYY-MM-DD (UTC, both inclusive)
+// and returns [from, to+1 day). A missing from defaults to defFrom (zero =
+// unbounded); a missing to means no upper bound for listings and today for
+// reports.
+func parseDateRange(c *gin.Context, defFrom time.Time) (time.Time, time.Time, error) {
+	from, to := defFrom, time.Time{}
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return from, to, fmt.Errorf("from must be YYYY-MM-DD")
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return from, to, fmt.Errorf("to must be YYYY-MM-DD")
+		}
+		to = t.AddDate(0, 0, 1)
+	}
+	if defFrom.IsZero() {
+		return from, to, nil
+	}
+	if to.IsZero() {
+		to = time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	}
+	if !to.After(from) {
+		return from, to, fmt.Errorf("to must not be before from")
+	}
+	if to.Sub(from) > maxReportDays*24*time.Hour {
+		return from, to, fmt.Errorf("range exceeds %d days", maxReportDays)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+
+	"github.com/mvg-fi-dev/bridge/internal/mixin"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+const (
+	defaultQRSize = 256
+	minQRSize     = 128
+	maxQRSize     = 1024
+)
+
+func qrCodePath(o *models.Order) string {
+	return "/v1/orders/" + o.PublicID + "/qr.png"
+}
+
+// handleOrderQR renders the order's payment link as a PNG QR code:
+// ?size= (pixels, default 256) and ?scheme=mixin to encode the mixin:// link
+// instead of the https one. Only orders awaiting a deposit have one.
+func (s *Server) handleOrderQR(c *gin.Context) {
+	o, err := s.Orders.GetByPublicID(c.Request.Context(), c.Param("public_id"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	if o.MixinPayURL == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order has no payment link"})
+		return
+	}
+	if o.Status != models.StatusAwaitingDeposit {
+		c.JSON(http.StatusGone, gin.H{"error": "order is not awaiting a deposit"})
+		return
+	}
+	size := defaultQRSize
+	if v := c.Query("size"); v != "" {
+		if size, err = strconv.Atoi(v); err != nil || size < minQRSize || size > maxQRSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "size must be 128..1024"})
+			return
+		}
+	}
+	link := o.MixinPayURL
+	switch c.Query("scheme") {
+	case "", "https":
+	case "mixin":
+		link = mixin.SchemeURL(link)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scheme must be https or mixin"})
+		return
+	}
+	png, err := qrcode.Encode(link, qrcode.Medium, size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "qr"})
+		return
+	}
+	// The link never changes; the status check above is what expires.
+	c.Header("Cache-Control", "private, max-age=60")
+	c.Data(http.StatusOK, "image/png", png)
+}
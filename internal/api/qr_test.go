@@ -0,0 +1,116 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+
+	"github.com/mvg-fi-dev/bridge/internal/ids"
+	"github.com/mvg-fi-dev/bridge/internal/mixin"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+const botID = "a1b2c3d4-0000-4000-8000-000000000001"
+
+func TestCreateOrderPayURL(t *testing.T) {
+	ts := newTestServer(t)
+	ts.s.MixinBotUserID = botID
+
+	w, pid := ts.createOrder(t, "203.0.113.1", "", createOrderBody)
+	var resp CreateOrderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+	o, err := ts.orders.GetByPublicID(context.Background(), pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pay := resp.MixinPayment
+	u, err := url.Parse(pay.PayURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("recipient") != botID || q.Get("asset") != "src" || q.Get("amount") != "1" ||
+		q.Get("memo") != o.MixinPayMemo || q.Get("trace") != ids.DeterministicUUID("pay:"+o.ID) {
+		t.Errorf("pay_url = %s", pay.PayURL)
+	}
+	if pay.MixinURL != mixin.SchemeURL(pay.PayURL) || pay.QRCodeURL != "/v1/orders/"+pid+"/qr.png" {
+		t.Errorf("mixin_url = %s, qr_code_url = %s", pay.MixinURL, pay.QRCodeURL)
+	}
+
+	// Without a bot user id there is nothing to pay to.
+	ts.s.MixinBotUserID = ""
+	w, _ = ts.createOrder(t, "203.0.113.1", "", createOrderBody)
+	resp = CreateOrderResponse{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.MixinPayment.PayURL != "" || resp.MixinPayment.QRCodeURL != "" {
+		t.Errorf("pay links without a bot: %+v", resp.MixinPayment)
+	}
+}
+
+func TestOrderQR(t *testing.T) {
+	ts := newTestServer(t)
+	ts.s.RequireAPIKey = true // the QR route is public regardless
+	ts.withPartner(t, nil)
+	for _, o := range []*models.Order{
+		{ID: "o1", PublicID: "p1", Status: models.StatusAwaitingDeposit, MixinPayURL: mixin.PayURL(botID, "src", "1", "t1", "m1")},
+		{ID: "o2", PublicID: "p2", Status: models.StatusAwaitingDeposit, MixinPayURL: mixin.PayURL(botID, "src", "2", "t2", "m2")},
+		{ID: "o3", PublicID: "p3", Status: models.StatusDepositCredited, MixinPayURL: mixin.PayURL(botID, "src", "3", "t3", "m3")},
+		{ID: "o4", PublicID: "p4", Status: models.StatusAwaitingDeposit},
+	} {
+		ts.insert(t, o)
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		return ts.serve(httptest.NewRequest(http.MethodGet, path, nil), "203.0.113.1")
+	}
+	encode := func(link string, size int) []byte {
+		png, err := qrcode.Encode(link, qrcode.Medium, size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return png
+	}
+
+	// Each public id renders its own order's link and nothing else.
+	for _, o := range []struct{ pid, link string }{
+		{"p1", ts.order(t, "o1").MixinPayURL},
+		{"p2", ts.order(t, "o2").MixinPayURL},
+	} {
+		w := get("/v1/orders/" + o.pid + "/qr.png")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+			t.Fatalf("%s: %d %s", o.pid, w.Code, w.Body)
+		}
+		if !bytes.Equal(w.Body.Bytes(), encode(o.link, defaultQRSize)) {
+			t.Errorf("%s: QR does not encode %s", o.pid, o.link)
+		}
+	}
+	w := get("/v1/orders/p1/qr.png?scheme=mixin&size=512")
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), encode(mixin.SchemeURL(ts.order(t, "o1").MixinPayURL), 512)) {
+		t.Errorf("mixin scheme: %d", w.Code)
+	}
+
+	for path, want := range map[string]int{
+		"/v1/orders/o1/qr.png":            http.StatusNotFound, // internal ids are not accepted
+		"/v1/orders/nope/qr.png":          http.StatusNotFound,
+		"/v1/orders/p4/qr.png":            http.StatusNotFound, // no payment link
+		"/v1/orders/p3/qr.png":            http.StatusGone,     // already paid
+		"/v1/orders/p1/qr.png?size=64":    http.StatusBadRequest,
+		"/v1/orders/p1/qr.png?size=4096":  http.StatusBadRequest,
+		"/v1/orders/p1/qr.png?scheme=ftp": http.StatusBadRequest,
+		"/v1/orders/p1":                   http.StatusUnauthorized, // the order itself still needs a key
+	} {
+		if w := get(path); w.Code != want {
+			t.Errorf("%s: %d %s, want %d", path, w.Code, w.Body, want)
+		} else if w.Code != http.StatusOK && w.Header().Get("Content-Type") == "image/png" {
+			t.Errorf("%s: served an image", path)
+		}
+	}
+}
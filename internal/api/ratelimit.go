@@ -0,0 +1,115 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mvg-fi-dev/bridge/internal/ratelimit"
+)
+
+// rateLimitIP charges every request to its client IP's bucket. It runs
+// before partnerAuth, so requests with bad keys or signatures are throttled
+// before the key lookup and body hashing.
+func (s *Server) rateLimitIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.take(c, "ip:"+c.ClientIP(), s.RateLimitIP) {
+			c.Next()
+		}
+	}
+}
+
+// rateLimitKey charges authenticated requests to the partner's bucket as
+// well (it runs after partnerAuth). A partner's own rate_limit_per_min
+// replaces RateLimitKey's rate.
+func (s *Server) rateLimitKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p := partnerFrom(c)
+		if p == nil {
+			c.Next()
+			return
+		}
+		rule := s.RateLimitKey
+		if p.RateLimitPerMin > 0 {
+			rule = ratelimit.PerMinute(p.RateLimitPerMin, int64(s.RateLimitKey.Burst))
+		}
+		if s.take(c, "partner:"+p.ID, rule) {
+			c.Next()
+		}
+	}
+}
+
+// take takes a token from key's bucket, answering 429 when it is empty. It
+// reports whether the request may go on. Store errors fail open: the limiter
+// protects capacity, it must not take the API down.
+func (s *Server) take(c *gin.Context, key string, rule ratelimit.Rule) bool {
+	if s.RateLimiter == nil || !rule.Enabled() {
+		return true
+	}
+	ok, wait, err := s.RateLimiter.Take(c.Request.Context(), key, rule, time.Now())
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "rate limit failed", "err", err)
+		return true
+	}
+	if !ok {
+		abortTooManyRequests(c, "rate_limited", "rate limit exceeded", wait)
+		return false
+	}
+	return true
+}
+
+// abortTooManyRequests is the single 429 response shape used by the API
+// (rate limits and partner quotas).
+func abortTooManyRequests(c *gin.Context, code, msg string, wait time.Duration) {
+	secs := int64(math.Ceil(wait.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	c.Header("Retry-After", strconv.FormatInt(secs, 10))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":               msg,
+		"code":                code,
+		"retry_after_seconds": secs,
+	})
+}
+
+// limitBody rejects requests whose declared body exceeds MaxBodyBytes and
+// caps the reader for chunked bodies.
+func (s *Server) limitBody() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.MaxBodyBytes <= 0 {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > s.MaxBodyBytes {
+			abortBodyTooLarge(c)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, s.MaxBodyBytes)
+		c.Next()
+	}
+}
+
+func abortBodyTooLarge(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large", "code": "body_too_large"})
+}
+
+// bindJSON binds the request body, answering 413 for oversized bodies and
+// 400 otherwise. It reports whether the handler should continue.
+func bindJSON(c *gin.Context, v any) bool {
+	err := c.ShouldBindJSON(v)
+	if err == nil {
+		return true
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		abortBodyTooLarge(c)
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	return false
+}
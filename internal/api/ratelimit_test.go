@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mvg-fi-dev/bridge/internal/ratelimit"
+)
+
+// serve runs req from client ip through ts.
+func (ts *testServer) serve(req *http.Request, ip string) *httptest.ResponseRecorder {
+	req.RemoteAddr = ip + ":40000"
+	w := httptest.NewRecorder()
+	ts.r.ServeHTTP(w, req)
+	return w
+}
+
+// reregister rebuilds the routes after a test changed ts.s.
+func (ts *testServer) reregister() {
+	ts.r = gin.New()
+	ts.s.Register(ts.r)
+}
+
+func TestRateLimitIP(t *testing.T) {
+	ts := newTestServer(t)
+	ts.s.RateLimiter = ratelimit.NewMemory()
+	ts.s.RateLimitIP = ratelimit.Rule{Rate: 0.5, Burst: 2}
+	ts.reregister()
+
+	get := func(ip string) *httptest.ResponseRecorder {
+		return ts.serve(httptest.NewRequest(http.MethodGet, "/v1/orders/nope", nil), ip)
+	}
+	for i := 0; i < 2; i++ {
+		if w := get("203.0.113.1"); w.Code != http.StatusNotFound {
+			t.Fatalf("request %d: %d, want 404", i+1, w.Code)
+		}
+	}
+	w := get("203.0.113.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Fatalf("over the burst: %d Retry-After %q, want 429 after 2s", w.Code, w.Header().Get("Retry-After"))
+	}
+	var body struct {
+		Code       string `json:"code"`
+		RetryAfter int64  `json:"retry_after_seconds"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Code != "rate_limited" || body.RetryAfter != 2 {
+		t.Errorf("429 body = %s", w.Body)
+	}
+	// Buckets are per client IP.
+	if w := get("203.0.113.2"); w.Code != http.StatusNotFound {
+		t.Errorf("other ip: %d, want 404", w.Code)
+	}
+	// Health checks are not limited.
+	if w := ts.serve(httptest.NewRequest(http.MethodGet, "/healthz", nil), "203.0.113.1"); w.Code != http.StatusOK {
+		t.Errorf("healthz: %d", w.Code)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	ts := newTestServer(t)
+	ts.s.MaxBodyBytes = 64
+	ts.reregister()
+	big := `{"mixin_asset_id":"` + strings.Repeat("a", 100) + `"}`
+
+	// Declared length over the cap.
+	w := ts.serve(httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(big)), "203.0.113.1")
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "body_too_large") {
+		t.Errorf("declared: %d %s, want 413", w.Code, w.Body)
+	}
+	// Chunked: the length is unknown until the reader hits the cap.
+	req := httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(big))
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "application/json")
+	if w := ts.serve(req, "203.0.113.1"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("chunked: %d %s, want 413", w.Code, w.Body)
+	}
+	// Small bodies go through to validation.
+	if w := ts.serve(httptest.NewRequest(http.MethodPost, "/v1/orders", strings.NewReader(`{}`)), "203.0.113.1"); w.Code != http.StatusBadRequest {
+		t.Errorf("small body: %d, want 400", w.Code)
+	}
+}
@@ -1,31 +1,128 @@
 package api
 
 import (
-	"database/sql"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mvg-fi-dev/bridge/internal/health"
+	"github.com/mvg-fi-dev/bridge/internal/ledger"
+	"github.com/mvg-fi-dev/bridge/internal/limits"
+	"github.com/mvg-fi-dev/bridge/internal/orderfeed"
+	"github.com/mvg-fi-dev/bridge/internal/ratelimit"
+	"github.com/mvg-fi-dev/bridge/internal/screening"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+	"github.com/mvg-fi-dev/bridge/internal/switches"
 	"github.com/mvg-fi-dev/bridge/internal/webhooks"
 )
 
 type Server struct {
-	DB *sql.DB
+	Orders store.OrderStore
 
-	PayWindowSeconds int64
-	MixinBotUserID   string
+	// Admin API (operator actions + audit log); routes are skipped when unset.
+	Admin       store.AdminStore
+	AdminTokens map[string]string
+
+	// KV holds runtime state shared with the worker (kill-switches, limits).
+	KV     store.KVStore
+	Limits *limits.Checker
+
+	// Screener checks target addresses at order creation; Blocklist is the
+	// admin-managed list it reads from.
+	Screener  screening.Screener
+	Blocklist store.BlocklistStore
+
+	// Partner API keys; RequireAPIKey rejects anonymous /v1 requests.
+	Partners      store.PartnerStore
+	RequireAPIKey bool
+
+	// Public API protection: token buckets per client IP (every request) and
+	// per partner (signed requests; RateLimiter nil disables limiting) and a
+	// request body cap.
+	RateLimiter  ratelimit.Store
+	RateLimitIP  ratelimit.Rule
+	RateLimitKey ratelimit.Rule
+	MaxBodyBytes int64
+
+	// Partner callback endpoints and their delivery log. Endpoint URLs must
+	// be https unless AllowInsecureWebhooks (local testing).
+	Webhooks              store.WebhookStore
+	AllowInsecureWebhooks bool
+
+	// Order status streams (SSE), fed from the order change feed; the route
+	// is skipped when OrderFeed is unset. MaxOrderStreams caps open streams
+	// per process (0: no cap).
+	OrderEvents     store.OrderEventStore
+	OrderFeed       *orderfeed.Feed
+	MaxOrderStreams int
+
+	// Ledger backs the read-only ledger admin routes.
+	Ledger *ledger.Ledger
+
+	// ReadyChecks are run by GET /readyz.
+	ReadyChecks []health.Check
+
+	PayWindowSeconds   int64
+	MixinBotUserID     string
 	MixinWebhookSecret string
 }
 
 func (s *Server) Register(r *gin.Engine) {
-	r.GET("/healthz", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"ok": true})
-	})
+	r.Use(traceRequests(), s.limitBody())
+	r.GET("/healthz", s.handleHealthz)
+	r.GET("/readyz", s.handleReadyz)
 
 	// Orders (Mixin-first MVP)
-	r.POST("/v1/orders", s.handleCreateOrder)
-	r.GET("/v1/orders/:public_id", s.handleGetOrder)
+	v1 := r.Group("/v1", s.rateLimitIP(), s.partnerAuth(), s.rateLimitKey())
+	v1.POST("/orders", s.handleCreateOrder)
+	v1.GET("/orders", requirePartner, s.handlePartnerListOrders)
+	v1.GET("/orders/:public_id", s.handleGetOrder)
+	if s.OrderFeed != nil {
+		v1.GET("/orders/:public_id/stream", s.handleOrderStream)
+	}
+
+	// The QR code is loaded by the payer's browser (an <img> cannot sign), so
+	// it is served without partner auth; the public id is what grants it.
+	r.GET("/v1/orders/:public_id/qr.png", s.rateLimitIP(), s.handleOrderQR)
+
+	// Integrator self-service (signed requests only)
+	pg := v1.Group("/partner", requirePartner)
+	pg.GET("", s.handlePartnerGet)
+	pg.GET("/orders", s.handlePartnerListOrders)
+	pg.GET("/volume", s.handlePartnerVolume)
+	if s.Webhooks != nil {
+		pg.GET("/webhooks", s.handlePartnerListWebhooks)
+		pg.POST("/webhooks", s.handlePartnerCreateWebhook)
+		pg.DELETE("/webhooks/:id", s.handlePartnerDeleteWebhook)
+		pg.GET("/webhooks/deliveries", s.handlePartnerListDeliveries)
+		pg.POST("/webhooks/deliveries/:id/redeliver", s.handlePartnerRedeliver)
+	}
 
 	// Optional webhook ingestion (can be replaced by polling or blaze).
-	mw := &webhooks.MixinWebhookHandler{Secret: s.MixinWebhookSecret, DB: s.DB, MixinBotUserID: s.MixinBotUserID}
+	mw := &webhooks.MixinWebhookHandler{Secret: s.MixinWebhookSecret, Orders: s.Orders, MixinBotUserID: s.MixinBotUserID}
 	r.POST("/v1/webhooks/mixin", mw.Handle)
+
+	s.registerAdmin(r)
+}
+
+func (s *Server) handleHealthz(c *gin.Context) {
+	resp := gin.H{"ok": true}
+	if s.KV != nil {
+		st, err := switches.Load(c.Request.Context(), s.KV)
+		if err != nil {
+			resp["switches_error"] = err.Error()
+		} else {
+			resp["switches"] = st
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleReadyz runs the deep checks; 503 means a critical one failed.
+func (s *Server) handleReadyz(c *gin.Context) {
+	rep := health.Run(c.Request.Context(), s.ReadyChecks...)
+	code := http.StatusOK
+	if !rep.OK {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, rep)
 }
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/orderview"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+const (
+	// streamPingInterval keeps proxies from closing an idle stream.
+	streamPingInterval = 15 * time.Second
+	// streamMaxDuration caps one stream; EventSource reconnects by itself.
+	streamMaxDuration = 30 * time.Minute
+)
+
+// streamDone reports whether o will not change status again, so its stream
+// can end.
+func streamDone(o *models.Order) bool {
+	return o.Status == models.StatusCompleted || o.Status == models.StatusRefunded
+}
+
+// handleOrderStream serves an order as Server-Sent Events: an `order` event
+// with the current order right away, then one per status change, until the
+// order is completed or refunded. Event ids are change-feed ids; with a
+// Last-Event-ID header the stream starts after that event instead.
+func (s *Server) handleOrderStream(c *gin.Context) {
+	ctx := c.Request.Context()
+	o, err := s.Orders.GetByPublicID(ctx, c.Param("public_id"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	if s.MaxOrderStreams > 0 && s.OrderFeed.Subscribers() >= s.MaxOrderStreams {
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "too many streams"})
+		return
+	}
+	// Read the cursor before the order, so the first event is at least as
+	// new as the cursor and nothing after it is missed.
+	latest, err := s.OrderEvents.LatestOrderEventID(ctx, o.ID)
+	if err == nil {
+		o, err = s.Orders.GetByID(ctx, o.ID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	// A reconnecting EventSource resumes after the last event it got: the
+	// feed replays what it missed instead of the current order.
+	after, resumed := lastEventID(c)
+	if !resumed || after > latest {
+		after = latest
+	}
+	if resumed && after == latest && streamDone(o) {
+		c.Status(http.StatusNoContent) // tells EventSource to stop reconnecting
+		return
+	}
+	sub := s.OrderFeed.Subscribe(o.ID, after)
+	defer s.OrderFeed.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	if _, err := fmt.Fprint(c.Writer, "retry: 3000\n\n"); err != nil {
+		return
+	}
+	if !resumed {
+		if err := writeOrderEvent(c, after, o); err != nil || streamDone(o) {
+			return
+		}
+	} else {
+		c.Writer.Flush()
+	}
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+	deadline := time.NewTimer(streamMaxDuration)
+	defer deadline.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			return
+		case <-ping.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case e, ok := <-sub.C:
+			if !ok {
+				// Fell behind or shutting down; the client reconnects and
+				// gets the current order first.
+				return
+			}
+			if o, err = s.Orders.GetByID(ctx, e.OrderID); err != nil {
+				slog.WarnContext(ctx, "order stream reload failed", "err", err)
+				return
+			}
+			if err := writeOrderEvent(c, e.ID, o); err != nil || streamDone(o) {
+				return
+			}
+		}
+	}
+}
+
+// lastEventID parses the Last-Event-ID header a reconnecting EventSource
+// sends.
+func lastEventID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.GetHeader("Last-Event-ID"), 10, 64)
+	if err != nil || id < 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+func writeOrderEvent(c *gin.Context, id int64, o *models.Order) error {
+	data, err := json.Marshal(orderview.New(o))
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: order\ndata: %s\n\n", id, data); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/orderfeed"
+)
+
+// orderEvents is a fixed store.OrderEventStore.
+type orderEvents []*models.OrderEvent
+
+func (s orderEvents) LatestOrderEventID(ctx context.Context, orderID string) (int64, error) {
+	var id int64
+	for _, e := range s {
+		if e.OrderID == orderID {
+			id = e.ID
+		}
+	}
+	return id, nil
+}
+
+func (s orderEvents) ListOrderEvents(ctx context.Context, orderIDs []string, afterID int64, limit int) ([]*models.OrderEvent, error) {
+	var out []*models.OrderEvent
+	for _, e := range s {
+		if e.OrderID == orderIDs[0] && e.ID > afterID && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// stream opens the order stream of p1, sending lastEventID when set, and
+// returns the status and the body read until the stream ends or times out.
+func stream(t *testing.T, ts *testServer, lastEventID string) (int, string) {
+	t.Helper()
+	srv := httptest.NewServer(ts.r)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/orders/p1/stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body) // a timeout ends an open stream
+	return resp.StatusCode, string(body)
+}
+
+func TestOrderStreamResumes(t *testing.T) {
+	ts := newTestServer(t)
+	ts.insert(t, &models.Order{Status: models.StatusCompleted})
+	now := time.Now()
+	ts.s.OrderEvents = orderEvents{
+		{ID: 3, OrderID: "o1", Status: models.StatusWithdrawing, CreatedAt: now},
+		{ID: 7, OrderID: "o1", Status: models.StatusCompleted, CreatedAt: now},
+	}
+	ts.s.OrderFeed = orderfeed.New(ts.s.OrderEvents, 10*time.Millisecond)
+	ts.reregister()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ts.s.OrderFeed.Run(ctx)
+
+	// A fresh stream gets the current order under the latest id, then ends.
+	code, body := stream(t, ts, "")
+	if code != http.StatusOK || strings.Count(body, "event: order") != 1 || !strings.Contains(body, "id: 7\n") {
+		t.Errorf("fresh stream: %d %q", code, body)
+	}
+
+	// A reconnect after event 3 is replayed event 7 only.
+	code, body = stream(t, ts, "3")
+	if code != http.StatusOK || strings.Count(body, "event: order") != 1 || !strings.Contains(body, "id: 7\n") {
+		t.Errorf("resumed stream: %d %q", code, body)
+	}
+
+	// Nothing left to send for a finished order.
+	if code, body = stream(t, ts, "7"); code != http.StatusNoContent {
+		t.Errorf("caught-up stream: %d %q, want 204", code, body)
+	}
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mvg-fi-dev/bridge/internal/ids"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+	"github.com/mvg-fi-dev/bridge/internal/webhooks"
+)
+
+// maxWebhookEndpoints bounds how many callback URLs one partner registers.
+const maxWebhookEndpoints = 5
+
+// deliveryView shows the stored payload as JSON rather than a string.
+type deliveryView struct {
+	*models.WebhookDelivery
+	Payload json.RawMessage `json:"payload"`
+}
+
+func newDeliveryViews(ds []*models.WebhookDelivery) []deliveryView {
+	out := make([]deliveryView, 0, len(ds))
+	for _, d := range ds {
+		out = append(out, deliveryView{WebhookDelivery: d, Payload: json.RawMessage(d.Payload)})
+	}
+	return out
+}
+
+func (s *Server) handlePartnerListWebhooks(c *gin.Context) {
+	s.listWebhooks(c, partnerFrom(c).ID)
+}
+
+func (s *Server) listWebhooks(c *gin.Context, partnerID string) {
+	eps, err := s.Webhooks.ListWebhookEndpoints(c.Request.Context(), partnerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	if eps == nil {
+		eps = []*models.WebhookEndpoint{}
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": eps})
+}
+
+func (s *Server) validWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("url must be an absolute URL")
+	}
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && s.AllowInsecureWebhooks:
+	default:
+		return fmt.Errorf("url must use https")
+	}
+	if u.User != nil {
+		return fmt.Errorf("url must not carry credentials")
+	}
+	// The worker checks every resolved address before connecting; literal
+	// internal hosts are refused here already so partners learn at once.
+	if !s.AllowInsecureWebhooks {
+		host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+		if ip, err := netip.ParseAddr(host); (err == nil && !webhooks.PublicAddr(ip)) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			return fmt.Errorf("url must point to a public address")
+		}
+	}
+	return nil
+}
+
+// handlePartnerCreateWebhook registers a callback URL. The signing secret is
+// only returned here.
+func (s *Server) handlePartnerCreateWebhook(c *gin.Context) {
+	var req struct {
+		URL string `json:"url"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.validWebhookURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p := partnerFrom(c)
+	ctx := c.Request.Context()
+	eps, err := s.Webhooks.ListWebhookEndpoints(ctx, p.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	if len(eps) >= maxWebhookEndpoints {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("at most %d webhooks per partner", maxWebhookEndpoints)})
+		return
+	}
+	secret, err := ids.NewToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token"})
+		return
+	}
+	now := time.Now().UTC()
+	ep := &models.WebhookEndpoint{ID: ids.NewUUID(), PartnerID: p.ID, URL: req.URL, Secret: "whsec_" + secret, CreatedAt: now, UpdatedAt: now}
+	if err := s.Webhooks.InsertWebhookEndpoint(ctx, ep); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	slog.InfoContext(ctx, "webhook registered", "partner_id", p.ID, "webhook_id", ep.ID)
+	c.JSON(http.StatusCreated, gin.H{"webhook": ep, "secret": ep.Secret})
+}
+
+func (s *Server) handlePartnerDeleteWebhook(c *gin.Context) {
+	p := partnerFrom(c)
+	ctx := c.Request.Context()
+	ok, err := s.Webhooks.DeleteWebhookEndpoint(ctx, p.ID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	slog.InfoContext(ctx, "webhook removed", "partner_id", p.ID, "webhook_id", c.Param("id"))
+	c.Status(http.StatusNoContent)
+}
+
+// handlePartnerListDeliveries serves the partner's delivery log:
+// ?status=&webhook_id=&public_id=&limit=&offset=.
+func (s *Server) handlePartnerListDeliveries(c *gin.Context) {
+	f := store.DeliveryFilter{
+		PartnerID:  partnerFrom(c).ID,
+		EndpointID: c.Query("webhook_id"),
+		Status:     models.DeliveryStatus(c.Query("status")),
+	}
+	if pid := c.Query("public_id"); pid != "" {
+		o, err := s.Orders.GetByPublicID(c.Request.Context(), pid)
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusOK, gin.H{"deliveries": []deliveryView{}})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+			return
+		}
+		f.OrderID = o.ID
+	}
+	s.listDeliveries(c, f)
+}
+
+func (s *Server) listDeliveries(c *gin.Context, f store.DeliveryFilter) {
+	f.Limit, _ = strconv.Atoi(c.Query("limit"))
+	f.Offset, _ = strconv.Atoi(c.Query("offset"))
+	ds, err := s.Webhooks.ListWebhookDeliveries(c.Request.Context(), f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": newDeliveryViews(ds)})
+}
+
+// handlePartnerRedeliver queues one of the partner's deliveries again, due
+// now and with a fresh attempt budget.
+func (s *Server) handlePartnerRedeliver(c *gin.Context) {
+	ctx := c.Request.Context()
+	d, err := s.Webhooks.GetWebhookDelivery(ctx, c.Param("id"))
+	if errors.Is(err, store.ErrNotFound) || (err == nil && d.PartnerID != partnerFrom(c).ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	if _, err := s.Webhooks.RedeliverWebhook(ctx, d.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	slog.InfoContext(ctx, "webhook redelivery requested", "partner_id", d.PartnerID, "delivery_id", d.ID)
+	c.JSON(http.StatusAccepted, gin.H{"delivery_id": d.ID, "status": models.DeliveryPending})
+}
+
+func (s *Server) handleAdminPartnerWebhooks(c *gin.Context) {
+	if p, ok := s.adminPartner(c); ok {
+		s.listWebhooks(c, p.ID)
+	}
+}
+
+// handleAdminListDeliveries serves every partner's delivery log:
+// ?partner_id=&webhook_id=&order_id=&status=&limit=&offset=.
+func (s *Server) handleAdminListDeliveries(c *gin.Context) {
+	s.listDeliveries(c, store.DeliveryFilter{
+		PartnerID:  c.Query("partner_id"),
+		EndpointID: c.Query("webhook_id"),
+		OrderID:    c.Query("order_id"),
+		Status:     models.DeliveryStatus(c.Query("status")),
+	})
+}
+
+func (s *Server) handleAdminRedeliver(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	operator := operatorFrom(c)
+	var d *models.WebhookDelivery
+	err := s.Admin.InAdminTx(ctx, func(tx store.AdminTx) error {
+		var err error
+		if d, err = tx.Webhooks.GetWebhookDelivery(ctx, id); err != nil {
+			return err
+		}
+		if _, err := tx.Webhooks.RedeliverWebhook(ctx, id); err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx.Audit, operator, "webhook_redeliver", d.OrderID, gin.H{"delivery_id": id, "partner_id": d.PartnerID})
+	})
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "admin action failed", "action", "webhook_redeliver", "operator", operator, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db"})
+		return
+	}
+	slog.InfoContext(ctx, "admin action", "action", "webhook_redeliver", "delivery_id", id, "operator", operator)
+	c.JSON(http.StatusAccepted, gin.H{"delivery_id": id, "status": models.DeliveryPending})
+}
@@ -2,28 +2,124 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
 )
 
 type Config struct {
 	Port       string
 	SQLitePath string
 
+	// Storage backend: sqlite3 (default) or postgres
+	DBDriver    string
+	DatabaseURL string
+
 	PayWindowSeconds int64
 
 	// Mixin-first MVP payment
-	MixinBotUserID      string
-	MixinWebhookSecret  string
+	MixinBotUserID     string
+	MixinWebhookSecret string
 
 	// ExinSwap execution policy
 	ExinSwapLatestExecSeconds int64
+
+	// Executor retry policy (withdraw/refund stages)
+	RetryMaxAttempts      int64
+	RetryBaseDelaySeconds int64
+	RetryMaxDelaySeconds  int64
+
+	// Worker identity and lease length for multi-replica safety
+	WorkerID           string
+	WorkerLeaseSeconds int64
+
+	// Admin API operators: token -> operator name (ADMIN_TOKENS=name:token,...).
+	// The /admin routes are disabled when empty.
+	AdminTokens map[string]string
+
+	// Optional local sanctions/blocklist file (in addition to the DB blocklist)
+	ScreeningBlocklistFile string
+
+	// Public API rate limiting: RateLimitStore is memory (per replica), db
+	// (shared through the database) or off.
+	RateLimitStore     string
+	RateLimitIPPerMin  int64
+	RateLimitIPBurst   int64
+	RateLimitKeyPerMin int64
+	RateLimitKeyBurst  int64
+	MaxBodyBytes       int64
+
+	// Reject /v1 requests without a partner API key.
+	RequireAPIKey bool
+
+	// Reverse proxies whose X-Forwarded-For is trusted for the client IP.
+	TrustedProxies []string
+
+	// Wallet reconciliation (worker): how often safe outputs are compared with
+	// the ledger (0 disables), the allowed difference per asset and how many
+	// consecutive mismatching runs pause the outbound stages.
+	ReconcileIntervalSeconds int64
+	ReconcileTolerance       decimal.Decimal
+	ReconcileConfirmRuns     int64
+
+	// Read wallet balances from this JSON file instead of Mixin (local testing).
+	MixinFakeBalancesFile string
+
+	// How long cached wallet balances are reused by payout liquidity checks.
+	LiquidityCacheSeconds int64
+
+	// Structured logging: level (debug, info, warn, error) and format (json, text).
+	LogLevel  slog.Level
+	LogFormat string
+
+	// Trace exporter: none, otlp (OTEL_EXPORTER_OTLP_* configure the endpoint) or stdout.
+	TracesExporter string
+
+	// Port of the worker's HTTP listener for /metrics, /healthz and /readyz
+	// ("0" disables it). The API serves /healthz and /readyz on PORT and
+	// /metrics only on APIMetricsPort, which should not be exposed publicly.
+	MetricsPort    string
+	APIMetricsPort string
+
+	// A snapshot poll older than this fails the worker's health checks.
+	PollStaleSeconds int64
+
+	// Alert evaluation (worker): how often the rules run (0 disables; run it
+	// on one replica only) and where notifications go. Thresholds are set via
+	// PUT /admin/alerts.
+	AlertIntervalSeconds     int64
+	AlertStdout              bool
+	AlertWebhookURL          string
+	AlertMixinConversationID string
+
+	// Partner webhook delivery (worker): attempts before a delivery is marked
+	// failed, and the backoff between them. WebhookAllowHTTP (API) accepts
+	// plain http callback URLs and WebhookAllowPrivate (worker) delivers to
+	// loopback and private addresses; both for local testing only.
+	WebhookMaxAttempts      int64
+	WebhookBaseDelaySeconds int64
+	WebhookMaxDelaySeconds  int64
+	WebhookAllowHTTP        bool
+	WebhookAllowPrivate     bool
+
+	// Order status streams (API): how often the change feed is polled for
+	// open streams, and the cap on open streams per process (0: no cap).
+	OrderStreamPollMs int64
+	OrderStreamMax    int64
 }
 
 func Load() (*Config, error) {
 	c := &Config{}
 	c.Port = getenv("PORT", "8080")
 	c.SQLitePath = getenv("SQLITE_PATH", "bridge.db")
+	c.DBDriver = getenv("DB_DRIVER", "sqlite3")
+	c.DatabaseURL = os.Getenv("DATABASE_URL")
+	if (c.DBDriver == "postgres" || c.DBDriver == "postgresql") && c.DatabaseURL == "" {
+		return nil, fmt.Errorf("DATABASE_URL is required when DB_DRIVER=%s", c.DBDriver)
+	}
 
 	c.MixinBotUserID = os.Getenv("MIXIN_BOT_USER_ID")
 	c.MixinWebhookSecret = os.Getenv("MIXIN_WEBHOOK_SECRET")
@@ -42,9 +138,141 @@ func Load() (*Config, error) {
 	}
 	c.ExinSwapLatestExecSeconds = vv
 
+	if c.RetryMaxAttempts, err = getenvInt("RETRY_MAX_ATTEMPTS", "8"); err != nil {
+		return nil, err
+	}
+	if c.RetryBaseDelaySeconds, err = getenvInt("RETRY_BASE_DELAY_SECONDS", "10"); err != nil {
+		return nil, err
+	}
+	if c.RetryMaxDelaySeconds, err = getenvInt("RETRY_MAX_DELAY_SECONDS", "1800"); err != nil {
+		return nil, err
+	}
+
+	c.WorkerID = os.Getenv("WORKER_ID")
+	if c.WorkerID == "" {
+		host, _ := os.Hostname()
+		c.WorkerID = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if c.WorkerLeaseSeconds, err = getenvInt("WORKER_LEASE_SECONDS", "60"); err != nil {
+		return nil, err
+	}
+
+	c.ScreeningBlocklistFile = os.Getenv("SCREENING_BLOCKLIST_FILE")
+
+	c.RateLimitStore = getenv("RATE_LIMIT_STORE", "memory")
+	switch c.RateLimitStore {
+	case "memory", "db", "off":
+	default:
+		return nil, fmt.Errorf("invalid RATE_LIMIT_STORE %q (want memory, db or off)", c.RateLimitStore)
+	}
+	if c.RateLimitIPPerMin, err = getenvInt("RATE_LIMIT_IP_PER_MIN", "60"); err != nil {
+		return nil, err
+	}
+	if c.RateLimitIPBurst, err = getenvInt("RATE_LIMIT_IP_BURST", "20"); err != nil {
+		return nil, err
+	}
+	if c.RateLimitKeyPerMin, err = getenvInt("RATE_LIMIT_KEY_PER_MIN", "600"); err != nil {
+		return nil, err
+	}
+	if c.RateLimitKeyBurst, err = getenvInt("RATE_LIMIT_KEY_BURST", "100"); err != nil {
+		return nil, err
+	}
+	if c.MaxBodyBytes, err = getenvInt("MAX_BODY_BYTES", "65536"); err != nil {
+		return nil, err
+	}
+	c.RequireAPIKey = os.Getenv("REQUIRE_API_KEY") == "true"
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			c.TrustedProxies = append(c.TrustedProxies, p)
+		}
+	}
+
+	if c.ReconcileIntervalSeconds, err = getenvInt("RECONCILE_INTERVAL_SECONDS", "300"); err != nil {
+		return nil, err
+	}
+	if c.ReconcileTolerance, err = decimal.NewFromString(getenv("RECONCILE_TOLERANCE", "0.00000001")); err != nil {
+		return nil, fmt.Errorf("invalid RECONCILE_TOLERANCE: %w", err)
+	}
+	if c.ReconcileConfirmRuns, err = getenvInt("RECONCILE_CONFIRM_RUNS", "2"); err != nil {
+		return nil, err
+	}
+	c.MixinFakeBalancesFile = os.Getenv("MIXIN_FAKE_BALANCES_FILE")
+	if c.LiquidityCacheSeconds, err = getenvInt("LIQUIDITY_CACHE_SECONDS", "30"); err != nil {
+		return nil, err
+	}
+	c.MetricsPort = getenv("METRICS_PORT", "9090")
+	c.APIMetricsPort = getenv("API_METRICS_PORT", "9091")
+	if c.PollStaleSeconds, err = getenvInt("POLL_STALE_SECONDS", "60"); err != nil {
+		return nil, err
+	}
+	if c.AlertIntervalSeconds, err = getenvInt("ALERT_INTERVAL_SECONDS", "60"); err != nil {
+		return nil, err
+	}
+	c.AlertStdout = getenv("ALERT_STDOUT", "true") == "true"
+	c.AlertWebhookURL = os.Getenv("ALERT_WEBHOOK_URL")
+	c.AlertMixinConversationID = os.Getenv("ALERT_MIXIN_CONVERSATION_ID")
+	if c.WebhookMaxAttempts, err = getenvInt("WEBHOOK_MAX_ATTEMPTS", "12"); err != nil {
+		return nil, err
+	}
+	if c.WebhookBaseDelaySeconds, err = getenvInt("WEBHOOK_BASE_DELAY_SECONDS", "30"); err != nil {
+		return nil, err
+	}
+	if c.WebhookMaxDelaySeconds, err = getenvInt("WEBHOOK_MAX_DELAY_SECONDS", "21600"); err != nil {
+		return nil, err
+	}
+	c.WebhookAllowHTTP = getenv("WEBHOOK_ALLOW_HTTP", "false") == "true"
+	c.WebhookAllowPrivate = getenv("WEBHOOK_ALLOW_PRIVATE", "false") == "true"
+	if c.OrderStreamPollMs, err = getenvInt("ORDER_STREAM_POLL_MS", "1000"); err != nil {
+		return nil, err
+	}
+	if c.OrderStreamPollMs <= 0 {
+		return nil, fmt.Errorf("ORDER_STREAM_POLL_MS must be positive")
+	}
+	if c.OrderStreamMax, err = getenvInt("ORDER_STREAM_MAX", "1000"); err != nil {
+		return nil, err
+	}
+	if err := c.LogLevel.UnmarshalText([]byte(getenv("LOG_LEVEL", "info"))); err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+	}
+	c.LogFormat = getenv("LOG_FORMAT", "json")
+	c.TracesExporter = getenv("OTEL_TRACES_EXPORTER", "none")
+
+	if c.AdminTokens, err = parseAdminTokens(os.Getenv("ADMIN_TOKENS")); err != nil {
+		return nil, err
+	}
+
 	return c, nil
 }
 
+// parseAdminTokens parses "alice:tok1,bob:tok2" into token -> operator.
+func parseAdminTokens(v string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, part := range strings.Split(v, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, token, ok := strings.Cut(part, ":")
+		name, token = strings.TrimSpace(name), strings.TrimSpace(token)
+		if !ok || name == "" || token == "" {
+			return nil, fmt.Errorf("invalid ADMIN_TOKENS entry %q (want operator:token)", part)
+		}
+		if _, dup := out[token]; dup {
+			return nil, fmt.Errorf("invalid ADMIN_TOKENS: duplicate token for %q", name)
+		}
+		out[token] = name
+	}
+	return out, nil
+}
+
+// DBSource is the argument for db.Open: a file path for sqlite3, a URL for postgres.
+func (c *Config) DBSource() string {
+	if c.DatabaseURL != "" {
+		return c.DatabaseURL
+	}
+	return c.SQLitePath
+}
+
 func getenv(k, def string) string {
 	v := os.Getenv(k)
 	if v == "" {
@@ -52,3 +280,11 @@ func getenv(k, def string) string {
 	}
 	return v
 }
+
+func getenvInt(k, def string) (int64, error) {
+	v, err := strconv.ParseInt(getenv(k, def), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", k, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// AdminRepo stores the operator audit trail and order notes.
+type AdminRepo struct{ DB *DB }
+
+func NewAdminRepo(db *DB) *AdminRepo { return &AdminRepo{DB: db} }
+
+var _ store.AdminStore = (*AdminRepo)(nil)
+
+func (r *AdminRepo) InAdminTx(ctx context.Context, fn func(tx store.AdminTx) error) error {
+	return r.DB.InTx(ctx, func(tx *DB) error {
+		return fn(store.AdminTx{
+			Orders:    NewOrdersRepo(tx),
+			Audit:     NewAdminRepo(tx),
+			KV:        NewStateRepo(tx),
+			Blocklist: NewBlocklistRepo(tx),
+			Partners:  NewPartnerRepo(tx),
+			Webhooks:  NewWebhookRepo(tx),
+		})
+	})
+}
+
+func (r *AdminRepo) InsertAudit(ctx context.Context, e *models.AuditEntry) error {
+	_, err := r.DB.ExecContext(ctx, `
+INSERT INTO admin_audit(id, operator, action, order_id, detail, created_at)
+VALUES(?,?,?,?,?,?)
+`, e.ID, e.Operator, e.Action, e.OrderID, nullStr(e.Detail), formatTime(e.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("insert audit: %w", err)
+	}
+	return nil
+}
+
+// ListAudit returns the newest entries first; orderID == "" lists all orders.
+func (r *AdminRepo) ListAudit(ctx context.Context, orderID string, limit int) ([]*models.AuditEntry, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	q := `SELECT id, operator, action, order_id, detail, created_at FROM admin_audit `
+	args := []any{}
+	if orderID != "" {
+		q += `WHERE order_id = ? `
+		args = append(args, orderID)
+	}
+	q += `ORDER BY created_at DESC, id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list audit: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		var oid, detail sql.NullString
+		var createdAt string
+		if err := rows.Scan(&e.ID, &e.Operator, &e.Action, &oid, &detail, &createdAt); err != nil {
+			return nil, err
+		}
+		e.OrderID = nullStringValue(oid)
+		e.Detail = detail.String
+		if e.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &e)
+	}
+	return out, rows.Err()
+}
+
+func (r *AdminRepo) InsertNote(ctx context.Context, n *models.OrderNote) error {
+	_, err := r.DB.ExecContext(ctx, `
+INSERT INTO order_notes(id, order_id, operator, note, created_at)
+VALUES(?,?,?,?,?)
+`, n.ID, n.OrderID, n.Operator, n.Note, formatTime(n.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("insert note: %w", err)
+	}
+	return nil
+}
+
+// ListNotes returns an order's notes, oldest first.
+func (r *AdminRepo) ListNotes(ctx context.Context, orderID string) ([]*models.OrderNote, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+SELECT id, order_id, operator, note, created_at FROM order_notes
+WHERE order_id = ?
+ORDER BY created_at ASC, id ASC
+`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("list notes: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*models.OrderNote
+	for rows.Next() {
+		var n models.OrderNote
+		var createdAt string
+		if err := rows.Scan(&n.ID, &n.OrderID, &n.Operator, &n.Note, &createdAt); err != nil {
+			return nil, err
+		}
+		if n.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &n)
+	}
+	return out, rows.Err()
+}
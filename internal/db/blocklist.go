@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// BlocklistRepo is the DB-backed screening blocklist. Addresses are stored
+// lower-cased and matched case-insensitively.
+type BlocklistRepo struct{ DB *DB }
+
+func NewBlocklistRepo(db *DB) *BlocklistRepo { return &BlocklistRepo{DB: db} }
+
+var _ store.BlocklistStore = (*BlocklistRepo)(nil)
+
+const blocklistColumns = `id, chain, address, reason, created_by, created_at`
+
+func scanBlocklistEntry(rs rowScanner) (*models.BlocklistEntry, error) {
+	var e models.BlocklistEntry
+	var createdAt string
+	if err := rs.Scan(&e.ID, &e.Chain, &e.Address, &e.Reason, &e.CreatedBy, &createdAt); err != nil {
+		return nil, err
+	}
+	t, err := parseTime(createdAt)
+	if err != nil {
+		return nil, err
+	}
+	e.CreatedAt = t
+	return &e, nil
+}
+
+func (r *BlocklistRepo) ListBlocklist(ctx context.Context) ([]*models.BlocklistEntry, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+blocklistColumns+` FROM screening_blocklist ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("list blocklist: %w", err)
+	}
+	defer rows.Close()
+	var out []*models.BlocklistEntry
+	for rows.Next() {
+		e, err := scanBlocklistEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (r *BlocklistRepo) AddBlocklist(ctx context.Context, e *models.BlocklistEntry) error {
+	_, err := r.DB.ExecContext(ctx, `
+INSERT INTO screening_blocklist(`+blocklistColumns+`)
+VALUES(?,?,?,?,?,?)
+ON CONFLICT(chain, address) DO UPDATE SET reason = excluded.reason, created_by = excluded.created_by
+`, e.ID, e.Chain, strings.ToLower(e.Address), e.Reason, e.CreatedBy, formatTime(e.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("add blocklist: %w", err)
+	}
+	return nil
+}
+
+func (r *BlocklistRepo) RemoveBlocklist(ctx context.Context, id string) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM screening_blocklist WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("remove blocklist: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+func (r *BlocklistRepo) MatchBlocklist(ctx context.Context, chain, address string) (*models.BlocklistEntry, error) {
+	row := r.DB.QueryRowContext(ctx, `
+SELECT `+blocklistColumns+` FROM screening_blocklist
+WHERE address = ? AND (chain = ? OR chain = '*')
+LIMIT 1
+`, strings.ToLower(address), strings.ToUpper(chain))
+	e, err := scanBlocklistEntry(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	return e, err
+}
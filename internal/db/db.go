@@ -1,24 +1,130 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"runtime"
+	"strings"
 
+	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mvg-fi-dev/bridge/internal/tracing"
 )
 
+// DB is a connection pool, or a single transaction when returned by InTx.
+// Repos built on a transactional DB run every statement inside that transaction.
 type DB struct {
-	SQL *sql.DB
+	SQL     *sql.DB
+	Dialect Dialect
+
+	tx *sql.Tx
 }
 
-func Open(sqlitePath string) (*DB, error) {
-	dsn := fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000", sqlitePath)
-	s, err := sql.Open("sqlite3", dsn)
+// Open connects to the configured backend.
+// For sqlite3, source is a file path; for postgres, a connection URL/DSN.
+func Open(driver string, source string) (*DB, error) {
+	d, err := ParseDialect(driver)
+	if err != nil {
+		return nil, err
+	}
+	var s *sql.DB
+	switch d {
+	case DialectPostgres:
+		s, err = sql.Open("pgx", source)
+	default:
+		dsn := fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000", source)
+		s, err = sql.Open("sqlite3", dsn)
+	}
 	if err != nil {
 		return nil, err
 	}
 	if err := s.Ping(); err != nil {
 		return nil, err
 	}
-	return &DB{SQL: s}, nil
+	return &DB{SQL: s, Dialect: d}, nil
+}
+
+// InTx runs fn in a transaction and commits if fn returns nil.
+// Nested calls reuse the outer transaction.
+func (d *DB) InTx(ctx context.Context, fn func(tx *DB) error) (err error) {
+	if d.tx != nil {
+		return fn(d)
+	}
+	if tracing.Enabled() {
+		var span trace.Span
+		ctx, span = tracing.Start(ctx, "db.tx", attribute.String("db.system", string(d.Dialect)))
+		defer func() { tracing.End(span, err) }()
+	}
+	tx, err := d.SQL.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(&DB{SQL: d.SQL, Dialect: d.Dialect, tx: tx}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Repos write queries with `?` placeholders; these wrappers rebind them for
+// the active dialect so the same SQL runs on SQLite and Postgres. Each
+// statement gets a span named after the repo method that issued it.
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...any) (res sql.Result, err error) {
+	ctx, span := d.span(ctx, query)
+	defer func() { tracing.End(span, err) }()
+	if d.tx != nil {
+		return d.tx.ExecContext(ctx, d.Dialect.Rebind(query), args...)
+	}
+	return d.SQL.ExecContext(ctx, d.Dialect.Rebind(query), args...)
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...any) (rows *sql.Rows, err error) {
+	ctx, span := d.span(ctx, query)
+	defer func() { tracing.End(span, err) }()
+	if d.tx != nil {
+		return d.tx.QueryContext(ctx, d.Dialect.Rebind(query), args...)
+	}
+	return d.SQL.QueryContext(ctx, d.Dialect.Rebind(query), args...)
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...any) (row *sql.Row) {
+	ctx, span := d.span(ctx, query)
+	defer func() { tracing.End(span, row.Err()) }()
+	if d.tx != nil {
+		return d.tx.QueryRowContext(ctx, d.Dialect.Rebind(query), args...)
+	}
+	return d.SQL.QueryRowContext(ctx, d.Dialect.Rebind(query), args...)
+}
+
+// span starts the span for one statement. Its name is the first exported
+// method of this package up the stack, e.g. "db.OrdersRepo.MarkCompleted",
+// so helpers such as getOrder are attributed to their caller.
+func (d *DB) span(ctx context.Context, query string) (context.Context, trace.Span) {
+	if !tracing.Enabled() {
+		return ctx, trace.SpanFromContext(context.Background()) // no-op span
+	}
+	return tracing.Start(ctx, repoMethod(),
+		attribute.String("db.system", string(d.Dialect)),
+		attribute.String("db.query.text", strings.TrimSpace(query)),
+	)
+}
+
+func repoMethod() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		f, more := frames.Next()
+		name := f.Function[strings.LastIndex(f.Function, "/")+1:]
+		if parts := strings.Split(name, "."); len(parts) == 3 && parts[0] == "db" && parts[1] != "(*DB)" && parts[2] != "" && parts[2][0] >= 'A' && parts[2][0] <= 'Z' {
+			return "db." + strings.Trim(parts[1], "(*)") + "." + parts[2]
+		}
+		if !more {
+			return "db.query"
+		}
+	}
 }
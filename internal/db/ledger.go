@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// LedgerRepo stores ledger journals and their entries.
+type LedgerRepo struct{ DB *DB }
+
+func NewLedgerRepo(db *DB) *LedgerRepo { return &LedgerRepo{DB: db} }
+
+var _ store.LedgerStore = (*LedgerRepo)(nil)
+
+const ledgerEntryColumns = `id, journal_id, account, asset_id, amount, created_at`
+
+func scanLedgerEntry(rs rowScanner) (*models.LedgerEntry, error) {
+	var e models.LedgerEntry
+	var createdAt string
+	if err := rs.Scan(&e.ID, &e.JournalID, &e.Account, &e.AssetID, &e.Amount, &createdAt); err != nil {
+		return nil, err
+	}
+	t, err := parseTime(createdAt)
+	if err != nil {
+		return nil, err
+	}
+	e.CreatedAt = t
+	return &e, nil
+}
+
+func scanLedgerJournal(rs rowScanner) (*models.LedgerJournal, error) {
+	var j models.LedgerJournal
+	var orderID sql.NullString
+	var createdAt string
+	if err := rs.Scan(&j.ID, &j.Kind, &j.Ref, &orderID, &createdAt); err != nil {
+		return nil, err
+	}
+	t, err := parseTime(createdAt)
+	if err != nil {
+		return nil, err
+	}
+	j.OrderID = nullStringValue(orderID)
+	j.CreatedAt = t
+	return &j, nil
+}
+
+func (r *LedgerRepo) PostJournal(ctx context.Context, j *models.LedgerJournal) (bool, error) {
+	var inserted bool
+	err := r.DB.InTx(ctx, func(tx *DB) error {
+		res, err := tx.ExecContext(ctx, `
+INSERT INTO ledger_journals(id, kind, ref, order_id, created_at)
+VALUES(?,?,?,?,?)
+ON CONFLICT(kind, ref) DO NOTHING
+`, j.ID, j.Kind, j.Ref, j.OrderID, formatTime(j.CreatedAt))
+		if err != nil {
+			return fmt.Errorf("insert ledger journal: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil || n == 0 {
+			return err
+		}
+		for _, e := range j.Entries {
+			_, err := tx.ExecContext(ctx, `
+INSERT INTO ledger_entries(`+ledgerEntryColumns+`)
+VALUES(?,?,?,?,?,?)
+`, e.ID, j.ID, e.Account, e.AssetID, e.Amount, formatTime(j.CreatedAt))
+			if err != nil {
+				return fmt.Errorf("insert ledger entry: %w", err)
+			}
+		}
+		inserted = true
+		return nil
+	})
+	return inserted, err
+}
+
+func (r *LedgerRepo) GetJournal(ctx context.Context, kind, ref string) (*models.LedgerJournal, error) {
+	j, err := scanLedgerJournal(r.DB.QueryRowContext(ctx, `
+SELECT id, kind, ref, order_id, created_at FROM ledger_journals WHERE kind = ? AND ref = ?
+`, kind, ref))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get ledger journal: %w", err)
+	}
+	if j.Entries, err = r.listEntries(ctx, `WHERE journal_id = ?`, j.ID); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (r *LedgerRepo) ListOrderJournals(ctx context.Context, orderID string) ([]*models.LedgerJournal, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+SELECT id, kind, ref, order_id, created_at FROM ledger_journals WHERE order_id = ? ORDER BY created_at, id
+`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("list ledger journals: %w", err)
+	}
+	var out []*models.LedgerJournal
+	byID := map[string]*models.LedgerJournal{}
+	for rows.Next() {
+		j, err := scanLedgerJournal(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		j.Entries = []*models.LedgerEntry{}
+		byID[j.ID] = j
+		out = append(out, j)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	entries, err := r.listEntries(ctx, `WHERE journal_id IN (SELECT id FROM ledger_journals WHERE order_id = ?)`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if j := byID[e.JournalID]; j != nil {
+			j.Entries = append(j.Entries, e)
+		}
+	}
+	return out, nil
+}
+
+// ListBalances sums in SQL on Postgres, where amount is numeric, and sorts
+// bytewise like SQLite does. SQLite keeps amounts as TEXT and its SUM would
+// round them through a float, so there the entries are read in
+// (account, asset_id) index order and summed one group at a time, without
+// holding them all.
+func (r *LedgerRepo) ListBalances(ctx context.Context, accountPrefix string) ([]*models.LedgerBalance, error) {
+	esc := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(accountPrefix)
+	if r.DB.Dialect == DialectPostgres {
+		return r.sumBalances(ctx, esc+"%")
+	}
+	rows, err := r.DB.QueryContext(ctx, `
+SELECT account, asset_id, amount FROM ledger_entries
+WHERE account LIKE ? ESCAPE '\'
+ORDER BY account, asset_id
+`, esc+"%")
+	if err != nil {
+		return nil, fmt.Errorf("list ledger balances: %w", err)
+	}
+	defer rows.Close()
+	out := []*models.LedgerBalance{}
+	var cur *models.LedgerBalance
+	var sum decimal.Decimal
+	flush := func() {
+		if cur != nil && !sum.IsZero() {
+			cur.Amount = sum.String()
+			out = append(out, cur)
+		}
+	}
+	for rows.Next() {
+		var account, assetID, amount string
+		if err := rows.Scan(&account, &assetID, &amount); err != nil {
+			return nil, err
+		}
+		v, err := decimal.NewFromString(amount)
+		if err != nil {
+			return nil, fmt.Errorf("list ledger balances: %s %s amount %q: %w", account, assetID, amount, err)
+		}
+		if cur == nil || cur.Account != account || cur.AssetID != assetID {
+			flush()
+			cur, sum = &models.LedgerBalance{Account: account, AssetID: assetID}, decimal.Zero
+		}
+		sum = sum.Add(v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return out, nil
+}
+
+func (r *LedgerRepo) sumBalances(ctx context.Context, pattern string) ([]*models.LedgerBalance, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+SELECT account, asset_id, SUM(amount)::text FROM ledger_entries
+WHERE account LIKE ? ESCAPE '\'
+GROUP BY account, asset_id
+HAVING SUM(amount) <> 0
+ORDER BY account COLLATE "C", asset_id COLLATE "C"
+`, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("list ledger balances: %w", err)
+	}
+	defer rows.Close()
+	out := []*models.LedgerBalance{}
+	for rows.Next() {
+		var b models.LedgerBalance
+		if err := rows.Scan(&b.Account, &b.AssetID, &b.Amount); err != nil {
+			return nil, err
+		}
+		out = append(out, &b)
+	}
+	return out, rows.Err()
+}
+
+func (r *LedgerRepo) listEntries(ctx context.Context, where string, args ...any) ([]*models.LedgerEntry, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+ledgerEntryColumns+` FROM ledger_entries `+where+` ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list ledger entries: %w", err)
+	}
+	defer rows.Close()
+	var out []*models.LedgerEntry
+	for rows.Next() {
+		e, err := scanLedgerEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -1,19 +1,45 @@
 package db
 
 import (
-	"database/sql"
+	"context"
 	"embed"
 
 	"github.com/pressly/goose/v3"
 )
 
-//go:embed migrations/*.sql
+// Migrations are kept per dialect so Postgres can use native
+// timestamptz/numeric columns while SQLite stays on TEXT.
+//
+//go:embed migrations/sqlite3/*.sql migrations/postgres/*.sql
 var migrations embed.FS
 
-func Migrate(s *sql.DB) error {
+func Migrate(d *DB) error {
 	goose.SetBaseFS(migrations)
-	if err := goose.SetDialect("sqlite3"); err != nil {
+	if err := goose.SetDialect(string(d.Dialect)); err != nil {
 		return err
 	}
-	return goose.Up(s, "migrations")
+	return goose.Up(d.SQL, "migrations/"+string(d.Dialect))
+}
+
+// MigrationVersion returns the schema version applied to d and the newest
+// version embedded in this binary; they differ while another replica is
+// still migrating or after a rollback to an older binary.
+func MigrationVersion(ctx context.Context, d *DB) (current, latest int64, err error) {
+	goose.SetBaseFS(migrations)
+	if err := goose.SetDialect(string(d.Dialect)); err != nil {
+		return 0, 0, err
+	}
+	current, err = goose.GetDBVersionContext(ctx, d.SQL)
+	if err != nil {
+		return 0, 0, err
+	}
+	ms, err := goose.CollectMigrations("migrations/"+string(d.Dialect), 0, goose.MaxVersion)
+	if err != nil {
+		return 0, 0, err
+	}
+	last, err := ms.Last()
+	if err != nil {
+		return 0, 0, err
+	}
+	return current, last.Version, nil
 }
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+)
+
+// Rows written in RFC3339Nano before 00018 must come out in timeLayout, so
+// lexical comparisons (cursor pages, due retries, leases) keep time order.
+func TestMigrateNormalisesSQLiteTimes(t *testing.T) {
+	d, err := Open("sqlite3", filepath.Join(t.TempDir(), "bridge.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.SQL.Close()
+	goose.SetBaseFS(migrations)
+	if err := goose.SetDialect(string(d.Dialect)); err != nil {
+		t.Fatal(err)
+	}
+	if err := goose.UpTo(d.SQL, "migrations/sqlite3", 17); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_, err = d.ExecContext(ctx, `
+INSERT INTO orders (id, public_id, status, created_at, updated_at, source_chain, source_asset, amount_in,
+  target_chain, target_asset, target_address, estimated_out, min_out, pay_window_seconds, lease_until, next_attempt_at)
+VALUES ('o1', 'p1', 'awaiting_deposit', '2024-01-02T03:04:05Z', '2024-01-02T03:04:05.1Z', 'ETH', 'a', '1',
+  'ETH', 'b', '0x1', '1', '1', 900, '2024-01-02T03:04:05.123+08:00', NULL)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrate(d); err != nil {
+		t.Fatal(err)
+	}
+
+	var created, updated, lease string
+	err = d.QueryRowContext(ctx, `SELECT created_at, updated_at, lease_until FROM orders WHERE id = 'o1'`).Scan(&created, &updated, &lease)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct{ got, want string }{
+		{created, "2024-01-02T03:04:05.000000000Z"},
+		{updated, "2024-01-02T03:04:05.100000000Z"},
+		{lease, "2024-01-01T19:04:05.123000000Z"},
+	} {
+		if c.got != c.want {
+			t.Errorf("got %q, want %q", c.got, c.want)
+		}
+		if ts, err := parseTime(c.got); err != nil || formatTime(ts) != c.got {
+			t.Errorf("%q does not round-trip through timeLayout", c.got)
+		}
+	}
+	var n int
+	if err := d.QueryRowContext(ctx, `SELECT COUNT(*) FROM order_events WHERE order_id = 'o1' AND created_at = ?`, created).Scan(&n); err != nil || n != 1 {
+		t.Errorf("order event created_at not normalised: n=%d err=%v", n, err)
+	}
+}
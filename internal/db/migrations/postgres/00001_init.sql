@@ -0,0 +1,87 @@
+-- +goose Up
+
+-- Postgres schema starts at the state SQLite reached in 00006_leases.sql.
+-- Later migrations share version numbers across dialects.
+
+CREATE TABLE IF NOT EXISTS orders (
+  id TEXT PRIMARY KEY,
+  public_id TEXT NOT NULL UNIQUE,
+  status TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL,
+
+  source_chain TEXT NOT NULL,
+  source_asset TEXT NOT NULL,
+  amount_in NUMERIC NOT NULL,
+  target_chain TEXT NOT NULL,
+  target_asset TEXT NOT NULL,
+  target_address TEXT NOT NULL,
+
+  estimated_out NUMERIC NOT NULL,
+  min_out NUMERIC NOT NULL,
+  quote_expiry_at TIMESTAMPTZ,
+
+  pay_window_seconds BIGINT NOT NULL,
+
+  mixin_opponent_id TEXT,
+  mixin_asset_id TEXT,
+  mixin_pay_memo TEXT,
+  mixin_pay_url TEXT,
+
+  deposit_txid TEXT,
+  deposit_tx_detected_at TIMESTAMPTZ,
+  deposit_credited_at TIMESTAMPTZ,
+  amount_credited NUMERIC,
+  refund_to_address TEXT,
+
+  final_out NUMERIC,
+  swap_ref TEXT,
+  exinswap_trace_id TEXT,
+  withdraw_txid TEXT,
+  refund_txid TEXT,
+  refund_asset_id TEXT,
+  refund_amount NUMERIC,
+  refund_received_snapshot_id TEXT,
+
+  retry_stage TEXT,
+  attempts BIGINT NOT NULL DEFAULT 0,
+  last_error TEXT,
+  next_attempt_at TIMESTAMPTZ,
+
+  locked_by TEXT,
+  lease_until TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
+CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at);
+CREATE INDEX IF NOT EXISTS idx_orders_exinswap_trace_id ON orders(exinswap_trace_id);
+CREATE INDEX IF NOT EXISTS idx_orders_refund_received_snapshot_id ON orders(refund_received_snapshot_id);
+CREATE INDEX IF NOT EXISTS idx_orders_next_attempt_at ON orders(next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_orders_lease_until ON orders(lease_until);
+CREATE INDEX IF NOT EXISTS idx_orders_mixin_pay_memo ON orders(mixin_pay_memo);
+
+CREATE TABLE IF NOT EXISTS mixin_snapshots (
+  snapshot_id TEXT PRIMARY KEY,
+  received_at TIMESTAMPTZ NOT NULL,
+  raw_json TEXT NOT NULL,
+
+  -- extracted
+  created_at TIMESTAMPTZ,
+  amount NUMERIC,
+  asset_id TEXT,
+  opponent_id TEXT,
+  memo TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_mixin_snapshots_created_at ON mixin_snapshots(created_at);
+
+CREATE TABLE IF NOT EXISTS kv (
+  k TEXT PRIMARY KEY,
+  v TEXT NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS kv;
+DROP TABLE IF EXISTS mixin_snapshots;
+DROP TABLE IF EXISTS orders;
@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- Trace ids are persisted before the outbound Mixin call so a crash between
+-- the call and the status update can be reconciled.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS withdraw_trace_id TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS refund_trace_id TEXT;
+
+-- +goose Down
+
+ALTER TABLE orders DROP COLUMN IF EXISTS refund_trace_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS withdraw_trace_id;
@@ -0,0 +1,28 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS admin_audit (
+  id TEXT PRIMARY KEY,
+  operator TEXT NOT NULL,
+  action TEXT NOT NULL,
+  order_id TEXT,
+  detail TEXT,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_order_id ON admin_audit(order_id);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_created_at ON admin_audit(created_at);
+
+CREATE TABLE IF NOT EXISTS order_notes (
+  id TEXT PRIMARY KEY,
+  order_id TEXT NOT NULL REFERENCES orders(id),
+  operator TEXT NOT NULL,
+  note TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_notes_order_id ON order_notes(order_id);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS order_notes;
+DROP TABLE IF EXISTS admin_audit;
@@ -0,0 +1,14 @@
+-- +goose Up
+
+-- Set when a credited order passed the post-deposit limit check or an
+-- operator released it from review; the check is not repeated after that.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS limits_cleared_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_orders_target_address_created_at ON orders(target_address, created_at);
+CREATE INDEX IF NOT EXISTS idx_orders_refund_to_created_at ON orders(refund_to_address, created_at);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_orders_refund_to_created_at;
+DROP INDEX IF EXISTS idx_orders_target_address_created_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS limits_cleared_at;
@@ -0,0 +1,22 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS screening_blocklist (
+  id TEXT PRIMARY KEY,
+  chain TEXT NOT NULL,    -- '*' matches any chain
+  address TEXT NOT NULL,  -- stored lower-cased
+  reason TEXT NOT NULL,
+  created_by TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  UNIQUE(chain, address)
+);
+
+CREATE INDEX IF NOT EXISTS idx_screening_blocklist_address ON screening_blocklist(address);
+
+-- Set when an operator releases an order from compliance_hold; executors do
+-- not re-screen it after that.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS screening_cleared_at TIMESTAMPTZ;
+
+-- +goose Down
+
+ALTER TABLE orders DROP COLUMN IF EXISTS screening_cleared_at;
+DROP TABLE IF EXISTS screening_blocklist;
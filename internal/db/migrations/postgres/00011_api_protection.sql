@@ -0,0 +1,26 @@
+-- +goose Up
+
+-- Token buckets for the shared (RATE_LIMIT_STORE=db) rate limiter.
+-- updated_ns doubles as a compare-and-swap version.
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+  bucket_key TEXT PRIMARY KEY,
+  tokens DOUBLE PRECISION NOT NULL,
+  updated_ns BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_ns ON rate_limit_buckets(updated_ns);
+
+-- Idempotency-Key header on POST /v1/orders.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  scope TEXT NOT NULL,
+  idem_key TEXT NOT NULL,
+  request_hash TEXT NOT NULL,
+  order_id TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY(scope, idem_key)
+);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS idempotency_keys;
+DROP TABLE IF EXISTS rate_limit_buckets;
@@ -0,0 +1,19 @@
+-- +goose Up
+
+-- Fee terms fixed at quote time (NULL for orders created before fees).
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fee_bps BIGINT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fee_flat TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fee_share_bps BIGINT;
+
+-- Fee retained at swap time, in the source asset, and the partner's share of it.
+-- Set back to 0 if the swap is refunded.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fee_amount TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS partner_fee_amount TEXT;
+
+-- +goose Down
+
+ALTER TABLE orders DROP COLUMN IF EXISTS partner_fee_amount;
+ALTER TABLE orders DROP COLUMN IF EXISTS fee_amount;
+ALTER TABLE orders DROP COLUMN IF EXISTS fee_share_bps;
+ALTER TABLE orders DROP COLUMN IF EXISTS fee_flat;
+ALTER TABLE orders DROP COLUMN IF EXISTS fee_bps;
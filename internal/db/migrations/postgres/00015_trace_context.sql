@@ -0,0 +1,9 @@
+-- +goose Up
+
+-- W3C traceparent of the API request that created the order, so worker spans
+-- can link back to it.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS trace_context TEXT;
+
+-- +goose Down
+
+ALTER TABLE orders DROP COLUMN IF EXISTS trace_context;
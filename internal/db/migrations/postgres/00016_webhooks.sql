@@ -0,0 +1,78 @@
+-- +goose Up
+
+-- Change feed of order status transitions, written by a trigger so every
+-- transition is recorded whichever code path made it. dispatched_at is set
+-- once partner webhook deliveries were queued for the event.
+CREATE TABLE IF NOT EXISTS order_events (
+  id BIGSERIAL PRIMARY KEY,
+  order_id TEXT NOT NULL,
+  status TEXT NOT NULL,
+  prev_status TEXT,
+  created_at TIMESTAMPTZ NOT NULL,
+  dispatched_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_events_order ON order_events(order_id, id);
+CREATE INDEX IF NOT EXISTS idx_order_events_undispatched ON order_events(id) WHERE dispatched_at IS NULL;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_order_event() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    INSERT INTO order_events(order_id, status, prev_status, created_at)
+    VALUES (NEW.id, NEW.status, NULL, NEW.created_at);
+  ELSIF NEW.status IS DISTINCT FROM OLD.status THEN
+    INSERT INTO order_events(order_id, status, prev_status, created_at)
+    VALUES (NEW.id, NEW.status, OLD.status, NEW.updated_at);
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS trg_orders_event ON orders;
+CREATE TRIGGER trg_orders_event AFTER INSERT OR UPDATE OF status ON orders
+FOR EACH ROW EXECUTE FUNCTION record_order_event();
+
+-- Partner callback URLs; secret signs the payloads.
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+  id TEXT PRIMARY KEY,
+  partner_id TEXT NOT NULL,
+  url TEXT NOT NULL,
+  secret TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_partner ON webhook_endpoints(partner_id);
+
+-- One row per (endpoint, event): the delivery log and retry queue.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id TEXT PRIMARY KEY,
+  endpoint_id TEXT NOT NULL,
+  partner_id TEXT NOT NULL,
+  event_id BIGINT NOT NULL,
+  order_id TEXT NOT NULL,
+  event_type TEXT NOT NULL,
+  payload TEXT NOT NULL,
+  status TEXT NOT NULL,   -- pending, delivered, failed
+  attempts BIGINT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ,
+  last_status_code INTEGER,
+  last_error TEXT,
+  delivered_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL,
+  UNIQUE(endpoint_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_partner ON webhook_deliveries(partner_id, created_at);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_endpoints;
+DROP TRIGGER IF EXISTS trg_orders_event ON orders;
+DROP FUNCTION IF EXISTS record_order_event();
+DROP TABLE IF EXISTS order_events;
@@ -0,0 +1,9 @@
+-- +goose Up
+
+-- SQLite rewrites its TEXT timestamps into the fixed-width layout here;
+-- timestamptz columns need nothing.
+SELECT 1;
+
+-- +goose Down
+
+SELECT 1;
@@ -0,0 +1,15 @@
+-- +goose Up
+
+-- Fee and ledger amounts were added as TEXT; store them as numeric like the
+-- other amount columns, so they can be summed and compared in SQL.
+ALTER TABLE orders ALTER COLUMN fee_flat TYPE NUMERIC USING NULLIF(fee_flat, '')::numeric;
+ALTER TABLE orders ALTER COLUMN fee_amount TYPE NUMERIC USING NULLIF(fee_amount, '')::numeric;
+ALTER TABLE orders ALTER COLUMN partner_fee_amount TYPE NUMERIC USING NULLIF(partner_fee_amount, '')::numeric;
+ALTER TABLE ledger_entries ALTER COLUMN amount TYPE NUMERIC USING amount::numeric;
+
+-- +goose Down
+
+ALTER TABLE ledger_entries ALTER COLUMN amount TYPE TEXT;
+ALTER TABLE orders ALTER COLUMN partner_fee_amount TYPE TEXT;
+ALTER TABLE orders ALTER COLUMN fee_amount TYPE TEXT;
+ALTER TABLE orders ALTER COLUMN fee_flat TYPE TEXT;
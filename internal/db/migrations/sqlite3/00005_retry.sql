@@ -0,0 +1,13 @@
+-- +goose Up
+
+ALTER TABLE orders ADD COLUMN retry_stage TEXT;
+ALTER TABLE orders ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN last_error TEXT;
+ALTER TABLE orders ADD COLUMN next_attempt_at TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_orders_next_attempt_at ON orders(next_attempt_at);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_orders_next_attempt_at;
+-- SQLite doesn't support DROP COLUMN reliably; leave columns in place.
@@ -0,0 +1,11 @@
+-- +goose Up
+
+ALTER TABLE orders ADD COLUMN locked_by TEXT;
+ALTER TABLE orders ADD COLUMN lease_until TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_orders_lease_until ON orders(lease_until);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_orders_lease_until;
+-- SQLite doesn't support DROP COLUMN reliably; leave columns in place.
@@ -0,0 +1,10 @@
+-- +goose Up
+
+-- Trace ids are persisted before the outbound Mixin call so a crash between
+-- the call and the status update can be reconciled.
+ALTER TABLE orders ADD COLUMN withdraw_trace_id TEXT;
+ALTER TABLE orders ADD COLUMN refund_trace_id TEXT;
+
+-- +goose Down
+
+-- SQLite doesn't support DROP COLUMN reliably; leave columns in place.
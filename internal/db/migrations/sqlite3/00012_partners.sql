@@ -0,0 +1,25 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS partners (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  api_key TEXT NOT NULL UNIQUE,
+  secret TEXT NOT NULL,           -- HMAC key for request signatures
+  fee_share_bps INTEGER NOT NULL DEFAULT 0,
+  daily_order_quota INTEGER NOT NULL DEFAULT 0,
+  rate_limit_per_min INTEGER NOT NULL DEFAULT 0,
+  disabled INTEGER NOT NULL DEFAULT 0,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+
+-- Integrator that created the order (NULL for anonymous orders).
+ALTER TABLE orders ADD COLUMN partner_id TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_orders_partner_id_created_at ON orders(partner_id, created_at);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_orders_partner_id_created_at;
+DROP TABLE IF EXISTS partners;
+-- SQLite doesn't support DROP COLUMN reliably; leave columns in place.
@@ -0,0 +1,15 @@
+-- +goose Up
+
+-- Fee terms fixed at quote time (NULL for orders created before fees).
+ALTER TABLE orders ADD COLUMN fee_bps INTEGER;
+ALTER TABLE orders ADD COLUMN fee_flat TEXT;
+ALTER TABLE orders ADD COLUMN fee_share_bps INTEGER;
+
+-- Fee retained at swap time, in the source asset, and the partner's share of it.
+-- Set back to 0 if the swap is refunded.
+ALTER TABLE orders ADD COLUMN fee_amount TEXT;
+ALTER TABLE orders ADD COLUMN partner_fee_amount TEXT;
+
+-- +goose Down
+
+-- SQLite doesn't support DROP COLUMN reliably; leave columns in place.
@@ -0,0 +1,31 @@
+-- +goose Up
+
+-- Double-entry ledger of bot wallet movements. Each journal's entries sum to
+-- zero per asset; (kind, ref) makes posting idempotent.
+CREATE TABLE IF NOT EXISTS ledger_journals (
+  id TEXT PRIMARY KEY,
+  kind TEXT NOT NULL,
+  ref TEXT NOT NULL,       -- snapshot id or transfer trace id
+  order_id TEXT,
+  created_at TEXT NOT NULL,
+  UNIQUE(kind, ref)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_journals_order ON ledger_journals(order_id);
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+  id TEXT PRIMARY KEY,
+  journal_id TEXT NOT NULL REFERENCES ledger_journals(id),
+  account TEXT NOT NULL,
+  asset_id TEXT NOT NULL,
+  amount TEXT NOT NULL,    -- signed decimal string
+  created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_journal ON ledger_entries(journal_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account ON ledger_entries(account, asset_id);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS ledger_journals;
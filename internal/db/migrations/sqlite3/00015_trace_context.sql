@@ -0,0 +1,9 @@
+-- +goose Up
+
+-- W3C traceparent of the API request that created the order, so worker spans
+-- can link back to it.
+ALTER TABLE orders ADD COLUMN trace_context TEXT;
+
+-- +goose Down
+
+-- SQLite doesn't support DROP COLUMN reliably; leave columns in place.
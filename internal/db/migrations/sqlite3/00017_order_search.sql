@@ -0,0 +1,13 @@
+-- +goose Up
+
+-- Order search (GET /admin/orders?q=) matches transaction hashes exactly.
+-- target_address and refund_to_address lead the 00009 indexes, which serve it.
+CREATE INDEX IF NOT EXISTS idx_orders_deposit_txid ON orders(deposit_txid);
+CREATE INDEX IF NOT EXISTS idx_orders_withdraw_txid ON orders(withdraw_txid);
+CREATE INDEX IF NOT EXISTS idx_orders_refund_txid ON orders(refund_txid);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_orders_refund_txid;
+DROP INDEX IF EXISTS idx_orders_withdraw_txid;
+DROP INDEX IF EXISTS idx_orders_deposit_txid;
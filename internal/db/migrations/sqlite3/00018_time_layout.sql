@@ -0,0 +1,81 @@
+-- +goose Up
+
+-- Rows written before the Postgres backend carry RFC3339Nano timestamps, whose
+-- width varies with the fraction ("...:05Z", "...:05.1Z"). Queries compare the
+-- TEXT columns lexically against timeLayout (fixed nine-digit fraction, UTC), so
+-- rewrite them in that layout. Values with an offset go through strftime, which
+-- keeps milliseconds.
+
+UPDATE orders SET created_at = CASE
+  WHEN substr(created_at, -1) = 'Z' THEN substr(created_at, 1, 19) || '.' ||
+    substr(CASE WHEN substr(created_at, 20, 1) = '.' THEN substr(created_at, 21, length(created_at) - 21) ELSE '' END || '000000000', 1, 9) || 'Z'
+  ELSE strftime('%Y-%m-%dT%H:%M:%f', created_at) || '000000Z'
+END
+WHERE created_at IS NOT NULL AND NOT (length(created_at) = 30 AND substr(created_at, 20, 1) = '.' AND substr(created_at, 30, 1) = 'Z');
+
+UPDATE orders SET updated_at = CASE
+  WHEN substr(updated_at, -1) = 'Z' THEN substr(updated_at, 1, 19) || '.' ||
+    substr(CASE WHEN substr(updated_at, 20, 1) = '.' THEN substr(updated_at, 21, length(updated_at) - 21) ELSE '' END || '000000000', 1, 9) || 'Z'
+  ELSE strftime('%Y-%m-%dT%H:%M:%f', updated_at) || '000000Z'
+END
+WHERE updated_at IS NOT NULL AND NOT (length(updated_at) = 30 AND substr(updated_at, 20, 1) = '.' AND substr(updated_at, 30, 1) = 'Z');
+
+UPDATE orders SET quote_expiry_at = CASE
+  WHEN substr(quote_expiry_at, -1) = 'Z' THEN substr(quote_expiry_at, 1, 19) || '.' ||
+    substr(CASE WHEN substr(quote_expiry_at, 20, 1) = '.' THEN substr(quote_expiry_at, 21, length(quote_expiry_at) - 21) ELSE '' END || '000000000', 1, 9) || 'Z'
+  ELSE strftime('%Y-%m-%dT%H:%M:%f', quote_expiry_at) || '000000Z'
+END
+WHERE quote_expiry_at IS NOT NULL AND NOT (length(quote_expiry_at) = 30 AND substr(quote_expiry_at, 20, 1) = '.' AND substr(quote_expiry_at, 30, 1) = 'Z');
+
+UPDATE orders SET deposit_tx_detected_at = CASE
+  WHEN substr(deposit_tx_detected_at, -1) = 'Z' THEN substr(deposit_tx_detected_at, 1, 19) || '.' ||
+    substr(CASE WHEN substr(deposit_tx_detected_at, 20, 1) = '.' THEN substr(deposit_tx_detected_at, 21, length(deposit_tx_detected_at) - 21) ELSE '' END || '000000000', 1, 9) || 'Z'
+  ELSE strftime('%Y-%m-%dT%H:%M:%f', deposit_tx_detected_at) || '000000Z'
+END
+WHERE deposit_tx_detected_at IS NOT NULL AND NOT (length(deposit_tx_detected_at) = 30 AND substr(deposit_tx_detected_at, 20, 1) = '.' AND substr(deposit_tx_detected_at, 30, 1) = 'Z');
+
+UPDATE orders SET deposit_credited_at = CASE
+  WHEN substr(deposit_credited_at, -1) = 'Z' THEN substr(deposit_credited_at, 1, 19) || '.' ||
+    substr(CASE WHEN substr(deposit_credited_at, 20, 1) = '.' THEN substr(deposit_credited_at, 21, length(deposit_credited_at) - 21) ELSE '' END || '000000000', 1, 9) || 'Z'
+  ELSE strftime('%Y-%m-%dT%H:%M:%f', deposit_credited_at) || '000000Z'
+END
+WHERE deposit_credited_at IS NOT NULL AND NOT (length(deposit_credited_at) = 30 AND substr(deposit_credited_at, 20, 1) = '.' AND substr(deposit_credited_at, 30, 1) = 'Z');
+
+UPDATE orders SET next_attempt_at = CASE
+  WHEN substr(next_attempt_at, -1) = 'Z' THEN substr(next_attempt_at, 1, 19) || '.' ||
+    substr(CASE WHEN substr(next_attempt_at, 20, 1) = '.' THEN substr(next_attempt_at, 21, length(next_attempt_at) - 21) ELSE '' END || '000000000', 1, 9) || 'Z'
+  ELSE strftime('%Y-%m-%dT%H:%M:%f', next_attempt_at) || '000000Z'
+END
+WHERE next_attempt_at IS NOT NULL AND NOT (length(next_attempt_at) = 30 AND substr(next_attempt_at, 20, 1) = '.' AND substr(next_attempt_at, 30, 1) = 'Z');
+
+UPDATE orders SET lease_until = CASE
+  WHEN substr(lease_until, -1) = 'Z' THEN substr(lease_until, 1, 19) || '.' ||
+    substr(CASE WHEN substr(lease_until, 20, 1) = '.' THEN substr(lease_until, 21, length(lease_until) - 21) ELSE '' END || '000000000', 1, 9) || 'Z'
+  ELSE strftime('%Y-%m-%dT%H:%M:%f', lease_until) || '000000Z'
+END
+WHERE lease_until IS NOT NULL AND NOT (length(lease_until) = 30 AND substr(lease_until, 20, 1) = '.' AND substr(lease_until, 30, 1) = 'Z');
+
+UPDATE mixin_snapshots SET received_at = CASE
+  WHEN substr(received_at, -1) = 'Z' THEN substr(received_at, 1, 19) || '.' ||
+    substr(CASE WHEN substr(received_at, 20, 1) = '.' THEN substr(received_at, 21, length(received_at) - 21) ELSE '' END || '000000000', 1, 9) || 'Z'
+  ELSE strftime('%Y-%m-%dT%H:%M:%f', received_at) || '000000Z'
+END
+WHERE received_at IS NOT NULL AND NOT (length(received_at) = 30 AND substr(received_at, 20, 1) = '.' AND substr(received_at, 30, 1) = 'Z');
+
+UPDATE kv SET updated_at = CASE
+  WHEN substr(updated_at, -1) = 'Z' THEN substr(updated_at, 1, 19) || '.' ||
+    substr(CASE WHEN substr(updated_at, 20, 1) = '.' THEN substr(updated_at, 21, length(updated_at) - 21) ELSE '' END || '000000000', 1, 9) || 'Z'
+  ELSE strftime('%Y-%m-%dT%H:%M:%f', updated_at) || '000000Z'
+END
+WHERE updated_at IS NOT NULL AND NOT (length(updated_at) = 30 AND substr(updated_at, 20, 1) = '.' AND substr(updated_at, 30, 1) = 'Z');
+
+UPDATE order_events SET created_at = CASE
+  WHEN substr(created_at, -1) = 'Z' THEN substr(created_at, 1, 19) || '.' ||
+    substr(CASE WHEN substr(created_at, 20, 1) = '.' THEN substr(created_at, 21, length(created_at) - 21) ELSE '' END || '000000000', 1, 9) || 'Z'
+  ELSE strftime('%Y-%m-%dT%H:%M:%f', created_at) || '000000Z'
+END
+WHERE created_at IS NOT NULL AND NOT (length(created_at) = 30 AND substr(created_at, 20, 1) = '.' AND substr(created_at, 30, 1) = 'Z');
+
+-- +goose Down
+
+-- The normalised values still parse as RFC3339Nano; nothing to undo.
@@ -0,0 +1,9 @@
+-- +goose Up
+
+-- Postgres moves fee and ledger amounts to numeric; SQLite keeps amounts as
+-- TEXT.
+SELECT 1;
+
+-- +goose Down
+
+SELECT 1;
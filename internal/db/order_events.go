@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// OrderEventRepo reads the order_events change feed filled by triggers on
+// orders (see migration 00016).
+type OrderEventRepo struct{ DB *DB }
+
+func NewOrderEventRepo(db *DB) *OrderEventRepo { return &OrderEventRepo{DB: db} }
+
+var _ store.OrderEventStore = (*OrderEventRepo)(nil)
+
+const orderEventColumns = `id, order_id, status, prev_status, created_at, dispatched_at`
+
+func scanOrderEvent(rs rowScanner) (*models.OrderEvent, error) {
+	var e models.OrderEvent
+	var prev, dispatchedAt sql.NullString
+	var createdAt string
+	if err := rs.Scan(&e.ID, &e.OrderID, &e.Status, &prev, &createdAt, &dispatchedAt); err != nil {
+		return nil, err
+	}
+	t, err := parseTime(createdAt)
+	if err != nil {
+		return nil, err
+	}
+	e.CreatedAt = t
+	if prev.Valid {
+		st := models.OrderStatus(prev.String)
+		e.PrevStatus = &st
+	}
+	e.DispatchedAt = nullTimeValue(dispatchedAt)
+	return &e, nil
+}
+
+func listOrderEvents(ctx context.Context, db *DB, tail string, args ...any) ([]*models.OrderEvent, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+orderEventColumns+` FROM order_events `+tail, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list order events: %w", err)
+	}
+	defer rows.Close()
+	var out []*models.OrderEvent
+	for rows.Next() {
+		e, err := scanOrderEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (r *OrderEventRepo) LatestOrderEventID(ctx context.Context, orderID string) (int64, error) {
+	var id sql.NullInt64
+	if err := r.DB.QueryRowContext(ctx, `SELECT MAX(id) FROM order_events WHERE order_id = ?`, orderID).Scan(&id); err != nil {
+		return 0, fmt.Errorf("latest order event: %w", err)
+	}
+	return id.Int64, nil
+}
+
+func (r *OrderEventRepo) ListOrderEvents(ctx context.Context, orderIDs []string, afterID int64, limit int) ([]*models.OrderEvent, error) {
+	if len(orderIDs) == 0 {
+		return nil, nil
+	}
+	args := make([]any, 0, len(orderIDs)+2)
+	for _, id := range orderIDs {
+		args = append(args, id)
+	}
+	args = append(args, afterID, limit)
+	marks := strings.TrimSuffix(strings.Repeat("?,", len(orderIDs)), ",")
+	return listOrderEvents(ctx, r.DB, `WHERE order_id IN (`+marks+`) AND id > ? ORDER BY id LIMIT ?`, args...)
+}
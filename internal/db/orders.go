@@ -2,17 +2,25 @@ package db
 
 import (
 	"context"
-	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
 )
 
 type OrdersRepo struct {
-	DB *sql.DB
+	DB *DB
 }
 
-func NewOrdersRepo(db *sql.DB) *OrdersRepo { return &OrdersRepo{DB: db} }
+func NewOrdersRepo(db *DB) *OrdersRepo { return &OrdersRepo{DB: db} }
+
+// InTx runs fn with a repo bound to one transaction; see DB.InTx.
+func (r *OrdersRepo) InTx(ctx context.Context, fn func(tx store.OrderStore) error) error {
+	return r.DB.InTx(ctx, func(tx *DB) error {
+		return fn(NewOrdersRepo(tx))
+	})
+}
 
 func (r *OrdersRepo) Insert(ctx context.Context, o *models.Order) error {
 	_, err := r.DB.ExecContext(ctx, `
@@ -21,111 +29,29 @@ INSERT INTO orders (
   source_chain, source_asset, amount_in, target_chain, target_asset, target_address,
   estimated_out, min_out, quote_expiry_at,
   pay_window_seconds,
-  mixin_opponent_id, mixin_asset_id, mixin_pay_memo, mixin_pay_url
-) VALUES (?,?,?,?,?, ?,?,?,?,?,?, ?,?,?, ?,?,?,?,?)
+  mixin_opponent_id, mixin_asset_id, mixin_pay_memo, mixin_pay_url,
+  partner_id,
+  fee_bps, fee_flat, fee_share_bps,
+  trace_context
+) VALUES (?,?,?,?,?, ?,?,?,?,?,?, ?,?,?, ?,?,?,?,?, ?, ?,?,?, ?)
 `,
-		o.ID, o.PublicID, string(o.Status), o.CreatedAt.Format(time.RFC3339Nano), o.UpdatedAt.Format(time.RFC3339Nano),
+		o.ID, o.PublicID, string(o.Status), formatTime(o.CreatedAt), formatTime(o.UpdatedAt),
 		o.SourceChain, o.SourceAsset, o.AmountIn, o.TargetChain, o.TargetAsset, o.TargetAddress,
 		o.EstimatedOut, o.MinOut, nullableTime(o.QuoteExpiryAt),
 		o.PayWindowSeconds,
 		o.MixinOpponentID, o.MixinAssetID, o.MixinPayMemo, o.MixinPayURL,
+		o.PartnerID,
+		o.FeeBps, o.FeeFlat, o.FeeShareBps,
+		o.TraceContext,
 	)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("insert order %s: %w", o.ID, store.ErrConflict)
+	}
 	return err
 }
 
 func (r *OrdersRepo) GetByPublicID(ctx context.Context, publicID string) (*models.Order, error) {
-	row := r.DB.QueryRowContext(ctx, `
-SELECT
-  id, public_id, status, created_at, updated_at,
-  source_chain, source_asset, amount_in, target_chain, target_asset, target_address,
-  estimated_out, min_out, quote_expiry_at,
-  pay_window_seconds,
-  mixin_opponent_id, mixin_asset_id, mixin_pay_memo, mixin_pay_url,
-  deposit_txid, deposit_tx_detected_at, deposit_credited_at, amount_credited, refund_to_address,
-  final_out, swap_ref, exinswap_trace_id, withdraw_txid, refund_txid,
-  refund_asset_id, refund_amount, refund_received_snapshot_id
-FROM orders
-WHERE public_id = ?
-LIMIT 1
-`, publicID)
-
-	var o models.Order
-	var status string
-	var createdAt, updatedAt string
-	var quoteExpiry sql.NullString
-	var depositTxID, depositDetectedAt, depositCreditedAt sql.NullString
-	var amountCredited, refundToAddress sql.NullString
-	var finalOut, swapRef, exinTrace, withdrawTxID, refundTxID sql.NullString
-	var refundAssetID, refundAmount, refundReceivedSnapshotID sql.NullString
-
-	if err := row.Scan(
-		&o.ID, &o.PublicID, &status, &createdAt, &updatedAt,
-		&o.SourceChain, &o.SourceAsset, &o.AmountIn, &o.TargetChain, &o.TargetAsset, &o.TargetAddress,
-		&o.EstimatedOut, &o.MinOut, &quoteExpiry,
-		&o.PayWindowSeconds,
-		&o.MixinOpponentID, &o.MixinAssetID, &o.MixinPayMemo, &o.MixinPayURL,
-		&depositTxID, &depositDetectedAt, &depositCreditedAt, &amountCredited, &refundToAddress,
-		&finalOut, &swapRef, &exinTrace, &withdrawTxID, &refundTxID,
-		&refundAssetID, &refundAmount, &refundReceivedSnapshotID,
-	); err != nil {
-		return nil, err
-	}
-
-	o.Status = models.OrderStatus(status)
-	ct, _ := time.Parse(time.RFC3339Nano, createdAt)
-	ut, _ := time.Parse(time.RFC3339Nano, updatedAt)
-	o.CreatedAt = ct
-	o.UpdatedAt = ut
-	if quoteExpiry.Valid {
-		if t, err := time.Parse(time.RFC3339Nano, quoteExpiry.String); err == nil {
-			o.QuoteExpiryAt = &t
-		}
-	}
-	if depositTxID.Valid {
-		o.DepositTxID = &depositTxID.String
-	}
-	if depositDetectedAt.Valid {
-		if t, err := time.Parse(time.RFC3339Nano, depositDetectedAt.String); err == nil {
-			o.DepositTxDetectedAt = &t
-		}
-	}
-	if depositCreditedAt.Valid {
-		if t, err := time.Parse(time.RFC3339Nano, depositCreditedAt.String); err == nil {
-			o.DepositCreditedAt = &t
-		}
-	}
-	if amountCredited.Valid {
-		o.AmountCredited = &amountCredited.String
-	}
-	if refundToAddress.Valid {
-		o.RefundToAddress = &refundToAddress.String
-	}
-	if finalOut.Valid {
-		o.FinalOut = &finalOut.String
-	}
-	if swapRef.Valid {
-		o.SwapRef = &swapRef.String
-	}
-	if exinTrace.Valid {
-		o.ExinSwapTraceID = &exinTrace.String
-	}
-	if withdrawTxID.Valid {
-		o.WithdrawTxID = &withdrawTxID.String
-	}
-	if refundTxID.Valid {
-		o.RefundTxID = &refundTxID.String
-	}
-	if refundAssetID.Valid {
-		o.RefundAssetID = &refundAssetID.String
-	}
-	if refundAmount.Valid {
-		o.RefundAmount = &refundAmount.String
-	}
-	if refundReceivedSnapshotID.Valid {
-		o.RefundReceivedSnapshotID = &refundReceivedSnapshotID.String
-	}
-
-	return &o, nil
+	return r.getOrder(ctx, `WHERE public_id = ?`, publicID)
 }
 
 func (r *OrdersRepo) SetDepositCreditedByMemo(ctx context.Context, memo string, snapshotID string, creditedAt time.Time, amountCredited string, assetID string, opponentID string) (int64, error) {
@@ -148,11 +74,11 @@ WHERE
 `,
 		string(models.StatusDepositCredited),
 		snapshotID,
-		creditedAt.Format(time.RFC3339Nano),
-		creditedAt.Format(time.RFC3339Nano),
+		formatTime(creditedAt),
+		formatTime(creditedAt),
 		amountCredited,
 		opponentID,
-		time.Now().UTC().Format(time.RFC3339Nano),
+		formatTime(time.Now()),
 		string(models.StatusAwaitingDeposit),
 		string(models.StatusDepositDetected),
 		string(models.StatusDepositPendingMixin),
@@ -169,5 +95,7 @@ func nullableTime(t *time.Time) interface{} {
 	if t == nil {
 		return nil
 	}
-	return t.Format(time.RFC3339Nano)
+	return formatTime(*t)
 }
+
+var _ store.OrderStore = (*OrdersRepo)(nil)
@@ -0,0 +1,214 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// orderFilterWhere builds the WHERE clause (empty if f matches everything).
+func orderFilterWhere(f store.OrderFilter) (string, []any) {
+	var where []string
+	var args []any
+	if f.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, string(f.Status))
+	}
+	if f.Asset != "" {
+		where = append(where, "(source_asset = ? OR target_asset = ?)")
+		args = append(args, f.Asset, f.Asset)
+	}
+	if f.TargetAddress != "" {
+		where = append(where, "target_address = ?")
+		args = append(args, f.TargetAddress)
+	}
+	if f.Sender != "" {
+		where = append(where, "refund_to_address = ?")
+		args = append(args, f.Sender)
+	}
+	if f.PartnerID != "" {
+		where = append(where, "partner_id = ?")
+		args = append(args, f.PartnerID)
+	}
+	switch f.Venue {
+	case "":
+	case "exinswap":
+		where = append(where, "exinswap_trace_id IS NOT NULL")
+	default:
+		where = append(where, "1 = 0")
+	}
+	if f.Search != "" {
+		where = append(where, "(id = ? OR public_id = ? OR deposit_txid = ? OR withdraw_txid = ? OR refund_txid = ? OR target_address = ? OR refund_to_address = ?)")
+		for i := 0; i < 7; i++ {
+			args = append(args, f.Search)
+		}
+	}
+	if f.After != nil {
+		at := formatTime(f.After.CreatedAt)
+		where = append(where, "(created_at < ? OR (created_at = ? AND id < ?))")
+		args = append(args, at, at, f.After.ID)
+	}
+	if !f.CreatedFrom.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, formatTime(f.CreatedFrom))
+	}
+	if !f.CreatedTo.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, formatTime(f.CreatedTo))
+	}
+	if !f.UpdatedFrom.IsZero() {
+		where = append(where, "updated_at >= ?")
+		args = append(args, formatTime(f.UpdatedFrom))
+	}
+	if !f.UpdatedBefore.IsZero() {
+		where = append(where, "updated_at < ?")
+		args = append(args, formatTime(f.UpdatedBefore))
+	}
+	if len(where) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(where, " AND ") + "\n", args
+}
+
+// ListOrders returns orders matching f, newest first.
+func (r *OrdersRepo) ListOrders(ctx context.Context, f store.OrderFilter) ([]*models.Order, error) {
+	tail, args := orderFilterWhere(f)
+	limit := f.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	tail += "ORDER BY created_at DESC, id DESC\nLIMIT ? OFFSET ?\n"
+	args = append(args, limit, f.Offset)
+	out, err := r.listOrders(ctx, tail, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list orders: %w", err)
+	}
+	return out, nil
+}
+
+func (r *OrdersRepo) CountOrders(ctx context.Context, f store.OrderFilter) (int64, error) {
+	where, args := orderFilterWhere(f)
+	var n int64
+	if err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM orders `+where, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count orders: %w", err)
+	}
+	return n, nil
+}
+
+// ForceRetry makes an order in status due immediately by clearing its backoff.
+// The attempt counter is kept so the retry budget still applies.
+func (r *OrdersRepo) ForceRetry(ctx context.Context, orderID string, status models.OrderStatus) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `
+UPDATE orders
+SET next_attempt_at = NULL, updated_at = ?
+WHERE id = ? AND status = ?
+`, formatTime(time.Now()), orderID, string(status))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// ReleaseFromReview moves an order out of failed_manual_review or
+// compliance_hold (from) back to the status stage runs from, with a fresh
+// attempt budget. The release counts as operator approval, so the
+// post-deposit limit check (and, for compliance_hold, screening) is not
+// repeated.
+func (r *OrdersRepo) ReleaseFromReview(ctx context.Context, orderID string, from models.OrderStatus, stage models.Stage) (bool, error) {
+	to, ok := stage.Status()
+	if !ok {
+		return false, fmt.Errorf("unknown stage %q", stage)
+	}
+	if from != models.StatusFailedManual && from != models.StatusComplianceHold && from != models.StatusAwaitingLiquidity {
+		return false, fmt.Errorf("cannot release from %s", from)
+	}
+	now := formatTime(time.Now())
+	screeningCleared := any(nil)
+	if from == models.StatusComplianceHold {
+		screeningCleared = now
+	}
+	res, err := r.DB.ExecContext(ctx, `
+UPDATE orders
+SET status = ?, retry_stage = ?, attempts = 0, next_attempt_at = NULL,
+    limits_cleared_at = COALESCE(limits_cleared_at, ?),
+    screening_cleared_at = COALESCE(screening_cleared_at, ?),
+    updated_at = ?
+WHERE id = ? AND status = ?
+`,
+		string(to),
+		string(stage),
+		now,
+		screeningCleared,
+		now,
+		orderID,
+		string(from),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// MarkComplianceHold freezes an order whose address was flagged by screening.
+// Only an operator can move it on (release or manual refund).
+func (r *OrdersRepo) MarkComplianceHold(ctx context.Context, orderID string, from models.OrderStatus, stage models.Stage, reason string) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `
+UPDATE orders
+SET status = ?, retry_stage = ?, last_error = ?, next_attempt_at = NULL, updated_at = ?
+WHERE id = ? AND status = ?
+`,
+		string(models.StatusComplianceHold),
+		string(stage),
+		"screening: "+reason,
+		formatTime(time.Now()),
+		orderID,
+		string(from),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// MarkManualRefund moves an order from status `from` to refunding on operator
+// request. Refund details already recorded (e.g. from an ExinSwap refund) win
+// over the ones passed in; empty values leave the column unset. Refunding out
+// of compliance_hold is an operator decision, so screening is not repeated.
+func (r *OrdersRepo) MarkManualRefund(ctx context.Context, orderID string, from models.OrderStatus, refundAssetID, refundAmount string) (bool, error) {
+	now := formatTime(time.Now())
+	screeningCleared := any(nil)
+	if from == models.StatusComplianceHold {
+		screeningCleared = now
+	}
+	res, err := r.DB.ExecContext(ctx, `
+UPDATE orders
+SET status = ?,
+    refund_asset_id = COALESCE(refund_asset_id, ?),
+    refund_amount = COALESCE(refund_amount, ?),
+    retry_stage = ?, attempts = 0, next_attempt_at = NULL,
+    screening_cleared_at = COALESCE(screening_cleared_at, ?),
+    updated_at = ?
+WHERE id = ? AND status = ?
+`,
+		string(models.StatusRefunding),
+		nullStr(refundAssetID),
+		nullStr(refundAmount),
+		string(models.StageRefund),
+		screeningCleared,
+		now,
+		orderID,
+		string(from),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
@@ -2,105 +2,63 @@ package db
 
 import (
 	"context"
-	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/mvg-fi-dev/bridge/internal/models"
 )
 
 func (r *OrdersRepo) GetByID(ctx context.Context, id string) (*models.Order, error) {
-	row := r.DB.QueryRowContext(ctx, `
-SELECT
-  id, public_id, status, created_at, updated_at,
-  source_chain, source_asset, amount_in, target_chain, target_asset, target_address,
-  estimated_out, min_out, quote_expiry_at,
-  pay_window_seconds,
-  mixin_opponent_id, mixin_asset_id, mixin_pay_memo, mixin_pay_url,
-  deposit_txid, deposit_tx_detected_at, deposit_credited_at, amount_credited, refund_to_address,
-  final_out, swap_ref, exinswap_trace_id, withdraw_txid, refund_txid,
-  refund_asset_id, refund_amount, refund_received_snapshot_id
-FROM orders
-WHERE id = ?
-LIMIT 1
-`, id)
+	return r.getOrder(ctx, `WHERE id = ?`, id)
+}
 
-	var o models.Order
-	var status string
-	var createdAt, updatedAt string
-	var quoteExpiry sql.NullString
-	var depositTxID, depositDetectedAt, depositCreditedAt sql.NullString
-	var amountCredited, refundToAddress sql.NullString
-	var finalOut, swapRef, exinTrace, withdrawTxID, refundTxID sql.NullString
-	var refundAssetID, refundAmount, refundReceivedSnapshotID sql.NullString
+// GetByExinSwapTraceID maps an ExinSwap server memo TRACE back to our order.
+func (r *OrdersRepo) GetByExinSwapTraceID(ctx context.Context, traceID string) (*models.Order, error) {
+	return r.getOrder(ctx, `WHERE exinswap_trace_id = ?`, traceID)
+}
 
-	if err := row.Scan(
-		&o.ID, &o.PublicID, &status, &createdAt, &updatedAt,
-		&o.SourceChain, &o.SourceAsset, &o.AmountIn, &o.TargetChain, &o.TargetAsset, &o.TargetAddress,
-		&o.EstimatedOut, &o.MinOut, &quoteExpiry,
-		&o.PayWindowSeconds,
-		&o.MixinOpponentID, &o.MixinAssetID, &o.MixinPayMemo, &o.MixinPayURL,
-		&depositTxID, &depositDetectedAt, &depositCreditedAt, &amountCredited, &refundToAddress,
-		&finalOut, &swapRef, &exinTrace, &withdrawTxID, &refundTxID,
-		&refundAssetID, &refundAmount, &refundReceivedSnapshotID,
-	); err != nil {
-		return nil, err
-	}
+// GetByDepositTxID returns the order credited by the given deposit snapshot.
+func (r *OrdersRepo) GetByDepositTxID(ctx context.Context, txID string) (*models.Order, error) {
+	return r.getOrder(ctx, `WHERE deposit_txid = ?`, txID)
+}
 
-	o.Status = models.OrderStatus(status)
-	if t, err := time.Parse(time.RFC3339Nano, createdAt); err == nil {
-		o.CreatedAt = t
-	}
-	if t, err := time.Parse(time.RFC3339Nano, updatedAt); err == nil {
-		o.UpdatedAt = t
-	}
-	if quoteExpiry.Valid {
-		if t, err := time.Parse(time.RFC3339Nano, quoteExpiry.String); err == nil {
-			o.QuoteExpiryAt = &t
-		}
-	}
-	if depositTxID.Valid {
-		o.DepositTxID = &depositTxID.String
-	}
-	if depositDetectedAt.Valid {
-		if t, err := time.Parse(time.RFC3339Nano, depositDetectedAt.String); err == nil {
-			o.DepositTxDetectedAt = &t
-		}
-	}
-	if depositCreditedAt.Valid {
-		if t, err := time.Parse(time.RFC3339Nano, depositCreditedAt.String); err == nil {
-			o.DepositCreditedAt = &t
-		}
-	}
-	if amountCredited.Valid {
-		o.AmountCredited = &amountCredited.String
-	}
-	if refundToAddress.Valid {
-		o.RefundToAddress = &refundToAddress.String
-	}
-	if finalOut.Valid {
-		o.FinalOut = &finalOut.String
-	}
-	if swapRef.Valid {
-		o.SwapRef = &swapRef.String
-	}
-	if exinTrace.Valid {
-		o.ExinSwapTraceID = &exinTrace.String
-	}
-	if withdrawTxID.Valid {
-		o.WithdrawTxID = &withdrawTxID.String
-	}
-	if refundTxID.Valid {
-		o.RefundTxID = &refundTxID.String
-	}
-	if refundAssetID.Valid {
-		o.RefundAssetID = &refundAssetID.String
-	}
-	if refundAmount.Valid {
-		o.RefundAmount = &refundAmount.String
-	}
-	if refundReceivedSnapshotID.Valid {
-		o.RefundReceivedSnapshotID = &refundReceivedSnapshotID.String
-	}
+// GetByTransferTraceID returns the order that sent a transfer (swap, withdraw
+// or refund) under traceID.
+func (r *OrdersRepo) GetByTransferTraceID(ctx context.Context, traceID string) (*models.Order, error) {
+	return r.getOrder(ctx, `WHERE exinswap_trace_id = ? OR withdraw_trace_id = ? OR refund_trace_id = ?`, traceID, traceID, traceID)
+}
+
+// SetExinSwapTraceID stores the trace id of the transfer to ExinSwap; the
+// reconciler relies on it to match result memos.
+func (r *OrdersRepo) SetExinSwapTraceID(ctx context.Context, orderID string, traceID string) error {
+	_, err := r.DB.ExecContext(ctx, `
+UPDATE orders SET exinswap_trace_id = ?, updated_at = ? WHERE id = ?
+`, traceID, formatTime(time.Now()), orderID)
+	if err != nil {
+		return fmt.Errorf("set exinswap_trace_id: %w", err)
+	}
+	return nil
+}
+
+// SetWithdrawTraceID persists the withdrawal trace id before the Mixin call.
+// The first value wins so retries keep submitting under the same trace.
+func (r *OrdersRepo) SetWithdrawTraceID(ctx context.Context, orderID string, traceID string) error {
+	_, err := r.DB.ExecContext(ctx, `
+UPDATE orders SET withdraw_trace_id = COALESCE(withdraw_trace_id, ?), updated_at = ? WHERE id = ?
+`, traceID, formatTime(time.Now()), orderID)
+	if err != nil {
+		return fmt.Errorf("set withdraw_trace_id: %w", err)
+	}
+	return nil
+}
 
-	return &o, nil
+// SetRefundTraceID persists the refund transfer trace id before the Mixin call.
+func (r *OrdersRepo) SetRefundTraceID(ctx context.Context, orderID string, traceID string) error {
+	_, err := r.DB.ExecContext(ctx, `
+UPDATE orders SET refund_trace_id = COALESCE(refund_trace_id, ?), updated_at = ? WHERE id = ?
+`, traceID, formatTime(time.Now()), orderID)
+	if err != nil {
+		return fmt.Errorf("set refund_trace_id: %w", err)
+	}
+	return nil
 }
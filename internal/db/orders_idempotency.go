@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// InsertIdempotencyKey records the order created for a client key. Call it in
+// the same transaction as Insert so a lost race rolls the order back.
+func (r *OrdersRepo) InsertIdempotencyKey(ctx context.Context, k *models.IdempotencyKey) error {
+	res, err := r.DB.ExecContext(ctx, `
+INSERT INTO idempotency_keys(scope, idem_key, request_hash, order_id, created_at)
+VALUES(?,?,?,?,?)
+ON CONFLICT(scope, idem_key) DO NOTHING
+`, k.Scope, k.Key, k.RequestHash, k.OrderID, formatTime(k.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("insert idempotency key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return store.ErrConflict
+	}
+	return nil
+}
+
+func (r *OrdersRepo) GetIdempotencyKey(ctx context.Context, scope, key string) (*models.IdempotencyKey, error) {
+	var k models.IdempotencyKey
+	var createdAt string
+	err := r.DB.QueryRowContext(ctx, `
+SELECT scope, idem_key, request_hash, order_id, created_at FROM idempotency_keys WHERE scope = ? AND idem_key = ?
+`, scope, key).Scan(&k.Scope, &k.Key, &k.RequestHash, &k.OrderID, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get idempotency key: %w", err)
+	}
+	if k.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
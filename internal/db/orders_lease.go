@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+// TryLease claims an order for workerID until now+ttl.
+// It succeeds only if the order is still in status, is not due for a later
+// retry, and is unleased, expired, or already held by the same worker.
+// Returns false if another worker holds it (lost race).
+func (r *OrdersRepo) TryLease(ctx context.Context, orderID string, status models.OrderStatus, workerID string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	res, err := r.DB.ExecContext(ctx, `
+UPDATE orders
+SET locked_by = ?, lease_until = ?
+WHERE id = ? AND status = ?
+  AND (next_attempt_at IS NULL OR next_attempt_at <= ?)
+  AND (lease_until IS NULL OR lease_until <= ? OR locked_by = ?)
+`,
+		workerID,
+		formatTime(now.Add(ttl)),
+		orderID,
+		string(status),
+		formatTime(now),
+		formatTime(now),
+		workerID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// ReleaseLease drops workerID's lease on the order (no-op if it expired and
+// another worker took over).
+func (r *OrdersRepo) ReleaseLease(ctx context.Context, orderID string, workerID string) error {
+	_, err := r.DB.ExecContext(ctx, `
+UPDATE orders
+SET locked_by = NULL, lease_until = NULL
+WHERE id = ? AND locked_by = ?
+`, orderID, workerID)
+	return err
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+// MarkAwaitingLiquidity parks an order whose payout the wallet cannot cover.
+// The stage is kept in retry_stage; attempts are not consumed.
+func (r *OrdersRepo) MarkAwaitingLiquidity(ctx context.Context, orderID string, from models.OrderStatus, stage models.Stage, reason string) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `
+UPDATE orders
+SET status = ?, retry_stage = ?, last_error = ?, next_attempt_at = NULL, updated_at = ?
+WHERE id = ? AND status = ?
+`,
+		string(models.StatusAwaitingLiquidity),
+		string(stage),
+		"liquidity: "+reason,
+		formatTime(time.Now()),
+		orderID,
+		string(from),
+	)
+	if err != nil {
+		return false, fmt.Errorf("mark awaiting_liquidity: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// ListAwaitingLiquidity returns parked orders, longest waiting first.
+func (r *OrdersRepo) ListAwaitingLiquidity(ctx context.Context, limit int) ([]*models.Order, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	return r.listOrders(ctx, `
+WHERE status = ?
+ORDER BY updated_at ASC
+LIMIT ?
+`, string(models.StatusAwaitingLiquidity), limit)
+}
+
+// ResumeFromLiquidity moves a parked order back to its stage's status.
+func (r *OrdersRepo) ResumeFromLiquidity(ctx context.Context, orderID string, stage models.Stage) (bool, error) {
+	to, ok := stage.Status()
+	if !ok {
+		return false, fmt.Errorf("unknown stage %q", stage)
+	}
+	res, err := r.DB.ExecContext(ctx, `
+UPDATE orders SET status = ?, last_error = NULL, updated_at = ?
+WHERE id = ? AND status = ?
+`, string(to), formatTime(time.Now()), orderID, string(models.StatusAwaitingLiquidity))
+	if err != nil {
+		return false, fmt.Errorf("resume from awaiting_liquidity: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+// ScheduleRetry records a failed attempt for the given stage and defers the
+// next pick-up until nextAttemptAt. The update only applies while the order is
+// still in the status the attempt was made from.
+func (r *OrdersRepo) ScheduleRetry(ctx context.Context, orderID string, status models.OrderStatus, stage models.Stage, attempts int64, lastError string, nextAttemptAt time.Time) error {
+	_, err := r.DB.ExecContext(ctx, `
+UPDATE orders
+SET retry_stage = ?, attempts = ?, last_error = ?, next_attempt_at = ?, updated_at = ?
+WHERE id = ? AND status = ?
+`,
+		string(stage),
+		attempts,
+		lastError,
+		formatTime(nextAttemptAt),
+		formatTime(time.Now()),
+		orderID,
+		string(status),
+	)
+	return err
+}
+
+func (r *OrdersRepo) ClearRetry(ctx context.Context, orderID string, status models.OrderStatus) error {
+	_, err := r.DB.ExecContext(ctx, `
+UPDATE orders
+SET retry_stage = NULL, attempts = 0, next_attempt_at = NULL, updated_at = ?
+WHERE id = ? AND status = ?
+`, formatTime(time.Now()), orderID, string(status))
+	return err
+}
+
+// MarkFailedManual parks an order in failed_manual_review once its attempt
+// budget for a stage is exhausted (or the error is not retryable).
+func (r *OrdersRepo) MarkFailedManual(ctx context.Context, orderID string, status models.OrderStatus, stage models.Stage, attempts int64, lastError string) error {
+	_, err := r.DB.ExecContext(ctx, `
+UPDATE orders
+SET status = ?, retry_stage = ?, attempts = ?, last_error = ?, next_attempt_at = NULL, updated_at = ?
+WHERE id = ? AND status = ?
+`,
+		string(models.StatusFailedManual),
+		string(stage),
+		attempts,
+		lastError,
+		formatTime(time.Now()),
+		orderID,
+		string(status),
+	)
+	return err
+}
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// orderColumns is the canonical orders SELECT list; scanOrder reads it in this order.
+const orderColumns = `
+  id, public_id, status, created_at, updated_at,
+  source_chain, source_asset, amount_in, target_chain, target_asset, target_address,
+  estimated_out, min_out, quote_expiry_at,
+  pay_window_seconds,
+  mixin_opponent_id, mixin_asset_id, mixin_pay_memo, mixin_pay_url,
+  deposit_txid, deposit_tx_detected_at, deposit_credited_at, amount_credited, refund_to_address,
+  final_out, swap_ref, exinswap_trace_id, withdraw_txid, refund_txid,
+  refund_asset_id, refund_amount, refund_received_snapshot_id,
+  retry_stage, attempts, last_error, next_attempt_at,
+  locked_by, lease_until,
+  withdraw_trace_id, refund_trace_id,
+  limits_cleared_at, screening_cleared_at,
+  partner_id,
+  fee_bps, fee_flat, fee_share_bps, fee_amount, partner_fee_amount,
+  trace_context`
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOrder(rs rowScanner) (*models.Order, error) {
+	var o models.Order
+	var status string
+	var createdAt, updatedAt string
+	var quoteExpiry sql.NullString
+	var depositTxID, depositDetectedAt, depositCreditedAt sql.NullString
+	var amountCredited, refundToAddress sql.NullString
+	var finalOut, swapRef, exinTrace, withdrawTxID, refundTxID sql.NullString
+	var refundAssetID, refundAmount, refundReceivedSnapshotID sql.NullString
+	var retryStage, lastError, nextAttemptAt sql.NullString
+	var lockedBy, leaseUntil sql.NullString
+	var withdrawTraceID, refundTraceID sql.NullString
+	var limitsClearedAt, screeningClearedAt sql.NullString
+	var partnerID sql.NullString
+	var feeBps, feeShareBps sql.NullInt64
+	var feeFlat, feeAmount, partnerFeeAmount sql.NullString
+	var traceContext sql.NullString
+
+	if err := rs.Scan(
+		&o.ID, &o.PublicID, &status, &createdAt, &updatedAt,
+		&o.SourceChain, &o.SourceAsset, &o.AmountIn, &o.TargetChain, &o.TargetAsset, &o.TargetAddress,
+		&o.EstimatedOut, &o.MinOut, &quoteExpiry,
+		&o.PayWindowSeconds,
+		&o.MixinOpponentID, &o.MixinAssetID, &o.MixinPayMemo, &o.MixinPayURL,
+		&depositTxID, &depositDetectedAt, &depositCreditedAt, &amountCredited, &refundToAddress,
+		&finalOut, &swapRef, &exinTrace, &withdrawTxID, &refundTxID,
+		&refundAssetID, &refundAmount, &refundReceivedSnapshotID,
+		&retryStage, &o.Attempts, &lastError, &nextAttemptAt,
+		&lockedBy, &leaseUntil,
+		&withdrawTraceID, &refundTraceID,
+		&limitsClearedAt, &screeningClearedAt,
+		&partnerID,
+		&feeBps, &feeFlat, &feeShareBps, &feeAmount, &partnerFeeAmount,
+		&traceContext,
+	); err != nil {
+		return nil, err
+	}
+
+	o.Status = models.OrderStatus(status)
+	if t, err := parseTime(createdAt); err == nil {
+		o.CreatedAt = t
+	}
+	if t, err := parseTime(updatedAt); err == nil {
+		o.UpdatedAt = t
+	}
+	o.QuoteExpiryAt = nullTimeValue(quoteExpiry)
+
+	o.DepositTxID = nullStringValue(depositTxID)
+	o.DepositTxDetectedAt = nullTimeValue(depositDetectedAt)
+	o.DepositCreditedAt = nullTimeValue(depositCreditedAt)
+	o.AmountCredited = nullStringValue(amountCredited)
+	o.RefundToAddress = nullStringValue(refundToAddress)
+
+	o.FinalOut = nullStringValue(finalOut)
+	o.SwapRef = nullStringValue(swapRef)
+	o.ExinSwapTraceID = nullStringValue(exinTrace)
+	o.WithdrawTxID = nullStringValue(withdrawTxID)
+	o.RefundTxID = nullStringValue(refundTxID)
+	o.RefundAssetID = nullStringValue(refundAssetID)
+	o.RefundAmount = nullStringValue(refundAmount)
+	o.RefundReceivedSnapshotID = nullStringValue(refundReceivedSnapshotID)
+
+	o.RetryStage = nullStringValue(retryStage)
+	o.LastError = nullStringValue(lastError)
+	o.NextAttemptAt = nullTimeValue(nextAttemptAt)
+
+	o.LockedBy = nullStringValue(lockedBy)
+	o.LeaseUntil = nullTimeValue(leaseUntil)
+
+	o.WithdrawTraceID = nullStringValue(withdrawTraceID)
+	o.RefundTraceID = nullStringValue(refundTraceID)
+
+	o.LimitsClearedAt = nullTimeValue(limitsClearedAt)
+	o.ScreeningClearedAt = nullTimeValue(screeningClearedAt)
+
+	o.PartnerID = nullStringValue(partnerID)
+
+	o.FeeBps = nullInt64Value(feeBps)
+	o.FeeFlat = nullStringValue(feeFlat)
+	o.FeeShareBps = nullInt64Value(feeShareBps)
+	o.FeeAmount = nullStringValue(feeAmount)
+	o.PartnerFeeAmount = nullStringValue(partnerFeeAmount)
+
+	o.TraceContext = nullStringValue(traceContext)
+
+	return &o, nil
+}
+
+// getOrder runs a single-row orders query; where is appended after FROM orders.
+func (r *OrdersRepo) getOrder(ctx context.Context, where string, args ...any) (*models.Order, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT`+orderColumns+`
+FROM orders
+`+where+`
+LIMIT 1`, args...)
+	o, err := scanOrder(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	return o, err
+}
+
+// listOrders runs a multi-row orders query; tail (WHERE/ORDER/LIMIT) is appended after FROM orders.
+func (r *OrdersRepo) listOrders(ctx context.Context, tail string, args ...any) ([]*models.Order, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT`+orderColumns+`
+FROM orders
+`+tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*models.Order
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+func nullInt64Value(n sql.NullInt64) *int64 {
+	if !n.Valid {
+		return nil
+	}
+	v := n.Int64
+	return &v
+}
+
+func nullStringValue(ns sql.NullString) *string {
+	if !ns.Valid {
+		return nil
+	}
+	v := ns.String
+	return &v
+}
+
+func nullTimeValue(ns sql.NullString) *time.Time {
+	if !ns.Valid {
+		return nil
+	}
+	t, err := parseTime(ns.String)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
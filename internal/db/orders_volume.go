@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// ListVolume returns the per-order amounts that count toward a velocity cap.
+// Amounts are summed by the caller (with prices) since they span assets.
+func (r *OrdersRepo) ListVolume(ctx context.Context, f store.VolumeFilter) ([]store.AssetAmount, error) {
+	q := `
+SELECT source_asset, COALESCE(amount_credited, amount_in) FROM orders
+WHERE created_at >= ?
+  AND status NOT IN (?, ?)
+  AND NOT (status IN (?, ?) AND created_at < ?)
+`
+	args := []any{
+		formatTime(f.Since),
+		string(models.StatusRefunding), string(models.StatusRefunded),
+		string(models.StatusQuoteCreated), string(models.StatusAwaitingDeposit), formatTime(f.UnpaidSince),
+	}
+	if f.TargetAddress != "" {
+		q += "  AND target_address = ?\n"
+		args = append(args, f.TargetAddress)
+	}
+	if f.RefundTo != "" {
+		q += "  AND refund_to_address = ?\n"
+		args = append(args, f.RefundTo)
+	}
+	if f.ExcludeOrderID != "" {
+		q += "  AND id <> ?\n"
+		args = append(args, f.ExcludeOrderID)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list volume: %w", err)
+	}
+	defer rows.Close()
+	var out []store.AssetAmount
+	for rows.Next() {
+		var a store.AssetAmount
+		if err := rows.Scan(&a.AssetID, &a.Amount); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// ListOrderAmounts returns the amounts of every order matching f for volume
+// reports; f.Limit and f.Offset are ignored.
+func (r *OrdersRepo) ListOrderAmounts(ctx context.Context, f store.OrderFilter) ([]store.OrderAmount, error) {
+	where, args := orderFilterWhere(f)
+	rows, err := r.DB.QueryContext(ctx, `
+SELECT created_at, status, source_asset, amount_in, amount_credited, fee_amount, partner_fee_amount FROM orders
+`+where+`ORDER BY created_at`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list order amounts: %w", err)
+	}
+	defer rows.Close()
+	var out []store.OrderAmount
+	for rows.Next() {
+		var a store.OrderAmount
+		var createdAt, status string
+		var credited, fee, partnerFee sql.NullString
+		if err := rows.Scan(&createdAt, &status, &a.AssetID, &a.AmountIn, &credited, &fee, &partnerFee); err != nil {
+			return nil, err
+		}
+		if a.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, err
+		}
+		a.Status = models.OrderStatus(status)
+		a.AmountCredited = nullStringValue(credited)
+		a.FeeAmount = nullStringValue(fee)
+		a.PartnerFeeAmount = nullStringValue(partnerFee)
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (r *OrdersRepo) MarkLimitsCleared(ctx context.Context, orderID string) error {
+	_, err := r.DB.ExecContext(ctx, `
+UPDATE orders SET limits_cleared_at = COALESCE(limits_cleared_at, ?), updated_at = ? WHERE id = ?
+`, formatTime(time.Now()), formatTime(time.Now()), orderID)
+	return err
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// PartnerRepo stores integrator API keys.
+type PartnerRepo struct{ DB *DB }
+
+func NewPartnerRepo(db *DB) *PartnerRepo { return &PartnerRepo{DB: db} }
+
+var _ store.PartnerStore = (*PartnerRepo)(nil)
+
+const partnerColumns = `id, name, api_key, secret, fee_share_bps, daily_order_quota, rate_limit_per_min, disabled, created_at, updated_at`
+
+func scanPartner(rs rowScanner) (*models.Partner, error) {
+	var p models.Partner
+	var createdAt, updatedAt string
+	if err := rs.Scan(&p.ID, &p.Name, &p.APIKey, &p.Secret, &p.FeeShareBps, &p.DailyOrderQuota, &p.RateLimitPerMin, &p.Disabled, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if p.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if p.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *PartnerRepo) ListPartners(ctx context.Context) ([]*models.Partner, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+partnerColumns+` FROM partners ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("list partners: %w", err)
+	}
+	defer rows.Close()
+	var out []*models.Partner
+	for rows.Next() {
+		p, err := scanPartner(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (r *PartnerRepo) GetPartner(ctx context.Context, id string) (*models.Partner, error) {
+	return r.getPartner(ctx, `WHERE id = ?`, id)
+}
+
+func (r *PartnerRepo) GetPartnerByKey(ctx context.Context, apiKey string) (*models.Partner, error) {
+	return r.getPartner(ctx, `WHERE api_key = ?`, apiKey)
+}
+
+func (r *PartnerRepo) getPartner(ctx context.Context, where string, args ...any) (*models.Partner, error) {
+	p, err := scanPartner(r.DB.QueryRowContext(ctx, `SELECT `+partnerColumns+` FROM partners `+where, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get partner: %w", err)
+	}
+	return p, nil
+}
+
+func (r *PartnerRepo) InsertPartner(ctx context.Context, p *models.Partner) error {
+	_, err := r.DB.ExecContext(ctx, `
+INSERT INTO partners(`+partnerColumns+`)
+VALUES(?,?,?,?,?,?,?,?,?,?)
+`, p.ID, p.Name, p.APIKey, p.Secret, p.FeeShareBps, p.DailyOrderQuota, p.RateLimitPerMin, p.Disabled, formatTime(p.CreatedAt), formatTime(p.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("insert partner: %w", err)
+	}
+	return nil
+}
+
+func (r *PartnerRepo) UpdatePartner(ctx context.Context, p *models.Partner) error {
+	res, err := r.DB.ExecContext(ctx, `
+UPDATE partners
+SET name = ?, secret = ?, fee_share_bps = ?, daily_order_quota = ?, rate_limit_per_min = ?, disabled = ?, updated_at = ?
+WHERE id = ?
+`, p.Name, p.Secret, p.FeeShareBps, p.DailyOrderQuota, p.RateLimitPerMin, p.Disabled, formatTime(p.UpdatedAt), p.ID)
+	if err != nil {
+		return fmt.Errorf("update partner: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
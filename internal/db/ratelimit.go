@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/ratelimit"
+)
+
+// RateLimitRepo is a ratelimit.Store shared by all API replicas through the
+// rate_limit_buckets table. Updates are compare-and-swap on (tokens,
+// updated_ns), so it needs no row locks and works the same on SQLite and
+// Postgres. The pair never repeats: a take at the same instant lowers tokens,
+// anything else moves updated_ns forward.
+type RateLimitRepo struct {
+	DB *DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewRateLimitRepo(db *DB) *RateLimitRepo { return &RateLimitRepo{DB: db} }
+
+var _ ratelimit.Store = (*RateLimitRepo)(nil)
+
+// casAttempts bounds the retries when another replica updates the same bucket.
+const casAttempts = 5
+
+func (r *RateLimitRepo) Take(ctx context.Context, key string, rule ratelimit.Rule, now time.Time) (bool, time.Duration, error) {
+	r.sweep(ctx, now)
+	for i := 0; i < casAttempts; i++ {
+		var tokens float64
+		var updatedNs int64
+		err := r.DB.QueryRowContext(ctx, `SELECT tokens, updated_ns FROM rate_limit_buckets WHERE bucket_key = ?`, key).Scan(&tokens, &updatedNs)
+		if errors.Is(err, sql.ErrNoRows) {
+			res, err := r.DB.ExecContext(ctx, `
+INSERT INTO rate_limit_buckets(bucket_key, tokens, updated_ns) VALUES(?,?,?)
+ON CONFLICT(bucket_key) DO NOTHING
+`, key, float64(rule.Burst-1), now.UnixNano())
+			if err != nil {
+				return false, 0, fmt.Errorf("rate limit insert: %w", err)
+			}
+			if n, _ := res.RowsAffected(); n == 1 {
+				return true, 0, nil
+			}
+			continue
+		}
+		if err != nil {
+			return false, 0, fmt.Errorf("rate limit get: %w", err)
+		}
+
+		last := time.Unix(0, updatedNs)
+		next, allowed, wait := ratelimit.Refill(tokens, last, now, rule)
+		if !allowed {
+			return false, wait, nil
+		}
+		// Replicas' clocks differ slightly; never move updated_ns backwards.
+		ns := max(now.UnixNano(), updatedNs)
+		res, err := r.DB.ExecContext(ctx, `
+UPDATE rate_limit_buckets SET tokens = ?, updated_ns = ? WHERE bucket_key = ? AND tokens = ? AND updated_ns = ?
+`, next, ns, key, tokens, updatedNs)
+		if err != nil {
+			return false, 0, fmt.Errorf("rate limit update: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			return true, 0, nil
+		}
+	}
+	return false, 0, fmt.Errorf("rate limit %s: too much contention", key)
+}
+
+// sweep deletes idle buckets at most once per hour per process.
+func (r *RateLimitRepo) sweep(ctx context.Context, now time.Time) {
+	r.mu.Lock()
+	if now.Sub(r.lastSweep) < time.Hour {
+		r.mu.Unlock()
+		return
+	}
+	r.lastSweep = now
+	r.mu.Unlock()
+	_, _ = r.DB.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE updated_ns < ?`, now.Add(-time.Hour).UnixNano())
+}
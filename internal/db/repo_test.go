@@ -0,0 +1,568 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/ids"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/ratelimit"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// forEachDialect runs fn against a freshly migrated SQLite database and, when
+// TEST_DATABASE_URL is set, against Postgres in a throwaway schema. CI sets
+// TEST_REQUIRE_POSTGRES so a missing database fails instead of skipping.
+func forEachDialect(t *testing.T, fn func(t *testing.T, d *DB)) {
+	t.Run("sqlite3", func(t *testing.T) {
+		d, err := Open("sqlite3", filepath.Join(t.TempDir(), "bridge.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { d.SQL.Close() })
+		if err := Migrate(d); err != nil {
+			t.Fatal(err)
+		}
+		fn(t, d)
+	})
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv("TEST_DATABASE_URL")
+		if dsn == "" {
+			if os.Getenv("TEST_REQUIRE_POSTGRES") != "" {
+				t.Fatal("TEST_REQUIRE_POSTGRES is set but TEST_DATABASE_URL is not")
+			}
+			t.Skip("TEST_DATABASE_URL not set")
+		}
+		fn(t, openPostgresSchema(t, dsn))
+	})
+}
+
+// openPostgresSchema migrates a new schema and drops it when the test ends.
+func openPostgresSchema(t *testing.T, dsn string) *DB {
+	admin, err := Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.SQL.Close() })
+	schema := "test_" + strings.ReplaceAll(ids.NewUUID(), "-", "")
+	if _, err := admin.SQL.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.SQL.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+
+	if strings.Contains(dsn, "://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + "search_path=" + schema
+	} else {
+		dsn += " search_path=" + schema
+	}
+	d, err := Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.SQL.Close() })
+	if err := Migrate(d); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func newTestOrder(id string, createdAt time.Time) *models.Order {
+	return &models.Order{
+		ID:               id,
+		PublicID:         "pub-" + id,
+		Status:           models.StatusAwaitingDeposit,
+		CreatedAt:        createdAt,
+		UpdatedAt:        createdAt,
+		SourceChain:      "ETH",
+		SourceAsset:      "src-asset",
+		AmountIn:         "1.5",
+		TargetChain:      "BTC",
+		TargetAsset:      "dst-asset",
+		TargetAddress:    "bc1qtarget",
+		EstimatedOut:     "0.01",
+		MinOut:           "0.009",
+		PayWindowSeconds: 900,
+		MixinPayMemo:     "memo-" + id,
+	}
+}
+
+func TestOrderRoundTrip(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, d *DB) {
+		ctx := context.Background()
+		r := NewOrdersRepo(d)
+		at := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+		o := newTestOrder("o1", at)
+		expiry := at.Add(time.Minute)
+		o.QuoteExpiryAt = &expiry
+		if err := r.Insert(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.RecordFee(ctx, o.ID, "0.0015", "0.0005"); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := r.GetByPublicID(ctx, o.PublicID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.CreatedAt.Equal(at) || got.QuoteExpiryAt == nil || !got.QuoteExpiryAt.Equal(expiry) {
+			t.Errorf("times: created %v expiry %v", got.CreatedAt, got.QuoteExpiryAt)
+		}
+		for _, c := range []struct {
+			name, got, want string
+		}{
+			{"amount_in", got.AmountIn, "1.5"},
+			{"fee_amount", deref(got.FeeAmount), "0.0015"},
+			{"partner_fee_amount", deref(got.PartnerFeeAmount), "0.0005"},
+		} {
+			if !decimal.RequireFromString(c.got).Equal(decimal.RequireFromString(c.want)) {
+				t.Errorf("%s = %q, want %s", c.name, c.got, c.want)
+			}
+		}
+		if _, err := r.GetByID(ctx, "missing"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("GetByID(missing) = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func deref(p *string) string {
+	if p == nil {
+		return "<nil>"
+	}
+	return *p
+}
+
+// Cursor pages must follow time order even where timestamps fall on a whole
+// second, which RFC3339Nano would have written shorter.
+func TestListOrdersCursor(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, d *DB) {
+		ctx := context.Background()
+		r := NewOrdersRepo(d)
+		base := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+		offsets := []time.Duration{0, 500 * time.Millisecond, time.Second, time.Second, 1500 * time.Millisecond}
+		for i, off := range offsets {
+			if err := r.Insert(ctx, newTestOrder(fmt.Sprintf("o%d", i), base.Add(off))); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var seen []string
+		f := store.OrderFilter{Limit: 2}
+		for {
+			page, err := r.ListOrders(ctx, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, o := range page {
+				seen = append(seen, o.ID)
+			}
+			if len(page) < f.Limit {
+				break
+			}
+			last := page[len(page)-1]
+			f.After = &store.OrderCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+		if want := "o4 o3 o2 o1 o0"; strings.Join(seen, " ") != want {
+			t.Errorf("pages = %v, want %s", seen, want)
+		}
+
+		n, err := r.CountOrders(ctx, store.OrderFilter{CreatedFrom: base.Add(time.Second)})
+		if err != nil || n != 3 {
+			t.Errorf("CountOrders(from) = %d, %v; want 3", n, err)
+		}
+	})
+}
+
+// Admin search and the address filters must stay index lookups; a full
+// scan of orders would grow with the table. (SQLite only: Postgres plans a
+// sequential scan for an empty table regardless.)
+func TestOrderSearchUsesIndexes(t *testing.T) {
+	d, err := Open("sqlite3", filepath.Join(t.TempDir(), "bridge.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.SQL.Close()
+	if err := Migrate(d); err != nil {
+		t.Fatal(err)
+	}
+	for name, f := range map[string]store.OrderFilter{
+		"search": {Search: "x"},
+		"target": {TargetAddress: "x"},
+		"sender": {Sender: "x"},
+	} {
+		where, args := orderFilterWhere(f)
+		rows, err := d.QueryContext(context.Background(), `EXPLAIN QUERY PLAN SELECT id FROM orders `+where, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+			var id, parent, unused int
+			var detail string
+			if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+				t.Fatal(err)
+			}
+			if strings.HasPrefix(detail, "SCAN orders") {
+				t.Errorf("%s: %s", name, detail)
+			}
+		}
+		if err := rows.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLeaseAndRetrySchedule(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, d *DB) {
+		ctx := context.Background()
+		r := NewOrdersRepo(d)
+		o := newTestOrder("o1", time.Now().UTC())
+		o.Status = models.StatusDepositCredited
+		if err := r.Insert(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+
+		if ok, err := r.TryLease(ctx, o.ID, o.Status, "a", time.Minute); err != nil || !ok {
+			t.Fatalf("lease a = %v, %v", ok, err)
+		}
+		if ok, _ := r.TryLease(ctx, o.ID, o.Status, "b", time.Minute); ok {
+			t.Fatal("b leased an order held by a")
+		}
+		if list, _ := r.ListExecutable(ctx, 10); len(list) != 0 {
+			t.Fatal("leased order listed as executable")
+		}
+		if err := r.ReleaseLease(ctx, o.ID, "a"); err != nil {
+			t.Fatal(err)
+		}
+
+		err := r.ScheduleRetry(ctx, o.ID, o.Status, models.StageSwap, 1, "boom", time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, _ := r.TryLease(ctx, o.ID, o.Status, "b", time.Minute); ok {
+			t.Fatal("leased an order before its next attempt")
+		}
+		if err := r.ScheduleRetry(ctx, o.ID, o.Status, models.StageSwap, 2, "boom", time.Now().Add(-time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := r.TryLease(ctx, o.ID, o.Status, "b", time.Minute); err != nil || !ok {
+			t.Fatalf("lease after due = %v, %v", ok, err)
+		}
+		got, err := r.GetByID(ctx, o.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Attempts != 2 || deref(got.LockedBy) != "b" || deref(got.RetryStage) != string(models.StageSwap) {
+			t.Errorf("attempts %d locked_by %s retry_stage %s", got.Attempts, deref(got.LockedBy), deref(got.RetryStage))
+		}
+	})
+}
+
+func TestSwapRetryQueue(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, d *DB) {
+		ctx := context.Background()
+		r := NewOrdersRepo(d)
+		o := newTestOrder("o1", time.Now().UTC())
+		o.Status = models.StatusExecutingSwap
+		if err := r.Insert(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+		listed := func() int {
+			t.Helper()
+			list, err := r.ListExecutable(ctx, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return len(list)
+		}
+		if n := listed(); n != 0 {
+			t.Fatal("order awaiting ExinSwap listed as executable")
+		}
+
+		if err := r.ScheduleRetry(ctx, o.ID, o.Status, models.StageSwap, 1, "boom", time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if n := listed(); n != 0 {
+			t.Fatal("swap retry listed before its next attempt")
+		}
+		if err := r.ScheduleRetry(ctx, o.ID, o.Status, models.StageSwap, 2, "boom", time.Now().Add(-time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		if n := listed(); n != 1 {
+			t.Fatalf("due swap retry listed %d times, want 1", n)
+		}
+		if err := r.ClearRetry(ctx, o.ID, o.Status); err != nil {
+			t.Fatal(err)
+		}
+		if n := listed(); n != 0 {
+			t.Fatal("cleared swap retry still listed")
+		}
+
+		// Moving on to withdrawing starts the next stage's budget afresh.
+		if err := r.ScheduleRetry(ctx, o.ID, o.Status, models.StageSwap, 3, "boom", time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.MarkWithdrawing(ctx, o.ID, "ref", "0.5"); err != nil {
+			t.Fatal(err)
+		}
+		got, err := r.GetByID(ctx, o.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != models.StatusWithdrawing || got.Attempts != 0 || got.RetryStage != nil || got.NextAttemptAt != nil || deref(got.LastError) != "boom" {
+			t.Errorf("status %s attempts %d retry_stage %s next %v last_error %s", got.Status, got.Attempts, deref(got.RetryStage), got.NextAttemptAt, deref(got.LastError))
+		}
+	})
+}
+
+func TestInsertConflict(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, d *DB) {
+		ctx := context.Background()
+		r := NewOrdersRepo(d)
+		o := newTestOrder("o1", time.Now().UTC())
+		if err := r.Insert(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Insert(ctx, o); !errors.Is(err, store.ErrConflict) {
+			t.Errorf("same id = %v, want ErrConflict", err)
+		}
+		other := newTestOrder("o2", time.Now().UTC())
+		other.PublicID = o.PublicID
+		if err := r.Insert(ctx, other); !errors.Is(err, store.ErrConflict) {
+			t.Errorf("same public id = %v, want ErrConflict", err)
+		}
+	})
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, d *DB) {
+		ctx := context.Background()
+		r := NewOrdersRepo(d)
+		if err := r.Insert(ctx, newTestOrder("o1", time.Now().UTC())); err != nil {
+			t.Fatal(err)
+		}
+		k := &models.IdempotencyKey{Scope: "ip:203.0.113.1", Key: "k1", RequestHash: "h", OrderID: "o1", CreatedAt: time.Now().UTC()}
+		if err := r.InsertIdempotencyKey(ctx, k); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.InsertIdempotencyKey(ctx, k); !errors.Is(err, store.ErrConflict) {
+			t.Errorf("second insert = %v, want ErrConflict", err)
+		}
+		got, err := r.GetIdempotencyKey(ctx, k.Scope, k.Key)
+		if err != nil || got.OrderID != "o1" {
+			t.Errorf("get = %+v, %v", got, err)
+		}
+		if _, err := r.GetIdempotencyKey(ctx, "ip:203.0.113.2", k.Key); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("other scope = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestOrderEventsFollowStatus(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, d *DB) {
+		ctx := context.Background()
+		r := NewOrdersRepo(d)
+		o := newTestOrder("o1", time.Now().UTC())
+		o.Status = models.StatusDepositCredited
+		if err := r.Insert(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := r.TryMarkExecutingSwap(ctx, o.ID); err != nil || !ok {
+			t.Fatalf("mark executing = %v, %v", ok, err)
+		}
+		events, err := NewOrderEventRepo(d).ListOrderEvents(ctx, []string{o.ID}, 0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var statuses []string
+		for _, e := range events {
+			statuses = append(statuses, string(e.Status))
+		}
+		if want := "deposit_credited executing_swap"; strings.Join(statuses, " ") != want {
+			t.Errorf("events = %v, want %s", statuses, want)
+		}
+		latest, err := NewOrderEventRepo(d).LatestOrderEventID(ctx, o.ID)
+		if err != nil || latest != events[len(events)-1].ID {
+			t.Errorf("latest = %d, %v", latest, err)
+		}
+	})
+}
+
+func TestListDueDeliveriesPerPartner(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, d *DB) {
+		ctx := context.Background()
+		r := NewWebhookRepo(d)
+		base := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+		// Partner a has a backlog of four older deliveries; b has one.
+		for i, partner := range []string{"a", "a", "a", "a", "b"} {
+			at := base.Add(time.Duration(i) * time.Second)
+			err := r.InsertWebhookDelivery(ctx, &models.WebhookDelivery{
+				ID: fmt.Sprintf("d%d", i), EndpointID: "ep-" + partner, PartnerID: partner, EventID: int64(i),
+				OrderID: "o1", EventType: "order.completed", Payload: "{}", Status: models.DeliveryPending,
+				CreatedAt: at, UpdatedAt: at,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		due, err := r.ListDueDeliveries(ctx, base.Add(time.Minute), 2, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, dl := range due {
+			got = append(got, dl.ID)
+		}
+		if want := "d0 d1 d4"; strings.Join(got, " ") != want {
+			t.Errorf("due = %v, want %s", got, want)
+		}
+	})
+}
+
+func TestLedgerPostJournal(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, d *DB) {
+		ctx := context.Background()
+		r := NewLedgerRepo(d)
+		now := time.Now().UTC()
+		j := &models.LedgerJournal{ID: "j1", Kind: "deposit", Ref: "snap-1", CreatedAt: now, Entries: []*models.LedgerEntry{
+			{ID: "e1", JournalID: "j1", Account: "wallet", AssetID: "a", Amount: "2.50", CreatedAt: now},
+			{ID: "e2", JournalID: "j1", Account: "order:o1", AssetID: "a", Amount: "-2.50", CreatedAt: now},
+		}}
+		if ok, err := r.PostJournal(ctx, j); err != nil || !ok {
+			t.Fatalf("post = %v, %v", ok, err)
+		}
+		dup := *j
+		dup.ID = "j2"
+		if ok, err := r.PostJournal(ctx, &dup); err != nil || ok {
+			t.Fatalf("repost = %v, %v; want false", ok, err)
+		}
+
+		got, err := r.GetJournal(ctx, "deposit", "snap-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != "j1" || len(got.Entries) != 2 {
+			t.Fatalf("journal %s with %d entries", got.ID, len(got.Entries))
+		}
+		sum := decimal.Zero
+		for _, e := range got.Entries {
+			sum = sum.Add(decimal.RequireFromString(e.Amount))
+		}
+		if !sum.IsZero() {
+			t.Errorf("entries sum to %s", sum)
+		}
+
+		// o1 is settled back to zero and drops out; o_2 keeps a balance. The
+		// "_" in the prefix must match literally, not as a LIKE wildcard.
+		for i, e := range [][2]string{{"order:o1", "2.5"}, {"order:o_2", "0.12345678"}, {"order:o_2", "0.00000001"}, {"order:oX2", "1"}} {
+			id := fmt.Sprintf("j%d", i+3)
+			_, err := r.PostJournal(ctx, &models.LedgerJournal{ID: id, Kind: "settle", Ref: id, CreatedAt: now, Entries: []*models.LedgerEntry{
+				{ID: id + "a", Account: e[0], AssetID: "a", Amount: e[1]},
+				{ID: id + "b", Account: "wallet", AssetID: "a", Amount: "-" + e[1]},
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		balances, err := r.ListBalances(ctx, "order:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sums []string
+		for _, b := range balances {
+			sums = append(sums, b.Account+" "+b.AssetID+" "+decimal.RequireFromString(b.Amount).String())
+		}
+		if want := "order:oX2 a 1,order:o_2 a 0.12345679"; strings.Join(sums, ",") != want {
+			t.Errorf("balances = %v, want %s", sums, want)
+		}
+		if balances, err = r.ListBalances(ctx, "order:o_"); err != nil || len(balances) != 1 || balances[0].Account != "order:o_2" {
+			t.Errorf("prefix order:o_ = %v, %v", balances, err)
+		}
+	})
+}
+
+// The shared and in-process limiters must agree on the bucket arithmetic.
+func TestRateLimitStores(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, d *DB) {
+		ctx := context.Background()
+		rule := ratelimit.Rule{Rate: 2, Burst: 3}
+		for name, s := range map[string]ratelimit.Store{"memory": ratelimit.NewMemory(), "db": NewRateLimitRepo(d)} {
+			t.Run(name, func(t *testing.T) {
+				now := time.Unix(1_700_000_000, 0)
+				take := func(key string, at time.Time) (bool, time.Duration) {
+					t.Helper()
+					ok, wait, err := s.Take(ctx, key, rule, at)
+					if err != nil {
+						t.Fatal(err)
+					}
+					return ok, wait
+				}
+				for i := 0; i < rule.Burst; i++ {
+					if ok, _ := take("k", now); !ok {
+						t.Fatalf("take %d of the burst refused", i+1)
+					}
+				}
+				if ok, wait := take("k", now); ok || wait != 500*time.Millisecond {
+					t.Errorf("empty bucket: ok=%v wait=%s, want refused for 500ms", ok, wait)
+				}
+				if ok, _ := take("other", now); !ok {
+					t.Error("other key shares the bucket")
+				}
+				// Refills at Rate: one token after 500ms, not two.
+				now = now.Add(500 * time.Millisecond)
+				if ok, _ := take("k", now); !ok {
+					t.Error("refilled token refused")
+				}
+				if ok, wait := take("k", now); ok || wait != 500*time.Millisecond {
+					t.Errorf("after refill: ok=%v wait=%s", ok, wait)
+				}
+				// A long idle period fills up to Burst only.
+				now = now.Add(time.Minute)
+				n := 0
+				for ok := true; ok; n++ {
+					ok, _ = take("k", now)
+				}
+				if n-1 != rule.Burst {
+					t.Errorf("after idle took %d, want %d", n-1, rule.Burst)
+				}
+			})
+		}
+
+		// Concurrent takes on one shared bucket never hand out more than
+		// Burst tokens, whichever replica's update wins the CAS.
+		r := NewRateLimitRepo(d)
+		burst := ratelimit.Rule{Rate: 0.001, Burst: 5}
+		now := time.Unix(1_700_000_000, 0)
+		var mu sync.Mutex
+		allowed := 0
+		var wg sync.WaitGroup
+		for i := 0; i < 12; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ok, _, err := r.Take(ctx, "shared", burst, now)
+				mu.Lock()
+				defer mu.Unlock()
+				if err == nil && ok {
+					allowed++
+				}
+			}()
+		}
+		wg.Wait()
+		if allowed > burst.Burst || allowed == 0 {
+			t.Errorf("concurrent takes allowed %d, want 1..%d", allowed, burst.Burst)
+		}
+	})
+}
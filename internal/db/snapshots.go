@@ -8,9 +8,9 @@ import (
 	"github.com/mvg-fi-dev/bridge/internal/mixin"
 )
 
-type SnapshotsRepo struct{ DB *sql.DB }
+type SnapshotsRepo struct{ DB *DB }
 
-func NewSnapshotsRepo(db *sql.DB) *SnapshotsRepo { return &SnapshotsRepo{DB: db} }
+func NewSnapshotsRepo(db *DB) *SnapshotsRepo { return &SnapshotsRepo{DB: db} }
 
 // InsertIfNew stores the raw snapshot JSON (caller should pass raw JSON string).
 // Returns true if inserted, false if already existed.
@@ -18,16 +18,17 @@ func (r *SnapshotsRepo) InsertIfNew(ctx context.Context, snapshotID string, rece
 	createdAt := sql.NullString{}
 	if s != nil {
 		if t, err := s.CreatedAtTime(); err == nil && t != nil {
-			createdAt = sql.NullString{String: t.UTC().Format(time.RFC3339Nano), Valid: true}
+			createdAt = sql.NullString{String: formatTime(*t), Valid: true}
 		}
 	}
 
 	res, err := r.DB.ExecContext(ctx, `
-INSERT OR IGNORE INTO mixin_snapshots(
+INSERT INTO mixin_snapshots(
   snapshot_id, received_at, raw_json,
   created_at, amount, asset_id, opponent_id, memo
 ) VALUES(?,?,?,?,?,?,?,?)
-`, snapshotID, receivedAt.UTC().Format(time.RFC3339Nano), rawJSON,
+ON CONFLICT(snapshot_id) DO NOTHING
+`, snapshotID, formatTime(receivedAt), rawJSON,
 		createdAt,
 		nullStr(sAmount(s)), nullStr(sAssetID(s)), nullStr(sOpponentID(s)), nullStr(sMemo(s)),
 	)
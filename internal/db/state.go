@@ -3,12 +3,15 @@ package db
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/store"
 )
 
-type StateRepo struct{ DB *sql.DB }
+type StateRepo struct{ DB *DB }
 
-func NewStateRepo(db *sql.DB) *StateRepo { return &StateRepo{DB: db} }
+func NewStateRepo(db *DB) *StateRepo { return &StateRepo{DB: db} }
 
 func (r *StateRepo) Get(ctx context.Context, key string) (string, bool, error) {
 	row := r.DB.QueryRowContext(ctx, `SELECT v FROM kv WHERE k = ? LIMIT 1`, key)
@@ -23,8 +26,34 @@ func (r *StateRepo) Get(ctx context.Context, key string) (string, bool, error) {
 }
 
 func (r *StateRepo) Set(ctx context.Context, key, value string) error {
-	now := time.Now().UTC().Format(time.RFC3339Nano)
+	now := formatTime(time.Now())
 	_, err := r.DB.ExecContext(ctx, `INSERT INTO kv(k,v,updated_at) VALUES(?,?,?)
 ON CONFLICT(k) DO UPDATE SET v=excluded.v, updated_at=excluded.updated_at`, key, value, now)
 	return err
 }
+
+func (r *StateRepo) Delete(ctx context.Context, key string) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM kv WHERE k = ?`, key)
+	return err
+}
+
+// List returns every key/value whose key starts with prefix.
+func (r *StateRepo) List(ctx context.Context, prefix string) (map[string]string, error) {
+	esc := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix)
+	rows, err := r.DB.QueryContext(ctx, `SELECT k, v FROM kv WHERE k LIKE ? ESCAPE '\'`, esc+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]string{}
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, err
+		}
+		out[k] = v
+	}
+	return out, rows.Err()
+}
+
+var _ store.KVStore = (*StateRepo)(nil)
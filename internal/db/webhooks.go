@@ -0,0 +1,250 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// WebhookRepo stores partner webhook endpoints and deliveries, and reads the
+// order_events change feed.
+type WebhookRepo struct{ DB *DB }
+
+func NewWebhookRepo(db *DB) *WebhookRepo { return &WebhookRepo{DB: db} }
+
+var _ store.WebhookStore = (*WebhookRepo)(nil)
+
+const webhookEndpointColumns = `id, partner_id, url, secret, created_at, updated_at`
+
+func scanWebhookEndpoint(rs rowScanner) (*models.WebhookEndpoint, error) {
+	var e models.WebhookEndpoint
+	var createdAt, updatedAt string
+	if err := rs.Scan(&e.ID, &e.PartnerID, &e.URL, &e.Secret, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if e.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if e.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *WebhookRepo) ListWebhookEndpoints(ctx context.Context, partnerID string) ([]*models.WebhookEndpoint, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+webhookEndpointColumns+` FROM webhook_endpoints WHERE partner_id = ? ORDER BY created_at, id`, partnerID)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+	var out []*models.WebhookEndpoint
+	for rows.Next() {
+		e, err := scanWebhookEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (r *WebhookRepo) GetWebhookEndpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error) {
+	e, err := scanWebhookEndpoint(r.DB.QueryRowContext(ctx, `SELECT `+webhookEndpointColumns+` FROM webhook_endpoints WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get webhook endpoint: %w", err)
+	}
+	return e, nil
+}
+
+func (r *WebhookRepo) InsertWebhookEndpoint(ctx context.Context, e *models.WebhookEndpoint) error {
+	_, err := r.DB.ExecContext(ctx, `
+INSERT INTO webhook_endpoints(`+webhookEndpointColumns+`)
+VALUES(?,?,?,?,?,?)
+`, e.ID, e.PartnerID, e.URL, e.Secret, formatTime(e.CreatedAt), formatTime(e.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("insert webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepo) DeleteWebhookEndpoint(ctx context.Context, partnerID, id string) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = ? AND partner_id = ?`, id, partnerID)
+	if err != nil {
+		return false, fmt.Errorf("delete webhook endpoint: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+func (r *WebhookRepo) ListUndispatchedEvents(ctx context.Context, limit int) ([]*models.OrderEvent, error) {
+	return listOrderEvents(ctx, r.DB, `WHERE dispatched_at IS NULL ORDER BY id LIMIT ?`, limit)
+}
+
+func (r *WebhookRepo) MarkEventDispatched(ctx context.Context, eventID int64, at time.Time) error {
+	if _, err := r.DB.ExecContext(ctx, `UPDATE order_events SET dispatched_at = ? WHERE id = ?`, formatTime(at), eventID); err != nil {
+		return fmt.Errorf("mark event dispatched: %w", err)
+	}
+	return nil
+}
+
+const webhookDeliveryColumns = `id, endpoint_id, partner_id, event_id, order_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, delivered_at, created_at, updated_at`
+
+func scanWebhookDelivery(rs rowScanner) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var nextAttemptAt, lastError, deliveredAt sql.NullString
+	var lastStatusCode sql.NullInt64
+	var createdAt, updatedAt string
+	if err := rs.Scan(&d.ID, &d.EndpointID, &d.PartnerID, &d.EventID, &d.OrderID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+		&nextAttemptAt, &lastStatusCode, &lastError, &deliveredAt, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	var err error
+	if d.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	if d.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return nil, err
+	}
+	d.NextAttemptAt = nullTimeValue(nextAttemptAt)
+	d.LastStatusCode = nullInt64Value(lastStatusCode)
+	d.LastError = nullStringValue(lastError)
+	d.DeliveredAt = nullTimeValue(deliveredAt)
+	return &d, nil
+}
+
+func (r *WebhookRepo) listDeliveries(ctx context.Context, tail string, args ...any) ([]*models.WebhookDelivery, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries `+tail, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	var out []*models.WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func (r *WebhookRepo) InsertWebhookDelivery(ctx context.Context, d *models.WebhookDelivery) error {
+	_, err := r.DB.ExecContext(ctx, `
+INSERT INTO webhook_deliveries(`+webhookDeliveryColumns+`)
+VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)
+ON CONFLICT(endpoint_id, event_id) DO NOTHING
+`, d.ID, d.EndpointID, d.PartnerID, d.EventID, d.OrderID, d.EventType, d.Payload, string(d.Status), d.Attempts,
+		nullableTime(d.NextAttemptAt), d.LastStatusCode, d.LastError, nullableTime(d.DeliveredAt), formatTime(d.CreatedAt), formatTime(d.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("insert webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepo) ListDueDeliveries(ctx context.Context, now time.Time, perPartner, limit int) ([]*models.WebhookDelivery, error) {
+	return r.listDeliveries(ctx, `
+WHERE id IN (
+  SELECT id FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY partner_id ORDER BY created_at ASC) AS n
+    FROM webhook_deliveries
+    WHERE status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)
+  ) due
+  WHERE n <= ?
+)
+ORDER BY created_at ASC
+LIMIT ?
+`, string(models.DeliveryPending), formatTime(now), perPartner, limit)
+}
+
+func (r *WebhookRepo) ClaimDelivery(ctx context.Context, id string, now, until time.Time) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `
+UPDATE webhook_deliveries
+SET next_attempt_at = ?
+WHERE id = ? AND status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)
+`, formatTime(until), id, string(models.DeliveryPending), formatTime(now))
+	if err != nil {
+		return false, fmt.Errorf("claim webhook delivery: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+func (r *WebhookRepo) RecordDeliveryAttempt(ctx context.Context, d *models.WebhookDelivery) error {
+	_, err := r.DB.ExecContext(ctx, `
+UPDATE webhook_deliveries
+SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?, delivered_at = ?, updated_at = ?
+WHERE id = ?
+`, string(d.Status), d.Attempts, nullableTime(d.NextAttemptAt), d.LastStatusCode, d.LastError, nullableTime(d.DeliveredAt), formatTime(d.UpdatedAt), d.ID)
+	if err != nil {
+		return fmt.Errorf("record webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepo) GetWebhookDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	d, err := scanWebhookDelivery(r.DB.QueryRowContext(ctx, `SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get webhook delivery: %w", err)
+	}
+	return d, nil
+}
+
+func (r *WebhookRepo) ListWebhookDeliveries(ctx context.Context, f store.DeliveryFilter) ([]*models.WebhookDelivery, error) {
+	var where []string
+	var args []any
+	if f.PartnerID != "" {
+		where = append(where, "partner_id = ?")
+		args = append(args, f.PartnerID)
+	}
+	if f.EndpointID != "" {
+		where = append(where, "endpoint_id = ?")
+		args = append(args, f.EndpointID)
+	}
+	if f.OrderID != "" {
+		where = append(where, "order_id = ?")
+		args = append(args, f.OrderID)
+	}
+	if f.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, string(f.Status))
+	}
+	tail := ""
+	if len(where) > 0 {
+		tail = "WHERE " + strings.Join(where, " AND ") + "\n"
+	}
+	limit := f.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	tail += "ORDER BY created_at DESC, id DESC\nLIMIT ? OFFSET ?\n"
+	args = append(args, limit, f.Offset)
+	return r.listDeliveries(ctx, tail, args...)
+}
+
+func (r *WebhookRepo) RedeliverWebhook(ctx context.Context, id string) (bool, error) {
+	now := formatTime(time.Now())
+	res, err := r.DB.ExecContext(ctx, `
+UPDATE webhook_deliveries
+SET status = ?, attempts = 0, next_attempt_at = ?, updated_at = ?
+WHERE id = ?
+`, string(models.DeliveryPending), now, now, id)
+	if err != nil {
+		return false, fmt.Errorf("redeliver webhook: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
@@ -0,0 +1,557 @@
+package executor
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bot "github.com/MixinNetwork/bot-api-go-client/v2"
+
+	"github.com/mvg-fi-dev/bridge/internal/ids"
+	"github.com/mvg-fi-dev/bridge/internal/mixin"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/screening"
+	"github.com/mvg-fi-dev/bridge/internal/store/memstore"
+)
+
+const (
+	srcAsset = "c6d0c728-2624-429b-8e0d-d9d19b6592fa"
+	dstAsset = "43d61dcd-e413-450d-80b8-101d5e903357"
+	sender   = "7b3f0a95-3ee9-4c1b-8ae9-170e3877d909"
+)
+
+type mixinCall struct {
+	AssetID, Recipient, Amount, Memo, TraceID string
+}
+
+// fakeMixin records transfers and withdrawals instead of sending them.
+type fakeMixin struct {
+	mu        sync.Mutex
+	transfers []mixinCall
+	withdraws []mixinCall
+	err       error
+}
+
+func (f *fakeMixin) Transfer(ctx context.Context, assetID, opponentUserID, amount, memo, traceID string) (*bot.SequencerTransactionRequest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.transfers = append(f.transfers, mixinCall{assetID, opponentUserID, amount, memo, traceID})
+	return &bot.SequencerTransactionRequest{RequestID: traceID}, nil
+}
+
+func (f *fakeMixin) Withdraw(ctx context.Context, assetID, destination, tag, amount, traceID string) (*bot.SequencerTransactionRequest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.withdraws = append(f.withdraws, mixinCall{assetID, destination, amount, "", traceID})
+	return &bot.SequencerTransactionRequest{RequestID: traceID}, nil
+}
+
+func strp(s string) *string { return &s }
+
+// creditedOrder inserts an order whose deposit of amount has been credited;
+// edit adjusts it before the insert.
+func creditedOrder(t *testing.T, orders *memstore.Orders, id, amount string, edit ...func(o *models.Order)) *models.Order {
+	t.Helper()
+	now := time.Now().UTC()
+	o := &models.Order{
+		ID:               id,
+		PublicID:         "pub-" + id,
+		Status:           models.StatusDepositCredited,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		SourceChain:      "ETH",
+		SourceAsset:      srcAsset,
+		AmountIn:         amount,
+		TargetChain:      "BTC",
+		TargetAsset:      dstAsset,
+		TargetAddress:    "bc1qtarget",
+		EstimatedOut:     "0.02",
+		MinOut:           "0.019",
+		PayWindowSeconds: 900,
+		AmountCredited:   strp(amount),
+		RefundToAddress:  strp(sender),
+	}
+	for _, fn := range edit {
+		fn(o)
+	}
+	if err := orders.Insert(context.Background(), o); err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func exinSwapReply(trace, typ, assetID, amount string) *mixin.Snapshot {
+	return &mixin.Snapshot{
+		SnapshotID: ids.NewUUID(),
+		AssetID:    assetID,
+		Amount:     amount,
+		OpponentID: ExinSwapBotUserID,
+		Memo:       base64.StdEncoding.EncodeToString([]byte("0|" + trace + "|SW|" + typ)),
+	}
+}
+
+func mustGet(t *testing.T, orders *memstore.Orders, id string) *models.Order {
+	t.Helper()
+	o, err := orders.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestSwapThenWithdraw(t *testing.T) {
+	ctx := context.Background()
+	orders := memstore.NewOrders()
+	mx := &fakeMixin{}
+	o := creditedOrder(t, orders, "o1", "1")
+
+	if err := NewExinSwapExecutor(orders, mx).ExecuteDepositCredited(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	o = mustGet(t, orders, "o1")
+	trace := ids.DeterministicUUID("o1")
+	if o.Status != models.StatusExecutingSwap || o.ExinSwapTraceID == nil || *o.ExinSwapTraceID != trace {
+		t.Fatalf("after swap: status %s trace %v", o.Status, o.ExinSwapTraceID)
+	}
+	if len(mx.transfers) != 1 {
+		t.Fatalf("transfers = %d, want 1", len(mx.transfers))
+	}
+	if tr := mx.transfers[0]; tr.Recipient != ExinSwapBotUserID || tr.AssetID != srcAsset || tr.Amount != "1" || tr.TraceID != trace {
+		t.Errorf("swap transfer = %+v", tr)
+	}
+
+	// A replayed executor call must not swap twice.
+	if err := NewExinSwapExecutor(orders, mx).ExecuteDepositCredited(ctx, o); err != nil || len(mx.transfers) != 1 {
+		t.Fatalf("second execute: err %v transfers %d", err, len(mx.transfers))
+	}
+
+	rec := NewReconcileExinSwapSnapshots(orders)
+	if err := rec.HandleSnapshot(ctx, exinSwapReply(trace, "RL", dstAsset, "0.0195")); err != nil {
+		t.Fatal(err)
+	}
+	o = mustGet(t, orders, "o1")
+	if o.Status != models.StatusWithdrawing || o.FinalOut == nil || *o.FinalOut != "0.0195" {
+		t.Fatalf("after RL: status %s final_out %v", o.Status, o.FinalOut)
+	}
+
+	if err := NewWithdrawExecutor(orders, mx).ExecuteWithdrawing(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	o = mustGet(t, orders, "o1")
+	wtrace := ids.DeterministicUUID("o1:withdraw")
+	if o.Status != models.StatusCompleted || o.WithdrawTxID == nil || *o.WithdrawTxID != wtrace {
+		t.Fatalf("after withdraw: status %s withdraw_tx_id %v", o.Status, o.WithdrawTxID)
+	}
+	if len(mx.withdraws) != 1 {
+		t.Fatalf("withdraws = %d, want 1", len(mx.withdraws))
+	}
+	if w := mx.withdraws[0]; w.AssetID != dstAsset || w.Recipient != "bc1qtarget" || w.Amount != "0.0195" || w.TraceID != wtrace {
+		t.Errorf("withdraw = %+v", w)
+	}
+}
+
+// An RF reply refunds what ExinSwap returned plus the fee kept before the
+// swap, and zeroes the recorded fee.
+func TestSwapRefundedByExinSwap(t *testing.T) {
+	ctx := context.Background()
+	orders := memstore.NewOrders()
+	mx := &fakeMixin{}
+	bps := int64(100)
+	o := creditedOrder(t, orders, "o1", "1", func(o *models.Order) { o.FeeBps = &bps })
+
+	if err := NewExinSwapExecutor(orders, mx).ExecuteDepositCredited(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, orders, "o1"); got.FeeAmount == nil || *got.FeeAmount != "0.01" {
+		t.Fatalf("fee_amount = %v, want 0.01", got.FeeAmount)
+	}
+	if len(mx.transfers) != 1 || mx.transfers[0].Amount != "0.99" {
+		t.Fatalf("swap transfers = %+v", mx.transfers)
+	}
+
+	reply := exinSwapReply(ids.DeterministicUUID("o1"), "RF", srcAsset, "0.99")
+	if err := NewReconcileExinSwapSnapshots(orders).HandleSnapshot(ctx, reply); err != nil {
+		t.Fatal(err)
+	}
+	o = mustGet(t, orders, "o1")
+	if o.Status != models.StatusRefunding || o.RefundAmount == nil || *o.RefundAmount != "1" ||
+		o.RefundReceivedSnapshotID == nil || *o.RefundReceivedSnapshotID != reply.SnapshotID {
+		t.Fatalf("after RF: status %s refund_amount %v snapshot %v", o.Status, o.RefundAmount, o.RefundReceivedSnapshotID)
+	}
+	if o.FeeAmount == nil || *o.FeeAmount != "0" {
+		t.Errorf("fee_amount after RF = %v, want 0", o.FeeAmount)
+	}
+
+	if err := NewRefundExecutor(orders, mx).ExecuteRefunding(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	o = mustGet(t, orders, "o1")
+	if o.Status != models.StatusRefunded {
+		t.Fatalf("after refund: status %s", o.Status)
+	}
+	if len(mx.transfers) != 2 {
+		t.Fatalf("transfers = %d, want 2", len(mx.transfers))
+	}
+	if r := mx.transfers[1]; r.Recipient != sender || r.AssetID != srcAsset || r.Amount != "1" || r.TraceID != ids.DeterministicUUID("o1:refund") {
+		t.Errorf("refund transfer = %+v", r)
+	}
+}
+
+func TestSwapRecordsFee(t *testing.T) {
+	ctx := context.Background()
+	bps, share := int64(30), int64(5000)
+	for _, tc := range []struct {
+		name                         string
+		edit                         func(o *models.Order)
+		credited                     string
+		wantSwap, wantFee, wantShare string
+	}{
+		{"no terms", func(o *models.Order) {}, "1", "1", "", ""},
+		{"spread", func(o *models.Order) { o.FeeBps = &bps }, "1", "0.997", "0.003", "0"},
+		{"spread and flat", func(o *models.Order) { o.FeeBps, o.FeeFlat = &bps, strp("0.01") }, "1", "0.987", "0.013", "0"},
+		{"partner share", func(o *models.Order) { o.FeeBps, o.FeeShareBps = &bps, &share }, "1", "0.997", "0.003", "0.0015"},
+		{"rounded down", func(o *models.Order) { o.FeeBps, o.FeeShareBps = &bps, &share }, "0.12345678", "0.12308641", "0.00037037", "0.00018518"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			orders := memstore.NewOrders()
+			mx := &fakeMixin{}
+			o := creditedOrder(t, orders, "o1", tc.credited, tc.edit)
+			if err := NewExinSwapExecutor(orders, mx).ExecuteDepositCredited(ctx, o); err != nil {
+				t.Fatal(err)
+			}
+			if len(mx.transfers) != 1 || mx.transfers[0].Amount != tc.wantSwap {
+				t.Fatalf("swap transfers = %+v, want %s", mx.transfers, tc.wantSwap)
+			}
+			o = mustGet(t, orders, "o1")
+			if tc.wantFee == "" {
+				if o.FeeAmount != nil || o.PartnerFeeAmount != nil {
+					t.Errorf("fee recorded without terms: %v %v", o.FeeAmount, o.PartnerFeeAmount)
+				}
+				return
+			}
+			if o.FeeAmount == nil || *o.FeeAmount != tc.wantFee || o.PartnerFeeAmount == nil || *o.PartnerFeeAmount != tc.wantShare {
+				t.Errorf("fee = %v, partner fee = %v; want %s, %s", o.FeeAmount, o.PartnerFeeAmount, tc.wantFee, tc.wantShare)
+			}
+		})
+	}
+}
+
+// A credited amount that does not cover the fee is refunded, not swapped.
+func TestSwapAmountBelowFee(t *testing.T) {
+	orders := memstore.NewOrders()
+	mx := &fakeMixin{}
+	bps := int64(0)
+	o := creditedOrder(t, orders, "o1", "0.001", func(o *models.Order) { o.FeeBps, o.FeeFlat = &bps, strp("0.001") })
+	if err := NewExinSwapExecutor(orders, mx).ExecuteDepositCredited(context.Background(), o); err != nil {
+		t.Fatal(err)
+	}
+	if o = mustGet(t, orders, "o1"); o.Status != models.StatusRefunding || len(mx.transfers) != 0 {
+		t.Errorf("status %s, %d transfers; want refunding without a swap", o.Status, len(mx.transfers))
+	}
+}
+
+// A failed swap transfer is retried under the same trace, not refunded: the
+// error may hide a transfer that went out.
+func TestSwapTransferFailureRetries(t *testing.T) {
+	ctx := context.Background()
+	orders := memstore.NewOrders()
+	mx := &fakeMixin{err: errors.New("network down")}
+	exec := NewExinSwapExecutor(orders, mx)
+	r := NewRetrier(orders, DefaultRetryPolicies(2, time.Minute, time.Hour))
+	trace := ids.DeterministicUUID("o1")
+
+	o := creditedOrder(t, orders, "o1", "1")
+	if err := exec.ExecuteDepositCredited(ctx, o); err == nil {
+		t.Fatal("want transfer error")
+	} else if err := r.Fail(ctx, o, models.StageSwap, err); err != nil {
+		t.Fatal(err)
+	}
+	o = mustGet(t, orders, "o1")
+	if o.Status != models.StatusExecutingSwap || o.RetryStage == nil || *o.RetryStage != string(models.StageSwap) || o.Attempts != 1 || o.NextAttemptAt == nil {
+		t.Fatalf("after failure: status %s stage %v attempts %d next %v", o.Status, o.RetryStage, o.Attempts, o.NextAttemptAt)
+	}
+
+	// Not resubmitted before it is due.
+	if got, _ := orders.ListExecutable(ctx, 10); len(got) != 0 {
+		t.Fatalf("listed %d orders before next_attempt_at", len(got))
+	}
+	orders.Now = func() time.Time { return time.Now().Add(time.Hour) }
+	got, _ := orders.ListExecutable(ctx, 10)
+	if len(got) != 1 {
+		t.Fatalf("listed %d orders once due, want 1", len(got))
+	}
+
+	mx.err = nil
+	if err := exec.ExecuteDepositCredited(ctx, got[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(mx.transfers) != 1 || mx.transfers[0].TraceID != trace {
+		t.Fatalf("resubmit = %+v, want one transfer under %s", mx.transfers, trace)
+	}
+	o = mustGet(t, orders, "o1")
+	if o.Status != models.StatusExecutingSwap || o.RetryStage != nil || o.Attempts != 0 || o.NextAttemptAt != nil {
+		t.Fatalf("after resubmit: status %s stage %v attempts %d next %v", o.Status, o.RetryStage, o.Attempts, o.NextAttemptAt)
+	}
+	if got, _ := orders.ListExecutable(ctx, 10); len(got) != 0 {
+		t.Errorf("listed %d orders awaiting ExinSwap", len(got))
+	}
+
+	// A second failure spends the budget of 2 and parks the order.
+	mx.err = errors.New("network down")
+	o2 := creditedOrder(t, orders, "o2", "1")
+	for i := 0; i < 2; i++ {
+		err := exec.ExecuteDepositCredited(ctx, mustGet(t, orders, "o2"))
+		if err == nil {
+			t.Fatalf("attempt %d: want transfer error", i+1)
+		}
+		if err := r.Fail(ctx, o2, models.StageSwap, err); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if o2 = mustGet(t, orders, "o2"); o2.Status != models.StatusFailedManual || o2.Attempts != 2 {
+		t.Fatalf("exhausted: status %s attempts %d", o2.Status, o2.Attempts)
+	}
+}
+
+// Moving on to the next stage starts its attempt budget afresh.
+func TestStageTransitionResetsAttempts(t *testing.T) {
+	ctx := context.Background()
+	orders := memstore.NewOrders()
+	retrying := func(o *models.Order) {
+		o.Status = models.StatusExecutingSwap
+		o.ExinSwapTraceID = strp(ids.DeterministicUUID(o.ID))
+		o.RetryStage, o.Attempts = strp(string(models.StageSwap)), 3
+		next := time.Now().Add(time.Hour)
+		o.NextAttemptAt = &next
+	}
+	creditedOrder(t, orders, "o1", "1", retrying)
+	creditedOrder(t, orders, "o2", "1", retrying)
+
+	// The transfer did go out after all: ExinSwap's reply lands mid-retry.
+	rec := NewReconcileExinSwapSnapshots(orders)
+	if err := rec.HandleSnapshot(ctx, exinSwapReply(ids.DeterministicUUID("o1"), "RL", dstAsset, "0.0195")); err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.MarkRefunding(ctx, "o2", "test"); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]models.OrderStatus{"o1": models.StatusWithdrawing, "o2": models.StatusRefunding} {
+		o := mustGet(t, orders, id)
+		if o.Status != want || o.RetryStage != nil || o.Attempts != 0 || o.NextAttemptAt != nil {
+			t.Errorf("%s: status %s stage %v attempts %d next %v", id, o.Status, o.RetryStage, o.Attempts, o.NextAttemptAt)
+		}
+	}
+}
+
+// listed is a screening.Screener flagging the given addresses, or failing
+// every call when err is set.
+type listed struct {
+	addrs map[string]bool
+	err   error
+}
+
+func (l listed) Screen(ctx context.Context, chain, address string) (screening.Result, error) {
+	if l.err != nil {
+		return screening.Result{}, l.err
+	}
+	return screening.Result{Hit: l.addrs[address], Source: "test", Reason: "listed"}, nil
+}
+
+func TestScreeningHolds(t *testing.T) {
+	ctx := context.Background()
+	withdrawing := func(o *models.Order) {
+		o.Status = models.StatusWithdrawing
+		o.FinalOut = strp("0.0195")
+	}
+	refunding := func(o *models.Order) { o.Status = models.StatusRefunding }
+	cleared := func(o *models.Order) {
+		now := time.Now().UTC()
+		o.ScreeningClearedAt = &now
+	}
+
+	for _, tc := range []struct {
+		name     string
+		screener listed
+		edit     []func(o *models.Order)
+		want     models.OrderStatus
+		wantErr  bool
+	}{
+		{"withdraw target flagged", listed{addrs: map[string]bool{"bc1qtarget": true}}, []func(*models.Order){withdrawing}, models.StatusComplianceHold, false},
+		{"withdraw sender flagged", listed{addrs: map[string]bool{sender: true}}, []func(*models.Order){withdrawing}, models.StatusComplianceHold, false},
+		{"withdraw clean", listed{}, []func(*models.Order){withdrawing}, models.StatusCompleted, false},
+		{"withdraw cleared by operator", listed{addrs: map[string]bool{"bc1qtarget": true}}, []func(*models.Order){withdrawing, cleared}, models.StatusCompleted, false},
+		{"withdraw screener down", listed{err: errors.New("down")}, []func(*models.Order){withdrawing}, models.StatusWithdrawing, true},
+		{"refund sender flagged", listed{addrs: map[string]bool{sender: true}}, []func(*models.Order){refunding}, models.StatusComplianceHold, false},
+		{"refund clean", listed{}, []func(*models.Order){refunding}, models.StatusRefunded, false},
+		{"refund screener down", listed{err: errors.New("down")}, []func(*models.Order){refunding}, models.StatusRefunding, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			orders := memstore.NewOrders()
+			mx := &fakeMixin{}
+			o := creditedOrder(t, orders, "o1", "1", tc.edit...)
+
+			var err error
+			if o.Status == models.StatusWithdrawing {
+				e := NewWithdrawExecutor(orders, mx)
+				e.Screener = tc.screener
+				err = e.ExecuteWithdrawing(ctx, o)
+			} else {
+				e := NewRefundExecutor(orders, mx)
+				e.Screener = tc.screener
+				err = e.ExecuteRefunding(ctx, o)
+			}
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tc.wantErr)
+			}
+			o = mustGet(t, orders, "o1")
+			if o.Status != tc.want {
+				t.Fatalf("status %s, want %s", o.Status, tc.want)
+			}
+			paid := len(mx.withdraws) + len(mx.transfers)
+			if tc.want == models.StatusCompleted || tc.want == models.StatusRefunded {
+				if paid != 1 {
+					t.Errorf("%d payouts, want 1", paid)
+				}
+				return
+			}
+			if paid != 0 {
+				t.Errorf("paid out %d times while %s", paid, o.Status)
+			}
+			if tc.want == models.StatusComplianceHold && (o.LastError == nil || !strings.HasPrefix(*o.LastError, "screening: ")) {
+				t.Errorf("last_error = %v", o.LastError)
+			}
+		})
+	}
+}
+
+func TestRetrierBackoffThenExhaustion(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	orders := memstore.NewOrders()
+	orders.Now = func() time.Time { return now }
+	o := creditedOrder(t, orders, "o1", "1", func(o *models.Order) { o.Status = models.StatusRefunding })
+
+	r := NewRetrier(orders, map[ErrorClass]RetryPolicy{
+		ErrorTransient:      {MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour},
+		ErrorInvalidAddress: {MaxAttempts: 1, BaseDelay: time.Minute, MaxDelay: time.Hour},
+	})
+	boom := errors.New("timeout")
+
+	for i, wantDelay := range []time.Duration{time.Minute, 2 * time.Minute} {
+		start := time.Now()
+		if err := r.Fail(ctx, o, models.StageRefund, boom); err != nil {
+			t.Fatal(err)
+		}
+		o = mustGet(t, orders, "o1")
+		if o.Status != models.StatusRefunding || o.Attempts != int64(i+1) || o.RetryStage == nil || *o.RetryStage != string(models.StageRefund) {
+			t.Fatalf("attempt %d: status %s attempts %d stage %v", i+1, o.Status, o.Attempts, o.RetryStage)
+		}
+		// Fail schedules from the wall clock, not the store's.
+		if o.NextAttemptAt == nil || o.NextAttemptAt.Before(start.Add(wantDelay)) || o.NextAttemptAt.After(time.Now().Add(wantDelay)) {
+			t.Errorf("attempt %d: next_attempt_at %v, want now+%s", i+1, o.NextAttemptAt, wantDelay)
+		}
+		if o.LastError == nil || *o.LastError != "transient: timeout" {
+			t.Errorf("last_error = %v", o.LastError)
+		}
+	}
+
+	if err := r.Fail(ctx, o, models.StageRefund, boom); err != nil {
+		t.Fatal(err)
+	}
+	o = mustGet(t, orders, "o1")
+	if o.Status != models.StatusFailedManual || o.Attempts != 3 || o.NextAttemptAt != nil {
+		t.Fatalf("exhausted: status %s attempts %d next %v", o.Status, o.Attempts, o.NextAttemptAt)
+	}
+}
+
+func TestRetrierNewStageAndPermanentErrors(t *testing.T) {
+	ctx := context.Background()
+	orders := memstore.NewOrders()
+	// Attempts left over from another stage do not count.
+	o := creditedOrder(t, orders, "o1", "1", func(o *models.Order) {
+		o.Status = models.StatusRefunding
+		o.RetryStage, o.Attempts = strp(string(models.StageSwap)), 4
+	})
+	r := NewRetrier(orders, DefaultRetryPolicies(5, time.Second, time.Minute))
+	if err := r.Fail(ctx, o, models.StageRefund, errors.New("timeout")); err != nil {
+		t.Fatal(err)
+	}
+	if o = mustGet(t, orders, "o1"); o.Attempts != 1 || o.Status != models.StatusRefunding {
+		t.Fatalf("new stage: attempts %d status %s", o.Attempts, o.Status)
+	}
+
+	if err := r.Fail(ctx, o, models.StageRefund, errors.New("missing refund_to_address")); err != nil {
+		t.Fatal(err)
+	}
+	if o = mustGet(t, orders, "o1"); o.Status != models.StatusFailedManual {
+		t.Fatalf("invalid address: status %s, want %s", o.Status, models.StatusFailedManual)
+	}
+}
+
+func TestLeaserContention(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	var clock atomic.Int64
+	clock.Store(now.UnixNano())
+	orders := memstore.NewOrders()
+	orders.Now = func() time.Time { return time.Unix(0, clock.Load()) }
+	o := creditedOrder(t, orders, "o1", "1")
+
+	// Many replicas racing for one order: exactly one wins.
+	var wins atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		l := NewLeaser(orders, "w"+string(rune('a'+i)), time.Minute)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok, err := l.Acquire(ctx, o); err == nil && ok {
+				wins.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if wins.Load() != 1 {
+		t.Fatalf("%d workers leased the order, want 1", wins.Load())
+	}
+	holder := *mustGet(t, orders, "o1").LockedBy
+
+	a, b := NewLeaser(orders, holder, time.Minute), NewLeaser(orders, "other", time.Minute)
+	if _, ok, _ := b.Acquire(ctx, o); ok {
+		t.Fatal("other worker took a live lease")
+	}
+	if fresh, ok, err := a.Acquire(ctx, o); err != nil || !ok || fresh.LeaseUntil == nil {
+		t.Fatalf("holder renew = %v, %v", ok, err)
+	}
+
+	// An expired lease is up for grabs.
+	clock.Store(now.Add(2 * time.Minute).UnixNano())
+	if _, ok, _ := b.Acquire(ctx, o); !ok {
+		t.Fatal("expired lease not taken over")
+	}
+	a.Release(ctx, o) // a no longer holds it; must not drop b's lease
+	if got := mustGet(t, orders, "o1"); got.LockedBy == nil || *got.LockedBy != "other" {
+		t.Fatalf("locked_by = %v, want other", got.LockedBy)
+	}
+	b.Release(ctx, o)
+	if got := mustGet(t, orders, "o1"); got.LockedBy != nil {
+		t.Fatalf("locked_by after release = %v", *got.LockedBy)
+	}
+
+	// An order that moved on cannot be leased for its old status.
+	orders.MarkRefunding(ctx, o.ID, "test")
+	if _, ok, _ := a.Acquire(ctx, o); ok {
+		t.Fatal("leased an order in a different status")
+	}
+}
@@ -2,15 +2,21 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
-	"github.com/mvg-fi-dev/bridge/internal/db"
 	"github.com/mvg-fi-dev/bridge/internal/exinswap"
 	"github.com/mvg-fi-dev/bridge/internal/ids"
-	"github.com/mvg-fi-dev/bridge/internal/mixin"
+	"github.com/mvg-fi-dev/bridge/internal/ledger"
+	"github.com/mvg-fi-dev/bridge/internal/limits"
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/metrics"
 	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+	"github.com/mvg-fi-dev/bridge/internal/tracing"
+	"github.com/shopspring/decimal"
 )
 
 const (
@@ -19,14 +25,21 @@ const (
 )
 
 type ExinSwapExecutor struct {
-	Orders *db.OrdersRepo
-	Mixin  *mixin.SDKClient
+	Orders store.OrderStore
+	Mixin  MixinClient
 
 	// Policy knobs (env configurable later)
 	SwapTimeoutSeconds int64
+
+	// Limits, if set, re-checks credited orders before the swap; breaches are
+	// held in failed_manual_review instead of executed.
+	Limits *limits.Checker
+
+	// Ledger, if set, records each submitted swap transfer.
+	Ledger *ledger.Ledger
 }
 
-func NewExinSwapExecutor(orders *db.OrdersRepo, mixinClient *mixin.SDKClient) *ExinSwapExecutor {
+func NewExinSwapExecutor(orders store.OrderStore, mixinClient MixinClient) *ExinSwapExecutor {
 	return &ExinSwapExecutor{
 		Orders:             orders,
 		Mixin:              mixinClient,
@@ -38,7 +51,18 @@ func NewExinSwapExecutor(orders *db.OrdersRepo, mixinClient *mixin.SDKClient) *E
 // - enforce min_out using ExinSwap memo (min_out + latest_exec_time); ExinSwap will refund on failure.
 // - transfer SourceAsset to ExinSwap bot with memo
 // - TODO: wait for result credit + withdraw (next iteration)
+//
+// An executing_swap order with retry_stage swap had its transfer fail; it is
+// resubmitted under the trace id it already holds, so a transfer that did go
+// out is not paid twice.
 func (e *ExinSwapExecutor) ExecuteDepositCredited(ctx context.Context, o *models.Order) error {
+	if o.Status == models.StatusExecutingSwap && o.RetryStage != nil && *o.RetryStage == string(models.StageSwap) && o.ExinSwapTraceID != nil {
+		swapAmount, fee, _, err := orderFee(o)
+		if err != nil {
+			return err
+		}
+		return e.submit(ctx, o, *o.ExinSwapTraceID, swapAmount, fee)
+	}
 	if o.Status != models.StatusDepositCredited {
 		return nil
 	}
@@ -46,31 +70,95 @@ func (e *ExinSwapExecutor) ExecuteDepositCredited(ctx context.Context, o *models
 		return fmt.Errorf("missing amount_credited")
 	}
 
-	ok, err := e.Orders.TryMarkExecutingSwap(ctx, o.ID)
+	if e.Limits != nil && o.LimitsClearedAt == nil {
+		held, err := e.checkLimits(ctx, o)
+		if err != nil || held {
+			return err
+		}
+	}
+
+	// The broker fee stays in our wallet; only the rest is swapped.
+	swapAmount, fee, partnerFee, err := orderFee(o)
+	if err != nil {
+		return err
+	}
+	if !swapAmount.IsPositive() {
+		slog.WarnContext(ctx, "exinswap refund: amount below fee", "credited", *o.AmountCredited, "fee", fee.String())
+		metrics.Refund("amount_below_fee")
+		return e.Orders.MarkRefunding(ctx, o.ID, "amount_below_fee")
+	}
+
+	// Claim the order and record the trace id (and fee) in one transaction,
+	// before any funds move. If the process dies after Transfer, the trace id
+	// is already on the order, so ExinSwap's reply can still be reconciled.
+	traceID := ids.DeterministicUUID(o.ID) // trace_id must be UUID; stable idempotency
+	var claimed bool
+	err = e.Orders.InTx(ctx, func(tx store.OrderStore) error {
+		ok, err := tx.TryMarkExecutingSwap(ctx, o.ID)
+		if err != nil || !ok {
+			return err
+		}
+		if err := tx.SetExinSwapTraceID(ctx, o.ID, traceID); err != nil {
+			return err
+		}
+		if o.FeeBps != nil {
+			if err := tx.RecordFee(ctx, o.ID, fee.String(), partnerFee.String()); err != nil {
+				return err
+			}
+		}
+		claimed = true
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	if !ok {
+	if !claimed {
 		return nil // lost race
 	}
+	return e.submit(ctx, o, traceID, swapAmount, fee)
+}
 
+// submit sends the swap transfer of a claimed order. A failed transfer leaves
+// the order in executing_swap for the worker's retrier: the error may hide a
+// transfer that went out, and only a resubmit under the same trace is safe.
+func (e *ExinSwapExecutor) submit(ctx context.Context, o *models.Order, traceID string, swapAmount, fee decimal.Decimal) error {
 	latest := time.Now().UTC().Add(time.Duration(e.SwapTimeoutSeconds) * time.Second)
 	memo, err := exinswap.TradeMemoV2(o.TargetAsset, o.MinOut, &latest, "")
 	if err != nil {
 		_ = e.Orders.MarkRefunding(ctx, o.ID, "memo_build_failed")
+		metrics.Refund("memo_build_failed")
 		return err
 	}
 
-	traceID := ids.DeterministicUUID(o.ID) // trace_id must be UUID; stable idempotency
-	log.Printf("exinswap execute order=%s transfer asset=%s amt=%s target=%s minOut=%s latest=%d", o.PublicID, o.SourceAsset, *o.AmountCredited, o.TargetAsset, o.MinOut, latest.Unix())
-	_, err = e.Mixin.Transfer(ctx, o.SourceAsset, ExinSwapBotUserID, *o.AmountCredited, memo, traceID)
+	ctx = logging.With(ctx, logging.TraceID, traceID)
+	slog.InfoContext(ctx, "exinswap transfer", "asset", o.SourceAsset, "amount", swapAmount.String(), "fee", fee.String(), "target", o.TargetAsset, "min_out", o.MinOut, "latest", latest.Unix(), "attempt", o.Attempts+1)
+	vctx, span := tracing.Start(ctx, "exinswap.swap")
+	start := time.Now()
+	_, err = e.Mixin.Transfer(vctx, o.SourceAsset, ExinSwapBotUserID, swapAmount.String(), memo, traceID)
+	metrics.ObserveVenue("exinswap", "swap", start, err)
+	tracing.End(span, err)
 	if err != nil {
-		_ = e.Orders.MarkRefunding(ctx, o.ID, "transfer_failed")
 		return err
 	}
-
-	// Store exinswap_trace_id to help reconciliation when ExinSwap pays us back.
-	_ = SetExinSwapTraceOnOrder(ctx, e.Orders, o.ID, traceID)
-
+	postTransfer(ctx, e.Ledger, o, models.StageSwap, traceID, o.SourceAsset, swapAmount, fee)
+	if o.RetryStage != nil {
+		return e.Orders.ClearRetry(ctx, o.ID, models.StatusExecutingSwap)
+	}
 	return nil
 }
+
+// checkLimits holds o for review if it breaches a limit now that the credited
+// amount and sender are known. Evaluation errors (e.g. no price) are returned
+// so the order is simply retried on the next tick.
+func (e *ExinSwapExecutor) checkLimits(ctx context.Context, o *models.Order) (bool, error) {
+	err := e.Limits.CheckCredited(ctx, o)
+	var b *limits.Breach
+	if errors.As(err, &b) {
+		slog.WarnContext(ctx, "exinswap hold: limit breached", "limit", b.Code, "detail", b.Message)
+		return true, e.Orders.MarkFailedManual(ctx, o.ID, models.StatusDepositCredited, models.StageSwap, o.Attempts, "limit: "+b.Code+": "+b.Message)
+	}
+	if err != nil {
+		return false, fmt.Errorf("check limits: %w", err)
+	}
+	return false, e.Orders.MarkLimitsCleared(ctx, o.ID)
+}
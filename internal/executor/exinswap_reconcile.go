@@ -2,15 +2,17 @@ package executor
 
 import (
 	"context"
-	"database/sql"
-	"fmt"
-	"log"
-	"time"
+	"errors"
+	"log/slog"
+
+	"github.com/shopspring/decimal"
 
-	"github.com/mvg-fi-dev/bridge/internal/db"
 	"github.com/mvg-fi-dev/bridge/internal/exinswap"
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/metrics"
 	"github.com/mvg-fi-dev/bridge/internal/mixin"
 	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
 )
 
 // ReconcileExinSwapSnapshots scans new snapshots and updates orders:
@@ -19,76 +21,71 @@ import (
 //
 // NOTE: This is a polling, best-effort reconciler; it should be idempotent.
 type ReconcileExinSwapSnapshots struct {
-	Orders *db.OrdersRepo
+	Orders store.OrderStore
 }
 
-func NewReconcileExinSwapSnapshots(orders *db.OrdersRepo) *ReconcileExinSwapSnapshots {
+func NewReconcileExinSwapSnapshots(orders store.OrderStore) *ReconcileExinSwapSnapshots {
 	return &ReconcileExinSwapSnapshots{Orders: orders}
 }
 
-func (r *ReconcileExinSwapSnapshots) HandleSnapshot(ctx context.Context, s *mixin.Snapshot) {
+// HandleSnapshot returns an error only for storage failures, so the caller can
+// roll back and retry the snapshot; unrelated or unparseable snapshots are ignored.
+func (r *ReconcileExinSwapSnapshots) HandleSnapshot(ctx context.Context, s *mixin.Snapshot) error {
 	if s == nil {
-		return
+		return nil
 	}
 	// We only care about inbound credits from ExinSwap bot.
 	if s.OpponentID != ExinSwapBotUserID {
-		return
+		return nil
 	}
 	if s.Memo == "" {
-		return
+		return nil
 	}
 
 	memo, err := exinswap.ParseServerMemo(s.Memo)
 	if err != nil {
-		return
+		return nil
 	}
 
-	// memo.Trace should be our transfer trace id. But we used deterministic UUID of order.ID.
-	// We need to map trace -> order. For now we store swap_ref as trace id to allow lookup.
-	// Better: add a dedicated column exinswap_trace_id.
-	o, err := r.findOrderBySwapRef(ctx, memo.Trace)
-	if err != nil || o == nil {
-		return
+	// memo.Trace is the trace id of our transfer to ExinSwap (exinswap_trace_id).
+	o, err := r.Orders.GetByExinSwapTraceID(ctx, memo.Trace)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
 	}
+	ctx = logging.With(logging.WithStage(ctx, o, models.StageSwap), logging.TraceID, memo.Trace, logging.SnapshotID, s.SnapshotID)
 
 	// Update order status based on memo.Type.
 	// For swap: success RL means ExinSwap released target asset to us.
 	// RF means refund.
 	if memo.Type == "RF" {
-		log.Printf("exinswap refund order=%s trace=%s amount=%s asset=%s", o.PublicID, memo.Trace, s.Amount, s.AssetID)
-		_ = r.Orders.MarkRefundingWithDetails(ctx, o.ID, s.AssetID, s.Amount, s.SnapshotID)
-		return
+		// No fee is kept on a failed swap: refund what ExinSwap returned plus
+		// the fee withheld before the swap (both in the source asset).
+		amount := s.Amount
+		if o.FeeAmount != nil {
+			fee, err1 := decimal.NewFromString(*o.FeeAmount)
+			returned, err2 := decimal.NewFromString(s.Amount)
+			if err1 == nil && err2 == nil && fee.IsPositive() {
+				amount = returned.Add(fee).String()
+			}
+		}
+		slog.InfoContext(ctx, "exinswap refunded swap", "amount", amount, "asset", s.AssetID)
+		if err := r.Orders.MarkRefundingWithDetails(ctx, o.ID, s.AssetID, amount, s.SnapshotID); err != nil {
+			return err
+		}
+		metrics.Refund("swap_refunded")
+		if o.FeeAmount != nil {
+			return r.Orders.RecordFee(ctx, o.ID, "0", "0")
+		}
+		return nil
 	}
 	if memo.Type == "RL" {
 		finalOut := s.Amount
-		log.Printf("exinswap release order=%s trace=%s out=%s asset=%s", o.PublicID, memo.Trace, finalOut, s.AssetID)
+		slog.InfoContext(ctx, "exinswap release", "out", finalOut, "asset", s.AssetID)
 		// Save final_out and swap_ref; mark withdrawing next.
-		_ = r.Orders.MarkWithdrawing(ctx, o.ID, memo.Trace, finalOut)
-		return
-	}
-}
-
-func (r *ReconcileExinSwapSnapshots) findOrderBySwapRef(ctx context.Context, swapRef string) (*models.Order, error) {
-	row := r.Orders.DB.QueryRowContext(ctx, `
-SELECT id FROM orders WHERE exinswap_trace_id = ? LIMIT 1
-`, swapRef)
-	var id string
-	if err := row.Scan(&id); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return r.Orders.GetByID(ctx, id)
-}
-
-// Helper to set exinswap_trace_id after submitting transfer; this enables reconciliation.
-func SetExinSwapTraceOnOrder(ctx context.Context, orders *db.OrdersRepo, orderID string, traceID string) error {
-	_, err := orders.DB.ExecContext(ctx, `
-UPDATE orders SET exinswap_trace_id = ?, updated_at = ? WHERE id = ?
-`, traceID, time.Now().UTC().Format(time.RFC3339Nano), orderID)
-	if err != nil {
-		return fmt.Errorf("set exinswap_trace_id: %w", err)
+		return r.Orders.MarkWithdrawing(ctx, o.ID, memo.Trace, finalOut)
 	}
 	return nil
 }
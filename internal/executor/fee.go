@@ -0,0 +1,34 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/fees"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+// orderFee applies the fee terms fixed on o at quote time to the credited
+// amount. It returns the amount left to swap, the fee and the partner's
+// share of it. Orders without fee terms pay nothing.
+func orderFee(o *models.Order) (swap, fee, partnerFee decimal.Decimal, err error) {
+	credited, err := decimal.NewFromString(*o.AmountCredited)
+	if err != nil {
+		return swap, fee, partnerFee, fmt.Errorf("amount_credited: %w", err)
+	}
+	if o.FeeBps == nil {
+		return credited, decimal.Zero, decimal.Zero, nil
+	}
+	t := fees.Terms{SpreadBps: *o.FeeBps}
+	if o.FeeFlat != nil {
+		if t.Flat, err = decimal.NewFromString(*o.FeeFlat); err != nil {
+			return swap, fee, partnerFee, fmt.Errorf("fee_flat: %w", err)
+		}
+	}
+	fee = t.Fee(credited)
+	if o.FeeShareBps != nil {
+		partnerFee = fees.PartnerShare(fee, *o.FeeShareBps)
+	}
+	return credited.Sub(fee), fee, partnerFee, nil
+}
@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// Leaser hands out short row-level leases so several worker replicas can poll
+// the same stage without double-submitting to Mixin.
+type Leaser struct {
+	Orders   store.OrderStore
+	WorkerID string
+	TTL      time.Duration
+}
+
+func NewLeaser(orders store.OrderStore, workerID string, ttl time.Duration) *Leaser {
+	return &Leaser{Orders: orders, WorkerID: workerID, TTL: ttl}
+}
+
+// Acquire leases o for this worker and returns a fresh copy of the row.
+// ok=false means another worker holds it or the order already moved on.
+func (l *Leaser) Acquire(ctx context.Context, o *models.Order) (*models.Order, bool, error) {
+	ok, err := l.Orders.TryLease(ctx, o.ID, o.Status, l.WorkerID, l.TTL)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	fresh, err := l.Orders.GetByID(ctx, o.ID)
+	if err != nil {
+		l.Release(ctx, o)
+		return nil, false, err
+	}
+	return fresh, true, nil
+}
+
+// Release gives the lease back; failures are logged since the lease expires anyway.
+func (l *Leaser) Release(ctx context.Context, o *models.Order) {
+	if err := l.Orders.ReleaseLease(ctx, o.ID, l.WorkerID); err != nil {
+		slog.WarnContext(logging.WithOrder(ctx, o), "release lease failed", "worker", l.WorkerID, "err", err)
+	}
+}
@@ -0,0 +1,24 @@
+package executor
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/ledger"
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+// postTransfer books a submitted transfer in l (if set). Failures are only
+// logged: the funds have already moved, and the worker posts the same
+// journal again when the transfer's snapshot is ingested.
+func postTransfer(ctx context.Context, l *ledger.Ledger, o *models.Order, stage models.Stage, traceID, assetID string, amount, fee decimal.Decimal) {
+	if l == nil {
+		return
+	}
+	if err := l.PostTransfer(ctx, o, stage, traceID, assetID, amount, fee); err != nil {
+		slog.ErrorContext(ctx, "ledger post transfer failed", logging.Stage, string(stage), logging.TraceID, traceID, "err", err)
+	}
+}
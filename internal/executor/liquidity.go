@@ -0,0 +1,114 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/liquidity"
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// holdForLiquidity parks o in awaiting_liquidity if the wallet cannot cover
+// needs; the liquidity_hold alert rule reports parked orders. If balances cannot be read the payout is attempted anyway: the
+// transfer itself fails when funds are missing.
+func holdForLiquidity(ctx context.Context, c *liquidity.Checker, orders store.OrderStore, o *models.Order, stage models.Stage, needs []liquidity.Need) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+	short, err := c.Check(ctx, needs...)
+	if err != nil {
+		slog.WarnContext(ctx, "liquidity check failed; attempting payout", "err", err)
+		return false, nil
+	}
+	if short == nil {
+		return false, nil
+	}
+	slog.WarnContext(ctx, "liquidity hold", logging.Stage, string(stage), "shortfall", short.String())
+	_, err = orders.MarkAwaitingLiquidity(ctx, o.ID, o.Status, stage, short.String())
+	return true, err
+}
+
+// spend records submitted needs against the cached balances.
+func spend(c *liquidity.Checker, needs []liquidity.Need) {
+	if c == nil {
+		return
+	}
+	for _, n := range needs {
+		c.Spend(n.AssetID, n.Amount)
+	}
+}
+
+func withdrawNeeds(ctx context.Context, c *liquidity.Checker, o *models.Order) ([]liquidity.Need, error) {
+	amount, err := decimal.NewFromString(*o.FinalOut)
+	if err != nil {
+		return nil, fmt.Errorf("final_out: %w", err)
+	}
+	if c == nil {
+		return []liquidity.Need{{AssetID: o.TargetAsset, Amount: amount}}, nil
+	}
+	return c.WithdrawalNeeds(ctx, o.TargetAsset, amount), nil
+}
+
+func refundNeeds(o *models.Order) ([]liquidity.Need, error) {
+	assetID, amount := refundAssetAmount(o)
+	d, err := decimal.NewFromString(amount)
+	if err != nil {
+		return nil, fmt.Errorf("refund amount: %w", err)
+	}
+	return []liquidity.Need{{AssetID: assetID, Amount: d}}, nil
+}
+
+// ResumeLiquidity moves orders waiting in awaiting_liquidity back to their
+// stage once the wallet covers them. Orders resumed in one call are counted
+// against each other so a single top-up is not promised twice.
+func ResumeLiquidity(ctx context.Context, c *liquidity.Checker, orders store.OrderStore, limit int) error {
+	parked, err := orders.ListAwaitingLiquidity(ctx, limit)
+	if err != nil {
+		return err
+	}
+	var reserved []liquidity.Need
+	for _, o := range parked {
+		if o.RetryStage == nil {
+			continue
+		}
+		stage := models.Stage(*o.RetryStage)
+		ctx := logging.WithStage(ctx, o, stage)
+		var needs []liquidity.Need
+		switch stage {
+		case models.StageWithdraw:
+			if o.FinalOut == nil {
+				continue
+			}
+			needs, err = withdrawNeeds(ctx, c, o)
+		case models.StageRefund:
+			needs, err = refundNeeds(o)
+		default:
+			continue
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "liquidity resume failed", "err", err)
+			continue
+		}
+		short, err := c.Check(ctx, append(append([]liquidity.Need{}, reserved...), needs...)...)
+		if err != nil {
+			return err
+		}
+		if short != nil {
+			continue
+		}
+		ok, err := orders.ResumeFromLiquidity(ctx, o.ID, stage)
+		if err != nil {
+			return err
+		}
+		if ok {
+			slog.InfoContext(ctx, "liquidity resume")
+			reserved = append(reserved, needs...)
+		}
+	}
+	return nil
+}
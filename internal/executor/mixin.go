@@ -0,0 +1,14 @@
+package executor
+
+import (
+	"context"
+
+	bot "github.com/MixinNetwork/bot-api-go-client/v2"
+)
+
+// MixinClient is the subset of mixin.SDKClient the executors need, so tests
+// can substitute a fake instead of signing real safe transactions.
+type MixinClient interface {
+	Transfer(ctx context.Context, assetID, opponentUserID, amount, memo, traceID string) (*bot.SequencerTransactionRequest, error)
+	Withdraw(ctx context.Context, assetID, destination, tag, amount, traceID string) (*bot.SequencerTransactionRequest, error)
+}
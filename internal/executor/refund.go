@@ -3,21 +3,37 @@ package executor
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
+
+	"github.com/shopspring/decimal"
 
-	bot "github.com/MixinNetwork/bot-api-go-client/v2"
-	"github.com/mvg-fi-dev/bridge/internal/db"
 	"github.com/mvg-fi-dev/bridge/internal/ids"
-	"github.com/mvg-fi-dev/bridge/internal/mixin"
+	"github.com/mvg-fi-dev/bridge/internal/ledger"
+	"github.com/mvg-fi-dev/bridge/internal/liquidity"
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/metrics"
 	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/screening"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+	"github.com/mvg-fi-dev/bridge/internal/tracing"
 )
 
 type RefundExecutor struct {
-	Orders *db.OrdersRepo
-	Mixin  *mixin.SDKClient
+	Orders store.OrderStore
+	Mixin  MixinClient
+
+	// Screener, if set, screens the refund recipient before sending.
+	Screener screening.Screener
+
+	// Ledger, if set, records each submitted refund transfer.
+	Ledger *ledger.Ledger
+
+	// Liquidity, if set, parks refunds the wallet cannot cover.
+	Liquidity *liquidity.Checker
 }
 
-func NewRefundExecutor(orders *db.OrdersRepo, mixinClient *mixin.SDKClient) *RefundExecutor {
+func NewRefundExecutor(orders store.OrderStore, mixinClient MixinClient) *RefundExecutor {
 	return &RefundExecutor{Orders: orders, Mixin: mixinClient}
 }
 
@@ -31,28 +47,45 @@ func (e *RefundExecutor) ExecuteRefunding(ctx context.Context, o *models.Order)
 		return fmt.Errorf("missing refund_to_address")
 	}
 
-	assetID := o.SourceAsset
-	amount := ""
-	if o.RefundAssetID != nil && *o.RefundAssetID != "" {
-		assetID = *o.RefundAssetID
-	}
-	if o.RefundAmount != nil && *o.RefundAmount != "" {
-		amount = *o.RefundAmount
-	} else if o.AmountCredited != nil {
-		amount = *o.AmountCredited
-	}
+	assetID, amount := refundAssetAmount(o)
 	if amount == "" {
 		return fmt.Errorf("missing refund amount")
 	}
 
+	held, err := holdIfFlagged(ctx, e.Screener, e.Orders, o, models.StageRefund, senderOf(o))
+	if err != nil || held {
+		return err
+	}
+
+	needs, err := refundNeeds(o)
+	if err != nil {
+		return err
+	}
+	if held, err := holdForLiquidity(ctx, e.Liquidity, e.Orders, o, models.StageRefund, needs); err != nil || held {
+		return err
+	}
+
+	// Same as withdraw: the trace id is stored before the transfer is sent.
 	traceID := ids.DeterministicUUID(o.ID + ":refund")
+	if o.RefundTraceID != nil && *o.RefundTraceID != "" {
+		traceID = *o.RefundTraceID
+	} else if err := e.Orders.SetRefundTraceID(ctx, o.ID, traceID); err != nil {
+		return err
+	}
 	memo := "" // optional; could include reason
-	log.Printf("refund order=%s asset=%s amount=%s to=%s", o.PublicID, assetID, amount, *o.RefundToAddress)
+	ctx = logging.With(ctx, logging.TraceID, traceID)
+	slog.InfoContext(ctx, "refund submit", "asset", assetID, "amount", amount, "to", *o.RefundToAddress)
 
-	resp, err := e.Mixin.Transfer(ctx, assetID, *o.RefundToAddress, amount, memo, traceID)
+	vctx, span := tracing.Start(ctx, "mixin.refund")
+	start := time.Now()
+	resp, err := e.Mixin.Transfer(vctx, assetID, *o.RefundToAddress, amount, memo, traceID)
+	metrics.ObserveVenue("mixin", "refund", start, err)
+	tracing.End(span, err)
 	if err != nil {
 		return err
 	}
+	spend(e.Liquidity, needs)
+	postTransfer(ctx, e.Ledger, o, models.StageRefund, traceID, assetID, needs[0].Amount, decimal.Zero)
 	refundRef := resp.RequestID
 	if refundRef == "" {
 		refundRef = resp.SnapshotID
@@ -63,4 +96,17 @@ func (e *RefundExecutor) ExecuteRefunding(ctx context.Context, o *models.Order)
 	return e.Orders.MarkRefunded(ctx, o.ID, refundRef)
 }
 
-var _ = bot.SequencerTransactionRequest{} // keep import stable if needed
+// refundAssetAmount is what a refund sends back: the refund details recorded
+// on the order (e.g. ExinSwap's refund), else the credited source asset.
+func refundAssetAmount(o *models.Order) (assetID, amount string) {
+	assetID = o.SourceAsset
+	if o.RefundAssetID != nil && *o.RefundAssetID != "" {
+		assetID = *o.RefundAssetID
+	}
+	if o.RefundAmount != nil && *o.RefundAmount != "" {
+		amount = *o.RefundAmount
+	} else if o.AmountCredited != nil {
+		amount = *o.AmountCredited
+	}
+	return assetID, amount
+}
@@ -0,0 +1,135 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	bot "github.com/MixinNetwork/bot-api-go-client/v2"
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/retry"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// ErrorClass buckets executor failures so each class can get its own backoff.
+type ErrorClass string
+
+const (
+	ErrorTransient           ErrorClass = "transient"
+	ErrorInsufficientBalance ErrorClass = "insufficient_balance"
+	ErrorInvalidAddress      ErrorClass = "invalid_address"
+)
+
+// Mixin API error codes we map to non-transient classes.
+const (
+	mixinCodeInsufficientBalance = 20117
+	mixinCodeInsufficientFee     = 20124
+	mixinCodeInvalidAddress      = 30102
+)
+
+// ClassifyError maps an executor error to an ErrorClass.
+// The SDK often wraps API errors with fmt.Errorf("%v"), so we fall back to
+// matching on the message when the typed error is not reachable.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorTransient
+	}
+	var be bot.Error
+	if errors.As(err, &be) {
+		switch be.Code {
+		case mixinCodeInsufficientBalance, mixinCodeInsufficientFee:
+			return ErrorInsufficientBalance
+		case mixinCodeInvalidAddress:
+			return ErrorInvalidAddress
+		}
+	}
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "insufficient"):
+		return ErrorInsufficientBalance
+	case strings.Contains(msg, "invalid address"),
+		strings.Contains(msg, "missing target_address"),
+		strings.Contains(msg, "missing refund_to_address"):
+		return ErrorInvalidAddress
+	}
+	return ErrorTransient
+}
+
+// RetryPolicy is the attempt budget and exponential backoff for one ErrorClass.
+type RetryPolicy = retry.Policy
+
+// DefaultRetryPolicies derives per-class policies from the configured budget.
+// - transient: base delay, doubling up to max
+// - insufficient balance: slower start (ops need time to top up), same budget
+// - invalid address: never going to succeed; straight to manual review
+func DefaultRetryPolicies(maxAttempts int64, base, max time.Duration) map[ErrorClass]RetryPolicy {
+	slow := base * 30
+	if slow > max {
+		slow = max
+	}
+	return map[ErrorClass]RetryPolicy{
+		ErrorTransient:           {MaxAttempts: maxAttempts, BaseDelay: base, MaxDelay: max},
+		ErrorInsufficientBalance: {MaxAttempts: maxAttempts, BaseDelay: slow, MaxDelay: max},
+		ErrorInvalidAddress:      {MaxAttempts: 1, BaseDelay: base, MaxDelay: max},
+	}
+}
+
+// Retrier records executor failures on the order and escalates to
+// failed_manual_review once the attempt budget for the stage is spent.
+type Retrier struct {
+	Orders   store.OrderStore
+	Policies map[ErrorClass]RetryPolicy
+}
+
+func NewRetrier(orders store.OrderStore, policies map[ErrorClass]RetryPolicy) *Retrier {
+	return &Retrier{Orders: orders, Policies: policies}
+}
+
+// Fail is called by the worker after an executor returned err for order o in
+// stage. The order is re-read first: if the executor already moved it out of
+// the stage (e.g. a failed swap claim that refunded), there is nothing to
+// retry, and the attempts of an earlier stage never count against the next.
+func (r *Retrier) Fail(ctx context.Context, o *models.Order, stage models.Stage, err error) error {
+	o, gerr := r.Orders.GetByID(ctx, o.ID)
+	if gerr != nil {
+		return gerr
+	}
+	if !inStage(o.Status, stage) {
+		return nil
+	}
+
+	attempts := int64(1)
+	if o.RetryStage != nil && *o.RetryStage == string(stage) {
+		attempts = o.Attempts + 1
+	}
+
+	class := ClassifyError(err)
+	policy, ok := r.Policies[class]
+	if !ok {
+		policy = r.Policies[ErrorTransient]
+	}
+	msg := string(class) + ": " + err.Error()
+
+	if attempts >= policy.MaxAttempts {
+		slog.ErrorContext(ctx, "retries exhausted; moving to "+string(models.StatusFailedManual), logging.Stage, string(stage), "attempt", attempts, "class", string(class), "err", err)
+		return r.Orders.MarkFailedManual(ctx, o.ID, o.Status, stage, attempts, msg)
+	}
+
+	delay := policy.Delay(attempts)
+	slog.WarnContext(ctx, "retry scheduled", logging.Stage, string(stage), "attempt", attempts, "class", string(class), "next_in", delay.String(), "err", err)
+	return r.Orders.ScheduleRetry(ctx, o.ID, o.Status, stage, attempts, msg, time.Now().UTC().Add(delay))
+}
+
+// inStage reports whether an order in st is still being worked on in stage.
+// A swap stays in its stage while executing_swap, since its transfer is
+// resubmitted under the same trace.
+func inStage(st models.OrderStatus, stage models.Stage) bool {
+	if st == models.StatusExecutingSwap {
+		return stage == models.StageSwap
+	}
+	s, ok := models.StageForStatus(st)
+	return ok && s == stage
+}
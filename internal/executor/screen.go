@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/screening"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// screenedAddress is one address an executor must screen before paying it.
+type screenedAddress struct {
+	Role    string // target_address, sender
+	Chain   string
+	Address string
+}
+
+// holdIfFlagged screens addrs and freezes o in compliance_hold on the first
+// hit. It returns held=true if the caller must not move funds. Screening
+// errors are returned so the attempt is retried rather than paid out.
+func holdIfFlagged(ctx context.Context, s screening.Screener, orders store.OrderStore, o *models.Order, stage models.Stage, addrs ...screenedAddress) (bool, error) {
+	if s == nil || o.ScreeningClearedAt != nil {
+		return false, nil
+	}
+	for _, a := range addrs {
+		if a.Address == "" {
+			continue
+		}
+		r, err := s.Screen(ctx, a.Chain, a.Address)
+		if err != nil {
+			return false, fmt.Errorf("screen %s: %w", a.Role, err)
+		}
+		if !r.Hit {
+			continue
+		}
+		reason := fmt.Sprintf("%s flagged by %s: %s", a.Role, r.Source, r.Reason)
+		slog.WarnContext(ctx, "compliance hold", logging.Stage, string(stage), "reason", reason)
+		if _, err := orders.MarkComplianceHold(ctx, o.ID, o.Status, stage, reason); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func senderOf(o *models.Order) screenedAddress {
+	a := screenedAddress{Role: "sender", Chain: "MIXIN"}
+	if o.RefundToAddress != nil {
+		a.Address = *o.RefundToAddress
+	}
+	return a
+}
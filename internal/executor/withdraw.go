@@ -3,20 +3,38 @@ package executor
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
+
+	"github.com/shopspring/decimal"
 
-	"github.com/mvg-fi-dev/bridge/internal/db"
 	"github.com/mvg-fi-dev/bridge/internal/ids"
-	"github.com/mvg-fi-dev/bridge/internal/mixin"
+	"github.com/mvg-fi-dev/bridge/internal/ledger"
+	"github.com/mvg-fi-dev/bridge/internal/liquidity"
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/metrics"
 	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/screening"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+	"github.com/mvg-fi-dev/bridge/internal/tracing"
 )
 
 type WithdrawExecutor struct {
-	Orders *db.OrdersRepo
-	Mixin  *mixin.SDKClient
+	Orders store.OrderStore
+	Mixin  MixinClient
+
+	// Screener, if set, screens the target address and sender before paying out.
+	Screener screening.Screener
+
+	// Ledger, if set, records each submitted withdrawal.
+	Ledger *ledger.Ledger
+
+	// Liquidity, if set, parks withdrawals the wallet (or the chain's fee
+	// asset) cannot cover.
+	Liquidity *liquidity.Checker
 }
 
-func NewWithdrawExecutor(orders *db.OrdersRepo, mixinClient *mixin.SDKClient) *WithdrawExecutor {
+func NewWithdrawExecutor(orders store.OrderStore, mixinClient MixinClient) *WithdrawExecutor {
 	return &WithdrawExecutor{Orders: orders, Mixin: mixinClient}
 }
 
@@ -36,12 +54,42 @@ func (e *WithdrawExecutor) ExecuteWithdrawing(ctx context.Context, o *models.Ord
 		return fmt.Errorf("missing target_address")
 	}
 
+	held, err := holdIfFlagged(ctx, e.Screener, e.Orders, o, models.StageWithdraw,
+		screenedAddress{Role: "target_address", Chain: o.TargetChain, Address: o.TargetAddress},
+		senderOf(o),
+	)
+	if err != nil || held {
+		return err
+	}
+
+	needs, err := withdrawNeeds(ctx, e.Liquidity, o)
+	if err != nil {
+		return err
+	}
+	if held, err := holdForLiquidity(ctx, e.Liquidity, e.Orders, o, models.StageWithdraw, needs); err != nil || held {
+		return err
+	}
+
+	// Persist the trace id before submitting so a crash between the Mixin call
+	// and MarkCompleted retries under the same (idempotent) trace.
 	traceID := ids.DeterministicUUID(o.ID + ":withdraw")
-	log.Printf("withdraw order=%s asset=%s amount=%s dest=%s", o.PublicID, o.TargetAsset, *o.FinalOut, o.TargetAddress)
-	resp, err := e.Mixin.Withdraw(ctx, o.TargetAsset, o.TargetAddress, "", *o.FinalOut, traceID)
+	if o.WithdrawTraceID != nil && *o.WithdrawTraceID != "" {
+		traceID = *o.WithdrawTraceID
+	} else if err := e.Orders.SetWithdrawTraceID(ctx, o.ID, traceID); err != nil {
+		return err
+	}
+	ctx = logging.With(ctx, logging.TraceID, traceID)
+	slog.InfoContext(ctx, "withdraw submit", "asset", o.TargetAsset, "amount", *o.FinalOut, "dest", o.TargetAddress)
+	vctx, span := tracing.Start(ctx, "mixin.withdraw")
+	start := time.Now()
+	resp, err := e.Mixin.Withdraw(vctx, o.TargetAsset, o.TargetAddress, "", *o.FinalOut, traceID)
+	metrics.ObserveVenue("mixin", "withdraw", start, err)
+	tracing.End(span, err)
 	if err != nil {
 		return err
 	}
+	spend(e.Liquidity, needs)
+	postTransfer(ctx, e.Ledger, o, models.StageWithdraw, traceID, o.TargetAsset, needs[0].Amount, decimal.Zero)
 	withdrawRef := resp.RequestID
 	if withdrawRef == "" {
 		withdrawRef = resp.SnapshotID
@@ -49,5 +97,9 @@ func (e *WithdrawExecutor) ExecuteWithdrawing(ctx context.Context, o *models.Ord
 	if withdrawRef == "" {
 		withdrawRef = traceID
 	}
-	return e.Orders.MarkCompleted(ctx, o.ID, withdrawRef)
+	if err := e.Orders.MarkCompleted(ctx, o.ID, withdrawRef); err != nil {
+		return err
+	}
+	metrics.ObserveWithdrawSubmitted(o, time.Now().UTC())
+	return nil
 }
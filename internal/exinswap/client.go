@@ -7,6 +7,8 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/tracing"
 )
 
 type Client struct {
@@ -17,7 +19,7 @@ type Client struct {
 func NewClient() *Client {
 	return &Client{
 		BaseURL: "https://app.exinswap.com/api/v2",
-		HTTP:    &http.Client{Timeout: 15 * time.Second},
+		HTTP:    &http.Client{Timeout: 15 * time.Second, Transport: tracing.Transport(nil)},
 	}
 }
 
@@ -0,0 +1,151 @@
+// Package fees holds the broker fee schedule.
+//
+// The fee is charged in the source asset: a percentage spread of the credited
+// amount plus an optional flat amount per source asset. Terms are fixed on
+// the order at quote time (so later schedule changes do not affect it) and
+// the fee is deducted from the credited amount before the swap.
+//
+// The schedule lives in the kv table (key "fees") and is edited via the admin
+// API, like the limits document.
+package fees
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+const kvKey = "fees"
+
+// Mixin amounts have 8 decimal places; fees are rounded down to that.
+const amountPlaces = 8
+
+// Schedule is the fee document.
+type Schedule struct {
+	SpreadBps int64             `json:"spread_bps"`
+	Flat      map[string]string `json:"flat,omitempty"` // source asset id -> amount
+
+	// Per-partner overrides; unset fields fall back to the defaults above.
+	Partners map[string]Override `json:"partners,omitempty"`
+}
+
+type Override struct {
+	SpreadBps *int64            `json:"spread_bps,omitempty"`
+	Flat      map[string]string `json:"flat,omitempty"`
+}
+
+func (s *Schedule) Validate() error {
+	if err := validate("", s.SpreadBps, s.Flat); err != nil {
+		return err
+	}
+	for id, o := range s.Partners {
+		bps := int64(0)
+		if o.SpreadBps != nil {
+			bps = *o.SpreadBps
+		}
+		if err := validate("partners."+id+".", bps, o.Flat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validate(prefix string, bps int64, flat map[string]string) error {
+	if bps < 0 || bps >= 10000 {
+		return fmt.Errorf("%sspread_bps must be in [0, 10000)", prefix)
+	}
+	for asset, v := range flat {
+		if d, err := decimal.NewFromString(v); err != nil || d.IsNegative() {
+			return fmt.Errorf("%sflat.%s: must be a non-negative decimal", prefix, asset)
+		}
+	}
+	return nil
+}
+
+// Load returns the stored schedule (no fees if none was set).
+func Load(ctx context.Context, kv store.KVStore) (*Schedule, error) {
+	v, ok, err := kv.Get(ctx, kvKey)
+	if err != nil {
+		return nil, fmt.Errorf("load fees: %w", err)
+	}
+	s := &Schedule{}
+	if !ok {
+		return s, nil
+	}
+	if err := json.Unmarshal([]byte(v), s); err != nil {
+		return nil, fmt.Errorf("load fees: %w", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("load fees: %w", err)
+	}
+	return s, nil
+}
+
+func Save(ctx context.Context, kv store.KVStore, s *Schedule) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return kv.Set(ctx, kvKey, string(raw))
+}
+
+// Terms are the fee terms fixed on one order.
+type Terms struct {
+	SpreadBps int64
+	Flat      decimal.Decimal
+}
+
+// TermsFor resolves the terms for an order from partnerID (may be empty)
+// in sourceAsset.
+func (s *Schedule) TermsFor(partnerID, sourceAsset string) Terms {
+	t := Terms{SpreadBps: s.SpreadBps, Flat: flat(s.Flat, sourceAsset)}
+	if o, ok := s.Partners[partnerID]; ok && partnerID != "" {
+		if o.SpreadBps != nil {
+			t.SpreadBps = *o.SpreadBps
+		}
+		if _, ok := o.Flat[sourceAsset]; ok {
+			t.Flat = flat(o.Flat, sourceAsset)
+		}
+	}
+	return t
+}
+
+func flat(m map[string]string, asset string) decimal.Decimal {
+	d, err := decimal.NewFromString(m[asset])
+	if err != nil {
+		return decimal.Zero
+	}
+	return d
+}
+
+// Fee returns the fee on amount. It may exceed amount for tiny orders;
+// callers reject or refund those.
+func (t Terms) Fee(amount decimal.Decimal) decimal.Decimal {
+	spread := amount.Mul(decimal.NewFromInt(t.SpreadBps)).Div(decimal.NewFromInt(10000))
+	return spread.Add(t.Flat).Truncate(amountPlaces)
+}
+
+// PartnerShare is the part of fee owed to a partner with shareBps.
+func PartnerShare(fee decimal.Decimal, shareBps int64) decimal.Decimal {
+	return fee.Mul(decimal.NewFromInt(shareBps)).Div(decimal.NewFromInt(10000)).Truncate(amountPlaces)
+}
+
+// Net scales a quoted output for the gross amount down to what the amount
+// left after fee buys.
+func Net(out string, gross, fee decimal.Decimal) (string, error) {
+	d, err := decimal.NewFromString(out)
+	if err != nil {
+		return "", err
+	}
+	if fee.IsZero() {
+		return out, nil
+	}
+	return d.Mul(gross.Sub(fee)).Div(gross).Truncate(amountPlaces).String(), nil
+}
@@ -0,0 +1,133 @@
+package fees
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/store/memstore"
+)
+
+func d(s string) decimal.Decimal { return decimal.RequireFromString(s) }
+
+func TestFee(t *testing.T) {
+	for _, tc := range []struct {
+		terms  Terms
+		amount string
+		want   string
+	}{
+		{Terms{}, "1", "0"},
+		{Terms{SpreadBps: 30}, "1", "0.003"},
+		{Terms{Flat: d("0.5")}, "10", "0.5"},
+		{Terms{SpreadBps: 100, Flat: d("0.25")}, "10", "0.35"},
+		// Rounded down to 8 places: 0.12345678 * 0.003 = 0.00037037034.
+		{Terms{SpreadBps: 30}, "0.12345678", "0.00037037"},
+		{Terms{SpreadBps: 1}, "0.00000099", "0"},
+		// A flat fee can exceed a tiny amount; callers refuse those.
+		{Terms{Flat: d("1")}, "0.1", "1"},
+	} {
+		if got := tc.terms.Fee(d(tc.amount)); !got.Equal(d(tc.want)) {
+			t.Errorf("%+v.Fee(%s) = %s, want %s", tc.terms, tc.amount, got, tc.want)
+		}
+	}
+}
+
+func TestPartnerShare(t *testing.T) {
+	for _, tc := range []struct {
+		fee  string
+		bps  int64
+		want string
+	}{
+		{"0.003", 5000, "0.0015"},
+		{"0.003", 0, "0"},
+		{"0.003", 10000, "0.003"},
+		{"0.00000003", 5000, "0.00000001"}, // rounded down, never above the fee
+	} {
+		if got := PartnerShare(d(tc.fee), tc.bps); !got.Equal(d(tc.want)) {
+			t.Errorf("PartnerShare(%s, %d) = %s, want %s", tc.fee, tc.bps, got, tc.want)
+		}
+	}
+}
+
+func TestNet(t *testing.T) {
+	for _, tc := range []struct{ out, gross, fee, want string }{
+		{"2", "1", "0", "2"},
+		{"2", "1", "0.003", "1.994"},
+		{"1", "3", "1", "0.66666666"},
+	} {
+		got, err := Net(tc.out, d(tc.gross), d(tc.fee))
+		if err != nil || got != tc.want {
+			t.Errorf("Net(%s, %s, %s) = %s, %v; want %s", tc.out, tc.gross, tc.fee, got, err, tc.want)
+		}
+	}
+	if _, err := Net("x", d("1"), d("0.1")); err == nil {
+		t.Error("Net accepted a non-decimal quote")
+	}
+}
+
+func TestTermsFor(t *testing.T) {
+	bps := int64(10)
+	s := &Schedule{
+		SpreadBps: 30,
+		Flat:      map[string]string{"btc": "0.0001", "eth": "0.001"},
+		Partners: map[string]Override{
+			"p1": {SpreadBps: &bps, Flat: map[string]string{"btc": "0"}},
+			"p2": {Flat: map[string]string{"eth": "0.002"}},
+		},
+	}
+	for _, tc := range []struct {
+		partner, asset string
+		bps            int64
+		flat           string
+	}{
+		{"", "btc", 30, "0.0001"},
+		{"", "usdt", 30, "0"},
+		{"unknown", "eth", 30, "0.001"},
+		{"p1", "btc", 10, "0"},     // both overridden
+		{"p1", "eth", 10, "0.001"}, // flat falls back to the default
+		{"p2", "btc", 30, "0.0001"},
+		{"p2", "eth", 30, "0.002"},
+	} {
+		got := s.TermsFor(tc.partner, tc.asset)
+		if got.SpreadBps != tc.bps || !got.Flat.Equal(d(tc.flat)) {
+			t.Errorf("TermsFor(%q, %s) = %d bps + %s, want %d + %s", tc.partner, tc.asset, got.SpreadBps, got.Flat, tc.bps, tc.flat)
+		}
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	ctx := context.Background()
+	kv := memstore.NewKV()
+	if s, err := Load(ctx, kv); err != nil || s.SpreadBps != 0 || len(s.Flat) != 0 {
+		t.Fatalf("empty kv: %+v %v, want no fees", s, err)
+	}
+
+	neg := int64(-1)
+	for name, bad := range map[string]*Schedule{
+		"spread too high":  {SpreadBps: 10000},
+		"negative flat":    {Flat: map[string]string{"btc": "-1"}},
+		"not a decimal":    {Flat: map[string]string{"btc": "one"}},
+		"partner negative": {Partners: map[string]Override{"p1": {SpreadBps: &neg}}},
+	} {
+		if err := Save(ctx, kv, bad); err == nil {
+			t.Errorf("%s: saved", name)
+		}
+	}
+
+	if err := Save(ctx, kv, &Schedule{SpreadBps: 25, Flat: map[string]string{"btc": "0.0001"}}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(ctx, kv)
+	if err != nil || s.SpreadBps != 25 || s.Flat["btc"] != "0.0001" {
+		t.Fatalf("Load = %+v %v", s, err)
+	}
+
+	// A corrupt document fails loudly rather than charging nothing.
+	if err := kv.Set(ctx, kvKey, `{"spread_bps": 20000}`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(ctx, kv); err == nil {
+		t.Error("loaded an invalid schedule")
+	}
+}
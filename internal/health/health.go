@@ -0,0 +1,157 @@
+// Package health runs the readiness checks served by both binaries.
+//
+// A check is critical when its failure should take the binary out of
+// rotation (or get the worker restarted): DB, schema version, keystore and,
+// for the worker, a stale snapshot poll. Venue reachability and kill-switch
+// state are reported for operators but never fail readiness: restarting does
+// not bring Mixin back, and a paused stage is a deliberate state.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/db"
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/metrics"
+	"github.com/mvg-fi-dev/bridge/internal/mixin"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+	"github.com/mvg-fi-dev/bridge/internal/switches"
+)
+
+// checkTimeout bounds each check so one hung dependency cannot hang the probe.
+const checkTimeout = 3 * time.Second
+
+// Check is one named probe; Run returns details to show and an error if the
+// check failed.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) (any, error)
+}
+
+// Result is the outcome of one Check.
+type Result struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Critical bool   `json:"critical"`
+	Detail   any    `json:"detail,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Report is what /readyz returns. OK is false if any critical check failed.
+type Report struct {
+	OK        bool      `json:"ok"`
+	CheckedAt time.Time `json:"checked_at"`
+	Checks    []Result  `json:"checks"`
+}
+
+// Run runs checks concurrently and collects their results in order.
+func Run(ctx context.Context, checks ...Check) *Report {
+	rep := &Report{OK: true, CheckedAt: time.Now().UTC(), Checks: make([]Result, len(checks))}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c Check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			detail, err := c.Run(ctx)
+			r := Result{Name: c.Name, OK: err == nil, Critical: c.Critical, Detail: detail}
+			if err != nil {
+				r.Error = logging.Scrub(err.Error())
+			}
+			rep.Checks[i] = r
+		}(i, c)
+	}
+	wg.Wait()
+	for _, r := range rep.Checks {
+		if r.Critical && !r.OK {
+			rep.OK = false
+		}
+	}
+	return rep
+}
+
+// Handler serves Run(checks) as JSON: 200 when OK, 503 otherwise.
+func Handler(checks ...Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rep := Run(r.Context(), checks...)
+		w.Header().Set("Content-Type", "application/json")
+		if !rep.OK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(rep)
+	})
+}
+
+// DB pings the database.
+func DB(d *db.DB) Check {
+	return Check{Name: "db", Critical: true, Run: func(ctx context.Context) (any, error) {
+		return nil, d.SQL.PingContext(ctx)
+	}}
+}
+
+// Migrations fails unless the schema is at the version this binary embeds.
+func Migrations(d *db.DB) Check {
+	return Check{Name: "migrations", Critical: true, Run: func(ctx context.Context) (any, error) {
+		current, latest, err := db.MigrationVersion(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+		detail := map[string]int64{"current": current, "latest": latest}
+		if current != latest {
+			return detail, fmt.Errorf("schema at version %d, binary expects %d", current, latest)
+		}
+		return detail, nil
+	}}
+}
+
+// Keystore checks the bot keystore has what polling and payouts need.
+func Keystore(ks *mixin.SafeKeystore) Check {
+	return Check{Name: "keystore", Critical: true, Run: func(ctx context.Context) (any, error) {
+		if ks == nil {
+			return nil, fmt.Errorf("no keystore loaded")
+		}
+		if err := ks.Validate(); err != nil {
+			return nil, err
+		}
+		// Never return ks itself: it would be encoded with its keys.
+		detail := map[string]string{"user_id": ks.UserID, "session_id": ks.SessionID}
+		if ks.SpendPrivateKey == "" {
+			return detail, fmt.Errorf("keystore missing spend_private_key (needed for withdrawals)")
+		}
+		return detail, nil
+	}}
+}
+
+// Switches reports which chains, assets and stages are switched off.
+func Switches(kv store.KVStore) Check {
+	return Check{Name: "switches", Run: func(ctx context.Context) (any, error) {
+		return switches.Load(ctx, kv)
+	}}
+}
+
+// Venues reports the outcome of the latest call to each venue.
+func Venues() Check {
+	return Check{Name: "venues", Run: func(ctx context.Context) (any, error) {
+		seen := metrics.Venues()
+		var down []string
+		for name, v := range seen {
+			if !v.Reachable() {
+				down = append(down, name)
+			}
+		}
+		if len(down) > 0 {
+			sort.Strings(down)
+			return seen, fmt.Errorf("last call failed: %s", strings.Join(down, ", "))
+		}
+		return seen, nil
+	}}
+}
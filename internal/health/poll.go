@@ -0,0 +1,138 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// heartbeatPrefix keys each worker's last successful poll in the kv table, so
+// the API can report on the pollers too.
+const heartbeatPrefix = "health.worker."
+
+// Heartbeat is a worker's last successful snapshot poll.
+type Heartbeat struct {
+	WorkerID   string    `json:"worker_id"`
+	LastPollAt time.Time `json:"last_poll_at"`
+}
+
+// Poll tracks the worker's last successful snapshot poll. The zero value is
+// ready to use.
+type Poll struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+// Record notes a successful poll at t.
+func (p *Poll) Record(t time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.last = t
+}
+
+// Last returns the time of the last successful poll (zero if none yet).
+func (p *Poll) Last() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.last
+}
+
+// Check fails once no poll has succeeded for maxAge. started is when polling
+// began, so a worker gets maxAge to complete its first poll.
+func (p *Poll) Check(started time.Time, maxAge time.Duration) Check {
+	return Check{Name: "snapshot_poll", Critical: true, Run: func(ctx context.Context) (any, error) {
+		last := p.Last()
+		since := last
+		if since.IsZero() {
+			since = started
+		}
+		age := time.Since(since)
+		detail := map[string]any{"max_age_seconds": int64(maxAge.Seconds())}
+		if !last.IsZero() {
+			detail["last_poll_at"] = last.UTC()
+			detail["age_seconds"] = int64(age.Seconds())
+		}
+		if age > maxAge {
+			return detail, fmt.Errorf("no successful snapshot poll for %s", age.Truncate(time.Second))
+		}
+		return detail, nil
+	}}
+}
+
+// heartbeatExpiry is how many poll max-ages a heartbeat outlives its
+// worker. WORKER_ID defaults to hostname-pid, so every restart leaves one
+// behind.
+const heartbeatExpiry = 10
+
+// SaveHeartbeat stores hb for the API's readiness report and deletes the
+// heartbeats of workers that have not polled for heartbeatExpiry × maxAge
+// (restarted or scaled away). The last heartbeats survive when every worker
+// is down, since only a polling worker expires them.
+func SaveHeartbeat(ctx context.Context, kv store.KVStore, hb Heartbeat, maxAge time.Duration) error {
+	raw, err := json.Marshal(hb)
+	if err != nil {
+		return err
+	}
+	if err := kv.Set(ctx, heartbeatPrefix+hb.WorkerID, string(raw)); err != nil || maxAge <= 0 {
+		return err
+	}
+	hbs, _, err := LoadHeartbeats(ctx, kv)
+	if err != nil {
+		return err
+	}
+	cutoff := hb.LastPollAt.Add(-heartbeatExpiry * maxAge)
+	for _, old := range hbs {
+		if old.WorkerID != hb.WorkerID && old.LastPollAt.Before(cutoff) {
+			if err := kv.Delete(ctx, heartbeatPrefix+old.WorkerID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LoadHeartbeats returns every worker's last poll and the freshest poll
+// time (zero if none was stored yet).
+func LoadHeartbeats(ctx context.Context, kv store.KVStore) ([]Heartbeat, time.Time, error) {
+	rows, err := kv.List(ctx, heartbeatPrefix)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	hbs := make([]Heartbeat, 0, len(rows))
+	var newest time.Time
+	for k, v := range rows {
+		var hb Heartbeat
+		if err := json.Unmarshal([]byte(v), &hb); err != nil {
+			return nil, time.Time{}, fmt.Errorf("heartbeat %s: %w", strings.TrimPrefix(k, heartbeatPrefix), err)
+		}
+		hbs = append(hbs, hb)
+		if hb.LastPollAt.After(newest) {
+			newest = hb.LastPollAt
+		}
+	}
+	return hbs, newest, nil
+}
+
+// Heartbeats reports every worker's last poll and fails if none polled
+// within maxAge. Heartbeats of replicas that were scaled away linger until
+// SaveHeartbeat expires them, so only the freshest one decides.
+func Heartbeats(kv store.KVStore, maxAge time.Duration) Check {
+	return Check{Name: "worker_poll", Run: func(ctx context.Context) (any, error) {
+		hbs, newest, err := LoadHeartbeats(ctx, kv)
+		if err != nil {
+			return nil, err
+		}
+		if newest.IsZero() {
+			return hbs, fmt.Errorf("no worker has polled yet")
+		}
+		if age := time.Since(newest); age > maxAge {
+			return hbs, fmt.Errorf("no worker polled for %s", age.Truncate(time.Second))
+		}
+		return hbs, nil
+	}}
+}
@@ -0,0 +1,50 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/store/memstore"
+)
+
+func TestSaveHeartbeatExpiresStaleWorkers(t *testing.T) {
+	ctx := context.Background()
+	kv := memstore.NewKV()
+	maxAge := time.Minute
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	save := func(id string, at time.Time) {
+		t.Helper()
+		if err := SaveHeartbeat(ctx, kv, Heartbeat{WorkerID: id, LastPollAt: at}, maxAge); err != nil {
+			t.Fatal(err)
+		}
+	}
+	workers := func() string {
+		t.Helper()
+		hbs, _, err := LoadHeartbeats(ctx, kv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, hb := range hbs {
+			ids = append(ids, hb.WorkerID)
+		}
+		sort.Strings(ids)
+		return strings.Join(ids, " ")
+	}
+
+	save("host-1", now.Add(-heartbeatExpiry*maxAge-time.Second)) // a previous process
+	save("host-2", now.Add(-heartbeatExpiry*maxAge+time.Second)) // a slow replica
+	save("host-3", now)
+	if got := workers(); got != "host-2 host-3" {
+		t.Errorf("workers = %q, want the expired host-1 gone", got)
+	}
+
+	// A worker never expires its own heartbeat, however old the others are.
+	save("host-3", now.Add(time.Hour))
+	if got := workers(); got != "host-3" {
+		t.Errorf("workers = %q", got)
+	}
+}
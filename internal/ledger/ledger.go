@@ -0,0 +1,336 @@
+// Package ledger keeps a double-entry record of every movement through the
+// bot wallet.
+//
+// Amounts are signed per account: positive means the account holds funds.
+// Accounts inside the wallet are order:<id>, fees, unallocated and outbound
+// (sent but not yet seen as a snapshot); venue:<name> is funds held by a
+// swap venue on our behalf and external is everyone else. Every journal sums
+// to zero per asset, so the wallet balance of an asset is the sum of its
+// wallet accounts.
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/exinswap"
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/mixin"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// Accounts.
+const (
+	AccountFees        = "fees"
+	AccountUnallocated = "unallocated"
+	AccountOutbound    = "outbound"
+	AccountExternal    = "external"
+
+	orderPrefix = "order:"
+	venuePrefix = "venue:"
+
+	VenueExinSwap = "exinswap"
+)
+
+// Journal kinds. Submit journals are keyed by transfer trace id, the rest by
+// snapshot id.
+const (
+	KindDeposit        = "deposit"
+	KindSwapSubmit     = "swap_submit"
+	KindWithdrawSubmit = "withdraw_submit"
+	KindRefundSubmit   = "refund_submit"
+	KindSettle         = "settle"
+	KindVenueRelease   = "venue_release"
+	KindVenueRefund    = "venue_refund"
+	KindUnallocatedIn  = "unallocated_in"
+	KindUnallocatedOut = "unallocated_out"
+)
+
+// OrderAccount is the account holding an order's funds.
+func OrderAccount(orderID string) string { return orderPrefix + orderID }
+
+// VenueAccount is the account for funds sent to a swap venue.
+func VenueAccount(name string) string { return venuePrefix + name }
+
+// WalletAccount reports whether account is held in the bot wallet.
+func WalletAccount(account string) bool {
+	return strings.HasPrefix(account, orderPrefix) ||
+		account == AccountFees || account == AccountUnallocated || account == AccountOutbound
+}
+
+type Ledger struct {
+	Store  store.LedgerStore
+	Orders store.OrderStore
+
+	// ExinSwapUserID is the ExinSwap bot; transfers to and from it move
+	// funds through venue:exinswap.
+	ExinSwapUserID string
+
+	Now func() time.Time
+}
+
+func New(s store.LedgerStore, orders store.OrderStore, exinSwapUserID string) *Ledger {
+	return &Ledger{Store: s, Orders: orders, ExinSwapUserID: exinSwapUserID, Now: time.Now}
+}
+
+type leg struct {
+	account string
+	asset   string
+	amount  decimal.Decimal
+}
+
+// post writes one balanced journal; zero legs are dropped. Reposting the same
+// (kind, ref) is a no-op.
+func (l *Ledger) post(ctx context.Context, kind, ref, orderID string, legs ...leg) error {
+	sums := map[string]decimal.Decimal{}
+	j := &models.LedgerJournal{
+		ID:        uuid.NewString(),
+		Kind:      kind,
+		Ref:       ref,
+		CreatedAt: l.Now().UTC(),
+	}
+	if orderID != "" {
+		j.OrderID = &orderID
+	}
+	for _, g := range legs {
+		if g.amount.IsZero() {
+			continue
+		}
+		sums[g.asset] = sums[g.asset].Add(g.amount)
+		j.Entries = append(j.Entries, &models.LedgerEntry{
+			ID:      uuid.NewString(),
+			Account: g.account,
+			AssetID: g.asset,
+			Amount:  g.amount.String(),
+		})
+	}
+	for asset, sum := range sums {
+		if !sum.IsZero() {
+			return fmt.Errorf("ledger %s %s: unbalanced by %s in asset %s", kind, ref, sum, asset)
+		}
+	}
+	if len(j.Entries) == 0 {
+		return nil
+	}
+	if _, err := l.Store.PostJournal(ctx, j); err != nil {
+		return fmt.Errorf("ledger %s %s: %w", kind, ref, err)
+	}
+	return nil
+}
+
+// PostTransfer records a transfer submitted for o in the given stage: the
+// amount (plus any fee kept from it) leaves the order and waits in outbound
+// until its snapshot is seen.
+func (l *Ledger) PostTransfer(ctx context.Context, o *models.Order, stage models.Stage, traceID, assetID string, amount, fee decimal.Decimal) error {
+	var kind string
+	switch stage {
+	case models.StageSwap:
+		kind = KindSwapSubmit
+	case models.StageWithdraw:
+		kind = KindWithdrawSubmit
+	case models.StageRefund:
+		kind = KindRefundSubmit
+	default:
+		return fmt.Errorf("ledger: unknown stage %q", stage)
+	}
+	return l.post(ctx, kind, traceID, o.ID,
+		leg{OrderAccount(o.ID), assetID, amount.Add(fee).Neg()},
+		leg{AccountFees, assetID, fee},
+		leg{AccountOutbound, assetID, amount},
+	)
+}
+
+// PostSnapshot records one wallet snapshot. Callers run it in the same
+// transaction that ingests the snapshot and applies its order updates, so
+// the orders it looks up are current.
+func (l *Ledger) PostSnapshot(ctx context.Context, s *mixin.Snapshot) error {
+	if s == nil {
+		return nil
+	}
+	amount, err := decimal.NewFromString(s.Amount)
+	if err != nil {
+		return fmt.Errorf("ledger: snapshot %s amount %q: %w", s.SnapshotID, s.Amount, err)
+	}
+	if amount.IsNegative() {
+		return l.postOutflow(ctx, s, amount.Neg())
+	}
+	if amount.IsPositive() {
+		return l.postInflow(ctx, s, amount)
+	}
+	return nil
+}
+
+// counterparty is the account receiving funds sent to userID.
+func (l *Ledger) counterparty(userID string) string {
+	if userID != "" && userID == l.ExinSwapUserID {
+		return VenueAccount(VenueExinSwap)
+	}
+	return AccountExternal
+}
+
+func (l *Ledger) postOutflow(ctx context.Context, s *mixin.Snapshot, amount decimal.Decimal) error {
+	var o *models.Order
+	if s.TraceID != "" {
+		var err error
+		o, err = l.Orders.GetByTransferTraceID(ctx, s.TraceID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+	}
+	if o == nil {
+		slog.WarnContext(ctx, "ledger unallocated outflow", logging.SnapshotID, s.SnapshotID, logging.TraceID, s.TraceID, "asset", s.AssetID, "amount", amount.String())
+		return l.post(ctx, KindUnallocatedOut, s.SnapshotID, "",
+			leg{AccountUnallocated, s.AssetID, amount.Neg()},
+			leg{l.counterparty(s.OpponentID), s.AssetID, amount},
+		)
+	}
+
+	// The submit journal is normally posted by the executor; post it here too
+	// (a no-op if it exists) in case the process died right after the call.
+	stage := models.StageRefund
+	fee := decimal.Zero
+	if o.ExinSwapTraceID != nil && *o.ExinSwapTraceID == s.TraceID {
+		stage = models.StageSwap
+		if o.FeeAmount != nil {
+			fee, _ = decimal.NewFromString(*o.FeeAmount)
+		}
+	} else if o.WithdrawTraceID != nil && *o.WithdrawTraceID == s.TraceID {
+		stage = models.StageWithdraw
+	}
+	if err := l.PostTransfer(ctx, o, stage, s.TraceID, s.AssetID, amount, fee); err != nil {
+		return err
+	}
+	return l.post(ctx, KindSettle, s.SnapshotID, o.ID,
+		leg{AccountOutbound, s.AssetID, amount.Neg()},
+		leg{l.counterparty(s.OpponentID), s.AssetID, amount},
+	)
+}
+
+func (l *Ledger) postInflow(ctx context.Context, s *mixin.Snapshot, amount decimal.Decimal) error {
+	if s.OpponentID != "" && s.OpponentID == l.ExinSwapUserID {
+		done, err := l.postVenueReply(ctx, s, amount)
+		if err != nil || done {
+			return err
+		}
+	}
+
+	o, err := l.Orders.GetByDepositTxID(ctx, s.SnapshotID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	if o != nil {
+		return l.post(ctx, KindDeposit, s.SnapshotID, o.ID,
+			leg{AccountExternal, s.AssetID, amount.Neg()},
+			leg{OrderAccount(o.ID), s.AssetID, amount},
+		)
+	}
+
+	slog.WarnContext(ctx, "ledger unallocated inflow", logging.SnapshotID, s.SnapshotID, "asset", s.AssetID, "amount", amount.String(), "from", s.OpponentID)
+	return l.post(ctx, KindUnallocatedIn, s.SnapshotID, "",
+		leg{l.counterparty(s.OpponentID), s.AssetID, amount.Neg()},
+		leg{AccountUnallocated, s.AssetID, amount},
+	)
+}
+
+// postVenueReply books an ExinSwap release (RL) or refund (RF). The amount
+// sent to the venue is cleared from venue:exinswap; on a refund the fee kept
+// at swap time goes back to the order, which refunds it too.
+func (l *Ledger) postVenueReply(ctx context.Context, s *mixin.Snapshot, amount decimal.Decimal) (bool, error) {
+	memo, err := exinswap.ParseServerMemo(s.Memo)
+	if err != nil || (memo.Type != "RL" && memo.Type != "RF") {
+		return false, nil
+	}
+	o, err := l.Orders.GetByExinSwapTraceID(ctx, memo.Trace)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	venue := VenueAccount(VenueExinSwap)
+	var sent, fee decimal.Decimal
+	sentAsset, feeAsset := o.SourceAsset, o.SourceAsset
+	j, err := l.Store.GetJournal(ctx, KindSwapSubmit, memo.Trace)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return false, err
+	}
+	if j != nil {
+		for _, e := range j.Entries {
+			v, err := decimal.NewFromString(e.Amount)
+			if err != nil {
+				return false, fmt.Errorf("ledger: entry %s amount %q: %w", e.ID, e.Amount, err)
+			}
+			switch e.Account {
+			case AccountOutbound:
+				sent, sentAsset = v, e.AssetID
+			case AccountFees:
+				fee, feeAsset = v, e.AssetID
+			}
+		}
+	}
+
+	if memo.Type == "RL" {
+		return true, l.post(ctx, KindVenueRelease, s.SnapshotID, o.ID,
+			leg{venue, sentAsset, sent.Neg()},
+			leg{AccountExternal, sentAsset, sent},
+			leg{AccountExternal, s.AssetID, amount.Neg()},
+			leg{OrderAccount(o.ID), s.AssetID, amount},
+		)
+	}
+
+	// RF: the venue returns what it was sent, less anything it kept.
+	if sent.IsZero() || sentAsset != s.AssetID {
+		sent, sentAsset = amount, s.AssetID
+	}
+	return true, l.post(ctx, KindVenueRefund, s.SnapshotID, o.ID,
+		leg{venue, sentAsset, sent.Neg()},
+		leg{AccountExternal, sentAsset, sent.Sub(amount)},
+		leg{OrderAccount(o.ID), s.AssetID, amount},
+		leg{AccountFees, feeAsset, fee.Neg()},
+		leg{OrderAccount(o.ID), feeAsset, fee},
+	)
+}
+
+// Balances sums the entries of every account starting with accountPrefix,
+// sorted by account and asset. Zero balances are omitted.
+func (l *Ledger) Balances(ctx context.Context, accountPrefix string) ([]*models.LedgerBalance, error) {
+	out, err := l.Store.ListBalances(ctx, accountPrefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range out {
+		v, err := decimal.NewFromString(b.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("ledger: %s %s balance %q: %w", b.Account, b.AssetID, b.Amount, err)
+		}
+		b.Amount = v.String() // Postgres keeps the column's trailing zeros
+	}
+	return out, nil
+}
+
+// WalletBalances is the amount of each asset the ledger expects the bot
+// wallet to hold: the sum of all wallet accounts.
+func (l *Ledger) WalletBalances(ctx context.Context) (map[string]decimal.Decimal, error) {
+	all, err := l.Balances(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]decimal.Decimal{}
+	for _, b := range all {
+		if !WalletAccount(b.Account) {
+			continue
+		}
+		v, _ := decimal.NewFromString(b.Amount)
+		out[b.AssetID] = out[b.AssetID].Add(v)
+	}
+	return out, nil
+}
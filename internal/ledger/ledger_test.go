@@ -0,0 +1,247 @@
+package ledger
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/mixin"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store/memstore"
+)
+
+const (
+	exinSwap  = "29f23576-4651-47ff-8c16-6c8a5d76985e"
+	srcAsset  = "src-asset"
+	dstAsset  = "dst-asset"
+	swapTrace = "swap-trace"
+)
+
+func strp(s string) *string { return &s }
+
+func dec(s string) decimal.Decimal { return decimal.RequireFromString(s) }
+
+// fixture is a ledger over memstores with one order whose deposit of 1
+// src-asset is snapshot "dep-1" and whose swap went out under swapTrace with
+// a fee of 0.01.
+type fixture struct {
+	l      *Ledger
+	store  *memstore.Ledger
+	orders *memstore.Orders
+	order  *models.Order
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	f := &fixture{store: memstore.NewLedger(), orders: memstore.NewOrders()}
+	f.l = New(f.store, f.orders, exinSwap)
+	now := time.Now().UTC()
+	f.order = &models.Order{
+		ID: "o1", PublicID: "p1", Status: models.StatusExecutingSwap, CreatedAt: now, UpdatedAt: now,
+		SourceAsset: srcAsset, TargetAsset: dstAsset, AmountIn: "1",
+		AmountCredited: strp("1"), DepositTxID: strp("dep-1"),
+		ExinSwapTraceID: strp(swapTrace), FeeAmount: strp("0.01"),
+	}
+	if err := f.orders.Insert(context.Background(), f.order); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func (f *fixture) snapshot(t *testing.T, s *mixin.Snapshot) {
+	t.Helper()
+	if err := f.l.PostSnapshot(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// deposit, swap submit and its outgoing snapshot.
+func (f *fixture) swapSent(t *testing.T) {
+	t.Helper()
+	f.snapshot(t, &mixin.Snapshot{SnapshotID: "dep-1", AssetID: srcAsset, Amount: "1", OpponentID: "user"})
+	if err := f.l.PostTransfer(context.Background(), f.order, models.StageSwap, swapTrace, srcAsset, dec("0.99"), dec("0.01")); err != nil {
+		t.Fatal(err)
+	}
+	f.snapshot(t, &mixin.Snapshot{SnapshotID: "out-1", AssetID: srcAsset, Amount: "-0.99", OpponentID: exinSwap, TraceID: swapTrace})
+}
+
+func exinSwapMemo(typ string) string {
+	return base64.StdEncoding.EncodeToString([]byte("0|" + swapTrace + "|SW|" + typ))
+}
+
+// balances returns account/asset -> amount.
+func (f *fixture) balances(t *testing.T) map[string]string {
+	t.Helper()
+	bs, err := f.l.Balances(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]string{}
+	for _, b := range bs {
+		out[b.Account+" "+b.AssetID] = b.Amount
+	}
+	return out
+}
+
+func (f *fixture) assertBalances(t *testing.T, want map[string]string) {
+	t.Helper()
+	got := f.balances(t)
+	for k, v := range want {
+		if v == "0" {
+			if g, ok := got[k]; ok {
+				t.Errorf("%s = %s, want 0", k, g)
+			}
+			continue
+		}
+		if g, ok := got[k]; !ok || !dec(g).Equal(dec(v)) {
+			t.Errorf("%s = %q, want %s", k, g, v)
+		}
+	}
+}
+
+// assertJournalsBalanced checks every stored journal sums to zero per asset.
+func (f *fixture) assertJournalsBalanced(t *testing.T) {
+	t.Helper()
+	sums := map[string]decimal.Decimal{}
+	for _, j := range f.store.Journals() {
+		for _, e := range j.Entries {
+			k := j.ID + " " + e.AssetID
+			sums[k] = sums[k].Add(dec(e.Amount))
+		}
+	}
+	for k, s := range sums {
+		if !s.IsZero() {
+			t.Errorf("journal %s unbalanced by %s", k, s)
+		}
+	}
+}
+
+func TestJournalsBalance(t *testing.T) {
+	f := newFixture(t)
+	f.swapSent(t)
+	f.assertJournalsBalanced(t)
+	f.assertBalances(t, map[string]string{
+		"order:o1 " + srcAsset:       "0",
+		"fees " + srcAsset:           "0.01",
+		"outbound " + srcAsset:       "0",
+		"venue:exinswap " + srcAsset: "0.99",
+		"external " + srcAsset:       "-1",
+	})
+	wallet, err := f.l.WalletBalances(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !wallet[srcAsset].Equal(dec("0.01")) {
+		t.Errorf("wallet %s = %s, want 0.01 (the fee)", srcAsset, wallet[srcAsset])
+	}
+
+	err = f.l.post(context.Background(), "test", "unbalanced", "",
+		leg{AccountFees, srcAsset, dec("1")},
+		leg{AccountExternal, srcAsset, dec("-0.5")},
+	)
+	if err == nil {
+		t.Fatal("unbalanced journal was accepted")
+	}
+	if _, err := f.store.GetJournal(context.Background(), "test", "unbalanced"); err == nil {
+		t.Error("unbalanced journal was stored")
+	}
+}
+
+func TestRepostIsIdempotent(t *testing.T) {
+	f := newFixture(t)
+	f.swapSent(t)
+	before := f.balances(t)
+
+	// The snapshot poller and the executor may both post the same movement.
+	f.swapSent(t)
+	f.snapshot(t, &mixin.Snapshot{SnapshotID: "dep-1", AssetID: srcAsset, Amount: "1", OpponentID: "user"})
+	after := f.balances(t)
+	if len(before) != len(after) {
+		t.Fatalf("balances changed: %v -> %v", before, after)
+	}
+	for k, v := range before {
+		if after[k] != v {
+			t.Errorf("%s: %s -> %s", k, v, after[k])
+		}
+	}
+	js, err := f.store.ListOrderJournals(context.Background(), "o1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(js) != 3 { // deposit, swap_submit, settle
+		t.Errorf("order journals = %d, want 3", len(js))
+	}
+}
+
+func TestVenueReleaseMovesProceedsToOrder(t *testing.T) {
+	f := newFixture(t)
+	f.swapSent(t)
+	f.snapshot(t, &mixin.Snapshot{SnapshotID: "rl-1", AssetID: dstAsset, Amount: "0.0195", OpponentID: exinSwap, Memo: exinSwapMemo("RL")})
+	f.assertJournalsBalanced(t)
+	f.assertBalances(t, map[string]string{
+		"venue:exinswap " + srcAsset: "0",
+		"order:o1 " + dstAsset:       "0.0195",
+		"order:o1 " + srcAsset:       "0",
+		"fees " + srcAsset:           "0.01",
+		"unallocated " + dstAsset:    "0",
+	})
+	if _, err := f.store.GetJournal(context.Background(), KindVenueRelease, "rl-1"); err != nil {
+		t.Errorf("venue_release journal: %v", err)
+	}
+}
+
+// On RF the venue returns the swapped amount less what it kept, and the fee
+// withheld at swap time goes back to the order to be refunded with it.
+func TestVenueRefundReturnsFee(t *testing.T) {
+	f := newFixture(t)
+	f.swapSent(t)
+	f.snapshot(t, &mixin.Snapshot{SnapshotID: "rf-1", AssetID: srcAsset, Amount: "0.98", OpponentID: exinSwap, Memo: exinSwapMemo("RF")})
+	f.assertJournalsBalanced(t)
+	f.assertBalances(t, map[string]string{
+		"venue:exinswap " + srcAsset: "0",
+		"order:o1 " + srcAsset:       "0.99",
+		"fees " + srcAsset:           "0",
+		"external " + srcAsset:       "-0.99", // deposit in, 0.01 kept by the venue
+		"unallocated " + srcAsset:    "0",
+	})
+
+	j, err := f.store.GetJournal(context.Background(), KindVenueRefund, "rf-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feeBack, toOrder decimal.Decimal
+	for _, e := range j.Entries {
+		switch e.Account {
+		case AccountFees:
+			feeBack = feeBack.Add(dec(e.Amount))
+		case OrderAccount("o1"):
+			toOrder = toOrder.Add(dec(e.Amount))
+		}
+	}
+	if !feeBack.Equal(dec("-0.01")) || !toOrder.Equal(dec("0.99")) {
+		t.Errorf("fee leg %s, order legs %s; want -0.01 and 0.99", feeBack, toOrder)
+	}
+}
+
+func TestUnallocatedMovements(t *testing.T) {
+	f := newFixture(t)
+	f.snapshot(t, &mixin.Snapshot{SnapshotID: "in-x", AssetID: srcAsset, Amount: "5", OpponentID: "stranger"})
+	f.snapshot(t, &mixin.Snapshot{SnapshotID: "out-x", AssetID: srcAsset, Amount: "-2", OpponentID: "stranger", TraceID: "unknown-trace"})
+	// An ExinSwap reply for a trace we never sent is unallocated too.
+	f.snapshot(t, &mixin.Snapshot{SnapshotID: "rl-x", AssetID: dstAsset, Amount: "1", OpponentID: exinSwap,
+		Memo: base64.StdEncoding.EncodeToString([]byte("0|other-trace|SW|RL"))})
+	f.assertJournalsBalanced(t)
+	f.assertBalances(t, map[string]string{
+		"unallocated " + srcAsset:    "3",
+		"unallocated " + dstAsset:    "1",
+		"venue:exinswap " + dstAsset: "-1",
+	})
+	for kind, ref := range map[string]string{KindUnallocatedIn: "in-x", KindUnallocatedOut: "out-x"} {
+		if j, err := f.store.GetJournal(context.Background(), kind, ref); err != nil || j.OrderID != nil {
+			t.Errorf("%s %s: %+v, %v", kind, ref, j, err)
+		}
+	}
+}
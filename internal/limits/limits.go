@@ -0,0 +1,255 @@
+// Package limits enforces per-order amount bounds and velocity caps.
+//
+// The limits document lives in the kv table (key "limits") and is edited via
+// the admin API, so changes apply to the API and workers without a restart.
+// New orders are checked at creation; credited orders are checked again
+// before the swap, when the actual amount and the sender are known.
+package limits
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/pricing"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+const kvKey = "limits"
+
+// AssetLimit bounds amount_in for one Mixin asset id. Empty means unbounded.
+type AssetLimit struct {
+	Min string `json:"min,omitempty"`
+	Max string `json:"max,omitempty"`
+}
+
+// Config is the limits document. USD caps are compared against
+// amount × price; an empty cap is not enforced.
+type Config struct {
+	Assets map[string]AssetLimit `json:"assets,omitempty"`
+
+	// Rolling 24h caps.
+	TargetAddress24hUSD string `json:"target_address_24h_usd,omitempty"`
+	Sender24hUSD        string `json:"sender_24h_usd,omitempty"`
+
+	// Cap on all orders since 00:00 UTC.
+	GlobalDailyUSD string `json:"global_daily_usd,omitempty"`
+
+	// Static USD prices that override the live price source (e.g. stablecoins).
+	PricesUSD map[string]string `json:"prices_usd,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	for asset, l := range c.Assets {
+		min, err := optionalAmount(l.Min)
+		if err != nil {
+			return fmt.Errorf("assets.%s.min: %w", asset, err)
+		}
+		max, err := optionalAmount(l.Max)
+		if err != nil {
+			return fmt.Errorf("assets.%s.max: %w", asset, err)
+		}
+		if l.Min != "" && l.Max != "" && min.GreaterThan(max) {
+			return fmt.Errorf("assets.%s: min > max", asset)
+		}
+	}
+	for name, v := range map[string]string{
+		"target_address_24h_usd": c.TargetAddress24hUSD,
+		"sender_24h_usd":         c.Sender24hUSD,
+		"global_daily_usd":       c.GlobalDailyUSD,
+	} {
+		if _, err := optionalAmount(v); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	for asset, v := range c.PricesUSD {
+		if p, err := decimal.NewFromString(v); err != nil || !p.IsPositive() {
+			return fmt.Errorf("prices_usd.%s: must be a positive decimal", asset)
+		}
+	}
+	return nil
+}
+
+func optionalAmount(v string) (decimal.Decimal, error) {
+	if v == "" {
+		return decimal.Zero, nil
+	}
+	d, err := decimal.NewFromString(v)
+	if err != nil || d.IsNegative() {
+		return decimal.Zero, fmt.Errorf("must be a non-negative decimal")
+	}
+	return d, nil
+}
+
+// Load returns the stored limits (an empty Config if none were set).
+func Load(ctx context.Context, kv store.KVStore) (*Config, error) {
+	v, ok, err := kv.Get(ctx, kvKey)
+	if err != nil {
+		return nil, fmt.Errorf("load limits: %w", err)
+	}
+	c := &Config{}
+	if !ok {
+		return c, nil
+	}
+	if err := json.Unmarshal([]byte(v), c); err != nil {
+		return nil, fmt.Errorf("load limits: %w", err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("load limits: %w", err)
+	}
+	return c, nil
+}
+
+func Save(ctx context.Context, kv store.KVStore, c *Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return kv.Set(ctx, kvKey, string(raw))
+}
+
+// Breach is returned when an order violates a limit. Code is stable for API
+// clients; Limit is the configured value that was exceeded.
+type Breach struct {
+	Code    string `json:"code"`
+	Message string `json:"error"`
+	Limit   string `json:"limit,omitempty"`
+}
+
+func (b *Breach) Error() string { return b.Message }
+
+// Checker evaluates orders against the stored limits.
+type Checker struct {
+	KV     store.KVStore
+	Orders store.OrderStore
+	Prices pricing.Source
+
+	// Unpaid quotes older than this no longer count toward velocity caps.
+	UnpaidWindow time.Duration
+}
+
+func NewChecker(kv store.KVStore, orders store.OrderStore, prices pricing.Source, unpaidWindow time.Duration) *Checker {
+	return &Checker{KV: kv, Orders: orders, Prices: prices, UnpaidWindow: unpaidWindow}
+}
+
+// CheckNewOrder validates a not-yet-inserted order. It returns a *Breach for
+// limit violations and a plain error if limits could not be evaluated (e.g.
+// no USD price for a velocity cap); callers fail closed on both.
+func (c *Checker) CheckNewOrder(ctx context.Context, o *models.Order) error {
+	return c.check(ctx, o, o.AmountIn, "")
+}
+
+// CheckCredited re-checks a credited order with the amount actually received
+// and the sender that funds would be refunded to.
+func (c *Checker) CheckCredited(ctx context.Context, o *models.Order) error {
+	amount := o.AmountIn
+	if o.AmountCredited != nil && *o.AmountCredited != "" {
+		amount = *o.AmountCredited
+	}
+	sender := ""
+	if o.RefundToAddress != nil {
+		sender = *o.RefundToAddress
+	}
+	return c.check(ctx, o, amount, sender)
+}
+
+func (c *Checker) check(ctx context.Context, o *models.Order, amountStr, sender string) error {
+	amount, err := decimal.NewFromString(amountStr)
+	if err != nil || !amount.IsPositive() {
+		return &Breach{Code: "invalid_amount", Message: "amount must be a positive decimal"}
+	}
+	cfg, err := Load(ctx, c.KV)
+	if err != nil {
+		return err
+	}
+
+	if l, ok := cfg.Assets[o.SourceAsset]; ok {
+		if l.Min != "" && amount.LessThan(decimal.RequireFromString(l.Min)) {
+			return &Breach{Code: "amount_below_min", Message: "amount below minimum for asset", Limit: l.Min}
+		}
+		if l.Max != "" && amount.GreaterThan(decimal.RequireFromString(l.Max)) {
+			return &Breach{Code: "amount_above_max", Message: "amount above maximum for asset", Limit: l.Max}
+		}
+	}
+
+	if cfg.TargetAddress24hUSD == "" && cfg.Sender24hUSD == "" && cfg.GlobalDailyUSD == "" {
+		return nil
+	}
+	usd, err := c.usd(ctx, cfg, o.SourceAsset, amount)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	base := store.VolumeFilter{
+		Since:          now.Add(-24 * time.Hour),
+		UnpaidSince:    now.Add(-c.UnpaidWindow),
+		ExcludeOrderID: o.ID,
+	}
+	if cfg.TargetAddress24hUSD != "" {
+		f := base
+		f.TargetAddress = o.TargetAddress
+		if err := c.velocity(ctx, cfg, f, usd, cfg.TargetAddress24hUSD, "target_address_24h_cap", "24h volume cap for target address reached"); err != nil {
+			return err
+		}
+	}
+	if cfg.Sender24hUSD != "" && sender != "" {
+		f := base
+		f.RefundTo = sender
+		if err := c.velocity(ctx, cfg, f, usd, cfg.Sender24hUSD, "sender_24h_cap", "24h volume cap for sender reached"); err != nil {
+			return err
+		}
+	}
+	if cfg.GlobalDailyUSD != "" {
+		f := base
+		f.Since = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		if err := c.velocity(ctx, cfg, f, usd, cfg.GlobalDailyUSD, "global_daily_cap", "daily volume cap reached; try again tomorrow"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Checker) velocity(ctx context.Context, cfg *Config, f store.VolumeFilter, add decimal.Decimal, limit, code, msg string) error {
+	rows, err := c.Orders.ListVolume(ctx, f)
+	if err != nil {
+		return err
+	}
+	total := add
+	for _, r := range rows {
+		amt, err := decimal.NewFromString(r.Amount)
+		if err != nil {
+			continue
+		}
+		v, err := c.usd(ctx, cfg, r.AssetID, amt)
+		if err != nil {
+			return err
+		}
+		total = total.Add(v)
+	}
+	if total.GreaterThan(decimal.RequireFromString(limit)) {
+		return &Breach{Code: code, Message: msg, Limit: limit}
+	}
+	return nil
+}
+
+func (c *Checker) usd(ctx context.Context, cfg *Config, assetID string, amount decimal.Decimal) (decimal.Decimal, error) {
+	if p, ok := cfg.PricesUSD[assetID]; ok {
+		return amount.Mul(decimal.RequireFromString(p)), nil
+	}
+	if c.Prices == nil {
+		return decimal.Zero, fmt.Errorf("no usd price for asset %s", assetID)
+	}
+	p, err := c.Prices.PriceUSD(ctx, assetID)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return amount.Mul(p), nil
+}
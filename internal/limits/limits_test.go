@@ -0,0 +1,208 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store/memstore"
+)
+
+// prices is a pricing.Source; assets missing from it fail like a broken ticker.
+type prices map[string]string
+
+var errTicker = errors.New("ticker unavailable")
+
+func (p prices) PriceUSD(ctx context.Context, assetID string) (decimal.Decimal, error) {
+	v, ok := p[assetID]
+	if !ok {
+		return decimal.Zero, errTicker
+	}
+	return decimal.RequireFromString(v), nil
+}
+
+type fixture struct {
+	c      *Checker
+	kv     *memstore.KV
+	orders *memstore.Orders
+}
+
+func newFixture(t *testing.T, cfg *Config, p prices) *fixture {
+	t.Helper()
+	f := &fixture{kv: memstore.NewKV(), orders: memstore.NewOrders()}
+	if err := Save(context.Background(), f.kv, cfg); err != nil {
+		t.Fatal(err)
+	}
+	f.c = NewChecker(f.kv, f.orders, p, 30*time.Minute)
+	return f
+}
+
+// insert stores o, created age ago, with defaults for the fields limits
+// do not read.
+func (f *fixture) insert(t *testing.T, o *models.Order, age time.Duration) {
+	t.Helper()
+	o.CreatedAt = time.Now().UTC().Add(-age)
+	o.UpdatedAt, o.PublicID, o.TargetAsset = o.CreatedAt, "p-"+o.ID, "dst"
+	if err := f.orders.Insert(context.Background(), o); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newOrder(asset, amount, target string) *models.Order {
+	return &models.Order{ID: "new", SourceAsset: asset, AmountIn: amount, TargetAddress: target}
+}
+
+// code returns the breach code of err, "" for nil; other errors fail the test.
+func code(t *testing.T, err error) string {
+	t.Helper()
+	var b *Breach
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &b):
+		return b.Code
+	}
+	t.Fatalf("unexpected error: %v", err)
+	return ""
+}
+
+func TestAssetBounds(t *testing.T) {
+	f := newFixture(t, &Config{Assets: map[string]AssetLimit{
+		"btc": {Min: "0.001", Max: "2"},
+		"eth": {Max: "10"},
+	}}, nil)
+	for _, tc := range []struct {
+		asset, amount, want string
+	}{
+		{"btc", "0.001", ""},
+		{"btc", "0.0009999", "amount_below_min"},
+		{"btc", "2", ""},
+		{"btc", "2.00000001", "amount_above_max"},
+		{"eth", "0.00000001", ""},
+		{"eth", "10.1", "amount_above_max"},
+		{"xin", "1000000", ""}, // no bounds configured
+		{"btc", "0", "invalid_amount"},
+		{"btc", "-1", "invalid_amount"},
+		{"btc", "abc", "invalid_amount"},
+	} {
+		if got := code(t, f.c.CheckNewOrder(context.Background(), newOrder(tc.asset, tc.amount, "addr"))); got != tc.want {
+			t.Errorf("%s %s: %q, want %q", tc.amount, tc.asset, got, tc.want)
+		}
+	}
+}
+
+func TestVelocityCaps(t *testing.T) {
+	f := newFixture(t, &Config{
+		TargetAddress24hUSD: "1000",
+		PricesUSD:           map[string]string{"usdc": "1"},
+	}, prices{"btc": "50000"})
+	f.insert(t, &models.Order{ID: "a", Status: models.StatusCompleted, SourceAsset: "usdc", AmountIn: "500", TargetAddress: "addr"}, 23*time.Hour)
+	f.insert(t, &models.Order{ID: "b", Status: models.StatusWithdrawing, SourceAsset: "btc", AmountIn: "0.002", TargetAddress: "addr"}, time.Hour)
+	// Not counted: older than 24h, refunded, or another address.
+	f.insert(t, &models.Order{ID: "c", Status: models.StatusCompleted, SourceAsset: "usdc", AmountIn: "900", TargetAddress: "addr"}, 25*time.Hour)
+	f.insert(t, &models.Order{ID: "d", Status: models.StatusRefunded, SourceAsset: "usdc", AmountIn: "900", TargetAddress: "addr"}, time.Hour)
+	f.insert(t, &models.Order{ID: "e", Status: models.StatusCompleted, SourceAsset: "usdc", AmountIn: "900", TargetAddress: "other"}, time.Hour)
+
+	// 500 + 0.002 BTC × 50000 = 600 USD used of 1000.
+	for _, tc := range []struct {
+		name, asset, amount, target, want string
+	}{
+		{"at cap", "usdc", "400", "addr", ""},
+		{"just over", "usdc", "400.01", "addr", "target_address_24h_cap"},
+		{"ticker priced at cap", "btc", "0.008", "addr", ""},
+		{"ticker priced just over", "btc", "0.00800001", "addr", "target_address_24h_cap"},
+		{"other address", "usdc", "1000", "fresh", ""},
+		{"alone over cap", "usdc", "1000.01", "fresh", "target_address_24h_cap"},
+	} {
+		if got := code(t, f.c.CheckNewOrder(context.Background(), newOrder(tc.asset, tc.amount, tc.target))); got != tc.want {
+			t.Errorf("%s: %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSenderAndGlobalCaps(t *testing.T) {
+	f := newFixture(t, &Config{
+		Sender24hUSD:   "300",
+		GlobalDailyUSD: "1000",
+		PricesUSD:      map[string]string{"usdc": "1"},
+	}, nil)
+	f.insert(t, &models.Order{ID: "a", Status: models.StatusCompleted, SourceAsset: "usdc", AmountIn: "200", TargetAddress: "x", RefundToAddress: strp("alice")}, 0)
+	f.insert(t, &models.Order{ID: "b", Status: models.StatusCompleted, SourceAsset: "usdc", AmountIn: "600", TargetAddress: "y", RefundToAddress: strp("bob")}, 0)
+
+	credited := func(id, amount, sender string) *models.Order {
+		o := newOrder("usdc", "1", "z")
+		o.ID, o.AmountCredited, o.RefundToAddress = id, &amount, &sender
+		return o
+	}
+	for _, tc := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		// The sender is unknown before the deposit: only the global cap applies.
+		{"new order at global cap", f.c.CheckNewOrder(context.Background(), newOrder("usdc", "200", "z")), ""},
+		{"new order over global cap", f.c.CheckNewOrder(context.Background(), newOrder("usdc", "200.5", "z")), "global_daily_cap"},
+		{"sender at cap", f.c.CheckCredited(context.Background(), credited("n1", "100", "alice")), ""},
+		{"sender just over", f.c.CheckCredited(context.Background(), credited("n2", "100.01", "alice")), "sender_24h_cap"},
+		{"other sender", f.c.CheckCredited(context.Background(), credited("n3", "200", "carol")), ""},
+	} {
+		if got := code(t, tc.err); got != tc.want {
+			t.Errorf("%s: %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	// A credited order is re-checked without counting itself.
+	o := credited("a", "200", "alice")
+	if got := code(t, f.c.CheckCredited(context.Background(), o)); got != "" {
+		t.Errorf("re-check of a counted order: %q", got)
+	}
+}
+
+// Unpaid quotes hold volume only during the pay window, so abandoned quotes
+// do not block an address for a day.
+func TestVelocityPayWindow(t *testing.T) {
+	f := newFixture(t, &Config{TargetAddress24hUSD: "100", PricesUSD: map[string]string{"usdc": "1"}}, nil)
+	f.insert(t, &models.Order{ID: "fresh", Status: models.StatusAwaitingDeposit, SourceAsset: "usdc", AmountIn: "60", TargetAddress: "addr"}, 10*time.Minute)
+	f.insert(t, &models.Order{ID: "stale", Status: models.StatusAwaitingDeposit, SourceAsset: "usdc", AmountIn: "60", TargetAddress: "addr"}, 40*time.Minute)
+	f.insert(t, &models.Order{ID: "paid", Status: models.StatusDepositCredited, SourceAsset: "usdc", AmountIn: "30", TargetAddress: "addr"}, 40*time.Minute)
+
+	if got := code(t, f.c.CheckNewOrder(context.Background(), newOrder("usdc", "10", "addr"))); got != "" {
+		t.Errorf("within cap: %q", got)
+	}
+	if got := code(t, f.c.CheckNewOrder(context.Background(), newOrder("usdc", "10.01", "addr"))); got != "target_address_24h_cap" {
+		t.Errorf("over cap with the quote in its pay window: %q", got)
+	}
+}
+
+// Without a USD price a velocity cap cannot be evaluated, so the check fails
+// closed: a plain error, which the API answers with 503 and the worker
+// retries, never a pass.
+func TestPriceFailureFailsClosed(t *testing.T) {
+	f := newFixture(t, &Config{TargetAddress24hUSD: "1000"}, prices{"btc": "50000"})
+	err := f.c.CheckNewOrder(context.Background(), newOrder("doge", "1", "addr"))
+	var b *Breach
+	if !errors.Is(err, errTicker) || errors.As(err, &b) {
+		t.Errorf("unpriced new order: %v, want the ticker error", err)
+	}
+
+	// Volume in an unpriced asset blocks the check as well.
+	f.insert(t, &models.Order{ID: "a", Status: models.StatusCompleted, SourceAsset: "doge", AmountIn: "5", TargetAddress: "addr"}, time.Hour)
+	if err := f.c.CheckNewOrder(context.Background(), newOrder("btc", "0.001", "addr")); !errors.Is(err, errTicker) {
+		t.Errorf("unpriced volume: %v, want the ticker error", err)
+	}
+	if err := f.c.CheckNewOrder(context.Background(), newOrder("btc", "0.001", "other")); err != nil {
+		t.Errorf("other address: %v", err)
+	}
+
+	// Asset bounds need no price.
+	f = newFixture(t, &Config{Assets: map[string]AssetLimit{"doge": {Max: "10"}}}, nil)
+	if err := f.c.CheckNewOrder(context.Background(), newOrder("doge", "1", "addr")); err != nil {
+		t.Errorf("bounds only: %v", err)
+	}
+}
+
+func strp(s string) *string { return &s }
@@ -0,0 +1,303 @@
+// Package liquidity checks that the hot wallet can cover a payout before the
+// executors attempt it, and warns when balances drop below operator-set
+// thresholds.
+//
+// Balances come from the wallet's unspent safe outputs and are cached for a
+// short TTL, so a tick with many payouts costs one API call. Thresholds live
+// in the kv table (key "liquidity") and are edited via the admin API.
+package liquidity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/metrics"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+const (
+	kvKey = "liquidity"
+
+	// ReportKey is the kv key holding the latest low-balance Report.
+	ReportKey = "liquidity.report"
+)
+
+// BalanceSource returns the wallet's spendable balance per asset id.
+type BalanceSource interface {
+	SafeBalances(ctx context.Context) (map[string]decimal.Decimal, error)
+}
+
+// FeeSource returns the network fee of withdrawing assetID and the asset it
+// is paid in (the chain's native asset).
+type FeeSource interface {
+	WithdrawalFee(ctx context.Context, assetID string) (feeAssetID string, fee decimal.Decimal, err error)
+}
+
+// Thresholds is the operator document: warn when an asset's balance falls
+// below its minimum.
+type Thresholds struct {
+	MinBalances map[string]string `json:"min_balances"` // asset id -> amount
+}
+
+func (t *Thresholds) Validate() error {
+	for asset, v := range t.MinBalances {
+		if d, err := decimal.NewFromString(v); err != nil || d.IsNegative() {
+			return fmt.Errorf("min_balances.%s: must be a non-negative decimal", asset)
+		}
+	}
+	return nil
+}
+
+// Load returns the stored thresholds (none if unset).
+func Load(ctx context.Context, kv store.KVStore) (*Thresholds, error) {
+	v, ok, err := kv.Get(ctx, kvKey)
+	if err != nil {
+		return nil, fmt.Errorf("load liquidity: %w", err)
+	}
+	t := &Thresholds{MinBalances: map[string]string{}}
+	if !ok {
+		return t, nil
+	}
+	if err := json.Unmarshal([]byte(v), t); err != nil {
+		return nil, fmt.Errorf("load liquidity: %w", err)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("load liquidity: %w", err)
+	}
+	if t.MinBalances == nil {
+		t.MinBalances = map[string]string{}
+	}
+	return t, nil
+}
+
+func Save(ctx context.Context, kv store.KVStore, t *Thresholds) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return kv.Set(ctx, kvKey, string(raw))
+}
+
+// Need is an amount of one asset a payout spends.
+type Need struct {
+	AssetID string
+	Amount  decimal.Decimal
+}
+
+// Shortfall describes the first asset the wallet cannot cover.
+type Shortfall struct {
+	AssetID string
+	Need    decimal.Decimal
+	Have    decimal.Decimal
+}
+
+func (s *Shortfall) String() string {
+	return fmt.Sprintf("asset %s: need %s, have %s", s.AssetID, s.Need, s.Have)
+}
+
+// Low is an asset below its threshold.
+type Low struct {
+	AssetID string `json:"asset_id"`
+	Balance string `json:"balance"`
+	Min     string `json:"min"`
+}
+
+// Report is the latest threshold check, stored under ReportKey.
+type Report struct {
+	CheckedAt time.Time         `json:"checked_at"`
+	Balances  map[string]string `json:"balances"`
+	Low       []Low             `json:"low"`
+}
+
+type Checker struct {
+	Source BalanceSource
+	// Fees, if set, adds the chain's withdrawal fee to withdrawal needs.
+	Fees FeeSource
+	KV   store.KVStore
+	TTL  time.Duration
+	Now  func() time.Time
+
+	mu        sync.Mutex
+	balances  map[string]decimal.Decimal
+	fetchedAt time.Time
+	reported  time.Time
+	fees      map[string]cachedFee
+	low       map[string]bool
+}
+
+type cachedFee struct {
+	asset string
+	fee   decimal.Decimal
+	at    time.Time
+}
+
+// feeTTL bounds how long a withdrawal fee quote is reused.
+const feeTTL = 10 * time.Minute
+
+func NewChecker(src BalanceSource, fees FeeSource, kv store.KVStore, ttl time.Duration) *Checker {
+	return &Checker{Source: src, Fees: fees, KV: kv, TTL: ttl, Now: time.Now}
+}
+
+// Balances returns a copy of the cached balances, refreshing them once older
+// than TTL.
+func (c *Checker) Balances(ctx context.Context) (map[string]decimal.Decimal, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.Now()
+	if c.balances == nil || now.Sub(c.fetchedAt) >= c.TTL {
+		b, err := c.Source.SafeBalances(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("liquidity: balances: %w", err)
+		}
+		c.balances, c.fetchedAt = b, now
+		metrics.SetWalletBalances(b)
+	}
+	out := make(map[string]decimal.Decimal, len(c.balances))
+	for asset, v := range c.balances {
+		out[asset] = v
+	}
+	return out, nil
+}
+
+// Spend lowers the cached balance after a payout was submitted, so later
+// checks in the same cache window see it.
+func (c *Checker) Spend(assetID string, amount decimal.Decimal) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.balances != nil {
+		c.balances[assetID] = c.balances[assetID].Sub(amount)
+	}
+}
+
+// WithdrawalNeeds is what withdrawing amount of assetID spends: the amount
+// plus the chain fee. A fee lookup failure is logged and the fee skipped, so
+// the pre-flight check never blocks on it.
+func (c *Checker) WithdrawalNeeds(ctx context.Context, assetID string, amount decimal.Decimal) []Need {
+	needs := []Need{{AssetID: assetID, Amount: amount}}
+	if c.Fees == nil {
+		return needs
+	}
+	c.mu.Lock()
+	f, ok := c.fees[assetID]
+	c.mu.Unlock()
+	if !ok || c.Now().Sub(f.at) >= feeTTL {
+		feeAsset, fee, err := c.Fees.WithdrawalFee(ctx, assetID)
+		if err != nil {
+			slog.WarnContext(ctx, "liquidity fee lookup failed", "asset", assetID, "err", err)
+			return needs
+		}
+		f = cachedFee{asset: feeAsset, fee: fee, at: c.Now()}
+		c.mu.Lock()
+		if c.fees == nil {
+			c.fees = map[string]cachedFee{}
+		}
+		c.fees[assetID] = f
+		c.mu.Unlock()
+	}
+	if f.fee.IsPositive() {
+		needs = append(needs, Need{AssetID: f.asset, Amount: f.fee})
+	}
+	return needs
+}
+
+// Check returns the first asset in needs the wallet cannot cover, or nil.
+func (c *Checker) Check(ctx context.Context, needs ...Need) (*Shortfall, error) {
+	b, err := c.Balances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	total := map[string]decimal.Decimal{}
+	var order []string
+	for _, n := range needs {
+		if _, ok := total[n.AssetID]; !ok {
+			order = append(order, n.AssetID)
+		}
+		total[n.AssetID] = total[n.AssetID].Add(n.Amount)
+	}
+	for _, asset := range order {
+		if have := b[asset]; have.LessThan(total[asset]) {
+			return &Shortfall{AssetID: asset, Need: total[asset], Have: have}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Warn compares cached balances with the thresholds, logs when an asset drops
+// below its minimum (and when it recovers) and stores the report, from which
+// the liquidity_low alert rule notifies. It only does work when balances
+// were refreshed since the last call.
+func (c *Checker) Warn(ctx context.Context) (*Report, error) {
+	b, err := c.Balances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	fresh := c.fetchedAt.After(c.reported)
+	c.reported = c.fetchedAt
+	c.mu.Unlock()
+	if !fresh {
+		return nil, nil
+	}
+	t, err := Load(ctx, c.KV)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &Report{CheckedAt: c.Now().UTC(), Balances: map[string]string{}, Low: []Low{}}
+	for asset, v := range b {
+		rep.Balances[asset] = v.String()
+	}
+	low := map[string]bool{}
+	for asset, v := range t.MinBalances {
+		min, _ := decimal.NewFromString(v)
+		if bal := b[asset]; bal.LessThan(min) {
+			low[asset] = true
+			rep.Low = append(rep.Low, Low{AssetID: asset, Balance: bal.String(), Min: min.String()})
+			if !c.low[asset] {
+				slog.WarnContext(ctx, "liquidity low", "asset", asset, "balance", bal.String(), "min", min.String())
+			}
+		}
+	}
+	for asset := range c.low {
+		if !low[asset] {
+			slog.InfoContext(ctx, "liquidity recovered", "asset", asset)
+		}
+	}
+	c.low = low
+	sort.Slice(rep.Low, func(i, j int) bool { return rep.Low[i].AssetID < rep.Low[j].AssetID })
+
+	raw, err := json.Marshal(rep)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.KV.Set(ctx, ReportKey, string(raw)); err != nil {
+		return nil, fmt.Errorf("liquidity: save report: %w", err)
+	}
+	return rep, nil
+}
+
+// LoadReport returns the latest stored report, or nil if none was stored.
+func LoadReport(ctx context.Context, kv store.KVStore) (*Report, error) {
+	raw, ok, err := kv.Get(ctx, ReportKey)
+	if err != nil {
+		return nil, fmt.Errorf("liquidity: load report: %w", err)
+	}
+	if !ok || raw == "" {
+		return nil, nil
+	}
+	var rep Report
+	if err := json.Unmarshal([]byte(raw), &rep); err != nil {
+		return nil, fmt.Errorf("liquidity: load report: %w", err)
+	}
+	return &rep, nil
+}
@@ -0,0 +1,190 @@
+package liquidity
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/store/memstore"
+)
+
+// balances is a fixed BalanceSource counting its calls.
+type balances struct {
+	m     map[string]decimal.Decimal
+	calls int
+}
+
+func (b *balances) SafeBalances(ctx context.Context) (map[string]decimal.Decimal, error) {
+	b.calls++
+	out := map[string]decimal.Decimal{}
+	for k, v := range b.m {
+		out[k] = v
+	}
+	return out, nil
+}
+
+// fees is a FeeSource with one quote per asset; a missing asset fails.
+type fees struct {
+	m     map[string]Need
+	calls int
+}
+
+func (f *fees) WithdrawalFee(ctx context.Context, assetID string) (string, decimal.Decimal, error) {
+	f.calls++
+	n, ok := f.m[assetID]
+	if !ok {
+		return "", decimal.Zero, errors.New("no quote")
+	}
+	return n.AssetID, n.Amount, nil
+}
+
+func dec(s string) decimal.Decimal { return decimal.RequireFromString(s) }
+
+func newChecker(b map[string]string, fs *fees) (*Checker, *balances, *time.Time) {
+	src := &balances{m: map[string]decimal.Decimal{}}
+	for k, v := range b {
+		src.m[k] = dec(v)
+	}
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := NewChecker(src, nil, memstore.NewKV(), time.Minute)
+	if fs != nil {
+		c.Fees = fs
+	}
+	c.Now = func() time.Time { return now }
+	return c, src, &now
+}
+
+func TestWithdrawalNeeds(t *testing.T) {
+	ctx := context.Background()
+	fs := &fees{m: map[string]Need{
+		"usdt": {AssetID: "eth", Amount: dec("0.002")},
+		"eth":  {AssetID: "eth", Amount: dec("0.001")},
+		"xin":  {AssetID: "xin", Amount: decimal.Zero},
+	}}
+	c, _, now := newChecker(nil, fs)
+
+	got := c.WithdrawalNeeds(ctx, "usdt", dec("10"))
+	if len(got) != 2 || got[0].AssetID != "usdt" || !got[0].Amount.Equal(dec("10")) || got[1].AssetID != "eth" || !got[1].Amount.Equal(dec("0.002")) {
+		t.Fatalf("usdt needs = %v", got)
+	}
+	// The quote is reused within feeTTL and fetched again after it.
+	c.WithdrawalNeeds(ctx, "usdt", dec("1"))
+	if fs.calls != 1 {
+		t.Errorf("fee calls = %d, want the cached quote", fs.calls)
+	}
+	*now = now.Add(feeTTL)
+	c.WithdrawalNeeds(ctx, "usdt", dec("1"))
+	if fs.calls != 2 {
+		t.Errorf("fee calls = %d after feeTTL, want 2", fs.calls)
+	}
+
+	// A zero fee adds nothing; a failed lookup is skipped, not fatal.
+	if got := c.WithdrawalNeeds(ctx, "xin", dec("1")); len(got) != 1 {
+		t.Errorf("xin needs = %v", got)
+	}
+	if got := c.WithdrawalNeeds(ctx, "btc", dec("1")); len(got) != 1 || got[0].AssetID != "btc" {
+		t.Errorf("btc needs = %v", got)
+	}
+	// Without a FeeSource only the amount is needed.
+	c.Fees = nil
+	if got := c.WithdrawalNeeds(ctx, "usdt", dec("1")); len(got) != 1 {
+		t.Errorf("no fee source: needs = %v", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	fs := &fees{m: map[string]Need{"eth": {AssetID: "eth", Amount: dec("0.1")}}}
+	c, _, _ := newChecker(map[string]string{"eth": "1", "usdt": "5"}, fs)
+
+	// Amount and fee in the same asset add up.
+	if s, err := c.Check(ctx, c.WithdrawalNeeds(ctx, "eth", dec("0.9"))...); err != nil || s != nil {
+		t.Fatalf("0.9 + 0.1 of 1: %v, %v", s, err)
+	}
+	s, err := c.Check(ctx, c.WithdrawalNeeds(ctx, "eth", dec("0.95"))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s == nil || s.AssetID != "eth" || !s.Need.Equal(dec("1.05")) || !s.Have.Equal(dec("1")) {
+		t.Fatalf("shortfall = %v, want eth need 1.05 have 1", s)
+	}
+
+	// The first short asset in needs order is reported; unknown assets have 0.
+	s, _ = c.Check(ctx, Need{"usdt", dec("6")}, Need{"btc", dec("1")}, Need{"eth", dec("2")})
+	if s == nil || s.AssetID != "usdt" {
+		t.Errorf("shortfall = %v, want usdt first", s)
+	}
+	s, _ = c.Check(ctx, Need{"btc", dec("1")}, Need{"usdt", dec("6")})
+	if s == nil || s.AssetID != "btc" || !s.Have.IsZero() {
+		t.Errorf("shortfall = %v, want btc with nothing on hand", s)
+	}
+	if got := s.String(); got != "asset btc: need 1, have 0" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestBalancesCacheAndSpend(t *testing.T) {
+	ctx := context.Background()
+	c, src, now := newChecker(map[string]string{"usdt": "5"}, nil)
+
+	if s, _ := c.Check(ctx, Need{"usdt", dec("4")}); s != nil {
+		t.Fatalf("shortfall = %v", s)
+	}
+	// A submitted payout is taken off the cached balance until the refresh.
+	c.Spend("usdt", dec("4"))
+	if s, _ := c.Check(ctx, Need{"usdt", dec("4")}); s == nil || !s.Have.Equal(dec("1")) {
+		t.Fatalf("after spend: shortfall = %v", s)
+	}
+	if src.calls != 1 {
+		t.Errorf("balance calls = %d, want 1 within the TTL", src.calls)
+	}
+	*now = now.Add(time.Minute)
+	if s, _ := c.Check(ctx, Need{"usdt", dec("4")}); s != nil {
+		t.Errorf("after refresh: shortfall = %v", s)
+	}
+	if src.calls != 2 {
+		t.Errorf("balance calls = %d, want 2", src.calls)
+	}
+}
+
+func TestWarnStoresReport(t *testing.T) {
+	ctx := context.Background()
+	c, src, now := newChecker(map[string]string{"usdt": "5", "eth": "2"}, nil)
+	if err := Save(ctx, c.KV, &Thresholds{MinBalances: map[string]string{"usdt": "10", "eth": "1", "btc": "0.5"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	rep, err := c.Warn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep == nil || len(rep.Low) != 2 || rep.Low[0].AssetID != "btc" || rep.Low[1] != (Low{AssetID: "usdt", Balance: "5", Min: "10"}) {
+		t.Fatalf("report = %+v", rep)
+	}
+	stored, err := LoadReport(ctx, c.KV)
+	if err != nil || stored == nil || len(stored.Low) != 2 {
+		t.Fatalf("stored report = %+v, %v", stored, err)
+	}
+
+	// Nothing new to report until the balances are refreshed.
+	if rep, _ := c.Warn(ctx); rep != nil {
+		t.Errorf("second warn = %+v, want nil", rep)
+	}
+	src.m["usdt"], src.m["btc"] = dec("10"), dec("1")
+	*now = now.Add(time.Minute)
+	if rep, _ := c.Warn(ctx); rep == nil || len(rep.Low) != 0 {
+		t.Errorf("after top-up: report = %+v", rep)
+	}
+}
+
+func TestThresholdsValidate(t *testing.T) {
+	if err := Save(context.Background(), memstore.NewKV(), &Thresholds{MinBalances: map[string]string{"usdt": "-1"}}); err == nil {
+		t.Error("saved a negative minimum")
+	}
+	if err := (&Thresholds{MinBalances: map[string]string{"usdt": "x"}}).Validate(); err == nil {
+		t.Error("accepted a non-decimal minimum")
+	}
+}
@@ -0,0 +1,167 @@
+// Package logging sets up structured (log/slog) logging for the bridge
+// binaries and carries per-order correlation fields through context.
+//
+// Fields added with With / WithOrder are attached to every record logged with
+// a *Context slog call, so one order's journey can be followed across the API
+// and the worker by grepping its order_id or public_id. Lines still written
+// through the standard log package go to the same handler at INFO.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+// Correlation field names.
+const (
+	OrderID    = "order_id"
+	PublicID   = "public_id"
+	TraceID    = "trace_id"
+	SnapshotID = "snapshot_id"
+	Stage      = "stage"
+)
+
+// redacted replaces the value of any attribute whose key names secret
+// material (keystore keys, PINs, tokens).
+const redacted = "[REDACTED]"
+
+var secretWords = map[string]bool{"pin": true, "secret": true, "token": true, "password": true, "keystore": true, "seed": true, "mnemonic": true}
+
+// Setup installs the default slog logger writing to w in format ("json" or
+// "text") at level, and routes the standard log package through it.
+func Setup(w io.Writer, format string, level slog.Level) error {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
+	var h slog.Handler
+	switch format {
+	case "", "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("invalid log format %q (want json or text)", format)
+	}
+	slog.SetDefault(slog.New(contextHandler{h}))
+	return nil
+}
+
+// Fatal logs msg at ERROR and exits.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// AddSecrets registers values (e.g. keystore keys) that must never be
+// written: some SDK errors quote the key they failed to parse.
+func AddSecrets(vals ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, v := range vals {
+		if len(v) >= 8 {
+			secrets = append(secrets, v)
+		}
+	}
+}
+
+// Scrub replaces registered secrets in s.
+func Scrub(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, v := range secrets {
+		s = strings.ReplaceAll(s, v, redacted)
+	}
+	return s
+}
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	switch v := a.Value.Any().(type) {
+	case error:
+		a.Value = slog.StringValue(Scrub(v.Error()))
+	case string:
+		a.Value = slog.StringValue(Scrub(v))
+	}
+	k := strings.ToLower(a.Key)
+	if strings.Contains(k, "private_key") || strings.Contains(k, "privatekey") {
+		return slog.String(a.Key, redacted)
+	}
+	for _, w := range strings.FieldsFunc(k, func(r rune) bool { return r == '_' || r == '.' || r == '-' }) {
+		if secretWords[w] {
+			return slog.String(a.Key, redacted)
+		}
+	}
+	return a
+}
+
+type ctxKey struct{}
+
+// With returns ctx carrying args (key/value pairs, as for slog) in addition
+// to any fields already on it.
+func With(ctx context.Context, args ...any) context.Context {
+	attrs := fromContext(ctx)
+	r := slog.Record{}
+	r.Add(args...)
+	next := make([]slog.Attr, 0, len(attrs)+r.NumAttrs())
+	next = append(next, attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		next = append(next, a)
+		return true
+	})
+	return context.WithValue(ctx, ctxKey{}, next)
+}
+
+// WithOrder returns ctx carrying o's order_id and public_id.
+func WithOrder(ctx context.Context, o *models.Order) context.Context {
+	return With(ctx, OrderID, o.ID, PublicID, o.PublicID)
+}
+
+// WithStage returns ctx carrying o's ids and stage.
+func WithStage(ctx context.Context, o *models.Order, stage models.Stage) context.Context {
+	return With(ctx, OrderID, o.ID, PublicID, o.PublicID, Stage, string(stage))
+}
+
+func fromContext(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	return attrs
+}
+
+// contextHandler adds the fields stored by With to each record. A field set
+// again later (e.g. a more specific stage) overrides the earlier value.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs := fromContext(ctx); len(attrs) > 0 {
+		seen := map[string]int{}
+		var out []slog.Attr
+		for _, a := range attrs {
+			if i, ok := seen[a.Key]; ok {
+				out[i] = a
+				continue
+			}
+			seen[a.Key] = len(out)
+			out = append(out, a)
+		}
+		r.AddAttrs(out...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
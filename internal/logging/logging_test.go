@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+// capture installs a JSON logger writing to a buffer for the test.
+func capture(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	prev := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	var buf bytes.Buffer
+	if err := Setup(&buf, "json", slog.LevelInfo); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func lastRecord(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &rec); err != nil {
+		t.Fatalf("%v: %s", err, buf)
+	}
+	return rec
+}
+
+func TestContextFields(t *testing.T) {
+	buf := capture(t)
+	o := &models.Order{ID: "o1", PublicID: "BRG_1"}
+	ctx := WithStage(context.Background(), o, models.StageSwap)
+	ctx = With(ctx, Stage, string(models.StageWithdraw), TraceID, "t1")
+
+	slog.InfoContext(ctx, "hello", "n", 1)
+	rec := lastRecord(t, buf)
+	for k, want := range map[string]any{"msg": "hello", OrderID: "o1", PublicID: "BRG_1", Stage: "withdraw", TraceID: "t1", "n": 1.0} {
+		if rec[k] != want {
+			t.Errorf("%s = %v, want %v", k, rec[k], want)
+		}
+	}
+	if n := strings.Count(buf.String(), `"stage"`); n != 1 {
+		t.Errorf("stage logged %d times, want the latest value once", n)
+	}
+
+	// WithOrder carries the ids only.
+	slog.InfoContext(WithOrder(context.Background(), o), "plain")
+	if rec := lastRecord(t, buf); rec[Stage] != nil || rec[OrderID] != "o1" {
+		t.Errorf("WithOrder record = %v", rec)
+	}
+
+	// The standard log package goes through the same handler.
+	log.Print("from log")
+	if rec := lastRecord(t, buf); rec["msg"] != "from log" || rec["level"] != "INFO" {
+		t.Errorf("log.Print record = %v", rec)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	buf := capture(t)
+	AddSecrets("short", "keystore-private-key-material")
+
+	slog.Info("secrets",
+		"pin", "123456",
+		"session_private_key", "abc",
+		"app.client-secret", "s3",
+		"user_id", "u1",
+		"err", errors.New("bad key keystore-private-key-material"),
+	)
+	rec := lastRecord(t, buf)
+	for _, k := range []string{"pin", "session_private_key", "app.client-secret"} {
+		if rec[k] != redacted {
+			t.Errorf("%s = %v, want it redacted", k, rec[k])
+		}
+	}
+	if rec["user_id"] != "u1" {
+		t.Errorf("user_id = %v", rec["user_id"])
+	}
+	if rec["err"] != "bad key "+redacted {
+		t.Errorf("err = %v", rec["err"])
+	}
+	if got := Scrub("short stays"); got != "short stays" {
+		t.Errorf("Scrub = %q; values under 8 bytes are not registered", got)
+	}
+}
+
+func TestSetupFormats(t *testing.T) {
+	prev := slog.Default()
+	defer slog.SetDefault(prev)
+	var buf bytes.Buffer
+	if err := Setup(&buf, "text", slog.LevelWarn); err != nil {
+		t.Fatal(err)
+	}
+	slog.Info("dropped")
+	slog.Warn("kept", OrderID, "o1")
+	if out := buf.String(); strings.Contains(out, "dropped") || !strings.Contains(out, "msg=kept order_id=o1") {
+		t.Errorf("text output = %q", out)
+	}
+	if err := Setup(&buf, "xml", slog.LevelInfo); err == nil {
+		t.Error("accepted an unknown format")
+	}
+}
@@ -0,0 +1,236 @@
+// Package metrics exports the Prometheus metrics listed in docs/ops.md.
+//
+// Both binaries serve Registry at /metrics: the API on its own port, the
+// worker on METRICS_PORT. Latencies are histograms, so p50/p95 come from
+// histogram_quantile over the _bucket series.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+const namespace = "bridge"
+
+// Registry holds every bridge metric plus the Go runtime and process collectors.
+var Registry = prometheus.NewRegistry()
+
+// latencyBuckets span a few seconds to a few hours: deposits and payouts
+// wait on chains and venues.
+var latencyBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600, 7200, 14400}
+
+var (
+	depositDetect = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "deposit_detect_seconds",
+		Help:      "Time from order creation to the deposit being detected.",
+		Buckets:   latencyBuckets,
+	})
+	depositCredit = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "deposit_credit_seconds",
+		Help:      "Time from deposit detection (pending) to credit.",
+		Buckets:   latencyBuckets,
+	})
+	withdrawSubmit = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "withdraw_submit_seconds",
+		Help:      "Time from deposit credit to the withdrawal being submitted.",
+		Buckets:   latencyBuckets,
+	})
+	refunds = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "refunds_total",
+		Help:      "Orders sent to refunding, by reason.",
+	}, []string{"reason"})
+	venueLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "venue_request_seconds",
+		Help:      "Latency of calls to Mixin and swap venues.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"venue", "op"})
+	venueErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "venue_request_errors_total",
+		Help:      "Failed calls to Mixin and swap venues.",
+	}, []string{"venue", "op"})
+	pollLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "snapshot_poll_lag_seconds",
+		Help:      "Age of the newest ingested Mixin snapshot when it was ingested.",
+	})
+	pollLast = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "snapshot_poll_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful snapshot poll.",
+	})
+	walletBalance = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "wallet_balance",
+		Help:      "Bot wallet balance (unspent safe outputs) per asset.",
+	}, []string{"asset_id"})
+	ledgerBalance = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ledger_expected_balance",
+		Help:      "Wallet balance per asset expected by the ledger at the last reconciliation.",
+	}, []string{"asset_id"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		depositDetect, depositCredit, withdrawSubmit, refunds,
+		venueLatency, venueErrors, pollLag, pollLast, walletBalance, ledgerBalance,
+	)
+}
+
+// Handler serves Registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveCredited records the deposit latencies of an order that was just credited.
+func ObserveCredited(o *models.Order) {
+	if o.DepositTxDetectedAt != nil {
+		depositDetect.Observe(o.DepositTxDetectedAt.Sub(o.CreatedAt).Seconds())
+		if o.DepositCreditedAt != nil {
+			depositCredit.Observe(o.DepositCreditedAt.Sub(*o.DepositTxDetectedAt).Seconds())
+		}
+	}
+}
+
+// ObserveWithdrawSubmitted records the time from credit to withdrawal submit.
+func ObserveWithdrawSubmitted(o *models.Order, at time.Time) {
+	if o.DepositCreditedAt != nil {
+		withdrawSubmit.Observe(at.Sub(*o.DepositCreditedAt).Seconds())
+	}
+}
+
+// Refund counts an order sent to refunding.
+func Refund(reason string) {
+	refunds.WithLabelValues(reason).Inc()
+}
+
+// ObserveVenue records one call to venue (mixin, exinswap) that started at start.
+func ObserveVenue(venue, op string, start time.Time, err error) {
+	venueLatency.WithLabelValues(venue, op).Observe(time.Since(start).Seconds())
+	if err != nil {
+		venueErrors.WithLabelValues(venue, op).Inc()
+	}
+
+	venuesMu.Lock()
+	defer venuesMu.Unlock()
+	v := venues[venue]
+	now := time.Now().UTC()
+	if err != nil {
+		v.LastError, v.LastErrorOp, v.Error = &now, op, logging.Scrub(err.Error())
+	} else {
+		v.LastOK = &now
+	}
+	venues[venue] = v
+}
+
+// VenueSeen is the outcome of the latest calls to one venue in this process.
+type VenueSeen struct {
+	LastOK      *time.Time `json:"last_ok,omitempty"`
+	LastError   *time.Time `json:"last_error,omitempty"`
+	LastErrorOp string     `json:"last_error_op,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// Reachable reports whether the latest call to the venue succeeded.
+func (v VenueSeen) Reachable() bool {
+	return v.LastOK != nil && (v.LastError == nil || v.LastOK.After(*v.LastError))
+}
+
+var (
+	venuesMu sync.Mutex
+	venues   = map[string]VenueSeen{}
+)
+
+// Venues returns what ObserveVenue saw per venue.
+func Venues() map[string]VenueSeen {
+	venuesMu.Lock()
+	defer venuesMu.Unlock()
+	out := make(map[string]VenueSeen, len(venues))
+	for k, v := range venues {
+		out[k] = v
+	}
+	return out
+}
+
+// ObservePoll records a successful poll; newest is the creation time of the
+// newest snapshot ingested (zero if the poll returned nothing new).
+func ObservePoll(now, newest time.Time) {
+	pollLast.Set(float64(now.Unix()))
+	if !newest.IsZero() {
+		pollLag.Set(now.Sub(newest).Seconds())
+	}
+}
+
+// SetWalletBalances publishes the wallet's per-asset balances.
+func SetWalletBalances(b map[string]decimal.Decimal) {
+	for asset, v := range b {
+		walletBalance.WithLabelValues(asset).Set(v.InexactFloat64())
+	}
+}
+
+// SetLedgerBalances publishes the ledger's expected per-asset balances.
+func SetLedgerBalances(b map[string]string) {
+	for asset, v := range b {
+		d, err := decimal.NewFromString(v)
+		if err != nil {
+			continue
+		}
+		ledgerBalance.WithLabelValues(asset).Set(d.InexactFloat64())
+	}
+}
+
+// statuses are exported by the order status collector.
+var statuses = []models.OrderStatus{
+	models.StatusQuoteCreated, models.StatusAwaitingDeposit, models.StatusDepositDetected,
+	models.StatusDepositPendingMixin, models.StatusDepositCredited, models.StatusExecutingSwap,
+	models.StatusWithdrawing, models.StatusCompleted, models.StatusRefunding, models.StatusRefunded,
+	models.StatusFailedManual, models.StatusComplianceHold, models.StatusAwaitingLiquidity,
+}
+
+var ordersDesc = prometheus.NewDesc(namespace+"_orders", "Orders currently in each status.", []string{"status"}, nil)
+
+// orderStatusCollector counts orders per status from the store at scrape time.
+type orderStatusCollector struct {
+	orders store.OrderStore
+}
+
+// RegisterOrderStatus exports the number of orders per status, read from
+// orders on every scrape.
+func RegisterOrderStatus(orders store.OrderStore) {
+	Registry.MustRegister(&orderStatusCollector{orders: orders})
+}
+
+func (c *orderStatusCollector) Describe(ch chan<- *prometheus.Desc) { ch <- ordersDesc }
+
+func (c *orderStatusCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, st := range statuses {
+		n, err := c.orders.CountOrders(ctx, store.OrderFilter{Status: st})
+		if err != nil {
+			slog.ErrorContext(ctx, "metrics count orders failed", "status", string(st), "err", err)
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(ordersDesc, prometheus.GaugeValue, float64(n), string(st))
+	}
+}
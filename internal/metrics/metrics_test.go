@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store/memstore"
+)
+
+func TestScrape(t *testing.T) {
+	orders := memstore.NewOrders()
+	now := time.Now().UTC()
+	for i, st := range []models.OrderStatus{models.StatusCompleted, models.StatusCompleted, models.StatusRefunding} {
+		id := fmt.Sprintf("o%d", i)
+		o := &models.Order{ID: id, PublicID: "pub-" + id, Status: st, CreatedAt: now, UpdatedAt: now}
+		if err := orders.Insert(context.Background(), o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	RegisterOrderStatus(orders)
+
+	detected, credited := now.Add(30*time.Second), now.Add(90*time.Second)
+	ObserveCredited(&models.Order{CreatedAt: now, DepositTxDetectedAt: &detected, DepositCreditedAt: &credited})
+	Refund("amount_below_fee")
+	ObserveVenue("exinswap", "swap", time.Now(), errors.New("boom"))
+	SetWalletBalances(nil)
+	SetLedgerBalances(map[string]string{"usdt": "12.5", "bad": "x"})
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		`bridge_orders{status="completed"} 2`,
+		`bridge_orders{status="refunding"} 1`,
+		`bridge_orders{status="withdrawing"} 0`,
+		`bridge_deposit_detect_seconds_sum 30`,
+		`bridge_deposit_credit_seconds_sum 60`,
+		`bridge_refunds_total{reason="amount_below_fee"} 1`,
+		`bridge_venue_request_errors_total{op="swap",venue="exinswap"} 1`,
+		`bridge_ledger_expected_balance{asset_id="usdt"} 12.5`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("scrape is missing %s", want)
+		}
+	}
+	if strings.Contains(string(body), `asset_id="bad"`) {
+		t.Error("exported an unparsable ledger balance")
+	}
+}
+
+func TestVenues(t *testing.T) {
+	ObserveVenue("mixin", "transfer", time.Now(), errors.New("timeout"))
+	if v := Venues()["mixin"]; v.Reachable() || v.LastErrorOp != "transfer" || v.Error != "timeout" {
+		t.Fatalf("after error: %+v", v)
+	}
+	time.Sleep(time.Millisecond) // LastOK must be strictly after LastError
+	ObserveVenue("mixin", "transfer", time.Now(), nil)
+	if v := Venues()["mixin"]; !v.Reachable() || v.Error != "timeout" {
+		t.Errorf("after success: %+v", v)
+	}
+	if (VenueSeen{}).Reachable() {
+		t.Error("a venue never called is reachable")
+	}
+}
@@ -0,0 +1,96 @@
+package mixin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	bot "github.com/MixinNetwork/bot-api-go-client/v2"
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/metrics"
+	"github.com/mvg-fi-dev/bridge/internal/tracing"
+)
+
+const outputsPageSize = 500
+
+// SafeBalances sums the bot's unspent safe outputs per asset id.
+func (c *SDKClient) SafeBalances(ctx context.Context) (map[string]decimal.Decimal, error) {
+	ks := c.Keystore
+	if ks == nil {
+		return nil, fmt.Errorf("missing keystore")
+	}
+	u := ks.ToSafeUser()
+	members := bot.HashMembers([]string{ks.UserID})
+
+	out := map[string]decimal.Decimal{}
+	var offset uint64
+	for {
+		vctx, span := tracing.Start(ctx, "mixin.list_outputs")
+		start := time.Now()
+		page, err := bot.ListOutputs(vctx, members, 1, "", "unspent", offset, outputsPageSize, u)
+		metrics.ObserveVenue("mixin", "list_outputs", start, err)
+		tracing.End(span, err)
+		if err != nil {
+			return nil, fmt.Errorf("list safe outputs: %w", err)
+		}
+		for _, o := range page {
+			amt, err := decimal.NewFromString(o.Amount)
+			if err != nil {
+				return nil, fmt.Errorf("output %s amount %q: %w", o.OutputID, o.Amount, err)
+			}
+			out[o.AssetId] = out[o.AssetId].Add(amt)
+			offset = uint64(o.Sequence) + 1
+		}
+		if len(page) < outputsPageSize {
+			return out, nil
+		}
+	}
+}
+
+// FakeBalances stands in for the safe outputs API in local runs: it reads
+// {"<asset_id>": "<amount>"} from Path on every call, so balances can be
+// edited while the worker runs.
+type FakeBalances struct {
+	Path string
+}
+
+func (f *FakeBalances) SafeBalances(ctx context.Context) (map[string]decimal.Decimal, error) {
+	b, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read fake balances: %w", err)
+	}
+	var raw map[string]decimal.Decimal
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("parse fake balances %s: %w", f.Path, err)
+	}
+	out := map[string]decimal.Decimal{}
+	for asset, amt := range raw {
+		out[asset] = amt
+	}
+	return out, nil
+}
+
+// WithdrawalFee returns the network fee for withdrawing assetID, paid in the
+// chain's native asset. Assets without a published fee return zero.
+func (c *SDKClient) WithdrawalFee(ctx context.Context, assetID string) (string, decimal.Decimal, error) {
+	vctx, span := tracing.Start(ctx, "mixin.read_asset")
+	start := time.Now()
+	a, err := bot.ReadAsset(vctx, assetID)
+	metrics.ObserveVenue("mixin", "read_asset", start, err)
+	tracing.End(span, err)
+	if err != nil {
+		return "", decimal.Zero, fmt.Errorf("read asset %s: %w", assetID, err)
+	}
+	feeAsset := a.ChainID
+	if feeAsset == "" {
+		feeAsset = assetID
+	}
+	fee, err := decimal.NewFromString(a.Fee)
+	if err != nil {
+		return feeAsset, decimal.Zero, nil
+	}
+	return feeAsset, fee, nil
+}
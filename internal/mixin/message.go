@@ -0,0 +1,21 @@
+package mixin
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	bot "github.com/MixinNetwork/bot-api-go-client/v2"
+)
+
+// SendText posts a plain-text message from the bot to a conversation (e.g. an
+// ops group the bot was added to). Mixin drops a repeated messageID, so
+// retrying with the same id does not post twice.
+func (c *SDKClient) SendText(ctx context.Context, conversationID, messageID, text string) error {
+	ks := c.Keystore
+	if ks == nil {
+		return fmt.Errorf("missing keystore")
+	}
+	data := base64.StdEncoding.EncodeToString([]byte(text))
+	return bot.PostMessage(ctx, conversationID, "", messageID, bot.MessageCategoryPlainText, data, ks.UserID, ks.SessionID, ks.PrivateKey)
+}
@@ -0,0 +1,30 @@
+package mixin
+
+import (
+	"net/url"
+	"strings"
+)
+
+// PayURL returns the https://mixin.one/pay link that opens a prefilled
+// transfer in Mixin Messenger (or its download page without the app).
+// trace is the transfer's request id: paying the same link twice only moves
+// funds once.
+func PayURL(recipient, assetID, amount, trace, memo string) string {
+	q := url.Values{}
+	q.Set("recipient", recipient)
+	q.Set("asset", assetID)
+	q.Set("amount", amount)
+	q.Set("trace", trace)
+	q.Set("memo", memo)
+	return "https://mixin.one/pay?" + q.Encode()
+}
+
+// SchemeURL turns a PayURL into its mixin://pay form, which opens the app
+// directly.
+func SchemeURL(payURL string) string {
+	rest, ok := strings.CutPrefix(payURL, "https://mixin.one/pay")
+	if !ok {
+		return ""
+	}
+	return "mixin://pay" + rest
+}
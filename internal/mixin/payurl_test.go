@@ -0,0 +1,36 @@
+package mixin
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestPayURL(t *testing.T) {
+	link := PayURL("bot-id", "asset-id", "0.5", "trace-id", "memo with spaces&=")
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "https" || u.Host != "mixin.one" || u.Path != "/pay" {
+		t.Errorf("link = %s", link)
+	}
+	q := u.Query()
+	for k, want := range map[string]string{
+		"recipient": "bot-id",
+		"asset":     "asset-id",
+		"amount":    "0.5",
+		"trace":     "trace-id",
+		"memo":      "memo with spaces&=",
+	} {
+		if got := q.Get(k); got != want {
+			t.Errorf("%s = %q, want %q", k, got, want)
+		}
+	}
+
+	if got, want := SchemeURL(link), "mixin://pay?"+u.RawQuery; got != want {
+		t.Errorf("SchemeURL = %s, want %s", got, want)
+	}
+	if got := SchemeURL("https://example.com/pay?x=1"); got != "" {
+		t.Errorf("SchemeURL of a foreign link = %q", got)
+	}
+}
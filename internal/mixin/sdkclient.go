@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"time"
 
 	bot "github.com/MixinNetwork/bot-api-go-client/v2"
@@ -21,6 +22,11 @@ type SafeKeystore struct {
 	SpendPrivateKey string `json:"spend_private_key"`
 }
 
+// LogValue keeps keys out of logs if a keystore is ever logged.
+func (ks *SafeKeystore) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("user_id", ks.UserID), slog.String("session_id", ks.SessionID))
+}
+
 func (ks *SafeKeystore) Validate() error {
 	if ks.UserID == "" || ks.SessionID == "" || ks.PrivateKey == "" {
 		return fmt.Errorf("keystore missing user_id/session_id/private_key")
@@ -91,5 +97,6 @@ func SafeSnapshotToInternal(s *bot.SafeSnapshot) *Snapshot {
 		CreatedAt:  s.CreatedAt.UTC().Format(time.RFC3339Nano),
 		Memo:       s.Memo,
 		OpponentID: s.OpponentID,
+		TraceID:    s.RequestId,
 	}
 }
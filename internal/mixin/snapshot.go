@@ -14,6 +14,8 @@ type Snapshot struct {
 	CreatedAt  string `json:"created_at"`
 	Memo       string `json:"memo"`
 	OpponentID string `json:"opponent_id"`
+	// TraceID is the request id the transfer was sent under (outgoing snapshots).
+	TraceID string `json:"trace_id,omitempty"`
 }
 
 type SnapshotEnvelope struct {
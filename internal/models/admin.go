@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// AuditEntry records one operator action taken through the admin API.
+type AuditEntry struct {
+	ID        string    `json:"id"`
+	Operator  string    `json:"operator"`
+	Action    string    `json:"action"`
+	OrderID   *string   `json:"order_id,omitempty"`
+	Detail    string    `json:"detail,omitempty"` // JSON
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrderNote is a free-text operator annotation on an order.
+type OrderNote struct {
+	ID        string    `json:"id"`
+	OrderID   string    `json:"order_id"`
+	Operator  string    `json:"operator"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BlocklistEntry is one screened address. Chain "*" matches every chain.
+type BlocklistEntry struct {
+	ID        string    `json:"id"`
+	Chain     string    `json:"chain"`
+	Address   string    `json:"address"`
+	Reason    string    `json:"reason"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
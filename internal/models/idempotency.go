@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// IdempotencyKey maps a client-supplied Idempotency-Key to the order it
+// created. Scope separates API clients; RequestHash detects key reuse with a
+// different request body.
+type IdempotencyKey struct {
+	Scope       string
+	Key         string
+	RequestHash string
+	OrderID     string
+	CreatedAt   time.Time
+}
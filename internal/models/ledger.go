@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// LedgerEntry is one leg of a ledger journal. Amount is signed: positive
+// moves funds into Account, negative out of it. The legs of a journal sum to
+// zero per asset.
+type LedgerEntry struct {
+	ID        string    `json:"id"`
+	JournalID string    `json:"journal_id"`
+	Account   string    `json:"account"`
+	AssetID   string    `json:"asset_id"`
+	Amount    string    `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LedgerJournal is one balanced movement of funds. (Kind, Ref) is unique, so
+// posting the same movement twice is a no-op.
+type LedgerJournal struct {
+	ID        string         `json:"id"`
+	Kind      string         `json:"kind"`
+	Ref       string         `json:"ref"` // snapshot id or transfer trace id
+	OrderID   *string        `json:"order_id,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	Entries   []*LedgerEntry `json:"entries"`
+}
+
+// LedgerBalance is the net amount held by an account in one asset.
+type LedgerBalance struct {
+	Account string `json:"account"`
+	AssetID string `json:"asset_id"`
+	Amount  string `json:"amount"`
+}
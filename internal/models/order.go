@@ -5,62 +5,136 @@ import "time"
 type OrderStatus string
 
 const (
-	StatusQuoteCreated       OrderStatus = "quote_created"
-	StatusAwaitingDeposit    OrderStatus = "awaiting_deposit"
-	StatusDepositDetected    OrderStatus = "deposit_tx_detected"
+	StatusQuoteCreated        OrderStatus = "quote_created"
+	StatusAwaitingDeposit     OrderStatus = "awaiting_deposit"
+	StatusDepositDetected     OrderStatus = "deposit_tx_detected"
 	StatusDepositPendingMixin OrderStatus = "deposit_pending_mixin"
-	StatusDepositCredited    OrderStatus = "deposit_credited"
-	StatusExecutingSwap      OrderStatus = "executing_swap"
-	StatusWithdrawing        OrderStatus = "withdrawing"
-	StatusCompleted          OrderStatus = "completed"
-	StatusRefunding          OrderStatus = "refunding"
-	StatusRefunded           OrderStatus = "refunded"
-	StatusFailedManual       OrderStatus = "failed_manual_review"
+	StatusDepositCredited     OrderStatus = "deposit_credited"
+	StatusExecutingSwap       OrderStatus = "executing_swap"
+	StatusWithdrawing         OrderStatus = "withdrawing"
+	StatusCompleted           OrderStatus = "completed"
+	StatusRefunding           OrderStatus = "refunding"
+	StatusRefunded            OrderStatus = "refunded"
+	StatusFailedManual        OrderStatus = "failed_manual_review"
+	StatusComplianceHold      OrderStatus = "compliance_hold"
+	StatusAwaitingLiquidity   OrderStatus = "awaiting_liquidity"
 )
 
+// Stage identifies the executor step an order is being retried in.
+// Attempt counters are tracked per stage and reset when the stage changes.
+type Stage string
+
+const (
+	StageSwap     Stage = "swap"
+	StageWithdraw Stage = "withdraw"
+	StageRefund   Stage = "refund"
+)
+
+// Status is the order status a stage is executed from; an order released from
+// failed_manual_review goes back to this status.
+func (s Stage) Status() (OrderStatus, bool) {
+	switch s {
+	case StageSwap:
+		return StatusDepositCredited, true
+	case StageWithdraw:
+		return StatusWithdrawing, true
+	case StageRefund:
+		return StatusRefunding, true
+	}
+	return "", false
+}
+
+// StageForStatus is the inverse of Stage.Status. executing_swap has no stage:
+// the transfer to ExinSwap is out and only its reply may move the order.
+func StageForStatus(st OrderStatus) (Stage, bool) {
+	switch st {
+	case StatusDepositCredited:
+		return StageSwap, true
+	case StatusWithdrawing:
+		return StageWithdraw, true
+	case StatusRefunding:
+		return StageRefund, true
+	}
+	return "", false
+}
+
 type Order struct {
-	ID        string
-	PublicID  string
-	Status    OrderStatus
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID        string      `json:"id"`
+	PublicID  string      `json:"public_id"`
+	Status    OrderStatus `json:"status"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
 
 	// Requested swap
-	SourceChain  string
-	SourceAsset  string
-	AmountIn     string
-	TargetChain  string
-	TargetAsset  string
-	TargetAddress string
+	SourceChain   string `json:"source_chain"`
+	SourceAsset   string `json:"source_asset"`
+	AmountIn      string `json:"amount_in"`
+	TargetChain   string `json:"target_chain"`
+	TargetAsset   string `json:"target_asset"`
+	TargetAddress string `json:"target_address"`
 
 	// Quote
-	EstimatedOut string
-	MinOut       string
-	QuoteExpiryAt *time.Time
+	EstimatedOut  string     `json:"estimated_out"`
+	MinOut        string     `json:"min_out"`
+	QuoteExpiryAt *time.Time `json:"quote_expiry_at"`
 
 	// Timing
-	PayWindowSeconds int64
+	PayWindowSeconds int64 `json:"pay_window_seconds"`
+
+	// Integrator that created the order (nil for anonymous orders)
+	PartnerID *string `json:"partner_id"`
+
+	// Fee terms fixed at quote time (nil for orders created before fees)
+	FeeBps      *int64  `json:"fee_bps"`
+	FeeFlat     *string `json:"fee_flat"`
+	FeeShareBps *int64  `json:"fee_share_bps"`
+
+	// Fee retained at swap time (source asset) and the partner's share
+	FeeAmount        *string `json:"fee_amount"`
+	PartnerFeeAmount *string `json:"partner_fee_amount"`
 
 	// Mixin payment UX (for Mixin-first MVP)
-	MixinOpponentID   string
-	MixinAssetID      string
-	MixinPayMemo      string
-	MixinPayURL       string
+	MixinOpponentID string `json:"mixin_opponent_id"`
+	MixinAssetID    string `json:"mixin_asset_id"`
+	MixinPayMemo    string `json:"mixin_pay_memo"`
+	MixinPayURL     string `json:"mixin_pay_url"`
 
 	// Deposit tracking
-	DepositTxID          *string
-	DepositTxDetectedAt  *time.Time
-	DepositCreditedAt    *time.Time
-	AmountCredited       *string
-	RefundToAddress      *string
+	DepositTxID         *string    `json:"deposit_tx_id"`
+	DepositTxDetectedAt *time.Time `json:"deposit_tx_detected_at"`
+	DepositCreditedAt   *time.Time `json:"deposit_credited_at"`
+	AmountCredited      *string    `json:"amount_credited"`
+	RefundToAddress     *string    `json:"refund_to_address"`
 
 	// Execution
-	FinalOut        *string
-	SwapRef         *string
-	ExinSwapTraceID *string
-	WithdrawTxID    *string
-	RefundTxID      *string
-	RefundAssetID   *string
-	RefundAmount    *string
-	RefundReceivedSnapshotID *string
+	FinalOut                 *string `json:"final_out"`
+	SwapRef                  *string `json:"swap_ref"`
+	ExinSwapTraceID          *string `json:"exinswap_trace_id"`
+	WithdrawTraceID          *string `json:"withdraw_trace_id"`
+	RefundTraceID            *string `json:"refund_trace_id"`
+	WithdrawTxID             *string `json:"withdraw_tx_id"`
+	RefundTxID               *string `json:"refund_tx_id"`
+	RefundAssetID            *string `json:"refund_asset_id"`
+	RefundAmount             *string `json:"refund_amount"`
+	RefundReceivedSnapshotID *string `json:"refund_received_snapshot_id"`
+
+	// Retry scheduling (per stage)
+	RetryStage    *string    `json:"retry_stage"`
+	Attempts      int64      `json:"attempts"`
+	LastError     *string    `json:"last_error"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+
+	// Worker lease (multi-replica safety)
+	LockedBy   *string    `json:"locked_by"`
+	LeaseUntil *time.Time `json:"lease_until"`
+
+	// Set once the post-deposit limit check passed or an operator released the order.
+	LimitsClearedAt *time.Time `json:"limits_cleared_at"`
+
+	// Set when an operator released the order from compliance_hold.
+	ScreeningClearedAt *time.Time `json:"screening_cleared_at"`
+
+	// W3C traceparent of the API request that created the order; worker
+	// spans for the order link to it.
+	TraceContext *string `json:"trace_context"`
 }
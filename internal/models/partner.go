@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Partner is an integrator (front-end) that creates orders with an API key.
+// Requests are signed with Secret; it is only shown once, at creation or
+// rotation.
+type Partner struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	APIKey string `json:"api_key"`
+	Secret string `json:"-"`
+
+	// Share of the bridge fee credited to the partner, in basis points.
+	FeeShareBps int64 `json:"fee_share_bps"`
+	// Orders per UTC day; 0 means unlimited.
+	DailyOrderQuota int64 `json:"daily_order_quota"`
+	// Overrides the default per-key rate limit; 0 uses the default.
+	RateLimitPerMin int64 `json:"rate_limit_per_min"`
+
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// OrderEvent is one status transition from the order change feed. PrevStatus
+// is nil for the order's creation.
+type OrderEvent struct {
+	ID           int64        `json:"id"`
+	OrderID      string       `json:"order_id"`
+	Status       OrderStatus  `json:"status"`
+	PrevStatus   *OrderStatus `json:"prev_status,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+	DispatchedAt *time.Time   `json:"dispatched_at,omitempty"`
+}
+
+// WebhookEndpoint is a partner callback URL. Secret signs the payloads; it
+// is only shown once, at creation.
+type WebhookEndpoint struct {
+	ID        string    `json:"id"`
+	PartnerID string    `json:"partner_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed" // retries exhausted
+)
+
+// WebhookDelivery is one event sent (or to be sent) to one endpoint.
+type WebhookDelivery struct {
+	ID             string         `json:"id"`
+	EndpointID     string         `json:"endpoint_id"`
+	PartnerID      string         `json:"partner_id"`
+	EventID        int64          `json:"event_id"`
+	OrderID        string         `json:"-"`
+	EventType      string         `json:"event_type"`
+	Payload        string         `json:"-"` // JSON
+	Status         DeliveryStatus `json:"status"`
+	Attempts       int64          `json:"attempts"`
+	NextAttemptAt  *time.Time     `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int64         `json:"last_status_code,omitempty"`
+	LastError      *string        `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
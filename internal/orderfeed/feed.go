@@ -0,0 +1,203 @@
+// Package orderfeed pushes order status changes to in-process subscribers
+// (the API's SSE streams). The worker and the API are separate processes, so
+// changes are read from the order_events table, which the database fills on
+// every status change: one poller per API process queries it for all
+// subscribed orders at once.
+package orderfeed
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// maxOrdersPerQuery bounds the IN list of one feed query.
+const maxOrdersPerQuery = 500
+
+// DefaultLag is how long events stay open to late commits (see Feed.Lag).
+const DefaultLag = 10 * time.Second
+
+// Subscription receives the events of one order after a given event id.
+// C is closed when the subscriber falls behind (its buffer is full) or the
+// feed stops; the subscriber should then reload the order and resubscribe.
+type Subscription struct {
+	C <-chan *models.OrderEvent
+
+	c       chan *models.OrderEvent
+	orderID string
+	closed  bool
+	// after is the settled cursor: every event up to it was delivered or is
+	// older than the lag. sent holds the delivered events past it by id,
+	// with their created_at.
+	after int64
+	sent  map[int64]time.Time
+}
+
+// Feed polls the change feed every Interval while it has subscribers.
+//
+// Event ids come from a sequence, so on Postgres an event can commit after
+// one with a higher id is already visible. Each poll therefore re-reads the
+// events of the last Lag (by created_at) and skips the ones it delivered;
+// only older events move a subscription's cursor.
+type Feed struct {
+	Events   store.OrderEventStore
+	Interval time.Duration
+	Lag      time.Duration
+
+	mu   sync.Mutex
+	subs map[string]map[*Subscription]struct{}
+}
+
+func New(events store.OrderEventStore, interval time.Duration) *Feed {
+	return &Feed{Events: events, Interval: interval, Lag: DefaultLag, subs: map[string]map[*Subscription]struct{}{}}
+}
+
+// Subscribe delivers orderID's events with id > after.
+func (f *Feed) Subscribe(orderID string, after int64) *Subscription {
+	c := make(chan *models.OrderEvent, 16)
+	s := &Subscription{C: c, c: c, orderID: orderID, after: after, sent: map[int64]time.Time{}}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.subs[orderID] == nil {
+		f.subs[orderID] = map[*Subscription]struct{}{}
+	}
+	f.subs[orderID][s] = struct{}{}
+	return s
+}
+
+// Unsubscribe stops delivery to s. It is safe to call more than once.
+func (f *Feed) Unsubscribe(s *Subscription) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.remove(s)
+}
+
+// remove is called with f.mu held.
+func (f *Feed) remove(s *Subscription) {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.c)
+	delete(f.subs[s.orderID], s)
+	if len(f.subs[s.orderID]) == 0 {
+		delete(f.subs, s.orderID)
+	}
+}
+
+// Subscribers returns the number of open subscriptions.
+func (f *Feed) Subscribers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, m := range f.subs {
+		n += len(m)
+	}
+	return n
+}
+
+// Run polls until ctx is done, then closes every subscription.
+func (f *Feed) Run(ctx context.Context) {
+	t := time.NewTicker(f.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			f.mu.Lock()
+			for _, m := range f.subs {
+				for s := range m {
+					f.remove(s)
+				}
+			}
+			f.mu.Unlock()
+			return
+		case <-t.C:
+		}
+		if err := f.Poll(ctx); err != nil && ctx.Err() == nil {
+			slog.WarnContext(ctx, "order feed poll failed", "err", err)
+		}
+	}
+}
+
+// Poll fetches new events for the subscribed orders and hands them out,
+// querying from the lowest settled cursor among the subscribers.
+func (f *Feed) Poll(ctx context.Context) error {
+	f.mu.Lock()
+	var orderIDs []string
+	var after int64 = -1
+	for id, m := range f.subs {
+		orderIDs = append(orderIDs, id)
+		for s := range m {
+			if after < 0 || s.after < after {
+				after = s.after
+			}
+		}
+	}
+	f.mu.Unlock()
+
+	for len(orderIDs) > 0 {
+		batch := orderIDs
+		if len(batch) > maxOrdersPerQuery {
+			batch = batch[:maxOrdersPerQuery]
+		}
+		orderIDs = orderIDs[len(batch):]
+		if err := f.pollBatch(ctx, batch, after); err != nil {
+			return err
+		}
+	}
+	f.settle(time.Now().Add(-f.Lag))
+	return nil
+}
+
+// settle moves each cursor past the delivered events created before horizon.
+func (f *Feed) settle(horizon time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, m := range f.subs {
+		for s := range m {
+			for id, at := range s.sent {
+				if at.Before(horizon) && id > s.after {
+					s.after = id
+				}
+			}
+			for id := range s.sent {
+				if id <= s.after {
+					delete(s.sent, id)
+				}
+			}
+		}
+	}
+}
+
+func (f *Feed) pollBatch(ctx context.Context, orderIDs []string, after int64) error {
+	const limit = 1000
+	for {
+		events, err := f.Events.ListOrderEvents(ctx, orderIDs, after, limit)
+		if err != nil {
+			return err
+		}
+		f.mu.Lock()
+		for _, e := range events {
+			for s := range f.subs[e.OrderID] {
+				if _, ok := s.sent[e.ID]; ok || e.ID <= s.after {
+					continue
+				}
+				select {
+				case s.c <- e:
+					s.sent[e.ID] = e.CreatedAt
+				default:
+					f.remove(s)
+				}
+			}
+			after = e.ID
+		}
+		f.mu.Unlock()
+		if len(events) < limit {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package orderfeed
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+// events is a store.OrderEventStore whose rows become visible when
+// committed, in any id order.
+type events struct {
+	mu   sync.Mutex
+	rows []*models.OrderEvent
+}
+
+func (s *events) commit(id int64, orderID string, createdAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows = append(s.rows, &models.OrderEvent{ID: id, OrderID: orderID, Status: models.StatusWithdrawing, CreatedAt: createdAt})
+	sort.Slice(s.rows, func(i, j int) bool { return s.rows[i].ID < s.rows[j].ID })
+}
+
+func (s *events) LatestOrderEventID(ctx context.Context, orderID string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var id int64
+	for _, e := range s.rows {
+		if e.OrderID == orderID {
+			id = e.ID
+		}
+	}
+	return id, nil
+}
+
+func (s *events) ListOrderEvents(ctx context.Context, orderIDs []string, afterID int64, limit int) ([]*models.OrderEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*models.OrderEvent
+	for _, e := range s.rows {
+		for _, id := range orderIDs {
+			if e.OrderID == id && e.ID > afterID && len(out) < limit {
+				out = append(out, e)
+			}
+		}
+	}
+	return out, nil
+}
+
+// received drains what sub got so far.
+func received(sub *Subscription) []int64 {
+	var ids []int64
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return ids
+			}
+			ids = append(ids, e.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func poll(t *testing.T, f *Feed) {
+	t.Helper()
+	if err := f.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLateCommitIsDelivered(t *testing.T) {
+	store := &events{}
+	f := New(store, time.Second)
+	now := time.Now()
+	a, b := f.Subscribe("a", 0), f.Subscribe("b", 0)
+
+	// Event 2 commits while event 1 is still in flight.
+	store.commit(2, "a", now)
+	poll(t, f)
+	if got := received(a); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("a got %v", got)
+	}
+	store.commit(1, "a", now)
+	store.commit(3, "b", now)
+	poll(t, f)
+	if got := received(a); len(got) != 1 || got[0] != 1 {
+		t.Errorf("a got %v, want the late event 1", got)
+	}
+	if got := received(b); len(got) != 1 || got[0] != 3 {
+		t.Errorf("b got %v", got)
+	}
+	// Re-reading the lag window does not deliver twice.
+	poll(t, f)
+	if got := append(received(a), received(b)...); len(got) != 0 {
+		t.Errorf("third poll delivered %v", got)
+	}
+}
+
+func TestCursorSettlesAfterLag(t *testing.T) {
+	store := &events{}
+	f := New(store, time.Second)
+	now := time.Now()
+	sub := f.Subscribe("a", 0)
+
+	store.commit(1, "a", now.Add(-time.Minute))
+	store.commit(2, "a", now)
+	poll(t, f)
+	if got := received(sub); len(got) != 2 {
+		t.Fatalf("got %v", got)
+	}
+	if sub.after != 1 || len(sub.sent) != 1 {
+		t.Errorf("after = %d, sent = %v; want the old event settled and the new one kept", sub.after, sub.sent)
+	}
+
+	// With no lag, delivery settles right away.
+	f.Lag = 0
+	poll(t, f)
+	if sub.after != 2 || len(sub.sent) != 0 {
+		t.Errorf("after = %d, sent = %v", sub.after, sub.sent)
+	}
+}
+
+func TestSlowSubscriberIsClosed(t *testing.T) {
+	store := &events{}
+	f := New(store, time.Second)
+	sub := f.Subscribe("a", 0)
+	for i := int64(1); i <= 20; i++ {
+		store.commit(i, "a", time.Now())
+	}
+	poll(t, f)
+	got := received(sub)
+	if len(got) != 16 {
+		t.Errorf("got %d events, want the 16 buffered", len(got))
+	}
+	if _, ok := <-sub.C; ok {
+		t.Error("subscription still open")
+	}
+	if n := f.Subscribers(); n != 0 {
+		t.Errorf("subscribers = %d", n)
+	}
+}
@@ -0,0 +1,189 @@
+// Package orderview renders orders for integrators. The shape is versioned
+// and leaves out internal bookkeeping (internal ids, venue trace ids, leases,
+// retry state, operator notes), so it can be published in API responses and
+// webhook payloads without leaking how the bridge executes an order.
+package orderview
+
+import (
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/mixin"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+// Version is bumped on any incompatible change to Order. Fields may be added
+// within a version.
+const Version = 1
+
+// Order is the public view of an order.
+type Order struct {
+	Version       int                `json:"version"`
+	PublicID      string             `json:"public_id"`
+	Status        models.OrderStatus `json:"status"`
+	SourceChain   string             `json:"source_chain"`
+	SourceAsset   string             `json:"source_asset"`
+	AmountIn      string             `json:"amount_in"`
+	TargetChain   string             `json:"target_chain"`
+	TargetAsset   string             `json:"target_asset"`
+	TargetAddress string             `json:"target_address"`
+
+	EstimatedOut   string     `json:"estimated_out"`
+	MinOut         string     `json:"min_out"`
+	QuoteExpiresAt *time.Time `json:"quote_expires_at,omitempty"`
+
+	// Payment is what the user sends; ExpiresAt closes the pay window.
+	Payment          Payment   `json:"payment"`
+	PayWindowSeconds int64     `json:"pay_window_seconds"`
+	ExpiresAt        time.Time `json:"expires_at"`
+
+	DepositTxID       *string    `json:"deposit_tx_id,omitempty"`
+	DepositDetectedAt *time.Time `json:"deposit_detected_at,omitempty"`
+	AmountCredited    *string    `json:"amount_credited,omitempty"`
+	DepositCreditedAt *time.Time `json:"deposit_credited_at,omitempty"`
+	FeeAmount         *string    `json:"fee_amount,omitempty"`
+	FinalOut          *string    `json:"final_out,omitempty"`
+	WithdrawTxID      *string    `json:"withdraw_tx_id,omitempty"`
+	RefundAssetID     *string    `json:"refund_asset_id,omitempty"`
+	RefundAmount      *string    `json:"refund_amount,omitempty"`
+	RefundTxID        *string    `json:"refund_tx_id,omitempty"`
+	NextStep          NextStep   `json:"next_step"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// Payment tells the user how to pay the order through Mixin.
+type Payment struct {
+	OpponentID string `json:"opponent_id"`
+	AssetID    string `json:"asset_id"`
+	Amount     string `json:"amount"`
+	Memo       string `json:"memo"`
+	// Prefilled transfer links: PayURL (https, also encoded by
+	// /v1/orders/{public_id}/qr.png) and MixinURL (mixin://, opens the app).
+	PayURL   string `json:"pay_url,omitempty"`
+	MixinURL string `json:"mixin_url,omitempty"`
+}
+
+// Next-step actions. Clients branch on Action; Message is for display.
+const (
+	ActionPay            = "pay"             // send Payment before ExpiresAt
+	ActionWait           = "wait"            // nothing to do; the bridge is working
+	ActionExpired        = "expired"         // pay window closed; create a new order
+	ActionContactSupport = "contact_support" // held for review
+	ActionNone           = "none"            // finished
+)
+
+// NextStep is what the user should do now.
+type NextStep struct {
+	Action  string `json:"action"`
+	Message string `json:"message"`
+}
+
+func New(o *models.Order) *Order {
+	return newAt(o, time.Now())
+}
+
+func newAt(o *models.Order, now time.Time) *Order {
+	return &Order{
+		Version:       Version,
+		PublicID:      o.PublicID,
+		Status:        o.Status,
+		SourceChain:   o.SourceChain,
+		SourceAsset:   o.SourceAsset,
+		AmountIn:      o.AmountIn,
+		TargetChain:   o.TargetChain,
+		TargetAsset:   o.TargetAsset,
+		TargetAddress: o.TargetAddress,
+		EstimatedOut:  o.EstimatedOut,
+		MinOut:        o.MinOut,
+
+		QuoteExpiresAt: o.QuoteExpiryAt,
+		Payment: Payment{
+			OpponentID: o.MixinOpponentID,
+			AssetID:    o.MixinAssetID,
+			Amount:     o.AmountIn,
+			Memo:       o.MixinPayMemo,
+			PayURL:     o.MixinPayURL,
+			MixinURL:   mixin.SchemeURL(o.MixinPayURL),
+		},
+		PayWindowSeconds: o.PayWindowSeconds,
+		ExpiresAt:        expiresAt(o),
+
+		DepositTxID:       o.DepositTxID,
+		DepositDetectedAt: o.DepositTxDetectedAt,
+		AmountCredited:    o.AmountCredited,
+		DepositCreditedAt: o.DepositCreditedAt,
+		FeeAmount:         o.FeeAmount,
+		FinalOut:          o.FinalOut,
+		WithdrawTxID:      o.WithdrawTxID,
+		RefundAssetID:     o.RefundAssetID,
+		RefundAmount:      o.RefundAmount,
+		RefundTxID:        o.RefundTxID,
+		NextStep:          NextStepFor(o, now),
+		CreatedAt:         o.CreatedAt,
+		UpdatedAt:         o.UpdatedAt,
+	}
+}
+
+func expiresAt(o *models.Order) time.Time {
+	return o.CreatedAt.Add(time.Duration(o.PayWindowSeconds) * time.Second).UTC()
+}
+
+// NextStepFor describes what the user should do about o at now.
+func NextStepFor(o *models.Order, now time.Time) NextStep {
+	switch o.Status {
+	case models.StatusQuoteCreated:
+		return NextStep{ActionWait, "The order is being prepared."}
+	case models.StatusAwaitingDeposit:
+		if now.After(expiresAt(o)) {
+			return NextStep{ActionExpired, "The pay window has closed. Do not pay this order; a late payment is refunded. Create a new order."}
+		}
+		return NextStep{ActionPay, "Send exactly " + o.AmountIn + " " + o.SourceAsset + " through Mixin to the bridge with the memo in payment.memo before " + expiresAt(o).Format(time.RFC3339) + "."}
+	case models.StatusDepositDetected, models.StatusDepositPendingMixin:
+		return NextStep{ActionWait, "Your payment was detected and is waiting for confirmation."}
+	case models.StatusDepositCredited, models.StatusExecutingSwap:
+		return NextStep{ActionWait, "Your payment was received. Swapping to " + o.TargetAsset + "."}
+	case models.StatusWithdrawing:
+		return NextStep{ActionWait, "Sending " + o.TargetAsset + " to " + o.TargetAddress + " on " + o.TargetChain + "."}
+	case models.StatusAwaitingLiquidity:
+		return NextStep{ActionWait, "Your payout is queued and will be sent automatically."}
+	case models.StatusCompleted:
+		return NextStep{ActionNone, "Completed. The payout transaction is withdraw_tx_id."}
+	case models.StatusRefunding:
+		return NextStep{ActionWait, "The order could not be completed. Refunding your payment."}
+	case models.StatusRefunded:
+		return NextStep{ActionNone, "Refunded. The refund transaction is refund_tx_id."}
+	case models.StatusFailedManual, models.StatusComplianceHold:
+		return NextStep{ActionContactSupport, "The order is under review. Contact support with the public_id."}
+	}
+	return NextStep{ActionWait, "The order is being processed."}
+}
+
+// Admin is the operator view: every field of the order, plus the next step
+// the user is shown. Its shape follows models.Order and is not versioned.
+type Admin struct {
+	*models.Order
+	NextStep NextStep `json:"next_step"`
+}
+
+func NewAdmin(o *models.Order) *Admin {
+	return &Admin{Order: o, NextStep: NextStepFor(o, time.Now())}
+}
+
+// NewAdmins maps NewAdmin over orders.
+func NewAdmins(orders []*models.Order) []*Admin {
+	out := make([]*Admin, 0, len(orders))
+	for _, o := range orders {
+		out = append(out, NewAdmin(o))
+	}
+	return out
+}
+
+// NewList maps New over orders.
+func NewList(orders []*models.Order) []*Order {
+	out := make([]*Order, 0, len(orders))
+	now := time.Now()
+	for _, o := range orders {
+		out = append(out, newAt(o, now))
+	}
+	return out
+}
@@ -0,0 +1,119 @@
+package orderview
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+func testOrder(st models.OrderStatus, createdAt time.Time) *models.Order {
+	trace, lockedBy, note := "trace-1", "worker-1", "boom"
+	return &models.Order{
+		ID:               "o1",
+		PublicID:         "BRG_1",
+		Status:           st,
+		CreatedAt:        createdAt,
+		UpdatedAt:        createdAt,
+		SourceChain:      "ETH",
+		SourceAsset:      "USDT",
+		AmountIn:         "100",
+		TargetChain:      "TRON",
+		TargetAsset:      "USDT",
+		TargetAddress:    "Ttarget",
+		EstimatedOut:     "99",
+		MinOut:           "98",
+		PayWindowSeconds: 900,
+		MixinOpponentID:  "bot",
+		MixinAssetID:     "asset",
+		MixinPayMemo:     "memo",
+		MixinPayURL:      "https://mixin.one/pay/x?asset=asset",
+		ExinSwapTraceID:  &trace,
+		LockedBy:         &lockedBy,
+		LastError:        &note,
+	}
+}
+
+func TestNew(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("X", 3600))
+	v := New(testOrder(models.StatusCompleted, created))
+
+	if v.Version != Version || v.PublicID != "BRG_1" || v.Status != models.StatusCompleted || v.AmountIn != "100" {
+		t.Errorf("view = %+v", v)
+	}
+	if want := created.Add(15 * time.Minute).UTC(); !v.ExpiresAt.Equal(want) || v.ExpiresAt.Location() != time.UTC {
+		t.Errorf("expires_at = %v, want %v", v.ExpiresAt, want)
+	}
+	p := v.Payment
+	if p.OpponentID != "bot" || p.AssetID != "asset" || p.Amount != "100" || p.Memo != "memo" || p.MixinURL != "mixin://pay/x?asset=asset" {
+		t.Errorf("payment = %+v", p)
+	}
+	if v.NextStep.Action != ActionNone {
+		t.Errorf("next_step = %+v", v.NextStep)
+	}
+
+	// Internal bookkeeping stays out of the published JSON.
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leak := range []string{`"o1"`, "trace-1", "worker-1", "boom", `"id"`} {
+		if strings.Contains(string(raw), leak) {
+			t.Errorf("view leaks %s: %s", leak, raw)
+		}
+	}
+}
+
+func TestNextStepFor(t *testing.T) {
+	created := time.Now().Add(-time.Minute)
+	for st, want := range map[models.OrderStatus]string{
+		models.StatusQuoteCreated:        ActionWait,
+		models.StatusAwaitingDeposit:     ActionPay,
+		models.StatusDepositDetected:     ActionWait,
+		models.StatusDepositPendingMixin: ActionWait,
+		models.StatusDepositCredited:     ActionWait,
+		models.StatusExecutingSwap:       ActionWait,
+		models.StatusWithdrawing:         ActionWait,
+		models.StatusAwaitingLiquidity:   ActionWait,
+		models.StatusCompleted:           ActionNone,
+		models.StatusRefunding:           ActionWait,
+		models.StatusRefunded:            ActionNone,
+		models.StatusFailedManual:        ActionContactSupport,
+		models.StatusComplianceHold:      ActionContactSupport,
+		"unknown":                        ActionWait,
+	} {
+		got := NextStepFor(testOrder(st, created), time.Now())
+		if got.Action != want || got.Message == "" {
+			t.Errorf("%s: next_step = %+v, want %s", st, got, want)
+		}
+	}
+
+	// The pay step names the amount and the deadline, and lapses with the window.
+	o := testOrder(models.StatusAwaitingDeposit, created)
+	pay := NextStepFor(o, time.Now())
+	if !strings.Contains(pay.Message, "100 USDT") || !strings.Contains(pay.Message, expiresAt(o).Format(time.RFC3339)) {
+		t.Errorf("pay message = %q", pay.Message)
+	}
+	if got := NextStepFor(o, expiresAt(o).Add(time.Second)); got.Action != ActionExpired {
+		t.Errorf("after the window: %+v, want %s", got, ActionExpired)
+	}
+	if got := NextStepFor(o, expiresAt(o)); got.Action != ActionPay {
+		t.Errorf("at the deadline: %+v, want %s", got, ActionPay)
+	}
+}
+
+func TestNewList(t *testing.T) {
+	orders := []*models.Order{testOrder(models.StatusCompleted, time.Now()), testOrder(models.StatusRefunded, time.Now())}
+	if got := NewList(orders); len(got) != 2 || got[1].Status != models.StatusRefunded {
+		t.Errorf("NewList = %+v", got)
+	}
+	if got := NewList(nil); got == nil || len(got) != 0 {
+		t.Errorf("NewList(nil) = %#v, want an empty list", got)
+	}
+	admins := NewAdmins(orders)
+	if len(admins) != 2 || admins[0].ID != "o1" || admins[0].NextStep.Action != ActionNone {
+		t.Errorf("NewAdmins = %+v", admins)
+	}
+}
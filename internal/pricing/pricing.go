@@ -0,0 +1,66 @@
+// Package pricing provides USD prices for Mixin assets (used for notional
+// limits and reporting).
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	bot "github.com/MixinNetwork/bot-api-go-client/v2"
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/metrics"
+	"github.com/mvg-fi-dev/bridge/internal/tracing"
+)
+
+// Source returns the current USD price of one unit of a Mixin asset.
+type Source interface {
+	PriceUSD(ctx context.Context, assetID string) (decimal.Decimal, error)
+}
+
+type cached struct {
+	price decimal.Decimal
+	at    time.Time
+}
+
+// MixinTicker reads prices from Mixin's public /network/ticker endpoint and
+// caches them for TTL so request paths do not hit the network every time.
+type MixinTicker struct {
+	TTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+func NewMixinTicker(ttl time.Duration) *MixinTicker {
+	return &MixinTicker{TTL: ttl, cache: map[string]cached{}}
+}
+
+func (t *MixinTicker) PriceUSD(ctx context.Context, assetID string) (decimal.Decimal, error) {
+	t.mu.Lock()
+	c, ok := t.cache[assetID]
+	t.mu.Unlock()
+	if ok && time.Since(c.at) < t.TTL {
+		return c.price, nil
+	}
+
+	vctx, span := tracing.Start(ctx, "mixin.ticker")
+	start := time.Now()
+	tk, err := bot.ReadAssetTicker(vctx, assetID)
+	metrics.ObserveVenue("mixin", "ticker", start, err)
+	tracing.End(span, err)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("read ticker %s: %w", assetID, err)
+	}
+	p, err := decimal.NewFromString(tk.PriceUSD)
+	if err != nil || !p.IsPositive() {
+		return decimal.Zero, fmt.Errorf("no usd price for asset %s", assetID)
+	}
+
+	t.mu.Lock()
+	t.cache[assetID] = cached{price: p, at: time.Now()}
+	t.mu.Unlock()
+	return p, nil
+}
@@ -0,0 +1,105 @@
+// Package ratelimit implements token-bucket rate limiting for the HTTP API.
+//
+// A bucket holds up to Burst tokens and refills at Rate tokens per second;
+// each request takes one token. Memory keeps buckets in-process (one API
+// replica); db.RateLimitRepo shares them between replicas.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Rule configures one class of buckets. A zero Rate disables the rule.
+type Rule struct {
+	Rate  float64 // tokens per second
+	Burst int
+}
+
+// PerMinute returns a rule allowing n requests per minute with the given
+// burst. A burst <= 0 picks DefaultBurst(n) rather than disabling the rule.
+func PerMinute(n int64, burst int64) Rule {
+	if burst <= 0 {
+		burst = DefaultBurst(n)
+	}
+	return Rule{Rate: float64(n) / 60, Burst: int(burst)}
+}
+
+// DefaultBurst is ten seconds' worth of requests at n per minute, at least 1.
+func DefaultBurst(n int64) int64 {
+	return max(n/6, 1)
+}
+
+func (r Rule) Enabled() bool { return r.Rate > 0 && r.Burst > 0 }
+
+// Store takes one token from the bucket identified by key.
+type Store interface {
+	// Take reports whether the request is allowed and, if not, how long
+	// until a token is available.
+	Take(ctx context.Context, key string, rule Rule, now time.Time) (bool, time.Duration, error)
+}
+
+// Refill applies the elapsed time to a bucket and takes one token if
+// possible. It returns the new token count, whether the take succeeded and
+// the wait until the next token otherwise. Stores share it so the in-process
+// and shared limiters behave identically.
+func Refill(tokens float64, last, now time.Time, rule Rule) (float64, bool, time.Duration) {
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens = math.Min(float64(rule.Burst), tokens+elapsed*rule.Rate)
+	}
+	if tokens >= 1 {
+		return tokens - 1, true, 0
+	}
+	wait := time.Duration((1 - tokens) / rule.Rate * float64(time.Second))
+	return tokens, false, wait
+}
+
+// idleTTL is how long an untouched bucket is kept. A bucket idle this long
+// would be full again for any sane rule, so dropping it changes nothing.
+const idleTTL = time.Hour
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Memory is an in-process Store.
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: map[string]*bucket{}}
+}
+
+var _ Store = (*Memory)(nil)
+
+func (m *Memory) Take(ctx context.Context, key string, rule Rule, now time.Time) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) > idleTTL {
+		for k, b := range m.buckets {
+			if now.Sub(b.last) > idleTTL {
+				delete(m.buckets, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), last: now}
+		m.buckets[key] = b
+	}
+	tokens, allowed, wait := Refill(b.tokens, b.last, now, rule)
+	b.tokens = tokens
+	if now.After(b.last) {
+		b.last = now
+	}
+	return allowed, wait, nil
+}
@@ -0,0 +1,212 @@
+// Package reconcile compares what the ledger says the bot wallet holds with
+// the wallet's actual unspent safe outputs.
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/ledger"
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+	"github.com/mvg-fi-dev/bridge/internal/switches"
+)
+
+// ReportKey is the kv key holding the latest Report.
+const ReportKey = "reconcile.balances"
+
+// Operator is recorded on the stage switches the reconciler turns off.
+const Operator = "reconciler"
+
+// BalanceSource returns the wallet's spendable balance per asset id.
+// mixin.SDKClient and mixin.FakeBalances implement it.
+type BalanceSource interface {
+	SafeBalances(ctx context.Context) (map[string]decimal.Decimal, error)
+}
+
+// AssetReport compares one asset. Expected is what orders in flight, retained
+// fees and unallocated credits add up to in the ledger; transfers already
+// submitted (Outbound) are excluded since their outputs are spent.
+type AssetReport struct {
+	AssetID  string `json:"asset_id"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Outbound string `json:"outbound"`
+	Diff     string `json:"diff"` // actual - expected
+	Breach   bool   `json:"breach"`
+}
+
+// Report is the outcome of one run. Streak counts consecutive runs with a
+// breach; stages are paused once it reaches the confirm threshold.
+type Report struct {
+	CheckedAt time.Time     `json:"checked_at"`
+	OK        bool          `json:"ok"`
+	Streak    int           `json:"streak"`
+	Paused    []string      `json:"paused,omitempty"`
+	Assets    []AssetReport `json:"assets"`
+}
+
+type Reconciler struct {
+	Ledger *ledger.Ledger
+	Source BalanceSource
+	KV     store.KVStore
+
+	// Tolerance is the absolute difference allowed per asset.
+	Tolerance decimal.Decimal
+	// Confirm is how many consecutive breaching runs raise the alert, so a
+	// deposit that is spendable but not yet polled does not trip it.
+	Confirm int
+	// Stages are switched off when the alert fires.
+	Stages []models.Stage
+
+	Now func() time.Time
+}
+
+func New(l *ledger.Ledger, src BalanceSource, kv store.KVStore, tolerance decimal.Decimal, confirm int) *Reconciler {
+	if confirm < 1 {
+		confirm = 1
+	}
+	return &Reconciler{
+		Ledger:    l,
+		Source:    src,
+		KV:        kv,
+		Tolerance: tolerance,
+		Confirm:   confirm,
+		Stages:    []models.Stage{models.StageSwap, models.StageWithdraw, models.StageRefund},
+		Now:       time.Now,
+	}
+}
+
+// Run compares balances once, stores the report under ReportKey and, on a
+// confirmed discrepancy, logs an alert and pauses the outbound stages.
+func (r *Reconciler) Run(ctx context.Context) (*Report, error) {
+	actual, err := r.Source.SafeBalances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: balances: %w", err)
+	}
+	balances, err := r.Ledger.Balances(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: ledger: %w", err)
+	}
+	expected := map[string]decimal.Decimal{}
+	outbound := map[string]decimal.Decimal{}
+	for _, b := range balances {
+		if !ledger.WalletAccount(b.Account) {
+			continue
+		}
+		v, err := decimal.NewFromString(b.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("reconcile: %s %s amount %q: %w", b.Account, b.AssetID, b.Amount, err)
+		}
+		if b.Account == ledger.AccountOutbound {
+			outbound[b.AssetID] = outbound[b.AssetID].Add(v)
+			continue
+		}
+		expected[b.AssetID] = expected[b.AssetID].Add(v)
+	}
+
+	rep := &Report{CheckedAt: r.Now().UTC(), OK: true, Assets: []AssetReport{}}
+	assets := map[string]bool{}
+	for a := range expected {
+		assets[a] = true
+	}
+	for a := range actual {
+		assets[a] = true
+	}
+	for a := range assets {
+		diff := actual[a].Sub(expected[a])
+		ar := AssetReport{
+			AssetID:  a,
+			Expected: expected[a].String(),
+			Actual:   actual[a].String(),
+			Outbound: outbound[a].String(),
+			Diff:     diff.String(),
+			Breach:   diff.Abs().GreaterThan(r.Tolerance),
+		}
+		if ar.Breach {
+			rep.OK = false
+		}
+		rep.Assets = append(rep.Assets, ar)
+	}
+	sort.Slice(rep.Assets, func(i, j int) bool { return rep.Assets[i].AssetID < rep.Assets[j].AssetID })
+
+	if !rep.OK {
+		prev, err := LoadReport(ctx, r.KV)
+		if err != nil {
+			return nil, err
+		}
+		rep.Streak = 1
+		if prev != nil && !prev.OK {
+			rep.Streak = prev.Streak + 1
+		}
+		if rep.Streak >= r.Confirm {
+			if err := r.alert(ctx, rep); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	raw, err := json.Marshal(rep)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.KV.Set(ctx, ReportKey, string(raw)); err != nil {
+		return nil, fmt.Errorf("reconcile: save report: %w", err)
+	}
+	return rep, nil
+}
+
+// alert logs the breaching assets and switches off every outbound stage that
+// is not already off. Operators turn them back on through the admin API.
+func (r *Reconciler) alert(ctx context.Context, rep *Report) error {
+	for _, a := range rep.Assets {
+		if a.Breach {
+			slog.ErrorContext(ctx, "ALERT reconcile balance mismatch", "asset", a.AssetID, "expected", a.Expected, "actual", a.Actual, "diff", a.Diff, "streak", rep.Streak)
+		}
+	}
+	st, err := switches.Load(ctx, r.KV)
+	if err != nil {
+		return err
+	}
+	for _, stage := range r.Stages {
+		if _, off := st.StagePaused(stage); off {
+			continue
+		}
+		err := switches.Disable(ctx, r.KV, switches.Switch{
+			Kind:     switches.KindStage,
+			Name:     string(stage),
+			Reason:   "wallet balance does not match ledger",
+			Operator: Operator,
+			Since:    rep.CheckedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("reconcile: pause %s: %w", stage, err)
+		}
+		slog.ErrorContext(ctx, "ALERT reconcile paused stage", logging.Stage, string(stage))
+		rep.Paused = append(rep.Paused, string(stage))
+	}
+	return nil
+}
+
+// LoadReport returns the latest stored report, or nil if none was stored.
+func LoadReport(ctx context.Context, kv store.KVStore) (*Report, error) {
+	raw, ok, err := kv.Get(ctx, ReportKey)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: load report: %w", err)
+	}
+	if !ok || raw == "" {
+		return nil, nil
+	}
+	var rep Report
+	if err := json.Unmarshal([]byte(raw), &rep); err != nil {
+		return nil, fmt.Errorf("reconcile: load report: %w", err)
+	}
+	return &rep, nil
+}
@@ -0,0 +1,208 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/ledger"
+	"github.com/mvg-fi-dev/bridge/internal/mixin"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store/memstore"
+	"github.com/mvg-fi-dev/bridge/internal/switches"
+)
+
+const (
+	exinSwap = "29f23576-4651-47ff-8c16-6c8a5d76985e"
+	asset    = "asset-a"
+)
+
+type fixture struct {
+	r      *Reconciler
+	l      *ledger.Ledger
+	kv     *memstore.KV
+	orders *memstore.Orders
+	path   string
+}
+
+// newFixture reconciles a ledger over memstores against FakeBalances read
+// from a temp file, with zero tolerance.
+func newFixture(t *testing.T, confirm int) *fixture {
+	t.Helper()
+	f := &fixture{kv: memstore.NewKV(), orders: memstore.NewOrders(), path: filepath.Join(t.TempDir(), "balances.json")}
+	f.l = ledger.New(memstore.NewLedger(), f.orders, exinSwap)
+	f.r = New(f.l, &mixin.FakeBalances{Path: f.path}, f.kv, decimal.Zero, confirm)
+	return f
+}
+
+func (f *fixture) wallet(t *testing.T, balances map[string]string) {
+	t.Helper()
+	b, err := json.Marshal(balances)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(f.path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *fixture) snapshot(t *testing.T, s *mixin.Snapshot) {
+	t.Helper()
+	if err := f.l.PostSnapshot(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// deposit credits order o1 with amount through snapshot dep-1.
+func (f *fixture) deposit(t *testing.T, amount string) {
+	t.Helper()
+	dep := "dep-1"
+	now := time.Now().UTC()
+	err := f.orders.Insert(context.Background(), &models.Order{
+		ID: "o1", PublicID: "p1", Status: models.StatusDepositCredited, CreatedAt: now, UpdatedAt: now,
+		SourceAsset: asset, TargetAsset: "asset-b", AmountIn: amount, DepositTxID: &dep,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.snapshot(t, &mixin.Snapshot{SnapshotID: dep, AssetID: asset, Amount: amount, OpponentID: "user"})
+}
+
+func (f *fixture) run(t *testing.T) *Report {
+	t.Helper()
+	rep, err := f.r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rep
+}
+
+func assetReport(t *testing.T, rep *Report, id string) AssetReport {
+	t.Helper()
+	for _, a := range rep.Assets {
+		if a.AssetID == id {
+			return a
+		}
+	}
+	t.Fatalf("no report for %s in %+v", id, rep.Assets)
+	return AssetReport{}
+}
+
+func TestMatchingBalances(t *testing.T) {
+	f := newFixture(t, 1)
+	f.deposit(t, "1.5")
+	f.wallet(t, map[string]string{asset: "1.5"})
+
+	rep := f.run(t)
+	if !rep.OK || rep.Streak != 0 || len(rep.Paused) != 0 {
+		t.Fatalf("report = %+v, want ok", rep)
+	}
+	if a := assetReport(t, rep, asset); a.Breach || a.Expected != "1.5" || a.Diff != "0" {
+		t.Errorf("asset = %+v", a)
+	}
+	stored, err := LoadReport(context.Background(), f.kv)
+	if err != nil || stored == nil || !stored.OK {
+		t.Errorf("stored report = %+v, %v", stored, err)
+	}
+}
+
+func TestDriftAlertsAfterConfirm(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, 2)
+	f.deposit(t, "1.5")
+	f.wallet(t, map[string]string{asset: "1.2"})
+
+	rep := f.run(t)
+	if rep.OK || rep.Streak != 1 || len(rep.Paused) != 0 {
+		t.Fatalf("first run = %+v, want breach with streak 1 and nothing paused", rep)
+	}
+	if a := assetReport(t, rep, asset); !a.Breach || a.Diff != "-0.3" {
+		t.Errorf("asset = %+v", a)
+	}
+	st, err := switches.Load(ctx, f.kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, off := st.StagePaused(models.StageSwap); off {
+		t.Fatal("swap paused before the breach was confirmed")
+	}
+
+	rep = f.run(t)
+	if rep.OK || rep.Streak != 2 || len(rep.Paused) != 3 {
+		t.Fatalf("second run = %+v, want streak 2 and three stages paused", rep)
+	}
+	st, err = switches.Load(ctx, f.kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stage := range []models.Stage{models.StageSwap, models.StageWithdraw, models.StageRefund} {
+		sw, off := st.StagePaused(stage)
+		if !off || sw.Operator != Operator {
+			t.Errorf("%s: paused=%v switch=%+v", stage, off, sw)
+		}
+	}
+
+	// A third breach does not pause stages that are already off.
+	if rep = f.run(t); rep.Streak != 3 || len(rep.Paused) != 0 {
+		t.Errorf("third run = %+v", rep)
+	}
+
+	// Once balances match again the streak resets.
+	f.wallet(t, map[string]string{asset: "1.5"})
+	if rep = f.run(t); !rep.OK || rep.Streak != 0 {
+		t.Errorf("after fix = %+v", rep)
+	}
+}
+
+// Movements that match no order still change what the wallet holds, so they
+// count toward the expected balance.
+func TestUnallocatedInAndOut(t *testing.T) {
+	f := newFixture(t, 1)
+	f.deposit(t, "1")
+	f.snapshot(t, &mixin.Snapshot{SnapshotID: "in-x", AssetID: asset, Amount: "0.4", OpponentID: "stranger"})
+	f.snapshot(t, &mixin.Snapshot{SnapshotID: "out-x", AssetID: asset, Amount: "-0.1", OpponentID: "stranger", TraceID: "unknown"})
+	f.wallet(t, map[string]string{asset: "1.3"})
+
+	rep := f.run(t)
+	if !rep.OK {
+		t.Fatalf("report = %+v, want ok", rep)
+	}
+	if a := assetReport(t, rep, asset); a.Expected != "1.3" {
+		t.Errorf("expected = %s, want 1.3", a.Expected)
+	}
+
+	// An unallocated inflow the ledger has not seen yet is a breach.
+	f.wallet(t, map[string]string{asset: "1.3", "asset-c": "2"})
+	rep = f.run(t)
+	if a := assetReport(t, rep, "asset-c"); rep.OK || !a.Breach || a.Expected != "0" {
+		t.Errorf("unposted inflow: ok=%v asset=%+v", rep.OK, a)
+	}
+}
+
+// A submitted transfer spends its outputs before its snapshot is polled; it
+// is reported as outbound and left out of the expected balance.
+func TestOutboundExcluded(t *testing.T) {
+	f := newFixture(t, 1)
+	f.deposit(t, "1")
+	o, err := f.orders.GetByID(context.Background(), "o1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.l.PostTransfer(context.Background(), o, models.StageRefund, "refund-trace", asset, decimal.RequireFromString("1"), decimal.Zero); err != nil {
+		t.Fatal(err)
+	}
+	f.wallet(t, map[string]string{asset: "0"})
+
+	rep := f.run(t)
+	if !rep.OK {
+		t.Fatalf("report = %+v, want ok", rep)
+	}
+	if a := assetReport(t, rep, asset); a.Outbound != "1" || a.Expected != "0" {
+		t.Errorf("asset = %+v", a)
+	}
+}
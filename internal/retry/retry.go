@@ -0,0 +1,24 @@
+// Package retry has the attempt budget and exponential backoff shared by the
+// order executor and the partner webhook dispatcher.
+package retry
+
+import "time"
+
+// Policy is an attempt budget with exponential backoff.
+type Policy struct {
+	MaxAttempts int64
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Delay returns the wait before the next try after `attempts` failures.
+func (p Policy) Delay(attempts int64) time.Duration {
+	d := p.BaseDelay
+	for i := int64(1); i < attempts && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
@@ -0,0 +1,15 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicyDelay(t *testing.T) {
+	p := Policy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	for attempts, want := range map[int64]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 8 * time.Second, 5: 10 * time.Second, 9: 10 * time.Second} {
+		if got := p.Delay(attempts); got != want {
+			t.Errorf("Delay(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"net/url"
 	"strconv"
 	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/tracing"
 )
 
 type Client struct {
@@ -26,7 +28,7 @@ func NewClient(baseURL string) *Client {
 	if baseURL == "" {
 		baseURL = "https://api.route.mixin.one"
 	}
-	return &Client{BaseURL: baseURL, HTTP: &http.Client{Timeout: 15 * time.Second}}
+	return &Client{BaseURL: baseURL, HTTP: &http.Client{Timeout: 15 * time.Second, Transport: tracing.Transport(nil)}}
 }
 
 type QuoteResult struct {
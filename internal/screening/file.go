@@ -0,0 +1,89 @@
+package screening
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileBlocklist screens against a local text file, e.g. an exported
+// sanctions list. One entry per line:
+//
+//	address
+//	CHAIN,address[,reason]
+//
+// Blank lines and lines starting with # are ignored. A bare address (or chain
+// "*") matches every chain. The file is re-read when its mtime changes.
+type FileBlocklist struct {
+	Path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	entries map[string]string // "CHAIN|address" -> reason
+}
+
+func NewFileBlocklist(path string) *FileBlocklist {
+	return &FileBlocklist{Path: path}
+}
+
+func (f *FileBlocklist) Screen(ctx context.Context, chain, address string) (Result, error) {
+	addr := NormalizeAddress(address)
+	if addr == "" {
+		return Result{}, nil
+	}
+	entries, err := f.load()
+	if err != nil {
+		return Result{}, err
+	}
+	for _, k := range []string{NormalizeChain(chain) + "|" + addr, "*|" + addr} {
+		if reason, ok := entries[k]; ok {
+			return Result{Hit: true, Source: "file:" + f.Path, Reason: reason}, nil
+		}
+	}
+	return Result{}, nil
+}
+
+func (f *FileBlocklist) load() (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	st, err := os.Stat(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("screening file: %w", err)
+	}
+	if f.entries != nil && st.ModTime().Equal(f.modTime) {
+		return f.entries, nil
+	}
+	raw, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("screening file: %w", err)
+	}
+
+	entries := map[string]string{}
+	sc := bufio.NewScanner(bytes.NewReader(raw))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ",", 3)
+		chain, addr, reason := "*", parts[0], "listed in "+f.Path
+		if len(parts) >= 2 {
+			chain, addr = NormalizeChain(parts[0]), parts[1]
+		}
+		if len(parts) == 3 && strings.TrimSpace(parts[2]) != "" {
+			reason = strings.TrimSpace(parts[2])
+		}
+		entries[chain+"|"+NormalizeAddress(addr)] = reason
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("screening file: %w", err)
+	}
+	f.entries, f.modTime = entries, st.ModTime()
+	return entries, nil
+}
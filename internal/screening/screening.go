@@ -0,0 +1,88 @@
+// Package screening checks addresses against sanctions lists and blocklists.
+//
+// Orders are screened at creation (target address) and again before the
+// worker moves funds: withdrawals screen the target address and the deposit
+// sender, refunds screen the sender. A hit freezes the order in
+// compliance_hold instead of paying out or refunding automatically.
+package screening
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// Result of screening one address.
+type Result struct {
+	Hit    bool
+	Source string // which screener flagged it
+	Reason string
+}
+
+// Screener is the pluggable screening hook. Implementations must return an
+// error (not a miss) when they cannot decide, so callers fail closed.
+type Screener interface {
+	Screen(ctx context.Context, chain, address string) (Result, error)
+}
+
+// NormalizeAddress is the form addresses are listed and matched in: trimmed
+// and lower-cased. On chains with case-sensitive addresses this can flag an
+// address differing only in case, which errs toward a hold.
+func NormalizeAddress(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+// NormalizeChain upper-cases chain; an empty chain is "*" (any chain).
+func NormalizeChain(chain string) string {
+	chain = strings.ToUpper(strings.TrimSpace(chain))
+	if chain == "" {
+		return "*"
+	}
+	return chain
+}
+
+// Multi flags an address if any of its screeners does.
+type Multi []Screener
+
+func (m Multi) Screen(ctx context.Context, chain, address string) (Result, error) {
+	for _, s := range m {
+		r, err := s.Screen(ctx, chain, address)
+		if err != nil || r.Hit {
+			return r, err
+		}
+	}
+	return Result{}, nil
+}
+
+// DBBlocklist screens against the admin-managed screening_blocklist table.
+type DBBlocklist struct {
+	Store store.BlocklistStore
+}
+
+func NewDBBlocklist(s store.BlocklistStore) *DBBlocklist { return &DBBlocklist{Store: s} }
+
+func (b *DBBlocklist) Screen(ctx context.Context, chain, address string) (Result, error) {
+	address = NormalizeAddress(address)
+	if address == "" {
+		return Result{}, nil
+	}
+	e, err := b.Store.MatchBlocklist(ctx, NormalizeChain(chain), address)
+	if errors.Is(err, store.ErrNotFound) {
+		return Result{}, nil
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Hit: true, Source: "blocklist", Reason: e.Reason}, nil
+}
+
+// New combines the DB blocklist with an optional file blocklist.
+func New(blocklist store.BlocklistStore, filePath string) Screener {
+	m := Multi{NewDBBlocklist(blocklist)}
+	if filePath != "" {
+		m = append(m, NewFileBlocklist(filePath))
+	}
+	return m
+}
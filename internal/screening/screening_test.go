@@ -0,0 +1,130 @@
+package screening
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store/memstore"
+)
+
+// blocklists returns a file and a DB blocklist holding the same entries: a
+// bare address, a chain-specific one and one listed in mixed case.
+func blocklists(t *testing.T) map[string]Screener {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	raw := "# exported list\n\n  0xAnyChain  \neth, 0xEthOnly ,mixer\nBTC,bc1qListed,\n"
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	db := memstore.NewBlocklist()
+	for i, e := range []struct{ chain, address, reason string }{
+		{"", "  0xAnyChain  ", ""},
+		{"eth", " 0xEthOnly ", "mixer"},
+		{"BTC", "bc1qListed", ""},
+	} {
+		err := db.AddBlocklist(context.Background(), &models.BlocklistEntry{
+			ID:        string(rune('a' + i)),
+			Chain:     NormalizeChain(e.chain),
+			Address:   NormalizeAddress(e.address),
+			Reason:    e.reason,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return map[string]Screener{"file": NewFileBlocklist(path), "db": NewDBBlocklist(db)}
+}
+
+func TestBlocklistMatching(t *testing.T) {
+	for name, s := range blocklists(t) {
+		for _, tc := range []struct {
+			chain, address string
+			hit            bool
+		}{
+			{"ETH", "0xanychain", true},
+			{"BTC", "0xANYCHAIN", true},
+			{"", "0xanychain", true},
+			{"ETH", "0xethonly", true},
+			{"eth", " 0XETHONLY\t", true},
+			{"BSC", "0xethonly", false},
+			{"", "0xethonly", false},
+			{"btc", "BC1QLISTED", true},
+			{"ETH", "0xclean", false},
+			{"ETH", "   ", false},
+			{"ETH", "# exported list", false},
+		} {
+			r, err := s.Screen(context.Background(), tc.chain, tc.address)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if r.Hit != tc.hit {
+				t.Errorf("%s: Screen(%q, %q) hit = %v, want %v", name, tc.chain, tc.address, r.Hit, tc.hit)
+			}
+		}
+		if r, _ := s.Screen(context.Background(), "ETH", "0xethonly"); r.Reason != "mixer" {
+			t.Errorf("%s: reason = %q", name, r.Reason)
+		}
+	}
+}
+
+func TestFileBlocklistReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte("0xold\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f := NewFileBlocklist(path)
+	if r, err := f.Screen(context.Background(), "ETH", "0xold"); err != nil || !r.Hit {
+		t.Fatalf("0xold: %+v %v", r, err)
+	}
+
+	if err := os.WriteFile(path, []byte("0xnew\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := f.Screen(context.Background(), "ETH", "0xold"); r.Hit {
+		t.Error("0xold still listed after reload")
+	}
+	if r, _ := f.Screen(context.Background(), "ETH", "0xnew"); !r.Hit {
+		t.Error("0xnew not listed after reload")
+	}
+
+	// A list that disappears fails closed.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Screen(context.Background(), "ETH", "0xclean"); err == nil {
+		t.Error("missing file screened clean")
+	}
+}
+
+type screenFunc func(chain, address string) (Result, error)
+
+func (f screenFunc) Screen(ctx context.Context, chain, address string) (Result, error) {
+	return f(chain, address)
+}
+
+func TestMulti(t *testing.T) {
+	miss := screenFunc(func(string, string) (Result, error) { return Result{}, nil })
+	hit := screenFunc(func(string, string) (Result, error) { return Result{Hit: true, Source: "second"}, nil })
+	broken := screenFunc(func(string, string) (Result, error) { return Result{}, errors.New("down") })
+
+	if r, err := (Multi{miss, hit}).Screen(context.Background(), "ETH", "0x1"); err != nil || !r.Hit || r.Source != "second" {
+		t.Errorf("miss, hit: %+v %v", r, err)
+	}
+	if _, err := (Multi{miss, broken, hit}).Screen(context.Background(), "ETH", "0x1"); err == nil {
+		t.Error("an erroring screener was skipped")
+	}
+	if r, err := (Multi{miss, miss}).Screen(context.Background(), "ETH", "0x1"); err != nil || r.Hit {
+		t.Errorf("miss, miss: %+v %v", r, err)
+	}
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+// OrderFilter narrows ListOrders. Empty fields match everything.
+// Results are newest first.
+type OrderFilter struct {
+	Status        models.OrderStatus
+	Asset         string // source or target asset id
+	TargetAddress string
+	Sender        string // who paid the deposit (refund_to_address)
+	PartnerID     string
+	Venue         string    // swap venue the order went through; see Venues
+	CreatedFrom   time.Time // inclusive
+	CreatedTo     time.Time // exclusive
+	UpdatedFrom   time.Time // inclusive
+	UpdatedBefore time.Time // exclusive
+
+	// Search matches the order id or public id, a deposit, withdrawal or
+	// refund transaction, the target address or the sender exactly.
+	Search string
+
+	// After continues a listing after the last order of the previous page
+	// (keyset pagination); Offset is for the older offset-based callers.
+	After  *OrderCursor
+	Limit  int
+	Offset int
+}
+
+// OrderCursor is the position of an order in the newest-first listing.
+type OrderCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// Venues are the swap venues orders can be filtered by.
+var Venues = []string{"exinswap"}
+
+// AuditStore keeps the operator audit trail and order annotations.
+type AuditStore interface {
+	InsertAudit(ctx context.Context, e *models.AuditEntry) error
+	ListAudit(ctx context.Context, orderID string, limit int) ([]*models.AuditEntry, error)
+	InsertNote(ctx context.Context, n *models.OrderNote) error
+	ListNotes(ctx context.Context, orderID string) ([]*models.OrderNote, error)
+}
+
+// BlocklistStore holds the screening blocklist managed through the admin API.
+type BlocklistStore interface {
+	ListBlocklist(ctx context.Context) ([]*models.BlocklistEntry, error)
+	AddBlocklist(ctx context.Context, e *models.BlocklistEntry) error
+	RemoveBlocklist(ctx context.Context, id string) (bool, error)
+	// MatchBlocklist returns the entry for address on chain (or on "*"), or ErrNotFound.
+	MatchBlocklist(ctx context.Context, chain, address string) (*models.BlocklistEntry, error)
+}
+
+// PartnerStore holds integrator API keys.
+type PartnerStore interface {
+	ListPartners(ctx context.Context) ([]*models.Partner, error)
+	GetPartner(ctx context.Context, id string) (*models.Partner, error)
+	// GetPartnerByKey returns the partner owning apiKey, or ErrNotFound.
+	GetPartnerByKey(ctx context.Context, apiKey string) (*models.Partner, error)
+	InsertPartner(ctx context.Context, p *models.Partner) error
+	// UpdatePartner writes every mutable field of p (including the secret).
+	UpdatePartner(ctx context.Context, p *models.Partner) error
+}
+
+// KVStore is the small key/value table used for cursors and runtime switches.
+type KVStore interface {
+	Get(ctx context.Context, key string) (string, bool, error)
+	Set(ctx context.Context, key, value string) error
+	Delete(ctx context.Context, key string) error
+	// List returns all keys starting with prefix.
+	List(ctx context.Context, prefix string) (map[string]string, error)
+}
+
+// AdminTx is the set of stores bound to one admin transaction.
+type AdminTx struct {
+	Orders    OrderStore
+	Audit     AuditStore
+	KV        KVStore
+	Blocklist BlocklistStore
+	Partners  PartnerStore
+	Webhooks  WebhookStore
+}
+
+// AdminStore backs the admin API. InAdminTx applies an operator action and its
+// audit entry atomically, so no action is recorded without its audit row.
+type AdminStore interface {
+	AuditStore
+	InAdminTx(ctx context.Context, fn func(tx AdminTx) error) error
+}
@@ -0,0 +1,23 @@
+package store
+
+import (
+	"context"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+// LedgerStore persists ledger journals. Callers balance journals before
+// posting; the store only enforces (kind, ref) uniqueness.
+type LedgerStore interface {
+	// PostJournal inserts j and its entries. It returns false, and writes
+	// nothing, if a journal with the same (kind, ref) already exists.
+	PostJournal(ctx context.Context, j *models.LedgerJournal) (bool, error)
+	// GetJournal returns the journal (with entries) for (kind, ref), or ErrNotFound.
+	GetJournal(ctx context.Context, kind, ref string) (*models.LedgerJournal, error)
+	// ListOrderJournals returns an order's journals (with entries), oldest first.
+	ListOrderJournals(ctx context.Context, orderID string) ([]*models.LedgerJournal, error)
+	// ListBalances sums the entries of every account starting with
+	// accountPrefix per account and asset, sorted by account and asset.
+	// Zero balances are omitted, so settled orders cost nothing to report.
+	ListBalances(ctx context.Context, accountPrefix string) ([]*models.LedgerBalance, error)
+}
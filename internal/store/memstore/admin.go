@@ -0,0 +1,110 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// Admin is an in-memory store.AdminStore. InAdminTx runs inside Orders.InTx
+// and drops the audit entries and notes added by a failed fn; KV, Blocklist,
+// Partners and Webhooks (nil when unset) are handed to fn as they are.
+type Admin struct {
+	Orders    *Orders
+	KV        *KV
+	Blocklist store.BlocklistStore
+	Partners  store.PartnerStore
+	Webhooks  store.WebhookStore
+
+	mu    sync.Mutex
+	audit []*models.AuditEntry
+	notes []*models.OrderNote
+}
+
+func NewAdmin(orders *Orders, kv *KV) *Admin {
+	return &Admin{Orders: orders, KV: kv}
+}
+
+var _ store.AdminStore = (*Admin)(nil)
+
+func (a *Admin) InAdminTx(ctx context.Context, fn func(tx store.AdminTx) error) error {
+	return a.Orders.InTx(ctx, func(orders store.OrderStore) error {
+		a.mu.Lock()
+		nAudit, nNotes := len(a.audit), len(a.notes)
+		a.mu.Unlock()
+
+		err := fn(store.AdminTx{
+			Orders:    orders,
+			Audit:     a,
+			KV:        a.KV,
+			Blocklist: a.Blocklist,
+			Partners:  a.Partners,
+			Webhooks:  a.Webhooks,
+		})
+		if err != nil {
+			a.mu.Lock()
+			a.audit, a.notes = a.audit[:nAudit], a.notes[:nNotes]
+			a.mu.Unlock()
+		}
+		return err
+	})
+}
+
+func (a *Admin) InsertAudit(ctx context.Context, e *models.AuditEntry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c := *e
+	a.audit = append(a.audit, &c)
+	return nil
+}
+
+// ListAudit returns the newest entries first; orderID == "" lists all orders.
+func (a *Admin) ListAudit(ctx context.Context, orderID string, limit int) ([]*models.AuditEntry, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var out []*models.AuditEntry
+	for _, e := range a.audit {
+		if orderID != "" && (e.OrderID == nil || *e.OrderID != orderID) {
+			continue
+		}
+		c := *e
+		out = append(out, &c)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (a *Admin) InsertNote(ctx context.Context, n *models.OrderNote) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c := *n
+	a.notes = append(a.notes, &c)
+	return nil
+}
+
+func (a *Admin) ListNotes(ctx context.Context, orderID string) ([]*models.OrderNote, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var out []*models.OrderNote
+	for _, n := range a.notes {
+		if n.OrderID == orderID {
+			c := *n
+			out = append(out, &c)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,75 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// Blocklist is an in-memory store.BlocklistStore. Like the SQL repo it
+// stores addresses lower-cased and replaces an entry for the same chain and
+// address.
+type Blocklist struct {
+	mu      sync.Mutex
+	entries []*models.BlocklistEntry
+}
+
+func NewBlocklist() *Blocklist { return &Blocklist{} }
+
+var _ store.BlocklistStore = (*Blocklist)(nil)
+
+func (b *Blocklist) ListBlocklist(ctx context.Context) ([]*models.BlocklistEntry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]*models.BlocklistEntry, 0, len(b.entries))
+	for _, e := range b.entries {
+		c := *e
+		out = append(out, &c)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (b *Blocklist) AddBlocklist(ctx context.Context, e *models.BlocklistEntry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := *e
+	c.Address = strings.ToLower(c.Address)
+	for _, old := range b.entries {
+		if old.Chain == c.Chain && old.Address == c.Address {
+			old.Reason, old.CreatedBy = c.Reason, c.CreatedBy
+			return nil
+		}
+	}
+	b.entries = append(b.entries, &c)
+	return nil
+}
+
+func (b *Blocklist) RemoveBlocklist(ctx context.Context, id string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, e := range b.entries {
+		if e.ID == id {
+			b.entries = append(b.entries[:i], b.entries[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (b *Blocklist) MatchBlocklist(ctx context.Context, chain, address string) (*models.BlocklistEntry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	address, chain = strings.ToLower(address), strings.ToUpper(chain)
+	for _, e := range b.entries {
+		if e.Address == address && (e.Chain == chain || e.Chain == "*") {
+			c := *e
+			return &c, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
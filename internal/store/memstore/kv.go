@@ -0,0 +1,52 @@
+package memstore
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// KV is an in-memory store.KVStore.
+type KV struct {
+	mu sync.Mutex
+	m  map[string]string
+}
+
+func NewKV() *KV { return &KV{m: map[string]string{}} }
+
+var _ store.KVStore = (*KV)(nil)
+
+func (k *KV) Get(ctx context.Context, key string) (string, bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	v, ok := k.m[key]
+	return v, ok, nil
+}
+
+func (k *KV) Set(ctx context.Context, key, value string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.m[key] = value
+	return nil
+}
+
+func (k *KV) Delete(ctx context.Context, key string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.m, key)
+	return nil
+}
+
+func (k *KV) List(ctx context.Context, prefix string) (map[string]string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	out := map[string]string{}
+	for key, v := range k.m {
+		if strings.HasPrefix(key, prefix) {
+			out[key] = v
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,119 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// Ledger is an in-memory store.LedgerStore. Journals are kept in posting
+// order, which stands in for the SQL repo's created_at order.
+type Ledger struct {
+	mu       sync.Mutex
+	journals []*models.LedgerJournal
+}
+
+func NewLedger() *Ledger { return &Ledger{} }
+
+var _ store.LedgerStore = (*Ledger)(nil)
+
+func cloneJournal(j *models.LedgerJournal) *models.LedgerJournal {
+	c := *j
+	c.Entries = make([]*models.LedgerEntry, 0, len(j.Entries))
+	for _, e := range j.Entries {
+		ec := *e
+		c.Entries = append(c.Entries, &ec)
+	}
+	return &c
+}
+
+func (l *Ledger) PostJournal(ctx context.Context, j *models.LedgerJournal) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, e := range l.journals {
+		if e.Kind == j.Kind && e.Ref == j.Ref {
+			return false, nil
+		}
+	}
+	c := cloneJournal(j)
+	for _, e := range c.Entries {
+		e.JournalID = c.ID
+		e.CreatedAt = c.CreatedAt
+	}
+	l.journals = append(l.journals, c)
+	return true, nil
+}
+
+func (l *Ledger) GetJournal(ctx context.Context, kind, ref string) (*models.LedgerJournal, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, j := range l.journals {
+		if j.Kind == kind && j.Ref == ref {
+			return cloneJournal(j), nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (l *Ledger) ListOrderJournals(ctx context.Context, orderID string) ([]*models.LedgerJournal, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := []*models.LedgerJournal{}
+	for _, j := range l.journals {
+		if j.OrderID != nil && *j.OrderID == orderID {
+			out = append(out, cloneJournal(j))
+		}
+	}
+	return out, nil
+}
+
+func (l *Ledger) ListBalances(ctx context.Context, accountPrefix string) ([]*models.LedgerBalance, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	type key struct{ account, asset string }
+	sums := map[key]decimal.Decimal{}
+	for _, j := range l.journals {
+		for _, e := range j.Entries {
+			if !strings.HasPrefix(e.Account, accountPrefix) {
+				continue
+			}
+			v, err := decimal.NewFromString(e.Amount)
+			if err != nil {
+				return nil, fmt.Errorf("entry %s amount %q: %w", e.ID, e.Amount, err)
+			}
+			k := key{e.Account, e.AssetID}
+			sums[k] = sums[k].Add(v)
+		}
+	}
+	out := []*models.LedgerBalance{}
+	for k, v := range sums {
+		if !v.IsZero() {
+			out = append(out, &models.LedgerBalance{Account: k.account, AssetID: k.asset, Amount: v.String()})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Account != out[j].Account {
+			return out[i].Account < out[j].Account
+		}
+		return out[i].AssetID < out[j].AssetID
+	})
+	return out, nil
+}
+
+// Journals returns every posted journal (with entries) in posting order.
+func (l *Ledger) Journals() []*models.LedgerJournal {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]*models.LedgerJournal, 0, len(l.journals))
+	for _, j := range l.journals {
+		out = append(out, cloneJournal(j))
+	}
+	return out
+}
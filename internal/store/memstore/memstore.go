@@ -0,0 +1,607 @@
+// Package memstore has in-memory stores (orders, admin, partners, blocklist,
+// ledger, kv) for unit tests.
+// Orders mirrors the status guards of the SQL repo but has no persistence.
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+type Orders struct {
+	mu     sync.Mutex
+	txMu   sync.Mutex
+	orders map[string]*models.Order
+	idem   map[string]models.IdempotencyKey
+
+	// Now is the clock used for updated_at, retries and leases (defaults to time.Now).
+	Now func() time.Time
+}
+
+func NewOrders() *Orders {
+	return &Orders{orders: map[string]*models.Order{}, idem: map[string]models.IdempotencyKey{}, Now: time.Now}
+}
+
+var _ store.OrderStore = (*Orders)(nil)
+
+func (m *Orders) now() time.Time { return m.Now().UTC() }
+
+// clone returns a copy so callers cannot mutate stored rows by accident.
+func clone(o *models.Order) *models.Order {
+	c := *o
+	return &c
+}
+
+func strPtr(s string) *string { return &s }
+
+// InTx serializes transactions and restores the previous rows if fn fails.
+// Nested InTx calls from inside fn must not be made (txMu is not reentrant).
+func (m *Orders) InTx(ctx context.Context, fn func(tx store.OrderStore) error) error {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	m.mu.Lock()
+	saved := make(map[string]*models.Order, len(m.orders))
+	for id, o := range m.orders {
+		saved[id] = clone(o)
+	}
+	savedIdem := make(map[string]models.IdempotencyKey, len(m.idem))
+	for k, v := range m.idem {
+		savedIdem[k] = v
+	}
+	m.mu.Unlock()
+
+	if err := fn(m); err != nil {
+		m.mu.Lock()
+		m.orders = saved
+		m.idem = savedIdem
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (m *Orders) Insert(ctx context.Context, o *models.Order) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.orders[o.ID]; ok {
+		return fmt.Errorf("insert order %s: %w", o.ID, store.ErrConflict)
+	}
+	for _, e := range m.orders {
+		if e.PublicID == o.PublicID {
+			return fmt.Errorf("insert order %s: public id %s: %w", o.ID, o.PublicID, store.ErrConflict)
+		}
+	}
+	m.orders[o.ID] = clone(o)
+	return nil
+}
+
+func (m *Orders) find(match func(*models.Order) bool) (*models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, o := range m.orders {
+		if match(o) {
+			return clone(o), nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (m *Orders) GetByID(ctx context.Context, id string) (*models.Order, error) {
+	return m.find(func(o *models.Order) bool { return o.ID == id })
+}
+
+func (m *Orders) GetByPublicID(ctx context.Context, publicID string) (*models.Order, error) {
+	return m.find(func(o *models.Order) bool { return o.PublicID == publicID })
+}
+
+func (m *Orders) GetByExinSwapTraceID(ctx context.Context, traceID string) (*models.Order, error) {
+	return m.find(func(o *models.Order) bool { return o.ExinSwapTraceID != nil && *o.ExinSwapTraceID == traceID })
+}
+
+func (m *Orders) GetByDepositTxID(ctx context.Context, txID string) (*models.Order, error) {
+	return m.find(func(o *models.Order) bool { return o.DepositTxID != nil && *o.DepositTxID == txID })
+}
+
+func (m *Orders) GetByTransferTraceID(ctx context.Context, traceID string) (*models.Order, error) {
+	is := func(p *string) bool { return p != nil && *p == traceID }
+	return m.find(func(o *models.Order) bool {
+		return is(o.ExinSwapTraceID) || is(o.WithdrawTraceID) || is(o.RefundTraceID)
+	})
+}
+
+func (m *Orders) SetDepositCreditedByMemo(ctx context.Context, memo string, snapshotID string, creditedAt time.Time, amountCredited string, assetID string, opponentID string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, o := range m.orders {
+		if o.MixinPayMemo != memo || o.MixinAssetID != assetID {
+			continue
+		}
+		switch o.Status {
+		case models.StatusAwaitingDeposit, models.StatusDepositDetected, models.StatusDepositPendingMixin:
+		default:
+			continue
+		}
+		o.Status = models.StatusDepositCredited
+		if o.DepositTxID == nil {
+			o.DepositTxID = strPtr(snapshotID)
+		}
+		if o.DepositTxDetectedAt == nil {
+			t := creditedAt
+			o.DepositTxDetectedAt = &t
+		}
+		if o.DepositCreditedAt == nil {
+			t := creditedAt
+			o.DepositCreditedAt = &t
+		}
+		if o.AmountCredited == nil {
+			o.AmountCredited = strPtr(amountCredited)
+		}
+		if o.RefundToAddress == nil {
+			o.RefundToAddress = strPtr(opponentID)
+		}
+		o.UpdatedAt = m.now()
+		n++
+	}
+	return n, nil
+}
+
+// list returns copies of matching orders, oldest updated first.
+func (m *Orders) list(limit int, match func(*models.Order) bool) []*models.Order {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if limit <= 0 {
+		limit = 50
+	}
+	var out []*models.Order
+	for _, o := range m.orders {
+		if match(o) {
+			out = append(out, clone(o))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.Before(out[j].UpdatedAt) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+func (m *Orders) due(o *models.Order) bool {
+	now := m.now()
+	if o.NextAttemptAt != nil && o.NextAttemptAt.After(now) {
+		return false
+	}
+	if o.LeaseUntil != nil && o.LeaseUntil.After(now) {
+		return false
+	}
+	return true
+}
+
+func (m *Orders) ListExecutable(ctx context.Context, limit int) ([]*models.Order, error) {
+	return m.list(limit, func(o *models.Order) bool {
+		retrying := o.Status == models.StatusExecutingSwap && o.RetryStage != nil && *o.RetryStage == string(models.StageSwap)
+		return (o.Status == models.StatusDepositCredited || retrying) && m.due(o)
+	}), nil
+}
+
+func (m *Orders) ListWithdrawing(ctx context.Context, limit int) ([]*models.Order, error) {
+	return m.list(limit, func(o *models.Order) bool {
+		return o.Status == models.StatusWithdrawing && (o.WithdrawTxID == nil || *o.WithdrawTxID == "") && m.due(o)
+	}), nil
+}
+
+func (m *Orders) ListRefunding(ctx context.Context, limit int) ([]*models.Order, error) {
+	return m.list(limit, func(o *models.Order) bool {
+		return o.Status == models.StatusRefunding && (o.RefundTxID == nil || *o.RefundTxID == "") && m.due(o)
+	}), nil
+}
+
+// update applies fn to the order if its status is one of from (any status when from is empty).
+// Returns whether the order was updated.
+func (m *Orders) update(orderID string, from []models.OrderStatus, fn func(o *models.Order)) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	o, ok := m.orders[orderID]
+	if !ok {
+		return false
+	}
+	if len(from) > 0 {
+		match := false
+		for _, s := range from {
+			if o.Status == s {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	fn(o)
+	o.UpdatedAt = m.now()
+	return true
+}
+
+func (m *Orders) TryMarkExecutingSwap(ctx context.Context, orderID string) (bool, error) {
+	return m.update(orderID, []models.OrderStatus{models.StatusDepositCredited}, func(o *models.Order) {
+		o.Status = models.StatusExecutingSwap
+	}), nil
+}
+
+func (m *Orders) SetExinSwapTraceID(ctx context.Context, orderID string, traceID string) error {
+	m.update(orderID, nil, func(o *models.Order) { o.ExinSwapTraceID = strPtr(traceID) })
+	return nil
+}
+
+func (m *Orders) SetWithdrawTraceID(ctx context.Context, orderID string, traceID string) error {
+	m.update(orderID, nil, func(o *models.Order) {
+		if o.WithdrawTraceID == nil {
+			o.WithdrawTraceID = strPtr(traceID)
+		}
+	})
+	return nil
+}
+
+func (m *Orders) SetRefundTraceID(ctx context.Context, orderID string, traceID string) error {
+	m.update(orderID, nil, func(o *models.Order) {
+		if o.RefundTraceID == nil {
+			o.RefundTraceID = strPtr(traceID)
+		}
+	})
+	return nil
+}
+
+func (m *Orders) MarkWithdrawing(ctx context.Context, orderID string, swapRef string, finalOut string) error {
+	m.update(orderID, []models.OrderStatus{models.StatusExecutingSwap}, func(o *models.Order) {
+		o.Status = models.StatusWithdrawing
+		if o.SwapRef == nil {
+			o.SwapRef = strPtr(swapRef)
+		}
+		if o.FinalOut == nil {
+			o.FinalOut = strPtr(finalOut)
+		}
+		clearRetry(o)
+	})
+	return nil
+}
+
+func (m *Orders) MarkCompleted(ctx context.Context, orderID string, withdrawTxID string) error {
+	m.update(orderID, []models.OrderStatus{models.StatusWithdrawing}, func(o *models.Order) {
+		o.Status = models.StatusCompleted
+		if o.WithdrawTxID == nil {
+			o.WithdrawTxID = strPtr(withdrawTxID)
+		}
+	})
+	return nil
+}
+
+func (m *Orders) MarkRefunding(ctx context.Context, orderID string, reason string) error {
+	m.update(orderID, []models.OrderStatus{models.StatusExecutingSwap, models.StatusDepositCredited}, func(o *models.Order) {
+		o.Status = models.StatusRefunding
+		clearRetry(o)
+	})
+	return nil
+}
+
+func (m *Orders) MarkRefundingWithDetails(ctx context.Context, orderID, refundAssetID, refundAmount, refundReceivedSnapshotID string) error {
+	m.update(orderID, []models.OrderStatus{models.StatusExecutingSwap, models.StatusDepositCredited}, func(o *models.Order) {
+		o.Status = models.StatusRefunding
+		if o.RefundAssetID == nil {
+			o.RefundAssetID = strPtr(refundAssetID)
+		}
+		if o.RefundAmount == nil {
+			o.RefundAmount = strPtr(refundAmount)
+		}
+		if o.RefundReceivedSnapshotID == nil {
+			o.RefundReceivedSnapshotID = strPtr(refundReceivedSnapshotID)
+		}
+		clearRetry(o)
+	})
+	return nil
+}
+
+func (m *Orders) MarkRefunded(ctx context.Context, orderID string, refundTxID string) error {
+	m.update(orderID, []models.OrderStatus{models.StatusRefunding}, func(o *models.Order) {
+		o.Status = models.StatusRefunded
+		if o.RefundTxID == nil {
+			o.RefundTxID = strPtr(refundTxID)
+		}
+	})
+	return nil
+}
+
+func (m *Orders) ScheduleRetry(ctx context.Context, orderID string, status models.OrderStatus, stage models.Stage, attempts int64, lastError string, nextAttemptAt time.Time) error {
+	m.update(orderID, []models.OrderStatus{status}, func(o *models.Order) {
+		o.RetryStage = strPtr(string(stage))
+		o.Attempts = attempts
+		o.LastError = strPtr(lastError)
+		t := nextAttemptAt.UTC()
+		o.NextAttemptAt = &t
+	})
+	return nil
+}
+
+func (m *Orders) ClearRetry(ctx context.Context, orderID string, status models.OrderStatus) error {
+	m.update(orderID, []models.OrderStatus{status}, clearRetry)
+	return nil
+}
+
+func clearRetry(o *models.Order) {
+	o.RetryStage, o.Attempts, o.NextAttemptAt = nil, 0, nil
+}
+
+func (m *Orders) MarkFailedManual(ctx context.Context, orderID string, status models.OrderStatus, stage models.Stage, attempts int64, lastError string) error {
+	m.update(orderID, []models.OrderStatus{status}, func(o *models.Order) {
+		o.Status = models.StatusFailedManual
+		o.RetryStage = strPtr(string(stage))
+		o.Attempts = attempts
+		o.LastError = strPtr(lastError)
+		o.NextAttemptAt = nil
+	})
+	return nil
+}
+
+func (m *Orders) TryLease(ctx context.Context, orderID string, status models.OrderStatus, workerID string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	o, ok := m.orders[orderID]
+	if !ok || o.Status != status {
+		return false, nil
+	}
+	now := m.now()
+	if o.NextAttemptAt != nil && o.NextAttemptAt.After(now) {
+		return false, nil
+	}
+	if o.LeaseUntil != nil && o.LeaseUntil.After(now) && (o.LockedBy == nil || *o.LockedBy != workerID) {
+		return false, nil
+	}
+	until := now.Add(ttl)
+	o.LockedBy = strPtr(workerID)
+	o.LeaseUntil = &until
+	return true, nil
+}
+
+func (m *Orders) ReleaseLease(ctx context.Context, orderID string, workerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if o, ok := m.orders[orderID]; ok && o.LockedBy != nil && *o.LockedBy == workerID {
+		o.LockedBy = nil
+		o.LeaseUntil = nil
+	}
+	return nil
+}
+
+func matchFilter(o *models.Order, f store.OrderFilter) bool {
+	switch {
+	case f.Status != "" && o.Status != f.Status,
+		f.Asset != "" && o.SourceAsset != f.Asset && o.TargetAsset != f.Asset,
+		f.TargetAddress != "" && o.TargetAddress != f.TargetAddress,
+		f.Sender != "" && (o.RefundToAddress == nil || *o.RefundToAddress != f.Sender),
+		f.PartnerID != "" && (o.PartnerID == nil || *o.PartnerID != f.PartnerID),
+		f.Venue != "" && (f.Venue != "exinswap" || o.ExinSwapTraceID == nil),
+		f.Search != "" && !matchSearch(o, f.Search),
+		f.After != nil && !o.CreatedAt.Before(f.After.CreatedAt) && !(o.CreatedAt.Equal(f.After.CreatedAt) && o.ID < f.After.ID),
+		!f.CreatedFrom.IsZero() && o.CreatedAt.Before(f.CreatedFrom),
+		!f.CreatedTo.IsZero() && !o.CreatedAt.Before(f.CreatedTo),
+		!f.UpdatedFrom.IsZero() && o.UpdatedAt.Before(f.UpdatedFrom),
+		!f.UpdatedBefore.IsZero() && !o.UpdatedAt.Before(f.UpdatedBefore):
+		return false
+	}
+	return true
+}
+
+func matchSearch(o *models.Order, q string) bool {
+	if o.ID == q || o.PublicID == q || o.TargetAddress == q {
+		return true
+	}
+	for _, v := range []*string{o.DepositTxID, o.WithdrawTxID, o.RefundTxID, o.RefundToAddress} {
+		if v != nil && *v == q {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Orders) CountOrders(ctx context.Context, f store.OrderFilter) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, o := range m.orders {
+		if matchFilter(o, f) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *Orders) ListOrderAmounts(ctx context.Context, f store.OrderFilter) ([]store.OrderAmount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []store.OrderAmount
+	for _, o := range m.orders {
+		if matchFilter(o, f) {
+			out = append(out, store.OrderAmount{CreatedAt: o.CreatedAt, Status: o.Status, AssetID: o.SourceAsset, AmountIn: o.AmountIn, AmountCredited: o.AmountCredited, FeeAmount: o.FeeAmount, PartnerFeeAmount: o.PartnerFeeAmount})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (m *Orders) ListOrders(ctx context.Context, f store.OrderFilter) ([]*models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*models.Order
+	for _, o := range m.orders {
+		if matchFilter(o, f) {
+			out = append(out, clone(o))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].ID > out[j].ID
+		}
+		return out[i].CreatedAt.After(out[j].CreatedAt)
+	})
+	limit := f.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if f.Offset >= len(out) {
+		return nil, nil
+	}
+	out = out[f.Offset:]
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (m *Orders) ForceRetry(ctx context.Context, orderID string, status models.OrderStatus) (bool, error) {
+	return m.update(orderID, []models.OrderStatus{status}, func(o *models.Order) {
+		o.NextAttemptAt = nil
+	}), nil
+}
+
+func (m *Orders) ReleaseFromReview(ctx context.Context, orderID string, from models.OrderStatus, stage models.Stage) (bool, error) {
+	to, ok := stage.Status()
+	if !ok {
+		return false, fmt.Errorf("unknown stage %q", stage)
+	}
+	if from != models.StatusFailedManual && from != models.StatusComplianceHold && from != models.StatusAwaitingLiquidity {
+		return false, fmt.Errorf("cannot release from %s", from)
+	}
+	return m.update(orderID, []models.OrderStatus{from}, func(o *models.Order) {
+		o.Status = to
+		o.RetryStage = strPtr(string(stage))
+		o.Attempts = 0
+		o.NextAttemptAt = nil
+		now := m.now()
+		if o.LimitsClearedAt == nil {
+			o.LimitsClearedAt = &now
+		}
+		if from == models.StatusComplianceHold && o.ScreeningClearedAt == nil {
+			o.ScreeningClearedAt = &now
+		}
+	}), nil
+}
+
+func (m *Orders) MarkComplianceHold(ctx context.Context, orderID string, from models.OrderStatus, stage models.Stage, reason string) (bool, error) {
+	return m.update(orderID, []models.OrderStatus{from}, func(o *models.Order) {
+		o.Status = models.StatusComplianceHold
+		o.RetryStage = strPtr(string(stage))
+		o.LastError = strPtr("screening: " + reason)
+		o.NextAttemptAt = nil
+	}), nil
+}
+
+func (m *Orders) MarkAwaitingLiquidity(ctx context.Context, orderID string, from models.OrderStatus, stage models.Stage, reason string) (bool, error) {
+	return m.update(orderID, []models.OrderStatus{from}, func(o *models.Order) {
+		o.Status = models.StatusAwaitingLiquidity
+		o.RetryStage = strPtr(string(stage))
+		o.LastError = strPtr("liquidity: " + reason)
+		o.NextAttemptAt = nil
+	}), nil
+}
+
+func (m *Orders) ListAwaitingLiquidity(ctx context.Context, limit int) ([]*models.Order, error) {
+	return m.list(limit, func(o *models.Order) bool { return o.Status == models.StatusAwaitingLiquidity }), nil
+}
+
+func (m *Orders) ResumeFromLiquidity(ctx context.Context, orderID string, stage models.Stage) (bool, error) {
+	to, ok := stage.Status()
+	if !ok {
+		return false, fmt.Errorf("unknown stage %q", stage)
+	}
+	return m.update(orderID, []models.OrderStatus{models.StatusAwaitingLiquidity}, func(o *models.Order) {
+		o.Status = to
+		o.LastError = nil
+	}), nil
+}
+
+func (m *Orders) MarkManualRefund(ctx context.Context, orderID string, from models.OrderStatus, refundAssetID, refundAmount string) (bool, error) {
+	return m.update(orderID, []models.OrderStatus{from}, func(o *models.Order) {
+		o.Status = models.StatusRefunding
+		if o.RefundAssetID == nil && refundAssetID != "" {
+			o.RefundAssetID = strPtr(refundAssetID)
+		}
+		if o.RefundAmount == nil && refundAmount != "" {
+			o.RefundAmount = strPtr(refundAmount)
+		}
+		o.RetryStage = strPtr(string(models.StageRefund))
+		o.Attempts = 0
+		o.NextAttemptAt = nil
+		if from == models.StatusComplianceHold && o.ScreeningClearedAt == nil {
+			now := m.now()
+			o.ScreeningClearedAt = &now
+		}
+	}), nil
+}
+
+func (m *Orders) ListVolume(ctx context.Context, f store.VolumeFilter) ([]store.AssetAmount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []store.AssetAmount
+	for _, o := range m.orders {
+		switch {
+		case o.CreatedAt.Before(f.Since),
+			o.Status == models.StatusRefunding || o.Status == models.StatusRefunded,
+			(o.Status == models.StatusQuoteCreated || o.Status == models.StatusAwaitingDeposit) && o.CreatedAt.Before(f.UnpaidSince),
+			f.TargetAddress != "" && o.TargetAddress != f.TargetAddress,
+			f.RefundTo != "" && (o.RefundToAddress == nil || *o.RefundToAddress != f.RefundTo),
+			f.ExcludeOrderID != "" && o.ID == f.ExcludeOrderID:
+			continue
+		}
+		amount := o.AmountIn
+		if o.AmountCredited != nil {
+			amount = *o.AmountCredited
+		}
+		out = append(out, store.AssetAmount{AssetID: o.SourceAsset, Amount: amount})
+	}
+	return out, nil
+}
+
+func (m *Orders) MarkLimitsCleared(ctx context.Context, orderID string) error {
+	m.update(orderID, nil, func(o *models.Order) {
+		if o.LimitsClearedAt == nil {
+			t := m.now()
+			o.LimitsClearedAt = &t
+		}
+	})
+	return nil
+}
+
+func (m *Orders) InsertIdempotencyKey(ctx context.Context, k *models.IdempotencyKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := k.Scope + "\x00" + k.Key
+	if _, ok := m.idem[id]; ok {
+		return store.ErrConflict
+	}
+	m.idem[id] = *k
+	return nil
+}
+
+func (m *Orders) GetIdempotencyKey(ctx context.Context, scope, key string) (*models.IdempotencyKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.idem[scope+"\x00"+key]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &k, nil
+}
+
+func (m *Orders) RecordFee(ctx context.Context, orderID, feeAmount, partnerFeeAmount string) error {
+	m.update(orderID, nil, func(o *models.Order) {
+		o.FeeAmount = strPtr(feeAmount)
+		o.PartnerFeeAmount = strPtr(partnerFeeAmount)
+	})
+	return nil
+}
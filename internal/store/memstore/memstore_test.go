@@ -0,0 +1,36 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// The SQL repo's conflicts (see db.TestInsertConflict and TestIdempotencyKey)
+// are what callers rely on, so memstore returns the same errors.
+func TestInsertConflict(t *testing.T) {
+	ctx := context.Background()
+	m := NewOrders()
+	o := &models.Order{ID: "o1", PublicID: "p1", Status: models.StatusAwaitingDeposit, CreatedAt: time.Now()}
+	if err := m.Insert(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Insert(ctx, o); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("same id = %v, want ErrConflict", err)
+	}
+	if err := m.Insert(ctx, &models.Order{ID: "o2", PublicID: "p1"}); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("same public id = %v, want ErrConflict", err)
+	}
+
+	k := &models.IdempotencyKey{Scope: "ip:203.0.113.1", Key: "k1", RequestHash: "h", OrderID: "o1"}
+	if err := m.InsertIdempotencyKey(ctx, k); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.InsertIdempotencyKey(ctx, k); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("same idempotency key = %v, want ErrConflict", err)
+	}
+}
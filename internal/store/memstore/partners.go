@@ -0,0 +1,86 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+// Partners is an in-memory store.PartnerStore.
+type Partners struct {
+	mu       sync.Mutex
+	partners map[string]*models.Partner
+}
+
+func NewPartners() *Partners { return &Partners{partners: map[string]*models.Partner{}} }
+
+var _ store.PartnerStore = (*Partners)(nil)
+
+func (m *Partners) ListPartners(ctx context.Context) ([]*models.Partner, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*models.Partner
+	for _, p := range m.partners {
+		c := *p
+		out = append(out, &c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func (m *Partners) GetPartner(ctx context.Context, id string) (*models.Partner, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.partners[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	c := *p
+	return &c, nil
+}
+
+func (m *Partners) GetPartnerByKey(ctx context.Context, apiKey string) (*models.Partner, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range m.partners {
+		if p.APIKey == apiKey {
+			c := *p
+			return &c, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (m *Partners) InsertPartner(ctx context.Context, p *models.Partner) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.partners {
+		if e.ID == p.ID || e.APIKey == p.APIKey {
+			return fmt.Errorf("insert partner %s: %w", p.ID, store.ErrConflict)
+		}
+	}
+	c := *p
+	m.partners[p.ID] = &c
+	return nil
+}
+
+func (m *Partners) UpdatePartner(ctx context.Context, p *models.Partner) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.partners[p.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	e.Name, e.Secret, e.FeeShareBps, e.DailyOrderQuota = p.Name, p.Secret, p.FeeShareBps, p.DailyOrderQuota
+	e.RateLimitPerMin, e.Disabled, e.UpdatedAt = p.RateLimitPerMin, p.Disabled, p.UpdatedAt
+	return nil
+}
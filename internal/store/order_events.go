@@ -0,0 +1,18 @@
+package store
+
+import (
+	"context"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+// OrderEventStore reads the order change feed (order_events), which the
+// database fills on every order insert and status change, whichever process
+// made it.
+type OrderEventStore interface {
+	// LatestOrderEventID returns the id of orderID's newest event, 0 if none.
+	LatestOrderEventID(ctx context.Context, orderID string) (int64, error)
+	// ListOrderEvents returns up to limit events of orderIDs with id > afterID,
+	// oldest first. Events of one order are committed in id order.
+	ListOrderEvents(ctx context.Context, orderIDs []string, afterID int64, limit int) ([]*models.OrderEvent, error)
+}
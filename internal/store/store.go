@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+// ErrNotFound is returned by single-order lookups when no row matches.
+var ErrNotFound = errors.New("order not found")
+
+// ErrConflict is returned by inserts whose unique key already exists.
+var ErrConflict = errors.New("already exists")
+
+// OrderStore is the order persistence surface used by executors, the worker
+// and the API. db.OrdersRepo is the SQL implementation; memstore.Orders is an
+// in-memory fake for unit tests.
+//
+// Status transitions are conditional on the current status so concurrent
+// callers cannot move an order backwards; they return nil when the guard
+// does not match.
+type OrderStore interface {
+	// InTx runs fn against a store bound to a single transaction. fn's
+	// changes commit together if it returns nil and roll back otherwise.
+	InTx(ctx context.Context, fn func(tx OrderStore) error) error
+
+	// Insert returns ErrConflict if the id or public id is already taken.
+	Insert(ctx context.Context, o *models.Order) error
+	GetByID(ctx context.Context, id string) (*models.Order, error)
+	GetByPublicID(ctx context.Context, publicID string) (*models.Order, error)
+	GetByExinSwapTraceID(ctx context.Context, traceID string) (*models.Order, error)
+	GetByDepositTxID(ctx context.Context, txID string) (*models.Order, error)
+	// GetByTransferTraceID matches the swap, withdraw or refund trace id.
+	GetByTransferTraceID(ctx context.Context, traceID string) (*models.Order, error)
+
+	// Deposits
+	SetDepositCreditedByMemo(ctx context.Context, memo string, snapshotID string, creditedAt time.Time, amountCredited string, assetID string, opponentID string) (int64, error)
+
+	// Executor queues. ListExecutable also returns executing_swap orders whose
+	// swap transfer failed and is due for another attempt (retry_stage swap).
+	ListExecutable(ctx context.Context, limit int) ([]*models.Order, error)
+	ListWithdrawing(ctx context.Context, limit int) ([]*models.Order, error)
+	ListRefunding(ctx context.Context, limit int) ([]*models.Order, error)
+
+	// Transitions
+	TryMarkExecutingSwap(ctx context.Context, orderID string) (bool, error)
+	SetExinSwapTraceID(ctx context.Context, orderID string, traceID string) error
+	SetWithdrawTraceID(ctx context.Context, orderID string, traceID string) error
+	SetRefundTraceID(ctx context.Context, orderID string, traceID string) error
+	MarkWithdrawing(ctx context.Context, orderID string, swapRef string, finalOut string) error
+	MarkCompleted(ctx context.Context, orderID string, withdrawTxID string) error
+	MarkRefunding(ctx context.Context, orderID string, reason string) error
+	MarkRefundingWithDetails(ctx context.Context, orderID, refundAssetID, refundAmount, refundReceivedSnapshotID string) error
+	MarkRefunded(ctx context.Context, orderID string, refundTxID string) error
+
+	// Fees: RecordFee stores the fee retained at swap time; a refunded swap
+	// records "0".
+	RecordFee(ctx context.Context, orderID, feeAmount, partnerFeeAmount string) error
+
+	// Retries
+	ScheduleRetry(ctx context.Context, orderID string, status models.OrderStatus, stage models.Stage, attempts int64, lastError string, nextAttemptAt time.Time) error
+	// ClearRetry drops the retry state (keeping last_error) once an attempt
+	// succeeded without moving the order on. Transitions to the next stage
+	// (MarkWithdrawing, MarkRefunding*) clear it too.
+	ClearRetry(ctx context.Context, orderID string, status models.OrderStatus) error
+	MarkFailedManual(ctx context.Context, orderID string, status models.OrderStatus, stage models.Stage, attempts int64, lastError string) error
+
+	// Worker leases
+	TryLease(ctx context.Context, orderID string, status models.OrderStatus, workerID string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, orderID string, workerID string) error
+
+	// Screening
+	MarkComplianceHold(ctx context.Context, orderID string, from models.OrderStatus, stage models.Stage, reason string) (bool, error)
+
+	// Liquidity: payouts the wallet cannot cover wait in awaiting_liquidity
+	// (stage in retry_stage) until ResumeFromLiquidity.
+	MarkAwaitingLiquidity(ctx context.Context, orderID string, from models.OrderStatus, stage models.Stage, reason string) (bool, error)
+	ListAwaitingLiquidity(ctx context.Context, limit int) ([]*models.Order, error)
+	ResumeFromLiquidity(ctx context.Context, orderID string, stage models.Stage) (bool, error)
+
+	// Limits
+	ListVolume(ctx context.Context, f VolumeFilter) ([]AssetAmount, error)
+	MarkLimitsCleared(ctx context.Context, orderID string) error
+
+	// Idempotency keys for order creation. InsertIdempotencyKey returns
+	// ErrConflict if (scope, key) is already taken.
+	InsertIdempotencyKey(ctx context.Context, k *models.IdempotencyKey) error
+	GetIdempotencyKey(ctx context.Context, scope, key string) (*models.IdempotencyKey, error)
+
+	// Operator actions (admin API)
+	ListOrders(ctx context.Context, f OrderFilter) ([]*models.Order, error)
+	CountOrders(ctx context.Context, f OrderFilter) (int64, error)
+	// ListOrderAmounts returns every order matching f (Limit/Offset ignored).
+	ListOrderAmounts(ctx context.Context, f OrderFilter) ([]OrderAmount, error)
+	ForceRetry(ctx context.Context, orderID string, status models.OrderStatus) (bool, error)
+	ReleaseFromReview(ctx context.Context, orderID string, from models.OrderStatus, stage models.Stage) (bool, error)
+	MarkManualRefund(ctx context.Context, orderID string, from models.OrderStatus, refundAssetID, refundAmount string) (bool, error)
+}
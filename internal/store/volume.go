@@ -0,0 +1,37 @@
+package store
+
+import (
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+// VolumeFilter selects the orders counted toward a velocity cap.
+// Refunding/refunded orders never count, and unpaid quotes only count while
+// they are younger than UnpaidSince.
+type VolumeFilter struct {
+	Since          time.Time
+	UnpaidSince    time.Time
+	TargetAddress  string
+	RefundTo       string
+	ExcludeOrderID string
+}
+
+// AssetAmount is one order's contribution to a volume sum: the credited
+// amount if known, the requested amount_in otherwise.
+type AssetAmount struct {
+	AssetID string
+	Amount  string
+}
+
+// OrderAmount is the per-order row behind volume reports.
+type OrderAmount struct {
+	CreatedAt      time.Time
+	Status         models.OrderStatus
+	AssetID        string
+	AmountIn       string
+	AmountCredited *string
+
+	FeeAmount        *string
+	PartnerFeeAmount *string
+}
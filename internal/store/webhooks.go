@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+// DeliveryFilter narrows ListWebhookDeliveries. Empty fields match
+// everything; results are newest first.
+type DeliveryFilter struct {
+	PartnerID  string
+	EndpointID string
+	OrderID    string
+	Status     models.DeliveryStatus
+	Limit      int
+	Offset     int
+}
+
+// WebhookStore holds partner callback endpoints, the order change feed they
+// are fed from and the delivery log.
+type WebhookStore interface {
+	ListWebhookEndpoints(ctx context.Context, partnerID string) ([]*models.WebhookEndpoint, error)
+	GetWebhookEndpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error)
+	InsertWebhookEndpoint(ctx context.Context, e *models.WebhookEndpoint) error
+	// DeleteWebhookEndpoint removes partnerID's endpoint id; false if none.
+	DeleteWebhookEndpoint(ctx context.Context, partnerID, id string) (bool, error)
+
+	// ListUndispatchedEvents returns the oldest events no deliveries were
+	// queued for yet.
+	ListUndispatchedEvents(ctx context.Context, limit int) ([]*models.OrderEvent, error)
+	MarkEventDispatched(ctx context.Context, eventID int64, at time.Time) error
+
+	// InsertWebhookDelivery queues d; it is a no-op if the endpoint already
+	// has a delivery for the event.
+	InsertWebhookDelivery(ctx context.Context, d *models.WebhookDelivery) error
+	// ListDueDeliveries returns pending deliveries whose next attempt is due,
+	// oldest first and at most perPartner per partner, so one partner's
+	// backlog does not crowd out the others.
+	ListDueDeliveries(ctx context.Context, now time.Time, perPartner, limit int) ([]*models.WebhookDelivery, error)
+	// ClaimDelivery pushes a due delivery's next attempt to until, so other
+	// workers skip it while this one sends. False if it is no longer due.
+	ClaimDelivery(ctx context.Context, id string, now, until time.Time) (bool, error)
+	// RecordDeliveryAttempt stores the outcome of one attempt.
+	RecordDeliveryAttempt(ctx context.Context, d *models.WebhookDelivery) error
+	GetWebhookDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, f DeliveryFilter) ([]*models.WebhookDelivery, error)
+	// RedeliverWebhook makes a delivery pending and due now, with a fresh
+	// attempt budget.
+	RedeliverWebhook(ctx context.Context, id string) (bool, error)
+}
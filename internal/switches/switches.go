@@ -0,0 +1,154 @@
+// Package switches holds the emergency kill-switches operators flip at runtime.
+//
+// Switches live in the kv table under "switch.<kind>.<name>", so the API and
+// every worker replica see the same state without a restart. A key that is
+// present means "off"; removing it turns the chain/asset/stage back on.
+package switches
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+)
+
+const keyPrefix = "switch."
+
+type Kind string
+
+const (
+	// KindChain and KindAsset reject new orders touching the chain/asset.
+	KindChain Kind = "chain"
+	KindAsset Kind = "asset"
+	// KindStage pauses an executor stage (swap, withdraw, refund).
+	KindStage Kind = "stage"
+)
+
+// Switch is one disabled chain, asset or stage.
+type Switch struct {
+	Kind     Kind      `json:"kind"`
+	Name     string    `json:"name"`
+	Reason   string    `json:"reason"`
+	Operator string    `json:"operator"`
+	Since    time.Time `json:"since"`
+}
+
+// Normalize validates kind/name and canonicalizes name (chains upper-case,
+// asset ids lower-case) so lookups are case-insensitive.
+func Normalize(kind Kind, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("empty %s name", kind)
+	}
+	switch kind {
+	case KindChain:
+		return strings.ToUpper(name), nil
+	case KindAsset:
+		return strings.ToLower(name), nil
+	case KindStage:
+		st := models.Stage(strings.ToLower(name))
+		if _, ok := st.Status(); !ok {
+			return "", fmt.Errorf("unknown stage %q", name)
+		}
+		return string(st), nil
+	}
+	return "", fmt.Errorf("unknown switch kind %q", kind)
+}
+
+func key(kind Kind, name string) string {
+	return keyPrefix + string(kind) + "." + name
+}
+
+// Disable turns a chain, asset or stage off.
+func Disable(ctx context.Context, kv store.KVStore, sw Switch) error {
+	name, err := Normalize(sw.Kind, sw.Name)
+	if err != nil {
+		return err
+	}
+	sw.Name = name
+	raw, err := json.Marshal(sw)
+	if err != nil {
+		return err
+	}
+	return kv.Set(ctx, key(sw.Kind, name), string(raw))
+}
+
+// Enable turns a chain, asset or stage back on (no-op if it was not off).
+func Enable(ctx context.Context, kv store.KVStore, kind Kind, name string) error {
+	name, err := Normalize(kind, name)
+	if err != nil {
+		return err
+	}
+	return kv.Delete(ctx, key(kind, name))
+}
+
+// State is a point-in-time view of every switch that is off.
+type State struct {
+	Chains map[string]Switch       `json:"chains"`
+	Assets map[string]Switch       `json:"assets"`
+	Stages map[models.Stage]Switch `json:"stages"`
+}
+
+// Load reads all switches. Callers load once per request or worker tick.
+func Load(ctx context.Context, kv store.KVStore) (*State, error) {
+	rows, err := kv.List(ctx, keyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("load switches: %w", err)
+	}
+	st := &State{Chains: map[string]Switch{}, Assets: map[string]Switch{}, Stages: map[models.Stage]Switch{}}
+	for k, v := range rows {
+		var sw Switch
+		if err := json.Unmarshal([]byte(v), &sw); err != nil {
+			return nil, fmt.Errorf("load switches: %s: %w", k, err)
+		}
+		switch sw.Kind {
+		case KindChain:
+			st.Chains[sw.Name] = sw
+		case KindAsset:
+			st.Assets[sw.Name] = sw
+		case KindStage:
+			st.Stages[models.Stage(sw.Name)] = sw
+		}
+	}
+	return st, nil
+}
+
+// StageGate loads the switches for one worker tick and returns a func
+// reporting whether a stage is off. If the switches cannot be read it
+// returns the error with a gate that reports every stage off, so the worker
+// fails closed.
+func StageGate(ctx context.Context, kv store.KVStore) (func(models.Stage) (Switch, bool), error) {
+	st, err := Load(ctx, kv)
+	if err != nil {
+		return func(stage models.Stage) (Switch, bool) {
+			return Switch{Kind: KindStage, Name: string(stage), Reason: "switches unavailable"}, true
+		}, err
+	}
+	return st.StagePaused, nil
+}
+
+// StagePaused reports whether the executor stage is switched off.
+func (s *State) StagePaused(stage models.Stage) (Switch, bool) {
+	sw, ok := s.Stages[stage]
+	return sw, ok
+}
+
+// CheckNewOrder returns the first switch that blocks an order between the
+// given chains/assets, if any.
+func (s *State) CheckNewOrder(sourceChain, sourceAsset, targetChain, targetAsset string) (Switch, bool) {
+	for _, c := range []string{sourceChain, targetChain} {
+		if sw, ok := s.Chains[strings.ToUpper(c)]; ok {
+			return sw, true
+		}
+	}
+	for _, a := range []string{sourceAsset, targetAsset} {
+		if sw, ok := s.Assets[strings.ToLower(a)]; ok {
+			return sw, true
+		}
+	}
+	return Switch{}, false
+}
@@ -0,0 +1,111 @@
+package switches
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/store/memstore"
+)
+
+// brokenKV fails every List, as a lost DB connection would.
+type brokenKV struct{ *memstore.KV }
+
+func (brokenKV) List(ctx context.Context, prefix string) (map[string]string, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestNormalize(t *testing.T) {
+	for _, tc := range []struct {
+		kind      Kind
+		name      string
+		want      string
+		wantError bool
+	}{
+		{KindChain, " eth ", "ETH", false},
+		{KindAsset, "C6D0C728-2624-429B-8E0D-D9D19B6592FA", "c6d0c728-2624-429b-8e0d-d9d19b6592fa", false},
+		{KindStage, "Withdraw", "withdraw", false},
+		{KindStage, "teleport", "", true},
+		{KindChain, "  ", "", true},
+		{"network", "ETH", "", true},
+	} {
+		got, err := Normalize(tc.kind, tc.name)
+		if (err != nil) != tc.wantError || got != tc.want {
+			t.Errorf("Normalize(%s, %q) = %q, %v", tc.kind, tc.name, got, err)
+		}
+	}
+}
+
+func TestCheckNewOrder(t *testing.T) {
+	ctx := context.Background()
+	kv := memstore.NewKV()
+	for _, sw := range []Switch{
+		{Kind: KindChain, Name: "eth", Reason: "reorg"},
+		{Kind: KindAsset, Name: "ABC", Reason: "depeg"},
+	} {
+		if err := Disable(ctx, kv, sw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st, err := Load(ctx, kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		srcChain, srcAsset, dstChain, dstAsset string
+		want                                   string // blocking switch name
+	}{
+		{"MIXIN", "src", "ETH", "dst", "ETH"},
+		{"eth", "src", "BTC", "dst", "ETH"},
+		{"MIXIN", "abc", "BTC", "dst", "abc"},
+		{"MIXIN", "src", "BTC", "Abc", "abc"},
+		{"MIXIN", "src", "BTC", "dst", ""},
+	} {
+		sw, off := st.CheckNewOrder(tc.srcChain, tc.srcAsset, tc.dstChain, tc.dstAsset)
+		if off != (tc.want != "") || sw.Name != tc.want {
+			t.Errorf("CheckNewOrder(%s, %s, %s, %s) = %q %v, want %q", tc.srcChain, tc.srcAsset, tc.dstChain, tc.dstAsset, sw.Name, off, tc.want)
+		}
+	}
+
+	if err := Enable(ctx, kv, KindChain, "Eth"); err != nil {
+		t.Fatal(err)
+	}
+	if st, err = Load(ctx, kv); err != nil {
+		t.Fatal(err)
+	}
+	if _, off := st.CheckNewOrder("MIXIN", "src", "ETH", "dst"); off {
+		t.Error("ETH still off after Enable")
+	}
+}
+
+func TestStageGate(t *testing.T) {
+	ctx := context.Background()
+	kv := memstore.NewKV()
+	if err := Disable(ctx, kv, Switch{Kind: KindStage, Name: "withdraw", Operator: "alice", Reason: "incident"}); err != nil {
+		t.Fatal(err)
+	}
+	gate, err := StageGate(ctx, kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sw, off := gate(models.StageWithdraw); !off || sw.Operator != "alice" || sw.Reason != "incident" {
+		t.Errorf("withdraw: %+v %v, want paused by alice", sw, off)
+	}
+	for _, stage := range []models.Stage{models.StageSwap, models.StageRefund} {
+		if _, off := gate(stage); off {
+			t.Errorf("%s paused", stage)
+		}
+	}
+
+	// Unreadable switches pause every stage.
+	gate, err = StageGate(ctx, brokenKV{memstore.NewKV()})
+	if err == nil {
+		t.Fatal("want load error")
+	}
+	for _, stage := range []models.Stage{models.StageSwap, models.StageWithdraw, models.StageRefund} {
+		if _, off := gate(stage); !off {
+			t.Errorf("%s runs while switches are unreadable", stage)
+		}
+	}
+}
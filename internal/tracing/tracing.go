@@ -0,0 +1,158 @@
+// Package tracing sets up OpenTelemetry tracing for the bridge binaries.
+//
+// Spans are opened around repo statements (internal/db), venue calls and HTTP
+// requests. The W3C traceparent of the API request that created an order is
+// stored on the order (trace_context), and worker spans for that order link
+// back to it, so an order's path can be followed from the create call to the
+// payout even though the worker runs in a separate trace per tick.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+)
+
+const instrumentation = "github.com/mvg-fi-dev/bridge"
+
+// Exporters accepted by Setup.
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+var propagator = propagation.TraceContext{}
+
+// enabled is set once Setup installed a recording provider; hot paths skip
+// building span names otherwise.
+var enabled bool
+
+// Enabled reports whether spans are exported.
+func Enabled() bool { return enabled }
+
+// Setup installs the global tracer provider for service. exporter is one of
+// none (spans are not recorded), otlp (OTLP/HTTP, configured through the
+// standard OTEL_EXPORTER_OTLP_* variables) or stdout. The returned function
+// flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context, exporter, service string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+
+	var exp sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exp, err = otlptracehttp.New(ctx)
+	case ExporterStdout, "console":
+		exp, err = stdouttrace.New()
+	default:
+		return nil, fmt.Errorf("invalid traces exporter %q (want none, otlp or stdout)", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %s exporter: %w", exporter, err)
+	}
+
+	res, err := sdkresource.Merge(sdkresource.Default(), sdkresource.NewSchemaless(semconv.ServiceName(service)))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: resource: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	enabled = true
+	return tp.Shutdown, nil
+}
+
+// Start opens a span named name as a child of the span in ctx, if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartLinked is Start for work on behalf of an earlier request: the span
+// links to the span encoded in traceparent (see Inject).
+func StartLinked(ctx context.Context, name, traceparent string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{trace.WithAttributes(attrs...)}
+	if traceparent != "" {
+		if sc := trace.SpanContextFromContext(Extract(context.Background(), traceparent)); sc.IsValid() {
+			opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+		}
+	}
+	return otel.Tracer(instrumentation).Start(ctx, name, opts...)
+}
+
+// End records err (if any) on span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		msg := logging.Scrub(err.Error())
+		span.RecordError(errors.New(msg))
+		span.SetStatus(codes.Error, msg)
+	}
+	span.End()
+}
+
+// Inject returns the W3C traceparent of the span in ctx, or "" if there is none.
+func Inject(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// Extract returns ctx carrying the remote span encoded in traceparent.
+func Extract(ctx context.Context, traceparent string) context.Context {
+	return propagator.Extract(ctx, propagation.MapCarrier{"traceparent": traceparent})
+}
+
+// ExtractHTTP returns ctx carrying the caller's span from h, if it sent one.
+func ExtractHTTP(ctx context.Context, h http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(h))
+}
+
+// Transport wraps base (http.DefaultTransport if nil) so each outbound request
+// gets a client span and carries the traceparent header.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer(instrumentation).Start(req.Context(), "HTTP "+req.Method+" "+req.URL.Host,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.ServerAddress(req.URL.Host),
+			semconv.URLPath(req.URL.Path),
+		),
+	)
+	req = req.Clone(ctx)
+	propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+		if resp.StatusCode >= 500 {
+			span.SetStatus(codes.Error, resp.Status)
+		}
+	}
+	End(span, err)
+	return resp, err
+}
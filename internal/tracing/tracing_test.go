@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+)
+
+// record installs a provider that keeps ended spans in memory.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	return rec
+}
+
+func TestPropagationAndLinks(t *testing.T) {
+	rec := record(t)
+
+	ctx, span := Start(context.Background(), "api.create")
+	tp := Inject(ctx)
+	if tp == "" {
+		t.Fatal("no traceparent for a recording span")
+	}
+	if got := trace.SpanContextFromContext(Extract(context.Background(), tp)); got.TraceID() != span.SpanContext().TraceID() {
+		t.Errorf("extracted trace %s, want %s", got.TraceID(), span.SpanContext().TraceID())
+	}
+	End(span, nil)
+	if Inject(context.Background()) != "" {
+		t.Error("traceparent without a span")
+	}
+
+	// Worker spans start their own trace and link to the create call.
+	_, linked := StartLinked(context.Background(), "worker.swap", tp)
+	End(linked, nil)
+	_, bad := StartLinked(context.Background(), "worker.swap", "garbage")
+	End(bad, nil)
+
+	spans := rec.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("ended %d spans, want 3", len(spans))
+	}
+	if l := spans[1].Links(); len(l) != 1 || l[0].SpanContext.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("links = %+v, want the create span", l)
+	}
+	if spans[1].SpanContext().TraceID() == span.SpanContext().TraceID() {
+		t.Error("linked span joined the create trace")
+	}
+	if l := spans[2].Links(); len(l) != 0 {
+		t.Errorf("invalid traceparent linked %+v", l)
+	}
+}
+
+func TestEndScrubsErrors(t *testing.T) {
+	rec := record(t)
+	logging.AddSecrets("super-secret-pin-1234")
+
+	_, span := Start(context.Background(), "mixin.transfer")
+	End(span, errors.New("bad pin super-secret-pin-1234"))
+
+	s := rec.Ended()[0]
+	if s.Status().Code != codes.Error || s.Status().Description != "bad pin [REDACTED]" {
+		t.Errorf("status = %+v", s.Status())
+	}
+	if ev := s.Events(); len(ev) != 1 || ev[0].Name != "exception" {
+		t.Errorf("events = %+v", ev)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	rec := record(t)
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	ctx, parent := Start(context.Background(), "tick")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/quote", nil)
+	resp, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	End(parent, nil)
+
+	client := rec.Ended()[0]
+	if client.SpanKind() != trace.SpanKindClient || client.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("client span kind %s parent %s", client.SpanKind(), client.Parent().SpanID())
+	}
+	if got == "" || got != Inject(trace.ContextWithSpanContext(context.Background(), client.SpanContext())) {
+		t.Errorf("traceparent sent = %q, want the client span's", got)
+	}
+	if client.Status().Code != codes.Error {
+		t.Errorf("status = %+v, want an error for a 5xx", client.Status())
+	}
+	if req.Header.Get("traceparent") != "" {
+		t.Error("the caller's request was modified")
+	}
+}
+
+func TestSetup(t *testing.T) {
+	shutdown, err := Setup(context.Background(), ExporterNone, "test")
+	if err != nil || shutdown(context.Background()) != nil || Enabled() {
+		t.Fatalf("none: err %v enabled %v", err, Enabled())
+	}
+	if _, err := Setup(context.Background(), "jaeger", "test"); err == nil {
+		t.Error("accepted an unknown exporter")
+	}
+}
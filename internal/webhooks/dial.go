@@ -0,0 +1,67 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned when a callback URL resolves to an address
+// partners must not reach through the bridge.
+var ErrBlockedAddress = errors.New("webhook address not allowed")
+
+// blockedPrefixes are the non-public ranges netip does not classify:
+// "this network", carrier-grade NAT, benchmarking and reserved.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// PublicAddr reports whether ip is a public unicast address: not loopback,
+// private, link-local, unspecified, multicast or one of blockedPrefixes.
+func PublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// transport dials partner endpoints. Unless allowPrivate is set, the address
+// is checked after DNS resolution, right before connecting, so a public name
+// pointing at (or rebinding to) an internal address is refused. Proxies are
+// not used since they would connect on the bridge's behalf unchecked.
+func transport(allowPrivate bool) *http.Transport {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !PublicAddr(ip) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, ip)
+			}
+			return nil
+		}
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = dialer.DialContext
+	return t
+}
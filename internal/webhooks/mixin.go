@@ -3,20 +3,19 @@ package webhooks
 import (
 	"crypto/hmac"
 	"crypto/sha256"
-	"database/sql"
 	"encoding/hex"
 	"io"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/mvg-fi-dev/bridge/internal/db"
 	"github.com/mvg-fi-dev/bridge/internal/mixin"
+	"github.com/mvg-fi-dev/bridge/internal/store"
 )
 
 type MixinWebhookHandler struct {
-	Secret string
-	DB     *sql.DB
+	Secret         string
+	Orders         store.OrderStore
 	MixinBotUserID string
 }
 
@@ -70,9 +69,8 @@ func (h *MixinWebhookHandler) Handle(c *gin.Context) {
 		creditedAt = createdAt.UTC()
 	}
 
-	if h.DB != nil && snap.Memo != "" {
-		repo := db.NewOrdersRepo(h.DB)
-		_, _ = repo.SetDepositCreditedByMemo(c.Request.Context(), snap.Memo, snap.SnapshotID, creditedAt, snap.Amount, snap.AssetID, snap.OpponentID)
+	if h.Orders != nil && snap.Memo != "" {
+		_, _ = h.Orders.SetDepositCreditedByMemo(c.Request.Context(), snap.Memo, snap.SnapshotID, creditedAt, snap.Amount, snap.AssetID, snap.OpponentID)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"ok": true})
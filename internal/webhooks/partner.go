@@ -0,0 +1,317 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/ids"
+	"github.com/mvg-fi-dev/bridge/internal/logging"
+	"github.com/mvg-fi-dev/bridge/internal/models"
+	"github.com/mvg-fi-dev/bridge/internal/orderview"
+	"github.com/mvg-fi-dev/bridge/internal/retry"
+	"github.com/mvg-fi-dev/bridge/internal/store"
+	"github.com/mvg-fi-dev/bridge/internal/tracing"
+)
+
+// EventVersion is the version of the Event schema. Fields may be added
+// without bumping it; renames and removals bump it.
+const EventVersion = 1
+
+// Event is the JSON body POSTed to partner endpoints for each order status
+// transition. ID is the same on every retry, so receivers can dedupe on it.
+// Deliveries may arrive out of order (a retried one overtakes the next);
+// Sequence, the order event's id, grows with each transition of an order, so
+// receivers can drop an event older than the last one they applied.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"` // "order." + status
+	Version   int       `json:"version"`
+	Sequence  int64     `json:"sequence"`
+	CreatedAt time.Time `json:"created_at"` // when the transition happened
+	Data      EventData `json:"data"`
+}
+
+// EventData carries the transition and the order as it was when the event
+// was queued (it may already be past Status).
+type EventData struct {
+	Status         models.OrderStatus  `json:"status"`
+	PreviousStatus *models.OrderStatus `json:"previous_status"`
+	Order          *orderview.Order    `json:"order"`
+	OrderVersion   int                 `json:"order_version"`
+}
+
+func NewEvent(e *models.OrderEvent, o *models.Order) *Event {
+	return &Event{
+		ID:        ids.DeterministicUUID(fmt.Sprintf("order_event:%d", e.ID)),
+		Type:      "order." + string(e.Status),
+		Version:   EventVersion,
+		Sequence:  e.ID,
+		CreatedAt: e.CreatedAt.UTC(),
+		Data: EventData{
+			Status:         e.Status,
+			PreviousStatus: e.PrevStatus,
+			Order:          orderview.New(o),
+			OrderVersion:   orderview.Version,
+		},
+	}
+}
+
+// Sign returns the X-Signature for body sent at timestamp (unix seconds):
+// hex(HMAC-SHA256(secret, timestamp + "\n" + body)).
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// claimTTL is how long a claimed delivery is hidden from other workers; it
+// outlasts the client timeout.
+const claimTTL = time.Minute
+
+// Dispatcher turns order change-feed events into deliveries for the owning
+// partner's endpoints and sends the deliveries that are due, retrying
+// failures with backoff until Retry.MaxAttempts.
+type Dispatcher struct {
+	Webhooks store.WebhookStore
+	Orders   store.OrderStore
+	Client   *http.Client
+	Retry    retry.Policy
+
+	// Partners are delivered to concurrently, up to Concurrency at once;
+	// each partner's deliveries go one at a time, at most PerPartner per
+	// run, so a slow endpoint only delays its own partner.
+	Concurrency int
+	PerPartner  int
+
+	Now func() time.Time
+}
+
+// NewDispatcher returns a dispatcher whose client refuses endpoints that
+// resolve to loopback, private or link-local addresses unless allowPrivate
+// is set (local testing only).
+func NewDispatcher(webhooks store.WebhookStore, orders store.OrderStore, policy retry.Policy, allowPrivate bool) *Dispatcher {
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: tracing.Transport(transport(allowPrivate)),
+		// A redirect would re-send the payload somewhere the partner did
+		// not register; treat it as a failed attempt instead.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	return &Dispatcher{
+		Webhooks:    webhooks,
+		Orders:      orders,
+		Client:      client,
+		Retry:       policy,
+		Concurrency: 8,
+		PerPartner:  5,
+		Now:         time.Now,
+	}
+}
+
+// Run queues deliveries for up to limit new events, then sends up to limit
+// due deliveries.
+func (d *Dispatcher) Run(ctx context.Context, limit int) error {
+	if err := d.Enqueue(ctx, limit); err != nil {
+		return err
+	}
+	return d.Deliver(ctx, limit)
+}
+
+// Enqueue queues one delivery per endpoint of the order's partner for each
+// undispatched event. Inserts are idempotent, so an event is safe to enqueue
+// again if marking it dispatched fails.
+func (d *Dispatcher) Enqueue(ctx context.Context, limit int) error {
+	events, err := d.Webhooks.ListUndispatchedEvents(ctx, limit)
+	if err != nil {
+		return err
+	}
+	endpoints := map[string][]*models.WebhookEndpoint{}
+	for _, e := range events {
+		if err := d.enqueue(ctx, e, endpoints); err != nil {
+			return fmt.Errorf("webhooks: event %d: %w", e.ID, err)
+		}
+		if err := d.Webhooks.MarkEventDispatched(ctx, e.ID, d.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) enqueue(ctx context.Context, e *models.OrderEvent, endpoints map[string][]*models.WebhookEndpoint) error {
+	o, err := d.Orders.GetByID(ctx, e.OrderID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if o.PartnerID == nil {
+		return nil
+	}
+	eps, ok := endpoints[*o.PartnerID]
+	if !ok {
+		if eps, err = d.Webhooks.ListWebhookEndpoints(ctx, *o.PartnerID); err != nil {
+			return err
+		}
+		endpoints[*o.PartnerID] = eps
+	}
+	if len(eps) == 0 {
+		return nil
+	}
+	ev := NewEvent(e, o)
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	now := d.Now().UTC()
+	for _, ep := range eps {
+		err := d.Webhooks.InsertWebhookDelivery(ctx, &models.WebhookDelivery{
+			ID:         ids.NewUUID(),
+			EndpointID: ep.ID,
+			PartnerID:  ep.PartnerID,
+			EventID:    e.ID,
+			OrderID:    o.ID,
+			EventType:  ev.Type,
+			Payload:    string(payload),
+			Status:     models.DeliveryPending,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deliver sends up to limit due deliveries. Each is claimed first so worker
+// replicas do not send the same delivery twice.
+func (d *Dispatcher) Deliver(ctx context.Context, limit int) error {
+	now := d.Now()
+	due, err := d.Webhooks.ListDueDeliveries(ctx, now, max(d.PerPartner, 1), limit)
+	if err != nil {
+		return err
+	}
+	var partners []string
+	byPartner := map[string][]*models.WebhookDelivery{}
+	for _, dl := range due {
+		if _, ok := byPartner[dl.PartnerID]; !ok {
+			partners = append(partners, dl.PartnerID)
+		}
+		byPartner[dl.PartnerID] = append(byPartner[dl.PartnerID], dl)
+	}
+
+	sem := make(chan struct{}, max(d.Concurrency, 1))
+	errs := make([]error, len(partners))
+	var wg sync.WaitGroup
+	for i, p := range partners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			errs[i] = d.deliverPartner(ctx, now, byPartner[p])
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// deliverPartner sends one partner's due deliveries in order, one at a time.
+func (d *Dispatcher) deliverPartner(ctx context.Context, now time.Time, due []*models.WebhookDelivery) error {
+	for _, dl := range due {
+		ok, err := d.Webhooks.ClaimDelivery(ctx, dl.ID, now, d.Now().Add(claimTTL))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := d.deliver(ctx, dl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver makes one attempt and records its outcome.
+func (d *Dispatcher) deliver(ctx context.Context, dl *models.WebhookDelivery) error {
+	ctx = logging.With(ctx, logging.OrderID, dl.OrderID, "delivery_id", dl.ID, "event_type", dl.EventType)
+	var code int
+	ep, err := d.Webhooks.GetWebhookEndpoint(ctx, dl.EndpointID)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		// The partner removed the endpoint; nothing left to retry.
+		dl.Attempts = d.Retry.MaxAttempts
+		err = fmt.Errorf("endpoint deleted")
+	case err != nil:
+		return err
+	default:
+		code, err = d.post(ctx, ep, dl)
+		dl.Attempts++
+	}
+
+	now := d.Now().UTC()
+	dl.UpdatedAt = now
+	dl.NextAttemptAt = nil
+	if code != 0 {
+		c := int64(code)
+		dl.LastStatusCode = &c
+	}
+	if err == nil {
+		dl.Status, dl.DeliveredAt, dl.LastError = models.DeliveryDelivered, &now, nil
+		slog.DebugContext(ctx, "webhook delivered", "attempts", dl.Attempts)
+		return d.Webhooks.RecordDeliveryAttempt(ctx, dl)
+	}
+	msg := logging.Scrub(err.Error())
+	dl.LastError = &msg
+	if dl.Attempts >= d.Retry.MaxAttempts {
+		dl.Status = models.DeliveryFailed
+		slog.WarnContext(ctx, "webhook delivery failed; giving up", "attempts", dl.Attempts, "err", msg)
+	} else {
+		next := now.Add(d.Retry.Delay(dl.Attempts))
+		dl.NextAttemptAt = &next
+		slog.InfoContext(ctx, "webhook delivery failed; will retry", "attempts", dl.Attempts, "next_attempt_at", next, "err", msg)
+	}
+	return d.Webhooks.RecordDeliveryAttempt(ctx, dl)
+}
+
+// post sends dl's payload to ep and returns the response status. Any
+// non-2xx status is an error.
+func (d *Dispatcher) post(ctx context.Context, ep *models.WebhookEndpoint, dl *models.WebhookDelivery) (int, error) {
+	body := []byte(dl.Payload)
+	ts := strconv.FormatInt(d.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "mvg-bridge-webhooks/1")
+	req.Header.Set("X-Webhook-Id", dl.ID)
+	req.Header.Set("X-Webhook-Event", dl.EventType)
+	req.Header.Set("X-Timestamp", ts)
+	req.Header.Set("X-Signature", Sign(ep.Secret, ts, body))
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
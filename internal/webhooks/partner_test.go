@@ -0,0 +1,31 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mvg-fi-dev/bridge/internal/models"
+)
+
+func TestEventCarriesSequence(t *testing.T) {
+	prev := models.StatusExecutingSwap
+	o := &models.Order{ID: "o1", PublicID: "BRG_1", Status: models.StatusCompleted}
+	e := &models.OrderEvent{ID: 42, OrderID: "o1", Status: models.StatusWithdrawing, PrevStatus: &prev, CreatedAt: time.Now()}
+
+	body, err := json.Marshal(NewEvent(e, o))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		ID       string `json:"id"`
+		Type     string `json:"type"`
+		Sequence int64  `json:"sequence"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Sequence != 42 || got.Type != "order.withdrawing" || got.ID == "" {
+		t.Errorf("event = %s", body)
+	}
+}